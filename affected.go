@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// affectedModule is a module with changes since the comparison base.
+type affectedModule struct {
+	module
+	Files []string `json:"files"`
+}
+
+// runAffected implements "bump affected": it prints the modules that have
+// changes since a tag, so CI can decide what to test and deploy.
+func runAffected(_ context.Context, output io.Writer, args []string, _ []string) error {
+	flagSet := flag.NewFlagSet("affected", flag.ContinueOnError)
+	since := flagSet.String("since", "", "Tag or commit to compare against (default: the last version tag).")
+	format := flagSet.String("format", "text", "Output format: text or json.")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if flagSet.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", flagSet.Args())
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("invalid format '%s': must be text or json", *format)
+	}
+
+	repo, err := git.PlainOpen(".")
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	if *since == "" {
+		*since, err = lastTag(repo)
+		if err != nil {
+			return fmt.Errorf("failed to get last tag: %w", err)
+		}
+	}
+	base, err := resolveCommit(repo, *since)
+	if err != nil {
+		return err
+	}
+	files, err := changedFiles(repo, base)
+	if err != nil {
+		return err
+	}
+	modules, err := findModules()
+	if err != nil {
+		return err
+	}
+
+	affected := affectedModules(modules, files)
+	if *format == "json" {
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			Since   string           `json:"since"`
+			Modules []affectedModule `json:"modules"`
+		}{Since: *since, Modules: affected})
+	}
+	for _, m := range affected {
+		_, _ = fmt.Fprintln(output, m.Path)
+	}
+	return nil
+}
+
+// affectedModules groups the changed files by the module owning them. Only
+// modules with changes are returned, in module order.
+func affectedModules(modules []module, files []string) []affectedModule {
+	byPath := make(map[string]*affectedModule)
+	for _, file := range files {
+		m, ok := moduleFor(modules, file)
+		if !ok {
+			continue
+		}
+		if byPath[m.Path] == nil {
+			byPath[m.Path] = &affectedModule{module: m}
+		}
+		byPath[m.Path].Files = append(byPath[m.Path].Files, file)
+	}
+	affected := []affectedModule{}
+	for _, m := range modules {
+		if a := byPath[m.Path]; a != nil {
+			affected = append(affected, *a)
+		}
+	}
+	return affected
+}
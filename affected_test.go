@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// commitFiles writes the given files and commits them
+func commitFiles(t *testing.T, repo *git.Repository, message string, files map[string]string) {
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range files {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = w.Add(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = w.Commit(message, &git.CommitOptions{
+		Author: &object.Signature{
+			Name:  "Test User",
+			Email: "test@example.com",
+			When:  time.Now(),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// setupMonorepo creates a tagged repository with a root module and the
+// modules ledger and services/payments
+func setupMonorepo(t *testing.T) *git.Repository {
+	tempDir, repo := setupTestRepo(t)
+	t.Chdir(tempDir)
+
+	commitFiles(t, repo, "Add modules", map[string]string{
+		".version":                   "v1.0.0",
+		"ledger/.version":            "v1.0.0",
+		"ledger/ledger.go":           "package ledger",
+		"services/payments/.version": "v1.0.0",
+		"services/payments/main.go":  "package main",
+	})
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.CreateTag("v1.0.0", head.Hash(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func TestAffected(t *testing.T) {
+	repo := setupMonorepo(t)
+	commitFiles(t, repo, "Change payments and docs", map[string]string{
+		"services/payments/main.go": "package main // changed",
+		"docs/index.md":             "# docs",
+	})
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"affected"}, nil)
+	if err != nil {
+		t.Fatalf("affected failed: %v", err)
+	}
+	want := ".\nservices/payments\n"
+	if output.String() != want {
+		t.Errorf("Expected output %q, got %q", want, output.String())
+	}
+}
+
+func TestAffectedJSON(t *testing.T) {
+	repo := setupMonorepo(t)
+	commitFiles(t, repo, "Change ledger", map[string]string{
+		"ledger/ledger.go": "package ledger // changed",
+	})
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"affected", "-since", "v1.0.0", "-format", "json"}, nil)
+	if err != nil {
+		t.Fatalf("affected failed: %v", err)
+	}
+	var result struct {
+		Since   string `json:"since"`
+		Modules []struct {
+			Name  string   `json:"name"`
+			Path  string   `json:"path"`
+			Files []string `json:"files"`
+		} `json:"modules"`
+	}
+	err = json.Unmarshal(output.Bytes(), &result)
+	if err != nil {
+		t.Fatalf("Invalid JSON output: %v\n%s", err, output.String())
+	}
+	if result.Since != "v1.0.0" {
+		t.Errorf("Expected since v1.0.0, got %q", result.Since)
+	}
+	if len(result.Modules) != 1 {
+		t.Fatalf("Expected one affected module, got %+v", result.Modules)
+	}
+	m := result.Modules[0]
+	if m.Name != "ledger" || m.Path != "ledger" || len(m.Files) != 1 || m.Files[0] != "ledger/ledger.go" {
+		t.Errorf("Unexpected affected module: %+v", m)
+	}
+}
+
+func TestAffectedNothingChanged(t *testing.T) {
+	setupMonorepo(t)
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"affected", "-format", "json"}, nil)
+	if err != nil {
+		t.Fatalf("affected failed: %v", err)
+	}
+	var result struct {
+		Modules []any `json:"modules"`
+	}
+	err = json.Unmarshal(output.Bytes(), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Modules == nil || len(result.Modules) != 0 {
+		t.Errorf("Expected an empty module list, got %s", output.String())
+	}
+}
+
+func TestModuleFor(t *testing.T) {
+	modules := []module{
+		{Name: "", Path: "."},
+		{Name: "pay", Path: "pay"},
+		{Name: "payments", Path: "services/payments"},
+	}
+	tests := []struct {
+		file string
+		want string
+	}{
+		{file: "README.md", want: "."},
+		{file: "pay/main.go", want: "pay"},
+		{file: "payments/main.go", want: "."},
+		{file: "services/payments/api/handler.go", want: "services/payments"},
+		{file: "services/other.go", want: "."},
+	}
+	for _, tt := range tests {
+		got, ok := moduleFor(modules, tt.file)
+		if !ok || got.Path != tt.want {
+			t.Errorf("moduleFor(%q) = %q, want %q", tt.file, got.Path, tt.want)
+		}
+	}
+
+	_, ok := moduleFor(modules[1:], "README.md")
+	if ok {
+		t.Errorf("Expected no module for a file outside all modules")
+	}
+}
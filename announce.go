@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// release describes a finished release. It is the data available to
+// announcement templates.
+type release struct {
+	Project  string
+	Previous string
+	Version  string
+	Changes  []string
+	Notes    string    // release notes aggregated from the released changelogs
+	Commit   string    // id of the released commit, empty without a vcs
+	Date     time.Time // time of the release
+	env      []string  // for the env template function
+}
+
+// announcer is a backend that tells the world about a release.
+type announcer interface {
+	name() string
+	announce(ctx context.Context, rel release) error
+}
+
+// announcerBackends construct the announcement backends from the
+// environment. A backend that isn't configured returns a nil announcer.
+var announcerBackends = []func(cfg config, env []string) (announcer, error){
+	emailBackend,
+	slackBackend,
+	discordBackend,
+	teamsBackend,
+	matrixBackend,
+	githubIssueBackend,
+}
+
+// configuredAnnouncers returns the announcement backends configured in the
+// environment. BUMP_ANNOUNCE optionally restricts them to a comma-separated
+// list of backend names. It fails before anything is bumped if -announce was
+// given but no backend is configured.
+func configuredAnnouncers(cfg config, env []string) ([]announcer, error) {
+	if !cfg.announce {
+		return nil, nil
+	}
+	var selected map[string]bool
+	if names := getenv(env, "BUMP_ANNOUNCE"); names != "" {
+		selected = make(map[string]bool)
+		for _, name := range strings.Split(names, ",") {
+			selected[strings.TrimSpace(name)] = true
+		}
+	}
+
+	var announcers []announcer
+	for _, backend := range announcerBackends {
+		a, err := backend(cfg, env)
+		if err != nil {
+			return nil, err
+		}
+		if a == nil || (selected != nil && !selected[a.name()]) {
+			continue
+		}
+		announcers = append(announcers, a)
+	}
+	if len(announcers) == 0 {
+		return nil, errors.New("-announce given but no announcement backend is configured")
+	}
+	return announcers, nil
+}
+
+// announce runs all announcers. The release already exists at this point, so
+// a failing backend doesn't stop the others.
+func announce(ctx context.Context, announcers []announcer, output io.Writer, rel release) error {
+	var errs []error
+	for _, a := range announcers {
+		err := a.announce(ctx, rel)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", a.name(), err))
+			continue
+		}
+		_, _ = fmt.Fprintf(output, "Announced %s via %s\n", rel.Version, a.name())
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("release %s was created but announcing it failed: %w", rel.Version, errors.Join(errs...))
+	}
+	return nil
+}
+
+// renderTemplate executes a text template against the release, with the
+// functions of templateFuncs.
+func renderTemplate(name, text string, rel release) (string, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs(rel.env)).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s template: %w", name, err)
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, rel)
+	if err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// templateFromEnv returns the template stored in the file named by the
+// environment variable, or the fallback if the variable is unset.
+func templateFromEnv(env []string, key, fallback string) (string, error) {
+	path := getenv(env, key)
+	if path == "" {
+		return fallback, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	return string(content), nil
+}
+
+// getenv looks up key in an environment list in os.Environ format. Later
+// entries win, like they do for the process environment.
+func getenv(env []string, key string) string {
+	value := ""
+	for _, kv := range env {
+		k, v, ok := strings.Cut(kv, "=")
+		if ok && k == key {
+			value = v
+		}
+	}
+	return value
+}
+
+// projectName is the name used for the project in announcements: BUMP_PROJECT
+// if set, otherwise the name of the repository directory.
+func projectName(env []string) string {
+	if name := getenv(env, "BUMP_PROJECT"); name != "" {
+		return name
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "project"
+	}
+	return filepath.Base(wd)
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/mod/semver"
+)
+
+// apiSurface maps every exported identifier of a Go module, qualified by its
+// package directory (e.g. "client.Dial" or "client.Options.Timeout"), to its
+// declaration with names and bodies stripped, so two surfaces can be compared
+// by key and value.
+type apiSurface map[string]string
+
+// apiChanges is the difference between two API surfaces.
+type apiChanges struct {
+	removed []string
+	changed []string
+	added   []string
+}
+
+// level returns the bump the changes call for. Removed or changed API is
+// incompatible and needs a major bump, or a minor one before v1 where
+// anything goes. Additions need a minor bump.
+func (c apiChanges) level(currentVersion string) action {
+	switch {
+	case len(c.removed) > 0 || len(c.changed) > 0:
+		if semver.Major(normalizeVersion(currentVersion)) == "v0" {
+			return incrementMinor
+		}
+		return incrementMajor
+	case len(c.added) > 0:
+		return incrementMinor
+	}
+	return incrementPatch
+}
+
+// compareAPI lists what happened to the old surface's identifiers in the new
+// one, each list sorted.
+func compareAPI(old, new apiSurface) apiChanges {
+	var changes apiChanges
+	for name, decl := range old {
+		newDecl, ok := new[name]
+		switch {
+		case !ok:
+			changes.removed = append(changes.removed, name)
+		case newDecl != decl:
+			changes.changed = append(changes.changed, name)
+		}
+	}
+	for name := range new {
+		if _, ok := old[name]; !ok {
+			changes.added = append(changes.added, name)
+		}
+	}
+	sort.Strings(changes.removed)
+	sort.Strings(changes.changed)
+	sort.Strings(changes.added)
+	return changes
+}
+
+// exportedAPI collects the API surface of the Go code in a commit. Main
+// packages, tests, testdata, vendored code and internal packages are not part
+// of the API.
+func exportedAPI(commit *object.Commit) (apiSurface, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree of %s: %w", commit.Hash, err)
+	}
+	api := make(apiSurface)
+	fset := token.NewFileSet()
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if !isAPIFile(f.Name) {
+			return nil
+		}
+		reader, err := f.Reader()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		defer func() { _ = reader.Close() }()
+		src, err := io.ReadAll(reader)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		file, err := parser.ParseFile(fset, f.Name, src, parser.SkipObjectResolution)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", f.Name, err)
+		}
+		if file.Name.Name == "main" {
+			return nil
+		}
+		addFileAPI(api, fset, path.Dir(f.Name), file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return api, nil
+}
+
+// isAPIFile reports whether the repository path holds Go code that can be
+// imported by other modules.
+func isAPIFile(name string) bool {
+	if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+		return false
+	}
+	for _, dir := range strings.Split(path.Dir(name), "/") {
+		switch dir {
+		case "internal", "testdata", "vendor":
+			return false
+		}
+		if dir != "." && (strings.HasPrefix(dir, ".") || strings.HasPrefix(dir, "_")) {
+			return false
+		}
+	}
+	return true
+}
+
+// addFileAPI adds the exported declarations of a file to the surface.
+func addFileAPI(api apiSurface, fset *token.FileSet, pkg string, file *ast.File) {
+	render := func(node ast.Node) string {
+		var buf bytes.Buffer
+		_ = printer.Fprint(&buf, fset, node)
+		return buf.String()
+	}
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if !decl.Name.IsExported() {
+				continue
+			}
+			name := decl.Name.Name
+			if decl.Recv != nil {
+				receiver := receiverType(decl.Recv.List[0].Type)
+				if !ast.IsExported(receiver) {
+					continue
+				}
+				name = receiver + "." + name
+			}
+			api[pkg+"."+name] = render(signature(decl.Type))
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if !spec.Name.IsExported() {
+						continue
+					}
+					addTypeAPI(api, render, pkg+"."+spec.Name.Name, spec)
+				case *ast.ValueSpec:
+					kind := decl.Tok.String()
+					if spec.Type != nil {
+						kind += " " + render(spec.Type)
+					}
+					for _, name := range spec.Names {
+						if name.IsExported() {
+							api[pkg+"."+name.Name] = kind
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// addTypeAPI adds a type declaration. The exported fields of a struct are
+// entries of their own, so adding a field is an addition rather than a change
+// of the struct.
+func addTypeAPI(api apiSurface, render func(ast.Node) string, name string, spec *ast.TypeSpec) {
+	prefix := "type"
+	if spec.TypeParams != nil {
+		prefix += render(spec.TypeParams)
+	}
+	if spec.Assign.IsValid() {
+		prefix += " ="
+	}
+	structType, ok := spec.Type.(*ast.StructType)
+	if !ok {
+		api[name] = prefix + " " + render(spec.Type)
+		return
+	}
+	api[name] = prefix + " struct"
+	for _, field := range structType.Fields.List {
+		fieldType := render(field.Type)
+		if len(field.Names) == 0 { // embedded
+			embedded := receiverType(field.Type)
+			if ast.IsExported(embedded) {
+				api[name+"."+embedded] = "embedded " + fieldType
+			}
+			continue
+		}
+		for _, fieldName := range field.Names {
+			if fieldName.IsExported() {
+				api[name+"."+fieldName.Name] = fieldType
+			}
+		}
+	}
+}
+
+// signature returns the function type without parameter names, which callers
+// don't depend on.
+func signature(fn *ast.FuncType) *ast.FuncType {
+	unnamed := func(fields *ast.FieldList) *ast.FieldList {
+		if fields == nil {
+			return nil
+		}
+		list := &ast.FieldList{}
+		for _, field := range fields.List {
+			for range max(len(field.Names), 1) {
+				list.List = append(list.List, &ast.Field{Type: field.Type})
+			}
+		}
+		return list
+	}
+	return &ast.FuncType{TypeParams: fn.TypeParams, Params: unnamed(fn.Params), Results: unnamed(fn.Results)}
+}
+
+// receiverType returns the name of the type in a receiver or embedded field
+// expression such as *T, T[K] or pkg.T.
+func receiverType(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return receiverType(expr.X)
+	case *ast.IndexExpr:
+		return receiverType(expr.X)
+	case *ast.IndexListExpr:
+		return receiverType(expr.X)
+	case *ast.SelectorExpr:
+		return expr.Sel.Name
+	case *ast.Ident:
+		return expr.Name
+	}
+	return ""
+}
+
+// diffAPI compares the exported Go API at two revisions.
+func diffAPI(repo *git.Repository, base, head string) (apiChanges, error) {
+	baseCommit, err := resolveCommit(repo, base)
+	if err != nil {
+		return apiChanges{}, err
+	}
+	headCommit, err := resolveCommit(repo, head)
+	if err != nil {
+		return apiChanges{}, err
+	}
+	old, err := exportedAPI(baseCommit)
+	if err != nil {
+		return apiChanges{}, fmt.Errorf("API at %s: %w", base, err)
+	}
+	current, err := exportedAPI(headCommit)
+	if err != nil {
+		return apiChanges{}, fmt.Errorf("API at %s: %w", head, err)
+	}
+	return compareAPI(old, current), nil
+}
+
+// printAPIChanges lists the changes, incompatible ones first.
+func printAPIChanges(output io.Writer, changes apiChanges) {
+	for _, name := range changes.removed {
+		_, _ = fmt.Fprintf(output, "API removed: %s\n", name)
+	}
+	for _, name := range changes.changed {
+		_, _ = fmt.Fprintf(output, "API changed: %s\n", name)
+	}
+	for _, name := range changes.added {
+		_, _ = fmt.Fprintf(output, "API added: %s\n", name)
+	}
+}
+
+// apiBumpLevel compares the exported Go API at the last version tag (or
+// -since) with HEAD and returns the bump it calls for. An explicitly
+// requested level below that is refused, so -auto-api enforces semantic
+// versioning as well as suggesting it.
+func apiBumpLevel(repo vcs, cfg config, output io.Writer) (action, error) {
+	gitRepo, err := gitRepository(repo, "-auto-api")
+	if err != nil {
+		return noAction, err
+	}
+	currentVersion, err := repo.lastTag()
+	if err != nil {
+		return noAction, fmt.Errorf("failed to get last tag: %w", err)
+	}
+	since := cfg.since
+	if since == "" {
+		since, err = existingTag(repo, currentVersion)
+		if err != nil {
+			return noAction, err
+		}
+	}
+	changes, err := diffAPI(gitRepo, since, "HEAD")
+	if err != nil {
+		return noAction, err
+	}
+	printAPIChanges(output, changes)
+	level := changes.level(currentVersion)
+	_, _ = fmt.Fprintf(output, "API changes since %s call for a %s bump\n", since, level)
+
+	if cfg.action == noAction {
+		return level, nil
+	}
+	if cfg.action < level && !cfg.forced {
+		return noAction, fmt.Errorf("API changes since %s need a %s bump, not %s (use -force to override)", since, level, cfg.action)
+	}
+	return cfg.action, nil
+}
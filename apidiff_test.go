@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parseAPI returns the API surface of a single source file in package dir
+func parseAPI(t *testing.T, src string) apiSurface {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "lib.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := make(apiSurface)
+	addFileAPI(api, fset, "lib", file)
+	return api
+}
+
+const libV1 = `package lib
+
+type Options struct {
+	Timeout int
+	retries int
+}
+
+type Client interface {
+	Do(req string) error
+}
+
+const Version = "1"
+
+func Dial(addr string, opts Options) (*Conn, error) { return nil, nil }
+
+type Conn struct{}
+
+func (c *Conn) Close() error { return nil }
+
+func helper() {}
+`
+
+func TestCompareAPI(t *testing.T) {
+	tests := []struct {
+		name    string
+		new     string
+		current string
+		want    apiChanges
+		level   action
+	}{
+		{
+			name:  "unexported and cosmetic changes",
+			new:   strings.Replace(strings.Replace(libV1, "func helper() {}", "func helper(n int) {}", 1), "addr string", "address string", 1),
+			level: incrementPatch,
+		},
+		{
+			name:  "added function and field",
+			new:   libV1 + "func Listen() {}\n" + "type Extra struct{ Name string }\n",
+			want:  apiChanges{added: []string{"lib.Extra", "lib.Extra.Name", "lib.Listen"}},
+			level: incrementMinor,
+		},
+		{
+			name:  "removed method",
+			new:   strings.Replace(libV1, "func (c *Conn) Close() error { return nil }", "", 1),
+			want:  apiChanges{removed: []string{"lib.Conn.Close"}},
+			level: incrementMajor,
+		},
+		{
+			name:  "changed signature and interface",
+			new:   strings.Replace(strings.Replace(libV1, "opts Options)", "opts ...Options)", 1), "Do(req string) error", "Do(req string) error\n\tName() string", 1),
+			want:  apiChanges{changed: []string{"lib.Client", "lib.Dial"}},
+			level: incrementMajor,
+		},
+		{
+			name:    "breaking change before v1",
+			new:     strings.Replace(libV1, "Timeout int", "Timeout float64", 1),
+			current: "v0.4.0",
+			want:    apiChanges{changed: []string{"lib.Options.Timeout"}},
+			level:   incrementMinor,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compareAPI(parseAPI(t, libV1), parseAPI(t, tt.new))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("compareAPI() = %+v, want %+v", got, tt.want)
+			}
+			current := tt.current
+			if current == "" {
+				current = "v1.0.0"
+			}
+			if level := got.level(current); level != tt.level {
+				t.Errorf("level() = %s, want %s", level, tt.level)
+			}
+		})
+	}
+}
+
+func TestIsAPIFile(t *testing.T) {
+	tests := map[string]bool{
+		"lib.go":                  true,
+		"client/dial.go":          true,
+		"client/dial_test.go":     false,
+		"internal/util/util.go":   false,
+		"client/testdata/x.go":    false,
+		"vendor/example.com/a.go": false,
+		"README.md":               false,
+	}
+	for name, want := range tests {
+		if got := isAPIFile(name); got != want {
+			t.Errorf("isAPIFile(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestBumpAutoAPI(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		change      string
+		wantVersion string
+		errContains string
+	}{
+		{name: "addition", args: []string{"-auto-api"}, change: libV1 + "func Listen() {}\n", wantVersion: "v1.2.0"},
+		{name: "removal", args: []string{"-auto-api"}, change: strings.Replace(libV1, "const Version = \"1\"", "", 1), wantVersion: "v2.0.0"},
+		{name: "internal change", args: []string{"-auto-api"}, change: libV1 + "func listen() {}\n", wantVersion: "v1.1.1"},
+		{name: "explicit higher level", args: []string{"-auto-api", "-major"}, change: libV1 + "func Listen() {}\n", wantVersion: "v2.0.0"},
+		{name: "explicit lower level", args: []string{"-auto-api", "-patch"}, change: libV1 + "func Listen() {}\n", errContains: "need a minor bump, not patch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, repo := setupTaggedTestRepo(t, "v1.0.0")
+			commitFiles(t, repo, "Add lib", map[string]string{"lib/lib.go": libV1, ".version": "v1.1.0"})
+			_, err := repo.CreateTag("v1.1.0", mustHead(t, repo), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			commitFiles(t, repo, "Change lib", map[string]string{"lib/lib.go": tt.change})
+
+			var output bytes.Buffer
+			err = run(context.Background(), &output, tt.args, nil)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("Expected error containing %q, got: %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+			}
+			version, err := os.ReadFile(".version")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(version) != tt.wantVersion {
+				t.Errorf("Expected version %s, got %s\nOutput: %s", tt.wantVersion, version, output.String())
+			}
+		})
+	}
+}
+
+func TestAutoAPIConflictsWithVersion(t *testing.T) {
+	_, _, err := getConfig([]string{"-auto-api", "-version", "v1.0.0"})
+	if err == nil || !strings.Contains(err.Error(), "-auto-api") {
+		t.Errorf("Expected conflict error, got: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5/plumbing/format/gitattributes"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// worktreeRules decides how bump writes files in the worktree. It honours the
+// eol attribute from the repository's .gitattributes and never touches files
+// matched by the user's global excludes file (core.excludesfile).
+type worktreeRules struct {
+	attributes gitattributes.Matcher
+	excludes   gitignore.Matcher
+}
+
+func loadWorktreeRules() (worktreeRules, error) {
+	attrs, err := gitattributes.ReadPatterns(osfs.New("."), nil)
+	if err != nil {
+		return worktreeRules{}, fmt.Errorf("failed to read .gitattributes: %w", err)
+	}
+	excludes, err := gitignore.LoadGlobalPatterns(osfs.New("/"))
+	if err != nil {
+		return worktreeRules{}, fmt.Errorf("failed to read core.excludesfile: %w", err)
+	}
+	return worktreeRules{
+		attributes: gitattributes.NewMatcher(attrs),
+		excludes:   gitignore.NewMatcher(excludes),
+	}, nil
+}
+
+// excluded reports whether the repository path is matched by the global
+// excludes file.
+func (r worktreeRules) excluded(relPath string) bool {
+	return r.excludes.Match(strings.Split(relPath, "/"), false)
+}
+
+// crlf reports whether .gitattributes declares eol=crlf for the path.
+func (r worktreeRules) crlf(relPath string) bool {
+	results, _ := r.attributes.Match(strings.Split(relPath, "/"), []string{"eol"})
+	eol, ok := results["eol"]
+	return ok && eol.IsValueSet() && eol.Value() == "crlf"
+}
+
+// encode converts the line endings of content to what the path's attributes
+// require. Content is returned unchanged unless eol=crlf is declared.
+func (r worktreeRules) encode(relPath string, content []byte) []byte {
+	if !r.crlf(relPath) {
+		return content
+	}
+	lf := bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(lf, []byte("\n"), []byte("\r\n"))
+}
+
+// lineEnding returns the line terminator content ends with, if any.
+func lineEnding(content []byte) string {
+	switch {
+	case bytes.HasSuffix(content, []byte("\r\n")):
+		return "\r\n"
+	case bytes.HasSuffix(content, []byte("\n")):
+		return "\n"
+	}
+	return ""
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdateVersionFilesHonoursEOLAttribute(t *testing.T) {
+	tempDir, repo := setupTestRepo(t)
+	t.Chdir(tempDir)
+
+	err := os.WriteFile(".gitattributes", []byte(".version eol=crlf\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.MkdirAll("lf", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile("lf/.gitattributes", []byte(".version eol=lf\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{".version", "lf/.version"} {
+		err = os.WriteFile(path, []byte("v1.0.0\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var output bytes.Buffer
+	err = updateVersionFiles(&gitVCS{repo: repo}, config{}, &output, "v1.0.1", "", "")
+	if err != nil {
+		t.Fatalf("updateVersionFiles() error = %v", err)
+	}
+
+	want := map[string]string{
+		".version":    "v1.0.1\r\n",
+		"lf/.version": "v1.0.1\n",
+	}
+	for path, expected := range want {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != expected {
+			t.Errorf("File %s: got content %q, want %q", path, string(content), expected)
+		}
+	}
+}
+
+func TestUpdateVersionFilesSkipsGloballyExcluded(t *testing.T) {
+	tempDir, repo := setupTestRepo(t)
+	t.Chdir(tempDir)
+
+	// Point HOME at a fake global git configuration with an excludes file
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	excludesFile := filepath.Join(home, "excludes")
+	err := os.WriteFile(excludesFile, []byte("scratch/\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gitconfig := "[user]\n\tname = Test User\n\temail = test@example.com\n[core]\n\texcludesfile = " + filepath.ToSlash(excludesFile) + "\n"
+	err = os.WriteFile(filepath.Join(home, ".gitconfig"), []byte(gitconfig), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{".version", "scratch/.version"} {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, []byte("v1.0.0"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var output bytes.Buffer
+	err = updateVersionFiles(&gitVCS{repo: repo}, config{}, &output, "v1.0.1", "", "")
+	if err != nil {
+		t.Fatalf("updateVersionFiles() error = %v", err)
+	}
+
+	content, err := os.ReadFile("scratch/.version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "v1.0.0" {
+		t.Errorf("Expected globally excluded scratch/.version to remain 'v1.0.0', got %q", string(content))
+	}
+	content, err = os.ReadFile(".version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "v1.0.1" {
+		t.Errorf("Expected .version to be 'v1.0.1', got %q", string(content))
+	}
+}
+
+func TestLineEnding(t *testing.T) {
+	tests := map[string]string{
+		"v1.0.0":     "",
+		"v1.0.0\n":   "\n",
+		"v1.0.0\r\n": "\r\n",
+	}
+	for content, want := range tests {
+		if got := lineEnding([]byte(content)); got != want {
+			t.Errorf("lineEnding(%q) = %q, want %q", content, got, want)
+		}
+	}
+}
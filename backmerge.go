@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// backMergeBranchPrefix starts the names of the branches back-merge pull
+// requests are opened from, one per release.
+const backMergeBranchPrefix = "bump/back-merge-"
+
+// backMerger opens the back-merge pull request of a release made on a
+// release branch (-back-merge), so the default branch gets the release's
+// version files and changelog instead of still naming an older version.
+type backMerger struct {
+	gh      githubClient
+	project string
+}
+
+// newBackMerger prepares -back-merge before the release is made, so a
+// missing token or a remote that isn't on GitHub can't fail it halfway. It
+// returns nil without -back-merge.
+func newBackMerger(repo vcs, cfg config, env []string, output io.Writer) (*backMerger, error) {
+	if !cfg.backMerge {
+		return nil, nil
+	}
+	gitRepo, err := gitRepository(repo, "-back-merge")
+	if err != nil {
+		return nil, err
+	}
+	r, err := resolveRemotes(gitRepo, cfg)
+	if err != nil {
+		return nil, err
+	}
+	project, err := githubRepository(gitRepo, r.push, env)
+	if err != nil {
+		return nil, fmt.Errorf("-back-merge: %w", err)
+	}
+	gh, err := newGitHubClient(env, output)
+	if err != nil {
+		return nil, fmt.Errorf("-back-merge: %w", err)
+	}
+	return &backMerger{gh: gh, project: project}, nil
+}
+
+// open opens the pull request merging the pushed release commit into the
+// default branch, from a branch of its own at the release commit, so later
+// commits on the release branch aren't part of it. Nothing is opened for a
+// release made on the default branch.
+func (b *backMerger) open(ctx context.Context, repo vcs, cfg config, output io.Writer, tag string) error {
+	branch, err := repo.branch()
+	if err != nil {
+		return err
+	}
+	commit, err := repo.head()
+	if err != nil {
+		return err
+	}
+	if cfg.dryRun {
+		_, _ = fmt.Fprintf(output, "Would open a pull request merging %s back into the default branch of %s\n", tag, b.project)
+		return nil
+	}
+	var info struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	err = b.gh.do(ctx, http.MethodGet, "/repos/"+b.project, nil, &info)
+	if err != nil {
+		return err
+	}
+	if branch == info.DefaultBranch {
+		_, _ = fmt.Fprintf(output, "Released on the default branch %s, no back-merge needed\n", branch)
+		return nil
+	}
+
+	head := backMergeBranchPrefix + tag
+	err = b.gh.do(ctx, http.MethodPost, "/repos/"+b.project+"/git/refs", map[string]string{
+		"ref": "refs/heads/" + head,
+		"sha": commit,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create branch %s: %w", head, err)
+	}
+	var pull struct {
+		HTMLURL string `json:"html_url"`
+	}
+	err = b.gh.do(ctx, http.MethodPost, "/repos/"+b.project+"/pulls", map[string]string{
+		"title": fmt.Sprintf("Back-merge %s into %s", tag, info.DefaultBranch),
+		"head":  head,
+		"base":  info.DefaultBranch,
+		"body":  fmt.Sprintf("%s was released from %s. This merges the release, with its version files and changelog, back into %s.", tag, branch, info.DefaultBranch),
+	}, &pull)
+	if err != nil {
+		return fmt.Errorf("failed to open pull request: %w", err)
+	}
+	_, _ = fmt.Fprintf(output, "Opened back-merge pull request %s\n", pull.HTMLURL)
+	return nil
+}
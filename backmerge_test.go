@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestBumpBackMerge(t *testing.T) {
+	var refs, pulls []map[string]string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/acme/app":
+			_, _ = w.Write([]byte(`{"default_branch":"main"}`))
+		case "POST /repos/acme/app/git/refs":
+			refs = append(refs, body)
+		case "POST /repos/acme/app/pulls":
+			pulls = append(pulls, body)
+			_, _ = w.Write([]byte(`{"html_url":"https://github.com/acme/app/pull/7"}`))
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(api.Close)
+	env := []string{"BUMP_GITHUB_TOKEN=gh-token", "BUMP_GITHUB_API=" + api.URL, "BUMP_GITHUB_REPO=acme/app", "BUMP_API_CACHE=" + t.TempDir()}
+
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("release/1.0"), Create: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remoteDir := t.TempDir()
+	_, err = git.PlainInit(remoteDir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{remoteDir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-back-merge"}, env)
+	if err == nil || !strings.Contains(err.Error(), "-back-merge needs -push") {
+		t.Errorf("Expected -back-merge to need -push, got: %v", err)
+	}
+
+	err = run(context.Background(), &output, []string{"-push", "-back-merge"}, env)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	if len(refs) != 1 || refs[0]["ref"] != "refs/heads/bump/back-merge-v1.0.1" || refs[0]["sha"] != mustHead(t, repo).String() {
+		t.Errorf("Expected a back-merge branch at the release commit, got %v", refs)
+	}
+	if len(pulls) != 1 || pulls[0]["head"] != "bump/back-merge-v1.0.1" || pulls[0]["base"] != "main" || pulls[0]["title"] != "Back-merge v1.0.1 into main" {
+		t.Errorf("Expected a back-merge pull request into main, got %v", pulls)
+	}
+	if !strings.Contains(output.String(), "Opened back-merge pull request https://github.com/acme/app/pull/7") {
+		t.Errorf("Expected the pull request in the output, got:\n%s", output.String())
+	}
+}
+
+func TestGitHubRepository(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	_, err := repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{"git@github.com:acme/app.git"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: "mirror", URLs: []string{"https://git.example.com/acme/app.git"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		remote string
+		env    []string
+		want   string
+		err    string
+	}{
+		{remote: "origin", want: "acme/app"},
+		{remote: "mirror", err: "remote 'mirror' isn't on GitHub"},
+		{remote: "mirror", env: []string{"BUMP_GITHUB_API=https://git.example.com/api/v3"}, want: "acme/app"},
+		{remote: "mirror", env: []string{"BUMP_GITHUB_REPO=acme/fork"}, want: "acme/fork"},
+		{remote: "origin", env: []string{"BUMP_GITHUB_REPO=acme"}, err: "invalid BUMP_GITHUB_REPO"},
+	}
+	for _, tt := range tests {
+		got, err := githubRepository(repo, tt.remote, tt.env)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("githubRepository(%s, %v): expected error %q, got %v", tt.remote, tt.env, tt.err, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("githubRepository(%s, %v) = %s, %v, want %s", tt.remote, tt.env, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// branchVCS releases a branch other than the checked-out one (-branch), e.g.
+// an orphan releases branch holding only changelogs and manifests. Changes
+// are measured against the branch and the tag lands on its tip; the release
+// commit itself is written by bumpBranch, which never touches the worktree.
+type branchVCS struct {
+	vcs
+	repo *git.Repository
+	ref  plumbing.ReferenceName
+	loc  *time.Location // of the tag, nil for the local time zone
+}
+
+// withBranch wraps repo for -branch, tagging in loc. It is the innermost
+// wrapper, so the tag name wrappers hand it the final tag names.
+func withBranch(repo vcs, name string, loc *time.Location) (vcs, error) {
+	gitRepo, err := gitRepository(repo, "-branch")
+	if err != nil {
+		return nil, err
+	}
+	ref := plumbing.NewBranchReferenceName(name)
+	_, err = gitRepo.Reference(ref, true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, fmt.Errorf("branch '%s' does not exist", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve branch '%s': %w", name, err)
+	}
+	if head, err := gitRepo.Head(); err == nil && head.Name() == ref {
+		return nil, fmt.Errorf("branch '%s' is checked out: bump it without -branch", name)
+	}
+	return branchVCS{vcs: repo, repo: gitRepo, ref: ref, loc: loc}, nil
+}
+
+func (b branchVCS) goGit() *git.Repository {
+	return b.repo
+}
+
+// tip returns the commit the branch points to.
+func (b branchVCS) tip() (*object.Commit, error) {
+	ref, err := b.repo.Reference(b.ref, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve branch '%s': %w", b.ref.Short(), err)
+	}
+	commit, err := b.repo.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the tip of '%s': %w", b.ref.Short(), err)
+	}
+	return commit, nil
+}
+
+// dirtyFiles reports nothing: the worktree isn't part of the release.
+func (b branchVCS) dirtyFiles() (map[string]string, error) {
+	return nil, nil
+}
+
+func (b branchVCS) branch() (string, error) {
+	return b.ref.Short(), nil
+}
+
+func (b branchVCS) hasChangesSince(tag string) (bool, error) {
+	commit, err := tagCommit(b.repo, tag)
+	if err != nil {
+		return false, err
+	}
+	tip, err := b.tip()
+	if err != nil {
+		return false, err
+	}
+	return tip.Hash != commit.Hash, nil
+}
+
+// changesSince returns the subjects of the branch's commits since rev, for
+// the max-commits rule of .bumppolicy.
+func (b branchVCS) changesSince(rev, strategy string) ([]string, error) {
+	if rev == "" {
+		return nil, nil
+	}
+	tip, err := b.tip()
+	if err != nil {
+		return nil, err
+	}
+	commits, err := commitsBetween(b.repo, tip.Hash, rev, strategy)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]string, 0, len(commits))
+	for _, c := range commits {
+		changes = append(changes, commitSubject(c.Message))
+	}
+	return changes, nil
+}
+
+func (b branchVCS) head() (string, error) {
+	tip, err := b.tip()
+	if err != nil {
+		return "", err
+	}
+	return tip.Hash.String(), nil
+}
+
+func (b branchVCS) add(path string) error {
+	return fmt.Errorf("-branch: refusing to stage %s in the worktree", path)
+}
+
+func (b branchVCS) commit(string) error {
+	return errors.New("-branch: refusing to commit on the checked-out branch")
+}
+
+func (b branchVCS) createTag(name, message string) (string, error) {
+	tip, err := b.tip()
+	if err != nil {
+		return "", err
+	}
+	var opts *git.CreateTagOptions
+	if message != "" {
+		opts, err = tagOptions(b.repo, message, b.loc)
+		if err != nil {
+			return "", err
+		}
+	}
+	ref, err := b.repo.CreateTag(name, tip.Hash, opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to create tag: %w", err)
+	}
+	return ref.Hash().String(), nil
+}
+
+// bumpBranch is bump for -branch. It validates the release like bump does,
+// updates the .version files of the branch's tree, commits them on top of
+// the branch and tags the commit, without switching branches. The release
+// is then published like bump does: the -artifacts checksummed, the
+// -build-args written, pushed with -push and annotated in Kubernetes with
+// -k8s-annotate. The
+// worktree-based release files (changelogs, generated files, packages)
+// belong to the checked-out branch and are left out.
+func bumpBranch(ctx context.Context, repo vcs, cfg config, env []string, output io.Writer) error {
+	gitRepo, err := gitRepository(repo, "-branch")
+	if err != nil {
+		return err
+	}
+	b := branchVCS{repo: gitRepo, ref: plumbing.NewBranchReferenceName(cfg.branch), loc: cfg.location}
+	currentVersion, newVersion, err := nextVersion(repo, cfg)
+	if err != nil {
+		return err
+	}
+	checksumKey, err := signingKey(cfg, env)
+	if err != nil {
+		return err
+	}
+	kube, err := newKubeAnnotator(cfg, env)
+	if err != nil {
+		return err
+	}
+	cfg.eventSink.emit(progressEvent{Phase: phaseValidate, Version: newVersion})
+	err = validateRelease(ctx, repo, cfg, env, output, newVersion)
+	if err != nil {
+		return err
+	}
+	tip, err := b.tip()
+	if err != nil {
+		return err
+	}
+	files, err := branchVersionFiles(tip, cfg)
+	if err != nil {
+		return err
+	}
+	protected, err := loadProtectedPaths(".bumpprotect")
+	if err != nil {
+		return fmt.Errorf("failed to load .bumpprotect: %w", err)
+	}
+	err = checkProtected(protected, files)
+	if err != nil {
+		return err
+	}
+	rules, err := loadWorktreeRules()
+	if err != nil {
+		return err
+	}
+
+	updated := make(map[string][]byte)
+	for _, file := range files {
+		f, err := tip.File(file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s on %s: %w", file, b.ref.Short(), err)
+		}
+		content, err := f.Contents()
+		if err != nil {
+			return fmt.Errorf("failed to read %s on %s: %w", file, b.ref.Short(), err)
+		}
+		trimmed := parseVersionFile([]byte(content))
+		if _, err := scheme.parse(trimmed); len(trimmed) > 0 && err != nil {
+			return fmt.Errorf("invalid version in file %s: '%s'", file, trimmed)
+		}
+		_, _ = fmt.Fprintf(output, "Updating version in file %s on branch %s to %s\n", file, b.ref.Short(), newVersion)
+		cfg.eventSink.emit(progressEvent{Phase: phaseUpdateFiles, Version: newVersion, File: file})
+		newContent := versionFileContent(cfg, rules, file, []byte(content), newVersion)
+		if cfg.dryRun {
+			err = previewFile(output, file, newContent)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		updated[file] = newContent
+	}
+	if len(updated) > 0 {
+		cfg.eventSink.emit(progressEvent{Phase: phaseCommit, Version: newVersion})
+		message := commitMessage(cfg.skipCI, fmt.Sprintf("bump version to %s", newVersion), "", "")
+		err = commitToBranch(b.repo, b.ref, tip, updated, message, b.loc)
+		if err != nil {
+			return err
+		}
+	}
+
+	message, err := tagMessage(cfg.tagMessage, cfg.tagMetadata, newTagMetadata(cfg, currentVersion, newVersion, nil), nil)
+	if err != nil {
+		return err
+	}
+	if cfg.tagType == tagTypeLightweight {
+		message = ""
+	}
+	cfg.eventSink.emit(progressEvent{Phase: phaseTag, Tag: releaseTag(cfg, newVersion)})
+	tag, err := tagVersion(repo, cfg, newVersion, message)
+	if err != nil {
+		return fmt.Errorf("tagVersion: %w", err)
+	}
+	if cfg.version != "" || currentVersion == "" {
+		_, _ = fmt.Fprintf(output, "Set version %s on branch %s, tag=%s\n", newVersion, b.ref.Short(), tag)
+	} else {
+		_, _ = fmt.Fprintf(output, "Bumped version %s --> %s on branch %s, tag=%s\n", currentVersion, newVersion, b.ref.Short(), tag)
+	}
+	err = writeChecksums(cfg, output, checksumKey)
+	if err != nil {
+		return fmt.Errorf("release %s was created but writing the checksum manifest failed: %w", newVersion, err)
+	}
+	err = writeBuildArgs(repo, cfg, output, newVersion)
+	if err != nil {
+		return fmt.Errorf("release %s was created but writing the build args failed: %w", newVersion, err)
+	}
+	if cfg.push {
+		err = pushRelease(ctx, repo, cfg, env, output, releaseTag(cfg, newVersion))
+		if err != nil {
+			return fmt.Errorf("release %s was created but %w", newVersion, err)
+		}
+	}
+	if kube != nil {
+		err = kube.annotate(ctx, cfg, output, newVersion)
+		if err != nil {
+			return fmt.Errorf("release %s was created but %w", newVersion, err)
+		}
+	}
+	cfg.eventSink.emit(progressEvent{Phase: phaseDone, Version: newVersion})
+	return nil
+}
+
+// branchVersionFiles returns the paths of the .version files in the tree of
+// the commit that belong to the bumped stream, skipping directories
+// excluded by .bumpignore like findVersionFiles.
+func branchVersionFiles(commit *object.Commit, cfg config) ([]string, error) {
+	rules, err := loadIgnoreRules(".bumpignore")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load .bumpignore: %w", err)
+	}
+	rules = append(append([]ignoreRule{}, defaultIgnoreRules...), rules...)
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree: %w", err)
+	}
+	var files []string
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if path.Base(f.Name) != ".version" || streamOf(cfg.streams, f.Name) != cfg.stream {
+			return nil
+		}
+		for dir := path.Dir(f.Name); dir != "."; dir = path.Dir(dir) {
+			if shouldIgnore(dir, path.Base(dir), rules) {
+				return nil
+			}
+		}
+		files = append(files, f.Name)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk tree: %w", err)
+	}
+	return files, nil
+}
+
+// commitToBranch writes the files into a copy of the parent's tree, commits
+// it, dated in loc, and moves the branch to the commit. The branch is only
+// moved if it still points to the parent.
+func commitToBranch(repo *git.Repository, ref plumbing.ReferenceName, parent *object.Commit, files map[string][]byte, message string, loc *time.Location) error {
+	blobs := make(map[string]plumbing.Hash)
+	for file, content := range files {
+		hash, err := storeObject(repo, plumbing.BlobObject, func(obj plumbing.EncodedObject) error {
+			w, err := obj.Writer()
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(content)
+			if err != nil {
+				return err
+			}
+			return w.Close()
+		})
+		if err != nil {
+			return fmt.Errorf("failed to store %s: %w", file, err)
+		}
+		blobs[file] = hash
+	}
+	tree, err := parent.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to get tree: %w", err)
+	}
+	treeHash, err := replaceBlobs(repo, tree, "", blobs)
+	if err != nil {
+		return err
+	}
+
+	author, committer, err := signatures(repo, loc)
+	if err != nil {
+		return err
+	}
+	opts := &git.CommitOptions{Author: author, Committer: committer, Parents: []plumbing.Hash{parent.Hash}}
+	err = opts.Validate(repo)
+	if err != nil {
+		return fmt.Errorf("invalid commit options: %w", err)
+	}
+	commit := &object.Commit{
+		Author:       *opts.Author,
+		Committer:    *opts.Committer,
+		Message:      message,
+		TreeHash:     treeHash,
+		ParentHashes: opts.Parents,
+	}
+	commitHash, err := storeObject(repo, plumbing.CommitObject, commit.Encode)
+	if err != nil {
+		return fmt.Errorf("failed to store commit: %w", err)
+	}
+	err = repo.Storer.CheckAndSetReference(
+		plumbing.NewHashReference(ref, commitHash),
+		plumbing.NewHashReference(ref, parent.Hash))
+	if err != nil {
+		return fmt.Errorf("failed to move branch '%s': %w", ref.Short(), err)
+	}
+	return nil
+}
+
+// replaceBlobs stores a copy of the tree with the blobs of the files, keyed
+// by repository path, replaced. Only the subtrees on the way to a file are
+// rewritten.
+func replaceBlobs(repo *git.Repository, tree *object.Tree, prefix string, blobs map[string]plumbing.Hash) (plumbing.Hash, error) {
+	entries := make([]object.TreeEntry, len(tree.Entries))
+	copy(entries, tree.Entries)
+	for i, entry := range entries {
+		name := path.Join(prefix, entry.Name)
+		if hash, ok := blobs[name]; ok && entry.Mode != filemode.Dir {
+			entries[i].Hash = hash
+			continue
+		}
+		if entry.Mode != filemode.Dir || !hasPathUnder(blobs, name) {
+			continue
+		}
+		subtree, err := repo.TreeObject(entry.Hash)
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("failed to get tree of %s: %w", name, err)
+		}
+		entries[i].Hash, err = replaceBlobs(repo, subtree, name, blobs)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+	hash, err := storeObject(repo, plumbing.TreeObject, (&object.Tree{Entries: entries}).Encode)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to store tree: %w", err)
+	}
+	return hash, nil
+}
+
+// hasPathUnder reports whether one of the files is inside the directory.
+func hasPathUnder(files map[string]plumbing.Hash, dir string) bool {
+	for file := range files {
+		if strings.HasPrefix(file, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// storeObject writes an object of the type to the object database.
+func storeObject(repo *git.Repository, t plumbing.ObjectType, encode func(plumbing.EncodedObject) error) (plumbing.Hash, error) {
+	obj := repo.Storer.NewEncodedObject()
+	obj.SetType(t)
+	err := encode(obj)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return repo.Storer.SetEncodedObject(obj)
+}
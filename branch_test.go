@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// orphanBranch creates a branch without history holding the files, like
+// "git checkout --orphan" and a commit would.
+func orphanBranch(t *testing.T, repo *git.Repository, name string, files map[string]string) plumbing.Hash {
+	t.Helper()
+	blob := func(content string) plumbing.Hash {
+		hash, err := storeObject(repo, plumbing.BlobObject, func(obj plumbing.EncodedObject) error {
+			w, err := obj.Writer()
+			if err != nil {
+				return err
+			}
+			_, err = w.Write([]byte(content))
+			if err != nil {
+				return err
+			}
+			return w.Close()
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+	// one level of directories is all the tests need
+	dirs := make(map[string][]object.TreeEntry)
+	var root []object.TreeEntry
+	for file, content := range files {
+		dir, base, nested := strings.Cut(file, "/")
+		if nested {
+			dirs[dir] = append(dirs[dir], object.TreeEntry{Name: base, Mode: filemode.Regular, Hash: blob(content)})
+			continue
+		}
+		root = append(root, object.TreeEntry{Name: file, Mode: filemode.Regular, Hash: blob(content)})
+	}
+	for dir, entries := range dirs {
+		hash, err := storeObject(repo, plumbing.TreeObject, (&object.Tree{Entries: sortedEntries(entries)}).Encode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		root = append(root, object.TreeEntry{Name: dir, Mode: filemode.Dir, Hash: hash})
+	}
+	tree, err := storeObject(repo, plumbing.TreeObject, (&object.Tree{Entries: sortedEntries(root)}).Encode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := object.Signature{Name: "Test User", Email: "test@example.com", When: time.Now()}
+	commit := &object.Commit{Author: signature, Committer: signature, Message: "Start releases", TreeHash: tree}
+	hash, err := storeObject(repo, plumbing.CommitObject, commit.Encode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(name), hash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+// sortedEntries sorts tree entries the way git does for these plain names.
+func sortedEntries(entries []object.TreeEntry) []object.TreeEntry {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries
+}
+
+func branchFile(t *testing.T, repo *git.Repository, branch, file string) string {
+	t.Helper()
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := commit.File(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := f.Contents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestBumpOrphanBranch(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	head := mustHead(t, repo)
+	start := orphanBranch(t, repo, "releases", map[string]string{
+		".version":           "v1.0.0\n",
+		"CHANGELOG.md":       "# Changelog\n",
+		"manifests/.version": "v1.0.0\n",
+		"manifests/app.yaml": "image: app\n",
+	})
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-minor", "-branch", "releases"}, nil)
+	if err != nil {
+		t.Fatalf("bump: %v\nOutput: %s", err, output.String())
+	}
+	if !strings.Contains(output.String(), "Bumped version v1.0.0 --> v1.1.0 on branch releases") {
+		t.Errorf("Unexpected output: %s", output.String())
+	}
+
+	// the checked-out branch and the worktree are left alone
+	if mustHead(t, repo) != head {
+		t.Error("Expected HEAD to stay on the checked-out commit")
+	}
+	if got := readFile(t, ".version"); got != "v1.0.0" {
+		t.Errorf("Expected the worktree's .version to stay untouched, got %q", got)
+	}
+
+	for _, file := range []string{".version", "manifests/.version"} {
+		if got := branchFile(t, repo, "releases", file); got != "v1.1.0\n" {
+			t.Errorf("Expected %s on releases to be bumped, got %q", file, got)
+		}
+	}
+	if got := branchFile(t, repo, "releases", "manifests/app.yaml"); got != "image: app\n" {
+		t.Errorf("Expected manifests/app.yaml to stay untouched, got %q", got)
+	}
+	tagged, err := tagCommit(repo, "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tagged.ParentHashes) != 1 || tagged.ParentHashes[0] != start {
+		t.Errorf("Expected the release commit on top of the branch, got parents %v", tagged.ParentHashes)
+	}
+	if tagged.Message != "bump version to v1.1.0" {
+		t.Errorf("Unexpected commit message %q", tagged.Message)
+	}
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName("releases"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref.Hash() != tagged.Hash {
+		t.Error("Expected the tag on the new tip of releases")
+	}
+}
+
+func TestBumpBranchDryRun(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	start := orphanBranch(t, repo, "releases", map[string]string{".version": "v1.0.0\n"})
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-dry-run", "-branch", "releases"}, nil)
+	if err != nil {
+		t.Fatalf("bump: %v\nOutput: %s", err, output.String())
+	}
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName("releases"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref.Hash() != start {
+		t.Error("A dry run moved the branch")
+	}
+	if exists, _ := tagExists(repo, "v1.0.1"); exists {
+		t.Error("A dry run created the tag")
+	}
+}
+
+func TestBumpBranchErrors(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	orphanBranch(t, repo, "releases", map[string]string{".version": "v1.0.0\n"})
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-branch", "master"}, "branch 'master' is checked out"},
+		{[]string{"-branch", "missing"}, "branch 'missing' does not exist"},
+		{[]string{"-branch", "releases", "-no-vcs"}, "-branch can't be combined"},
+		{[]string{"-branch", "releases", "-push", "-back-merge"}, "-branch can't be combined"},
+		{[]string{"-branch", "releases", "-push", "-gitlab-release"}, "-branch can't be combined"},
+		{[]string{"-branch", "releases", "-push", "-github-release"}, "-branch can't be combined"},
+	}
+	for _, tt := range tests {
+		var output bytes.Buffer
+		err := run(context.Background(), &output, tt.args, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: expected %q, got: %v", tt.args, tt.want, err)
+		}
+	}
+}
+
+func TestBumpBranchPolicy(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	start := orphanBranch(t, repo, "releases", map[string]string{".version": "v1.0.0\n"})
+	err := os.WriteFile(".bumppolicy", []byte("protect v1.0.1\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-branch", "releases"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "policy") {
+		t.Fatalf("Expected the policy to refuse v1.0.1 on the branch, got: %v\nOutput: %s", err, output.String())
+	}
+	if exists, _ := tagExists(repo, "v1.0.1"); exists {
+		t.Error("Expected no tag after the policy refused the release")
+	}
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName("releases"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref.Hash() != start {
+		t.Error("Expected the branch to stay put after the policy refused the release")
+	}
+}
+
+func TestBumpBranchPush(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	orphanBranch(t, repo, "releases", map[string]string{".version": "v1.0.0\n"})
+	remoteDir := t.TempDir()
+	remote, err := git.PlainInit(remoteDir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{remoteDir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-minor", "-branch", "releases", "-push"}, nil)
+	if err != nil {
+		t.Fatalf("bump: %v\nOutput: %s", err, output.String())
+	}
+	tagged, err := tagCommit(repo, "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	branch, err := remote.Reference(plumbing.NewBranchReferenceName("releases"), true)
+	if err != nil || branch.Hash() != tagged.Hash {
+		t.Errorf("Expected the release commit to be pushed to releases, got %v (%v)", branch, err)
+	}
+	if exists, _ := tagExists(remote, "v1.1.0"); !exists {
+		t.Error("Expected the tag to be pushed")
+	}
+	if _, err := remote.Reference(plumbing.NewBranchReferenceName("master"), true); err == nil {
+		t.Error("Expected the checked-out branch not to be pushed")
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// buildArgs returns the lines of the -build-args file: the released version
+// without its "v" and the release commit, which is left out without a vcs.
+func buildArgs(version, commit string) []byte {
+	content := fmt.Sprintf("VERSION=%s\n", stripVPrefix(version))
+	if commit != "" {
+		content += fmt.Sprintf("VCS_REF=%s\n", commit)
+	}
+	return []byte(content)
+}
+
+// writeBuildArgs writes the -build-args file after the release, for image
+// builds to pick up the version with --build-arg or --env-file. It names the
+// release commit, so it can't be committed with it: it is build output.
+func writeBuildArgs(repo vcs, cfg config, output io.Writer, version string) error {
+	if cfg.buildArgs == "" {
+		return nil
+	}
+	if cfg.dryRun {
+		_, _ = fmt.Fprintf(output, "Would write build args VERSION=%s and VCS_REF of the release commit to %s\n", stripVPrefix(version), cfg.buildArgs)
+		return nil
+	}
+	commit, err := repo.head()
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(cfg.buildArgs, buildArgs(version, commit), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", cfg.buildArgs, err)
+	}
+	_, _ = fmt.Fprintf(output, "Wrote build args of %s to %s\n", version, cfg.buildArgs)
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBumpBuildArgs(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-minor", "-dry-run", "-build-args", "build.env"}, nil)
+	if err != nil {
+		t.Fatalf("Expected dry run to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	if _, err := os.Stat("build.env"); !os.IsNotExist(err) {
+		t.Error("Expected a dry run not to write the build args")
+	}
+	if !strings.Contains(output.String(), "Would write build args VERSION=1.1.0") {
+		t.Errorf("Expected the dry run to show the build args, got:\n%s", output.String())
+	}
+
+	output.Reset()
+	err = run(context.Background(), &output, []string{"-minor", "-build-args", "build.env"}, nil)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	want := "VERSION=1.1.0\nVCS_REF=" + mustHead(t, repo).String() + "\n"
+	if got := readFile(t, "build.env"); got != want {
+		t.Errorf("Got build args %q, want %q", got, want)
+	}
+}
+
+func TestBumpBranchBuildArgs(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	orphanBranch(t, repo, "releases", map[string]string{".version": "v1.0.0\n"})
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-minor", "-branch", "releases", "-build-args", "build.env"}, nil)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	tagged, err := tagCommit(repo, "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "VERSION=1.1.0\nVCS_REF=" + tagged.Hash.String() + "\n"
+	if got := readFile(t, "build.env"); got != want {
+		t.Errorf("Got build args %q, want the release commit on the branch, %q", got, want)
+	}
+}
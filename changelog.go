@@ -0,0 +1,464 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// changelogFile is the Keep a Changelog file of the default stream. A stream
+// keeps its own in its directory.
+const changelogFile = "CHANGELOG.md"
+
+const unreleasedHeading = "## [Unreleased]"
+
+// unreleasedLink matches the Keep a Changelog link reference of the
+// Unreleased section, e.g.
+//
+//	[Unreleased]: https://github.com/o/r/compare/v1.0.0...HEAD
+var unreleasedLink = regexp.MustCompile(`(?i)^\[unreleased\]:\s*(\S+/compare/)(\S+)\.\.\.HEAD\s*$`)
+
+// runChangelog implements "bump changelog add": it records an entry under
+// the Unreleased heading between releases. The next bump turns the
+// Unreleased section into the release's section.
+func runChangelog(_ context.Context, output io.Writer, args []string, _ []string) error {
+	if len(args) == 0 || args[0] != "add" {
+		return errors.New("usage: bump changelog add [-section name] [-stream name] <entry>")
+	}
+	flagSet := flag.NewFlagSet("changelog add", flag.ContinueOnError)
+	section := flagSet.String("section", "", "Subsection of Unreleased, e.g. Added, Changed or Fixed.")
+	streamName := flagSet.String("stream", "", "Stream of .bumpstreams whose changelog to add to.")
+	err := flagSet.Parse(args[1:])
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	entry := strings.TrimSpace(strings.Join(flagSet.Args(), " "))
+	if entry == "" {
+		return errors.New("usage: bump changelog add [-section name] [-stream name] <entry>")
+	}
+	streams, err := loadStreams(".bumpstreams")
+	if err != nil {
+		return fmt.Errorf("failed to load .bumpstreams: %w", err)
+	}
+	file, err := changelogPath(streams, *streamName)
+	if err != nil {
+		return err
+	}
+
+	osPath := filepath.FromSlash(file)
+	content, err := os.ReadFile(osPath)
+	if errors.Is(err, os.ErrNotExist) {
+		content, err = []byte("# Changelog\n"), nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	err = os.WriteFile(osPath, []byte(addUnreleased(string(content), *section, entry)), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	_, _ = fmt.Fprintf(output, "Added to the Unreleased section of %s\n", file)
+	return nil
+}
+
+// changelogPath returns the repository path of the stream's changelog.
+func changelogPath(streams []versionStream, name string) (string, error) {
+	stream, err := selectStream(streams, name)
+	if err != nil {
+		return "", err
+	}
+	if stream == nil {
+		return changelogFile, nil
+	}
+	return path.Join(stream.path, changelogFile), nil
+}
+
+// addUnreleased adds an entry to the Unreleased section, in the subsection
+// if one is given. Missing headings are created: Unreleased goes before the
+// first release section.
+func addUnreleased(content, section, entry string) string {
+	lines, start, end := ensureUnreleased(content)
+
+	if section != "" {
+		heading := "### " + section
+		found := -1
+		for i := start + 1; i < end; i++ {
+			if strings.EqualFold(strings.TrimSpace(lines[i]), heading) {
+				found = i
+				break
+			}
+		}
+		if found < 0 {
+			found = lastContent(lines, start, end) + 1
+			lines = insertBlock(lines, found, heading)
+			if strings.TrimSpace(lines[found]) == "" {
+				found++
+			}
+			start, end = unreleasedSection(lines)
+		}
+		// the subsection ends at the next heading
+		start = found
+		for i := found + 1; i < end; i++ {
+			if strings.HasPrefix(lines[i], "#") {
+				end = i
+				break
+			}
+		}
+	}
+	at := lastContent(lines, start, end) + 1
+	if at == start+1 {
+		lines = insertBlock(lines, at, "- "+entry)
+	} else {
+		lines = insertLines(lines, at, "- "+entry)
+		if at+1 < len(lines) && strings.TrimSpace(lines[at+1]) != "" {
+			lines = insertLines(lines, at+1, "")
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// ensureUnreleased splits a changelog into lines, adding the Unreleased
+// heading before the first release section if it is missing, and returns
+// the bounds of the Unreleased section.
+func ensureUnreleased(content string) ([]string, int, int) {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	start, end := unreleasedSection(lines)
+	if start < 0 {
+		at := len(lines)
+		for i, line := range lines {
+			if strings.HasPrefix(line, "## ") || isLinkReference(line) {
+				at = i
+				break
+			}
+		}
+		lines = insertBlock(lines, at, unreleasedHeading)
+		start, end = unreleasedSection(lines)
+	}
+	return lines, start, end
+}
+
+// changelogSubject matches a Conventional Commit subject, capturing the
+// type, scope, "!" and description.
+var changelogSubject = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?(!)?: (\S.*)$`)
+
+// changelogSections are the Unreleased subsections -changelog files commits
+// under by their Conventional Commit type. Other commits, including those
+// that don't follow the convention, are Changed.
+var changelogSections = map[string]string{
+	"feat": "Added",
+	"fix":  "Fixed",
+}
+
+// changelogSkipped are the Conventional Commit types -changelog leaves out:
+// they don't change what users get.
+var changelogSkipped = map[string]bool{
+	"build": true,
+	"chore": true,
+	"ci":    true,
+	"style": true,
+	"test":  true,
+}
+
+// commitEntry returns the subsection and changelog entry of a commit
+// subject, or false for a commit the changelog leaves out.
+func commitEntry(subject string) (string, string, bool) {
+	m := changelogSubject.FindStringSubmatch(subject)
+	if m == nil {
+		return "Changed", subject, true
+	}
+	kind, scope, breaking, description := strings.ToLower(m[1]), m[2], m[3] == "!", m[4]
+	if changelogSkipped[kind] && !breaking {
+		return "", "", false
+	}
+	section, ok := changelogSections[kind]
+	if !ok {
+		section = "Changed"
+	}
+	entry := description
+	if scope != "" {
+		entry = scope + ": " + entry
+	}
+	if breaking {
+		entry = "**Breaking:** " + entry
+	}
+	return section, entry, true
+}
+
+// addCommitEntries adds the commits since the last release to the
+// Unreleased section (-changelog), oldest first, grouped into subsections by
+// commitEntry. Entries the section already has, e.g. from
+// "bump changelog add", aren't repeated.
+func addCommitEntries(content string, changes []string) string {
+	lines, start, end := ensureUnreleased(content)
+	present := make(map[string]bool)
+	for _, entry := range sectionEntries(lines[start+1 : end]) {
+		present[entry] = true
+	}
+	for i := len(changes) - 1; i >= 0; i-- {
+		section, entry, ok := commitEntry(changes[i])
+		if !ok || present[entry] || present[changes[i]] {
+			continue
+		}
+		present[entry] = true
+		content = addUnreleased(content, section, entry)
+	}
+	return content
+}
+
+// addTemplateEntries appends the -changelog-template, rendered against the
+// release, to the Unreleased section. The template is free to write any
+// Markdown, subsection headings included.
+func addTemplateEntries(content, templateFile string, rel release) (string, error) {
+	text, err := os.ReadFile(templateFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read -changelog-template: %w", err)
+	}
+	rendered, err := renderTemplate(templateFile, string(text), rel)
+	if err != nil {
+		return "", err
+	}
+	rendered = strings.Trim(rendered, "\n")
+	if strings.TrimSpace(rendered) == "" {
+		return content, nil
+	}
+	lines, start, end := ensureUnreleased(content)
+	at := lastContent(lines, start, end) + 1
+	block := strings.Split(rendered, "\n")
+	if at < len(lines) && strings.TrimSpace(lines[at]) != "" {
+		block = append(block, "")
+	}
+	lines = insertLines(lines, at, append([]string{""}, block...)...)
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// releaseUnreleased moves the entries of the Unreleased section into a new
+// section of the version, dated, and updates the Keep a Changelog link
+// references. It reports false if there is nothing unreleased.
+func releaseUnreleased(content, previous, version, date string) (string, bool) {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	start, end := unreleasedSection(lines)
+	if start < 0 || !hasEntries(lines[start+1:end]) {
+		return content, false
+	}
+	heading := fmt.Sprintf("## [%s] - %s", version, date)
+	lines = insertBlock(lines, start+1, heading)
+
+	for i, line := range lines {
+		match := unreleasedLink.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		base, from := match[1], match[2]
+		if previous != "" {
+			from = previous
+		}
+		lines[i] = fmt.Sprintf("[Unreleased]: %s%s...HEAD", base, version)
+		lines = insertLines(lines, i+1, fmt.Sprintf("[%s]: %s%s...%s", version, base, from, version))
+		break
+	}
+	return strings.Join(lines, "\n") + "\n", true
+}
+
+// unreleasedSection returns the line of the Unreleased heading and the end
+// of its section, or -1 if there is none.
+func unreleasedSection(lines []string) (int, int) {
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "## ") || !strings.Contains(strings.ToLower(line), "unreleased") {
+			continue
+		}
+		for j := i + 1; j < len(lines); j++ {
+			if strings.HasPrefix(lines[j], "## ") || isLinkReference(lines[j]) {
+				return i, j
+			}
+		}
+		return i, len(lines)
+	}
+	return -1, -1
+}
+
+var linkReference = regexp.MustCompile(`^\[[^\]]+\]:\s`)
+
+// isLinkReference reports whether the line is a Markdown link reference
+// definition, which close a Keep a Changelog file.
+func isLinkReference(line string) bool {
+	return linkReference.MatchString(line)
+}
+
+// hasEntries reports whether a section has content beyond its subsection
+// headings.
+func hasEntries(lines []string) bool {
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			return true
+		}
+	}
+	return false
+}
+
+// lastContent returns the last non-empty line in [start, end).
+func lastContent(lines []string, start, end int) int {
+	last := start
+	for i := start + 1; i < end; i++ {
+		if strings.TrimSpace(lines[i]) != "" {
+			last = i
+		}
+	}
+	return last
+}
+
+func insertLines(lines []string, at int, insert ...string) []string {
+	return append(lines[:at], append(insert, lines[at:]...)...)
+}
+
+// insertBlock inserts a line as a block of its own, separated by blank lines
+// from its neighbours.
+func insertBlock(lines []string, at int, line string) []string {
+	block := []string{line}
+	if at > 0 && strings.TrimSpace(lines[at-1]) != "" {
+		block = append([]string{""}, block...)
+	}
+	if at < len(lines) && strings.TrimSpace(lines[at]) != "" {
+		block = append(block, "")
+	}
+	return insertLines(lines, at, block...)
+}
+
+// releasedChangelog is a changelog whose Unreleased section is released.
+type releasedChangelog struct {
+	generatedFile
+	// module is the path of the directory holding the changelog
+	module string
+	// highlights are the entries of the released section
+	highlights []string
+}
+
+// rollChangelogs releases the Unreleased sections of the changelog of the
+// stream being bumped and of the changelogs of the modules bumped with it,
+// if they have entries. Changelogs that are missing or have nothing
+// unreleased are skipped.
+func rollChangelogs(cfg config, rel release) ([]releasedChangelog, error) {
+	file, err := changelogPath(cfg.streams, cfg.stream)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{file}
+	modules, err := bumpedModules(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range modules {
+		if moduleFile := path.Join(m.Path, changelogFile); moduleFile != file {
+			files = append(files, moduleFile)
+		}
+	}
+
+	var released []releasedChangelog
+	for i, file := range files {
+		changelog, err := rollChangelog(cfg, file, rel, i == 0 && cfg.changelog)
+		if err != nil {
+			return nil, err
+		}
+		if changelog != nil {
+			released = append(released, *changelog)
+		}
+	}
+	return released, nil
+}
+
+// rollChangelog releases the Unreleased section of a changelog. With
+// commits, the commits since the last release are added to the section
+// first, creating the changelog if it is missing. It returns nil if there is
+// no changelog or nothing unreleased.
+func rollChangelog(cfg config, file string, rel release, commits bool) (*releasedChangelog, error) {
+	raw, err := os.ReadFile(filepath.FromSlash(file))
+	if errors.Is(err, os.ErrNotExist) {
+		if !commits {
+			return nil, nil
+		}
+		raw, err = []byte("# Changelog\n"), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	content := string(raw)
+	if commits && cfg.changelogTemplate != "" {
+		content, err = addTemplateEntries(content, cfg.changelogTemplate, rel)
+		if err != nil {
+			return nil, err
+		}
+	} else if commits {
+		content = addCommitEntries(content, rel.Changes)
+	}
+	released, ok := releaseUnreleased(content, rel.Previous, rel.Version, rel.Date.Format(cfg.dateFormat))
+	if !ok {
+		return nil, nil
+	}
+	lines := strings.Split(content, "\n")
+	start, end := unreleasedSection(lines)
+	return &releasedChangelog{
+		generatedFile: generatedFile{path: file, content: []byte(released)},
+		module:        path.Dir(file),
+		highlights:    sectionEntries(lines[start+1 : end]),
+	}, nil
+}
+
+// sectionEntries returns the list items of a changelog section.
+func sectionEntries(lines []string) []string {
+	var entries []string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if entry, ok := strings.CutPrefix(line, "- "); ok {
+			entries = append(entries, entry)
+		} else if entry, ok := strings.CutPrefix(line, "* "); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// bumpedModules returns the modules whose version files the release updates.
+func bumpedModules(cfg config) ([]module, error) {
+	modules, err := findModules()
+	if err != nil {
+		return nil, err
+	}
+	writeRules, err := loadWorktreeRules()
+	if err != nil {
+		return nil, err
+	}
+	var bumped []module
+	for _, m := range modules {
+		versionFile := path.Join(m.Path, ".version")
+		if !writeRules.excluded(versionFile) && streamOf(cfg.streams, versionFile) == cfg.stream {
+			bumped = append(bumped, m)
+		}
+	}
+	return bumped, nil
+}
+
+// writeChangelogs writes the released changelogs into the worktree and
+// stages them with the release.
+func writeChangelogs(repo vcs, cfg config, output io.Writer, changelogs []releasedChangelog, version string) error {
+	for _, changelog := range changelogs {
+		_, _ = fmt.Fprintf(output, "Releasing the Unreleased section of %s as %s\n", changelog.path, version)
+		if cfg.dryRun {
+			err := previewFile(output, changelog.path, changelog.content)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		err := writeReleaseFile(repo, changelog.path, changelog.content)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
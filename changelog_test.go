@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testChangelog = `# Changelog
+
+All notable changes to this project are documented in this file.
+
+## [v1.0.0] - 2026-01-02
+
+### Added
+
+- First release
+
+[Unreleased]: https://github.com/o/r/compare/v1.0.0...HEAD
+[v1.0.0]: https://github.com/o/r/releases/tag/v1.0.0
+`
+
+func TestAddUnreleased(t *testing.T) {
+	got := addUnreleased(testChangelog, "Fixed", "Crash on empty input")
+	got = addUnreleased(got, "fixed", "Leak in the pool")
+	got = addUnreleased(got, "Added", "Retries")
+	got = addUnreleased(got, "", "Faster startup")
+	want := `# Changelog
+
+All notable changes to this project are documented in this file.
+
+## [Unreleased]
+
+### Fixed
+
+- Crash on empty input
+- Leak in the pool
+
+### Added
+
+- Retries
+- Faster startup
+
+## [v1.0.0] - 2026-01-02
+
+### Added
+
+- First release
+
+[Unreleased]: https://github.com/o/r/compare/v1.0.0...HEAD
+[v1.0.0]: https://github.com/o/r/releases/tag/v1.0.0
+`
+	if got != want {
+		t.Errorf("Got changelog:\n%s\nwant:\n%s", got, want)
+	}
+
+	got = addUnreleased("# Changelog\n", "", "First entry")
+	if got != "# Changelog\n\n## [Unreleased]\n\n- First entry\n" {
+		t.Errorf("Got new changelog:\n%s", got)
+	}
+}
+
+func TestReleaseUnreleased(t *testing.T) {
+	date := "2026-03-04"
+	if _, ok := releaseUnreleased(testChangelog, "v1.0.0", "v1.1.0", date); ok {
+		t.Error("Expected nothing to release without an Unreleased section")
+	}
+	empty := addUnreleased(testChangelog, "Fixed", "x")
+	empty = strings.Replace(empty, "- x\n", "", 1)
+	if _, ok := releaseUnreleased(empty, "v1.0.0", "v1.1.0", date); ok {
+		t.Error("Expected nothing to release from empty subsections")
+	}
+
+	got, ok := releaseUnreleased(addUnreleased(testChangelog, "Fixed", "Crash on empty input"), "v1.0.0", "v1.1.0", date)
+	if !ok {
+		t.Fatal("Expected the Unreleased section to be released")
+	}
+	want := `# Changelog
+
+All notable changes to this project are documented in this file.
+
+## [Unreleased]
+
+## [v1.1.0] - 2026-03-04
+
+### Fixed
+
+- Crash on empty input
+
+## [v1.0.0] - 2026-01-02
+
+### Added
+
+- First release
+
+[Unreleased]: https://github.com/o/r/compare/v1.1.0...HEAD
+[v1.1.0]: https://github.com/o/r/compare/v1.0.0...v1.1.0
+[v1.0.0]: https://github.com/o/r/releases/tag/v1.0.0
+`
+	if got != want {
+		t.Errorf("Got changelog:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestBumpChangelog(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Add changelog", map[string]string{changelogFile: testChangelog})
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"changelog", "add", "-section", "Fixed", "Crash", "on", "empty", "input"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, repo, "Fix crash", map[string]string{changelogFile: readFile(t, changelogFile)})
+
+	err = run(context.Background(), &output, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	if !strings.Contains(output.String(), "Releasing the Unreleased section of CHANGELOG.md as v1.0.1") {
+		t.Errorf("Expected the changelog to be released, got:\n%s", output.String())
+	}
+	commit, err := tagCommit(repo, "v1.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := commit.File(changelogFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := file.Contents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(content, "## [Unreleased]\n\n## [v1.0.1] - ") || !strings.Contains(content, "[v1.0.1]: https://github.com/o/r/compare/v1.0.0...v1.0.1") {
+		t.Errorf("Expected the release commit to contain the released changelog, got:\n%s", content)
+	}
+
+	// nothing unreleased leaves the changelog alone
+	output.Reset()
+	err = run(context.Background(), &output, []string{"-force"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(output.String(), "Releasing the Unreleased section") {
+		t.Errorf("Expected an empty Unreleased section to be left alone, got:\n%s", output.String())
+	}
+
+	err = run(context.Background(), &output, []string{"changelog", "add"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "usage: bump changelog add") {
+		t.Errorf("Expected a usage error, got: %v", err)
+	}
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestAddCommitEntries(t *testing.T) {
+	// newest first, as changesSince returns them
+	changes := []string{
+		"chore: update dependencies",
+		"feat(api)!: drop the v1 endpoints",
+		"Tidy the README",
+		"fix: crash on empty input",
+		"feat: export to CSV",
+	}
+	got := addCommitEntries("# Changelog\n\n## [Unreleased]\n\n### Fixed\n\n- crash on empty input\n", changes)
+	want := `# Changelog
+
+## [Unreleased]
+
+### Fixed
+
+- crash on empty input
+
+### Added
+
+- export to CSV
+- **Breaking:** api: drop the v1 endpoints
+
+### Changed
+
+- Tidy the README
+`
+	if got != want {
+		t.Errorf("Got changelog:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestBumpChangelogFromCommits(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "feat: export to CSV", map[string]string{"export.go": "package main\n"})
+	commitFiles(t, repo, "fix: crash on empty input", map[string]string{"export.go": "package main\n\n"})
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-changelog"}, nil)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	commit, err := tagCommit(repo, "v1.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := commit.File(changelogFile)
+	if err != nil {
+		t.Fatalf("Expected the release commit to create %s: %v", changelogFile, err)
+	}
+	content, err := file.Contents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "### Added\n\n- export to CSV\n\n### Fixed\n\n- crash on empty input\n"
+	if !strings.Contains(content, "## [Unreleased]\n\n## [v1.0.1] - ") || !strings.Contains(content, want) {
+		t.Errorf("Expected the commits in the released section, got:\n%s", content)
+	}
+
+	commitFiles(t, repo, "Speed up the export", map[string]string{
+		"export.go":    "package main\n\n\n",
+		"entries.tmpl": "{{range .Changes}}- {{.}} ({{$.Version}})\n{{end}}",
+	})
+	output.Reset()
+	err = run(context.Background(), &output, []string{"-changelog-template", "entries.tmpl"}, nil)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	content = readFile(t, changelogFile)
+	if !strings.Contains(content, "## [v1.0.2] - ") || !strings.Contains(content, "- Speed up the export (v1.0.2)\n") {
+		t.Errorf("Expected the rendered template in the released section, got:\n%s", content)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"golang.org/x/mod/semver"
+)
+
+// channel is a release channel from .bumpchannels. A version enters a
+// channel by promotion, which tags the version's commit <channel>/<version>.
+// Promotion into a channel with a source requires the version to be in the
+// source channel.
+type channel struct {
+	name string
+	from string // source channel, empty if any version may enter
+}
+
+// defaultChannels are used if there is no .bumpchannels file.
+var defaultChannels = []channel{{name: "edge"}, {name: "beta", from: "edge"}, {name: "stable", from: "beta"}}
+
+var channelName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// loadChannels reads the release channels. The format is line based:
+//
+//	edge                 # any version can be promoted to edge
+//	beta from edge
+//	stable from beta
+//
+// A source channel must be declared before the channels it feeds.
+func loadChannels(path string) ([]channel, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return defaultChannels, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var channels []channel
+	for i, line := range strings.Split(string(content), "\n") {
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(strings.ToLower(line))
+		if len(fields) == 0 {
+			continue
+		}
+		var ch channel
+		switch {
+		case len(fields) == 1:
+			ch.name = fields[0]
+		case len(fields) == 3 && fields[1] == "from":
+			ch.name, ch.from = fields[0], fields[2]
+		default:
+			return nil, fmt.Errorf("line %d: expected '<channel> [from <channel>]'", i+1)
+		}
+		if !channelName.MatchString(ch.name) {
+			return nil, fmt.Errorf("line %d: invalid channel name '%s'", i+1, ch.name)
+		}
+		if _, ok := findChannel(channels, ch.name); ok {
+			return nil, fmt.Errorf("line %d: channel %s already declared", i+1, ch.name)
+		}
+		if _, ok := findChannel(channels, ch.from); ch.from != "" && !ok {
+			return nil, fmt.Errorf("line %d: channel %s must be declared before %s", i+1, ch.from, ch.name)
+		}
+		channels = append(channels, ch)
+	}
+	return channels, nil
+}
+
+func findChannel(channels []channel, name string) (channel, bool) {
+	for _, ch := range channels {
+		if ch.name == name {
+			return ch, true
+		}
+	}
+	return channel{}, false
+}
+
+// channelTag is the tag marking version as promoted to the channel.
+func channelTag(name, version string) string {
+	return name + "/" + version
+}
+
+// channelVersion returns the highest version promoted to the channel, or ""
+// if none was.
+func channelVersion(repo *git.Repository, name string) (string, error) {
+	var versions []string
+	err := forEachTag(repo, func(t *plumbing.Reference) error {
+		if version, ok := strings.CutPrefix(t.Name().Short(), name+"/"); ok {
+			versions = append(versions, version)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(versions) == 0 {
+		return "", nil
+	}
+	version, err := highestVersion(versions)
+	if err != nil {
+		return "", nil // only tags that aren't versions
+	}
+	return version, nil
+}
+
+// runChannel implements "bump channel": "list" prints the version of every
+// channel, "promote <channel> <version>" promotes a version.
+func runChannel(_ context.Context, output io.Writer, args []string, _ []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	channels, err := loadChannels(".bumpchannels")
+	if err != nil {
+		return fmt.Errorf("failed to load .bumpchannels: %w", err)
+	}
+	switch args[0] {
+	case "list":
+		if len(args) > 1 {
+			return fmt.Errorf("unexpected arguments: %s", args[1:])
+		}
+		repo, err := git.PlainOpen(".")
+		if err != nil {
+			return fmt.Errorf("failed to open repository: %w", err)
+		}
+		for _, ch := range channels {
+			version, err := channelVersion(repo, ch.name)
+			if err != nil {
+				return err
+			}
+			if version == "" {
+				version = "-"
+			}
+			_, _ = fmt.Fprintf(output, "%s: %s\n", ch.name, version)
+		}
+		return nil
+	case "promote":
+		return promote(output, args[1:], channels)
+	default:
+		return fmt.Errorf("unknown channel command '%s': must be list or promote", args[0])
+	}
+}
+
+// promote tags a version's commit as promoted to a channel. The version must
+// be in the channel's source channel, unless forced. Older versions can be
+// promoted too, e.g. a backported patch, but the channel's version stays the
+// highest one promoted.
+func promote(output io.Writer, args []string, channels []channel) error {
+	flagSet := flag.NewFlagSet("channel promote", flag.ContinueOnError)
+	dryRun := flagSet.Bool("dry-run", false, "Check the promotion without creating the tag.")
+	force := flagSet.Bool("force", false, "Promote even if the version is not in the source channel.")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if flagSet.NArg() != 2 {
+		return errors.New("usage: bump channel promote [-dry-run] [-force] <channel> <version>")
+	}
+	name, version := flagSet.Arg(0), flagSet.Arg(1)
+	ch, ok := findChannel(channels, name)
+	if !ok {
+		return fmt.Errorf("unknown channel '%s'", name)
+	}
+
+	if !semver.IsValid(normalizeVersion(version)) {
+		return fmt.Errorf("invalid version '%s'", version)
+	}
+
+	repo, err := git.PlainOpen(".")
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	commit, err := tagCommit(repo, version)
+	if err != nil {
+		return fmt.Errorf("%s is not a released version: %w", version, err)
+	}
+	tag := channelTag(ch.name, version)
+	exists, err := tagExists(repo, tag)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("%s is already in %s", version, ch.name)
+	}
+	if ch.from != "" && !*force {
+		inSource, err := tagExists(repo, channelTag(ch.from, version))
+		if err != nil {
+			return err
+		}
+		if !inSource {
+			return fmt.Errorf("%s is not in %s, promote it there first (or use -force)", version, ch.from)
+		}
+	}
+
+	if *dryRun {
+		_, _ = fmt.Fprintf(output, "Would promote %s to %s, tag=%s\n", version, ch.name, tag)
+		return nil
+	}
+	_, err = repo.CreateTag(tag, commit.Hash, &git.CreateTagOptions{
+		Message: fmt.Sprintf("Promote %s to %s", version, ch.name),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create tag: %w", err)
+	}
+	_, _ = fmt.Fprintf(output, "Promoted %s to %s, tag=%s\n", version, ch.name, tag)
+	return nil
+}
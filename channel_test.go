@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoadChannels(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		errText string
+	}{
+		{name: "chain", content: "# channels\nnightly\nrc from nightly\nga from rc   # customers\n", want: "nightly rc<nightly ga<rc"},
+		{name: "two roots", content: "edge\nlts\n", want: "edge lts"},
+		{name: "undeclared source", content: "beta from edge\nedge\n", errText: "line 1: channel edge must be declared before beta"},
+		{name: "duplicate", content: "edge\nedge\n", errText: "line 2: channel edge already declared"},
+		{name: "syntax", content: "beta after edge\n", errText: "line 1: expected '<channel> [from <channel>]'"},
+		{name: "name", content: "be/ta\n", errText: "line 1: invalid channel name 'be/ta'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := t.TempDir() + "/.bumpchannels"
+			err := os.WriteFile(path, []byte(tt.content), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			channels, err := loadChannels(path)
+			if tt.errText != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errText) {
+					t.Fatalf("Expected error containing %q, got: %v", tt.errText, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, ch := range channels {
+				if ch.from != "" {
+					got = append(got, ch.name+"<"+ch.from)
+				} else {
+					got = append(got, ch.name)
+				}
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("Got channels %q, want %q", strings.Join(got, " "), tt.want)
+			}
+		})
+	}
+
+	channels, err := loadChannels(t.TempDir() + "/.bumpchannels")
+	if err != nil || len(channels) != 3 || channels[2] != (channel{name: "stable", from: "beta"}) {
+		t.Errorf("Expected the default channels, got %v, %v", channels, err)
+	}
+}
+
+func TestChannelPromote(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	var output bytes.Buffer
+	err := run(context.Background(), &output, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v", err)
+	}
+	channel := func(args ...string) error {
+		output.Reset()
+		return run(context.Background(), &output, append([]string{"channel"}, args...), nil)
+	}
+
+	err = channel("promote", "beta", "v1.0.0")
+	if err == nil || !strings.Contains(err.Error(), "v1.0.0 is not in edge, promote it there first") {
+		t.Errorf("Expected promotion to skip a channel to fail, got: %v", err)
+	}
+	for _, name := range []string{"edge", "beta", "stable"} {
+		err = channel("promote", name, "v1.0.0")
+		if err != nil {
+			t.Fatalf("Expected promotion to %s to succeed, got: %v", name, err)
+		}
+	}
+	if !strings.Contains(output.String(), "Promoted v1.0.0 to stable, tag=stable/v1.0.0") {
+		t.Errorf("Unexpected output %q", output.String())
+	}
+	err = channel("promote", "edge", "v1.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the channel tags point at the release, not at HEAD
+	released, err := tagCommit(repo, "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	promoted, err := tagCommit(repo, "stable/v1.0.0")
+	if err != nil || promoted.Hash != released.Hash {
+		t.Errorf("Expected stable/v1.0.0 to point at v1.0.0, got %v, %v", promoted, err)
+	}
+	// and don't count as version tags
+	last, err := lastTag(repo)
+	if err != nil || last != "v1.0.1" {
+		t.Errorf("Expected the last tag to stay v1.0.1, got %q, %v", last, err)
+	}
+
+	err = channel("list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.String() != "edge: v1.0.1\nbeta: v1.0.0\nstable: v1.0.0\n" {
+		t.Errorf("Unexpected channel list:\n%s", output.String())
+	}
+
+	for _, tt := range []struct {
+		args    []string
+		errText string
+	}{
+		{[]string{"promote", "edge", "v1.0.0"}, "v1.0.0 is already in edge"},
+		{[]string{"promote", "edge", "v9.0.0"}, "v9.0.0 is not a released version"},
+		{[]string{"promote", "beta", "edge/v1.0.0"}, "invalid version 'edge/v1.0.0'"},
+		{[]string{"promote", "nightly", "v1.0.1"}, "unknown channel 'nightly'"},
+		{[]string{"promote", "edge"}, "usage: bump channel promote"},
+		{[]string{"rollback"}, "unknown channel command 'rollback'"},
+	} {
+		err = channel(tt.args...)
+		if err == nil || !strings.Contains(err.Error(), tt.errText) {
+			t.Errorf("channel %v: expected error containing %q, got: %v", tt.args, tt.errText, err)
+		}
+	}
+}
+
+func TestChannelPromoteForced(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"channel", "promote", "-dry-run", "-force", "stable", "v1.0.0"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := tagExists(repo, "stable/v1.0.0"); exists {
+		t.Error("Expected a dry run not to create the tag")
+	}
+	err = run(context.Background(), &output, []string{"channel", "promote", "-force", "stable", "v1.0.0"}, nil)
+	if err != nil {
+		t.Fatalf("Expected a forced promotion to succeed, got: %v", err)
+	}
+	if exists, _ := tagExists(repo, "stable/v1.0.0"); !exists {
+		t.Error("Expected the version to be promoted to stable")
+	}
+}
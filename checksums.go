@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// artifactGlobs splits the -artifacts value and validates the patterns, so a
+// typo fails the bump before anything is written.
+func artifactGlobs(value string) ([]string, error) {
+	return splitGlobs(value, "artifact")
+}
+
+// splitGlobs splits a comma-separated list of glob patterns and validates
+// them; what names the patterns in errors.
+func splitGlobs(value, what string) ([]string, error) {
+	var globs []string
+	for _, glob := range strings.Split(value, ",") {
+		glob = strings.TrimSpace(glob)
+		if glob == "" {
+			continue
+		}
+		_, err := filepath.Match(glob, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s pattern '%s': %w", what, glob, err)
+		}
+		globs = append(globs, glob)
+	}
+	return globs, nil
+}
+
+// matchArtifacts returns the regular files matching any of the globs, sorted
+// and without duplicates. The manifest and its signature are skipped, in case
+// they live next to the artifacts.
+func matchArtifacts(globs []string, manifest string) ([]string, error) {
+	seen := map[string]bool{filepath.Clean(manifest): true, filepath.Clean(manifest + ".asc"): true}
+	var files []string
+	for _, glob := range globs {
+		matches, err := filepath.Glob(glob)
+		if err != nil {
+			return nil, fmt.Errorf("invalid artifact pattern '%s': %w", glob, err)
+		}
+		for _, match := range matches {
+			match = filepath.Clean(match)
+			if seen[match] {
+				continue
+			}
+			info, err := os.Stat(match)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			seen[match] = true
+			files = append(files, match)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no artifacts match %s", strings.Join(globs, ", "))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// checksumManifest renders the SHA-256 of every file in the format of
+// sha256sum, so `sha256sum -c checksums.txt` verifies a download.
+func checksumManifest(files []string) ([]byte, error) {
+	var manifest bytes.Buffer
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read artifact: %w", err)
+		}
+		hash := sha256.New()
+		_, err = io.Copy(hash, f)
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read artifact %s: %w", file, err)
+		}
+		_, _ = fmt.Fprintf(&manifest, "%s  %s\n", hex.EncodeToString(hash.Sum(nil)), filepath.ToSlash(file))
+	}
+	return manifest.Bytes(), nil
+}
+
+// signingKey loads the key from BUMP_CHECKSUMS_KEY (see loadPrivateKey). It
+// returns nil if no key is configured or there is no manifest to sign. The key
+// is loaded before the release, so a bad key can't fail it halfway.
+func signingKey(cfg config, env []string) (*openpgp.Entity, error) {
+	if getenv(env, "BUMP_CHECKSUMS_KEY") == "" || len(cfg.artifacts) == 0 {
+		return nil, nil
+	}
+	return loadPrivateKey(env, "BUMP_CHECKSUMS_KEY")
+}
+
+// writeChecksums writes the checksum manifest of the release artifacts and,
+// with a signing key, its armored detached signature next to it (.asc).
+func writeChecksums(cfg config, output io.Writer, key *openpgp.Entity) error {
+	if len(cfg.artifacts) == 0 {
+		return nil
+	}
+	files, err := matchArtifacts(cfg.artifacts, cfg.checksums)
+	if err != nil {
+		return err
+	}
+	if cfg.dryRun {
+		_, _ = fmt.Fprintf(output, "Would write checksums of %d artifact(s) to %s\n", len(files), cfg.checksums)
+		return nil
+	}
+	manifest, err := checksumManifest(files)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(cfg.checksums, manifest, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", cfg.checksums, err)
+	}
+	_, _ = fmt.Fprintf(output, "Wrote checksums of %d artifact(s) to %s\n", len(files), cfg.checksums)
+	if key == nil {
+		return nil
+	}
+
+	var signature bytes.Buffer
+	err = openpgp.ArmoredDetachSign(&signature, key, bytes.NewReader(manifest), nil)
+	if err != nil {
+		return fmt.Errorf("failed to sign %s: %w", cfg.checksums, err)
+	}
+	err = os.WriteFile(cfg.checksums+".asc", signature.Bytes(), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write signature: %w", err)
+	}
+	_, _ = fmt.Fprintf(output, "Signed %s with key %X\n", cfg.checksums, key.PrimaryKey.Fingerprint)
+	return nil
+}
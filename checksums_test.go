@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+func TestArtifactGlobs(t *testing.T) {
+	globs, err := artifactGlobs(" dist/*.tar.gz, ,dist/*.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(globs, "|") != "dist/*.tar.gz|dist/*.zip" {
+		t.Errorf("Unexpected globs %q", globs)
+	}
+	_, err = artifactGlobs("dist/[")
+	if err == nil || !strings.Contains(err.Error(), "invalid artifact pattern 'dist/['") {
+		t.Errorf("Expected an invalid pattern error, got: %v", err)
+	}
+}
+
+func TestChecksumManifest(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeTestFile(t, "dist/b.zip", "b")
+	writeTestFile(t, "dist/a.tar.gz", "a")
+	writeTestFile(t, "dist/checksums.txt", "stale")
+	err := os.Mkdir(filepath.Join("dist", "dir.zip"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := matchArtifacts([]string{"dist/*", "dist/*.zip"}, "dist/checksums.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := checksumManifest(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb  dist/a.tar.gz\n" +
+		"3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d  dist/b.zip\n"
+	if string(manifest) != want {
+		t.Errorf("Got manifest:\n%s\nwant:\n%s", manifest, want)
+	}
+
+	_, err = matchArtifacts([]string{"build/*"}, "checksums.txt")
+	if err == nil || !strings.Contains(err.Error(), "no artifacts match build/*") {
+		t.Errorf("Expected a no match error, got: %v", err)
+	}
+}
+
+func TestBumpWithChecksums(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	key, keyFile := testSigningKey(t)
+	writeTestFile(t, "dist/widget.tar.gz", "widget")
+
+	var output bytes.Buffer
+	env := []string{"BUMP_CHECKSUMS_KEY=" + keyFile, "BUMP_CHECKSUMS_KEY_PASSPHRASE=secret"}
+	err := run(context.Background(), &output, []string{"-artifacts", "dist/*"}, env)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	if exists, _ := tagExists(repo, "v1.0.1"); !exists {
+		t.Error("Expected the release to be tagged")
+	}
+	manifest, err := os.ReadFile("checksums.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(manifest), "  dist/widget.tar.gz\n") {
+		t.Errorf("Unexpected manifest:\n%s", manifest)
+	}
+	signature, err := os.Open("checksums.txt.asc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = signature.Close() }()
+	_, err = openpgp.CheckArmoredDetachedSignature(openpgp.EntityList{key}, bytes.NewReader(manifest), signature, nil)
+	if err != nil {
+		t.Errorf("Expected a valid signature, got: %v", err)
+	}
+}
+
+func TestBumpBranchWithChecksums(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	orphanBranch(t, repo, "releases", map[string]string{".version": "v1.0.0\n"})
+	writeTestFile(t, "dist/widget.tar.gz", "widget")
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-branch", "releases", "-artifacts", "dist/*"}, nil)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	manifest, err := os.ReadFile("checksums.txt")
+	if err != nil {
+		t.Fatalf("Expected the -branch release to write the manifest: %v", err)
+	}
+	if !strings.HasSuffix(string(manifest), "  dist/widget.tar.gz\n") {
+		t.Errorf("Unexpected manifest:\n%s", manifest)
+	}
+}
+
+func TestBumpWithChecksumsFailures(t *testing.T) {
+	t.Run("bad key", func(t *testing.T) {
+		_, repo := setupTaggedTestRepo(t, "v1.0.0")
+		_, keyFile := testSigningKey(t)
+		writeTestFile(t, "dist/widget.tar.gz", "widget")
+		commits := countCommits(t, repo)
+
+		var output bytes.Buffer
+		env := []string{"BUMP_CHECKSUMS_KEY=" + keyFile, "BUMP_CHECKSUMS_KEY_PASSPHRASE=wrong"}
+		err := run(context.Background(), &output, []string{"-artifacts", "dist/*"}, env)
+		if err == nil || !strings.Contains(err.Error(), "failed to decrypt BUMP_CHECKSUMS_KEY") {
+			t.Fatalf("Expected a decryption error, got: %v", err)
+		}
+		if exists, _ := tagExists(repo, "v1.0.1"); exists || countCommits(t, repo) != commits {
+			t.Error("Expected no release with an unusable key")
+		}
+	})
+	t.Run("no artifacts", func(t *testing.T) {
+		_, repo := setupTaggedTestRepo(t, "v1.0.0")
+
+		var output bytes.Buffer
+		err := run(context.Background(), &output, []string{"-artifacts", "dist/*"}, nil)
+		if err == nil || !strings.Contains(err.Error(), "release v1.0.1 was created but writing the checksum manifest failed") {
+			t.Fatalf("Expected a checksum error naming the release, got: %v", err)
+		}
+		if exists, _ := tagExists(repo, "v1.0.1"); !exists {
+			t.Error("Expected the release to be tagged")
+		}
+	})
+}
+
+// testSigningKey creates an OpenPGP key and writes it, encrypted with the
+// passphrase "secret", to an armored key file.
+func testSigningKey(t *testing.T) (*openpgp.Entity, string) {
+	t.Helper()
+	key, err := openpgp.NewEntity("Release Bot", "", "release@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = key.EncryptPrivateKeys([]byte("secret"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var armored bytes.Buffer
+	w, err := armor.Encode(&armored, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = key.SerializePrivateWithoutSigning(w, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = w.Close()
+	keyFile := filepath.Join(t.TempDir(), "release.asc")
+	err = os.WriteFile(keyFile, armored.Bytes(), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, keyFile
+}
+
+func writeTestFile(t *testing.T, name, content string) {
+	t.Helper()
+	err := os.MkdirAll(filepath.Dir(name), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(name, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
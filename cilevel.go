@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+)
+
+// levelBranch matches the branches BUMP_LEVEL=branch reads the bump level
+// from, e.g. release/minor.
+var levelBranch = regexp.MustCompile(`^release/(patch|minor|major)$`)
+
+// ciBranchVariables hold the branch being built on the CI systems bump
+// knows, GitHub Actions, GitLab, Buildkite, Bitbucket Pipelines and Jenkins.
+// CI checkouts are often detached, so they go before the checked-out branch.
+var ciBranchVariables = []string{"GITHUB_REF_NAME", "CI_COMMIT_BRANCH", "BUILDKITE_BRANCH", "BITBUCKET_BRANCH", "BRANCH_NAME"}
+
+// levelFromCI lets the pipeline choose the bump when no flag did, so a
+// pipeline template can run plain "bump": BUMP_LEVEL is patch, minor, major,
+// auto (as with -auto) or branch, which takes the level from a branch named
+// release/<level>. Flags always win over BUMP_LEVEL.
+func levelFromCI(cfg config, repo vcs, env []string, output io.Writer) (config, error) {
+	level := getenv(env, "BUMP_LEVEL")
+	if !cfg.defaultLevel || level == "" {
+		return cfg, nil
+	}
+	source := "BUMP_LEVEL"
+	if level == "branch" {
+		branch := ""
+		for _, key := range ciBranchVariables {
+			if branch = getenv(env, key); branch != "" {
+				break
+			}
+		}
+		if branch == "" {
+			var err error
+			branch, err = repo.branch()
+			if err != nil {
+				return cfg, err
+			}
+		}
+		m := levelBranch.FindStringSubmatch(branch)
+		if m == nil {
+			return cfg, fmt.Errorf("BUMP_LEVEL=branch needs a branch named release/patch, release/minor or release/major, not '%s'", branch)
+		}
+		level, source = m[1], "branch "+branch
+	}
+	switch level {
+	case "patch":
+		cfg.action = incrementPatch
+	case "minor":
+		cfg.action = incrementMinor
+	case "major":
+		cfg.action = incrementMajor
+	case "auto":
+		if cfg.branch != "" {
+			return cfg, fmt.Errorf("BUMP_LEVEL=auto can't be combined with -branch: it works on the checked-out branch")
+		}
+		cfg.action, cfg.auto = noAction, true
+		return cfg, nil
+	default:
+		return cfg, fmt.Errorf("invalid BUMP_LEVEL '%s': must be patch, minor, major, auto or branch", level)
+	}
+	cfg.levelSource = source
+	_, _ = fmt.Fprintf(output, "Bumping %s as %s asks\n", cfg.action, source)
+	return cfg, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLevelFromCI(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         []string
+		args        []string
+		message     string
+		wantVersion string
+		errContains string
+	}{
+		{name: "level", env: []string{"BUMP_LEVEL=minor"}, wantVersion: "v1.1.0"},
+		{name: "flag wins", env: []string{"BUMP_LEVEL=major"}, args: []string{"-patch"}, wantVersion: "v1.0.1"},
+		{name: "auto", env: []string{"BUMP_LEVEL=auto"}, message: "feat: widgets", wantVersion: "v1.1.0"},
+		{name: "ci branch", env: []string{"BUMP_LEVEL=branch", "GITHUB_REF_NAME=release/major"}, wantVersion: "v2.0.0"},
+		{name: "other branch", env: []string{"BUMP_LEVEL=branch", "CI_COMMIT_BRANCH=feature/x"}, errContains: "not 'feature/x'"},
+		{name: "invalid", env: []string{"BUMP_LEVEL=huge"}, errContains: "invalid BUMP_LEVEL 'huge'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, repo := setupTaggedTestRepo(t, "v1.0.0")
+			message := tt.message
+			if message == "" {
+				message = "Change"
+			}
+			commitFiles(t, repo, message, map[string]string{"change.txt": "x"})
+
+			var output bytes.Buffer
+			err := run(context.Background(), &output, tt.args, tt.env)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("Expected error containing %q, got: %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+			}
+			version, err := os.ReadFile(".version")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.TrimSpace(string(version)) != tt.wantVersion {
+				t.Errorf("Expected version %s, got %s\nOutput: %s", tt.wantVersion, version, output.String())
+			}
+		})
+	}
+}
+
+func TestReleaseReasonFromCI(t *testing.T) {
+	cfg := config{action: incrementMinor, levelSource: "branch release/minor"}
+	got := releaseReason(cfg, incrementMinor, "v1.0.0", 2)
+	if want := "minor bump requested by branch release/minor, 2 commit(s) since v1.0.0"; got != want {
+		t.Errorf("Got reason %q, want %q", got, want)
+	}
+}
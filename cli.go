@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+const (
+	backendGoGit = "go-git"
+	backendCLI   = "cli"
+	backendAuto  = "auto"
+)
+
+// cliVCS implements vcs for git repositories by running the system git
+// binary. It is faster than go-git on large repositories and behaves exactly
+// like the user's git, including hooks and signing configuration. Git-only
+// features that are built on go-git still use a go-git handle of the same
+// repository.
+type cliVCS struct {
+	bin  string
+	dir  string
+	repo *git.Repository
+	loc  *time.Location // of commits and tags, nil for the local time zone
+}
+
+// openGitBackend opens the git repository in dir with the requested backend.
+// The auto backend uses the system git binary if there is one and go-git
+// otherwise. Commits and tags are dated in loc.
+func openGitBackend(dir, backend string, loc *time.Location) (vcs, error) {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+	switch backend {
+	case backendGoGit, "":
+		return &gitVCS{repo: repo, loc: loc}, nil
+	case backendCLI, backendAuto:
+		bin, err := exec.LookPath("git")
+		if err == nil {
+			return &cliVCS{bin: bin, dir: dir, repo: repo, loc: loc}, nil
+		}
+		if backend == backendAuto {
+			return &gitVCS{repo: repo, loc: loc}, nil
+		}
+		return nil, fmt.Errorf("-backend cli needs the git binary: %w", err)
+	}
+	return nil, fmt.Errorf("invalid backend '%s': must be %s, %s or %s", backend, backendGoGit, backendCLI, backendAuto)
+}
+
+// run executes git with the given arguments and returns its standard output.
+func (c *cliVCS) run(args ...string) (string, error) {
+	err := budget.err()
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.CommandContext(budget.ctx, c.bin, args...)
+	cmd.Dir = c.dir
+	if c.loc != nil && c.loc != time.Local {
+		cmd.Env = append(os.Environ(), "TZ="+c.loc.String())
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// succeeds runs git and reports whether it exited with status zero, for
+// commands that answer a question through their exit status.
+func (c *cliVCS) succeeds(args ...string) (bool, error) {
+	_, err := c.run(args...)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (c *cliVCS) name() string {
+	return "git (cli)"
+}
+
+func (c *cliVCS) goGit() *git.Repository {
+	return c.repo
+}
+
+// dirtyFiles reports staged and unstaged changes. Untracked files don't block
+// a bump, matching the go-git backend.
+func (c *cliVCS) dirtyFiles() (map[string]string, error) {
+	out, err := c.run("status", "--porcelain=v1", "-z")
+	if err != nil {
+		return nil, err
+	}
+	dirty := make(map[string]string)
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		code, file := entry[:2], entry[3:]
+		if code[0] == 'R' || code[0] == 'C' {
+			i++ // the original path of a rename or copy follows
+		}
+		if code == "??" || code == "!!" {
+			continue
+		}
+		dirty[file] = fmt.Sprintf("worktree=%c staging=%c", code[1], code[0])
+	}
+	return dirty, nil
+}
+
+func (c *cliVCS) branch() (string, error) {
+	out, err := c.run("symbolic-ref", "--short", "-q", "HEAD")
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return "", nil // detached HEAD
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (c *cliVCS) lastTag() (string, error) {
+	out, err := c.run("tag", "--list")
+	if err != nil {
+		return "", err
+	}
+	return highestVersion(strings.Fields(out))
+}
+
+func (c *cliVCS) tagExists(name string) (bool, error) {
+	return c.succeeds("rev-parse", "-q", "--verify", "refs/tags/"+name)
+}
+
+func (c *cliVCS) hasChangesSince(tag string) (bool, error) {
+	tagCommit, err := c.run("rev-parse", "refs/tags/"+tag+"^{commit}")
+	if err != nil {
+		return false, fmt.Errorf("tag not found: %s: %w", tag, err)
+	}
+	head, err := c.head()
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(tagCommit) != head, nil
+}
+
+func (c *cliVCS) changesSince(rev, strategy string) ([]string, error) {
+	if rev == "" {
+		return nil, nil
+	}
+	// prefer the tag, like resolveCommit, if a branch has the same name
+	if _, err := c.run("rev-parse", "--verify", "--quiet", "refs/tags/"+rev); err == nil {
+		rev = "refs/tags/" + rev
+	}
+	args := []string{"log", "--format=%s"}
+	switch strategy {
+	case commitsFirstParent:
+		args = append(args, "--first-parent")
+	case commitsMergesOnly:
+		args = append(args, "--merges")
+	}
+	out, err := c.run(append(args, rev+"..HEAD")...)
+	if err != nil {
+		return nil, err
+	}
+	var changes []string
+	for _, line := range strings.Split(out, "\n") {
+		if line != "" {
+			changes = append(changes, line)
+		}
+	}
+	return changes, nil
+}
+
+func (c *cliVCS) add(path string) error {
+	_, err := c.run("add", "--", path)
+	return err
+}
+
+func (c *cliVCS) commit(message string) error {
+	_, err := c.run("commit", "-q", "-m", message)
+	return err
+}
+
+func (c *cliVCS) createTag(name, message string) (string, error) {
+	args := []string{"tag", name}
+	if message != "" {
+		args = []string{"tag", "-a", name, "-m", message}
+	}
+	_, err := c.run(args...)
+	if err != nil {
+		return "", err
+	}
+	out, err := c.run("rev-parse", "refs/tags/"+name)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (c *cliVCS) head() (string, error) {
+	out, err := c.run("rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func requireGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not available")
+	}
+}
+
+func TestBumpWithCLIBackend(t *testing.T) {
+	requireGit(t)
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-backend", "cli", "-minor", "-tag-metadata", "json"}, nil)
+	if err != nil {
+		t.Fatalf("Expected bump with the cli backend to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+
+	content, err := os.ReadFile(".version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "v1.1.0" {
+		t.Errorf("Expected .version to be v1.1.0, got %q", string(content))
+	}
+	messages := commitMessages(t, repo, 1)
+	if messages[0] != "bump version to v1.1.0" {
+		t.Errorf("Unexpected commit message %q", messages[0])
+	}
+	meta, ok, err := parseTagMetadata(tagMessageOf(t, repo, "v1.1.0"))
+	if err != nil || !ok {
+		t.Fatalf("Expected tag metadata, got ok=%v err=%v", ok, err)
+	}
+	if meta.Previous != "v1.0.0" || meta.Level != "minor" {
+		t.Errorf("Unexpected metadata %+v", meta)
+	}
+}
+
+func TestCLIBackendMatchesGoGit(t *testing.T) {
+	requireGit(t)
+	tempDir, repo := setupTaggedTestRepo(t, "v1.0.0")
+	_, err := repo.CreateTag("not-a-version", mustHead(t, repo), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// staged, unstaged and untracked changes
+	err = os.WriteFile("feature.txt", []byte("staged"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = w.Add("feature.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile("untracked.txt", []byte("new"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cli, err := openGitBackend(tempDir, backendCLI, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	goGit := &gitVCS{repo: repo}
+
+	for _, backend := range []vcs{goGit, cli} {
+		dirty, err := backend.dirtyFiles()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dirty) != 1 || dirty["feature.txt"] == "" {
+			t.Errorf("%s: expected only feature.txt to be dirty, got %v", backend.name(), dirty)
+		}
+		latest, err := backend.lastTag()
+		if err != nil || latest != "v1.0.0" {
+			t.Errorf("%s: lastTag() = %q, %v", backend.name(), latest, err)
+		}
+		changes, err := backend.changesSince("v1.0.0", commitsAll)
+		if err != nil || len(changes) != 1 || changes[0] != "Add new feature" {
+			t.Errorf("%s: changesSince() = %v, %v", backend.name(), changes, err)
+		}
+		changes, err = backend.changesSince("HEAD", commitsAll)
+		if err != nil || len(changes) != 0 {
+			t.Errorf("%s: changesSince(HEAD) = %v, %v", backend.name(), changes, err)
+		}
+		exists, err := backend.tagExists("v1.0.0")
+		if err != nil || !exists {
+			t.Errorf("%s: tagExists(v1.0.0) = %v, %v", backend.name(), exists, err)
+		}
+		exists, err = backend.tagExists("v9.9.9")
+		if err != nil || exists {
+			t.Errorf("%s: tagExists(v9.9.9) = %v, %v", backend.name(), exists, err)
+		}
+		branch, err := backend.branch()
+		if err != nil || branch != "master" {
+			t.Errorf("%s: branch() = %q, %v", backend.name(), branch, err)
+		}
+	}
+}
+
+func TestInvalidBackend(t *testing.T) {
+	setupTaggedTestRepo(t, "v1.0.0")
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-backend", "svn"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid backend 'svn'") {
+		t.Errorf("Expected invalid backend error, got: %v", err)
+	}
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// planMarker identifies the comment "bump comment-plan" posted, so the next
+// run updates it instead of adding another.
+const planMarker = "<!-- bump:comment-plan -->"
+
+// pullRequestRef matches the ref GitHub Actions checks out for a pull
+// request, capturing its number.
+var pullRequestRef = regexp.MustCompile(`^refs/pull/(\d+)/`)
+
+// releasePlan is what a dry run of the bump would release.
+type releasePlan struct {
+	version string
+	files   []string
+	log     string
+	err     error
+}
+
+// forgeComment is a comment on a GitHub or Bitbucket pull request or a note
+// on a GitLab merge request.
+type forgeComment struct {
+	ID   int64  `json:"id"`
+	Body string `json:"body"`
+}
+
+// runCommentPlan implements "bump comment-plan": a dry run of the bump, with
+// the flags after "--", posted as a comment on the pull or merge request
+// being checked, so reviewers see what merging it would release. Later runs
+// update the comment. A bump that would fail is posted as well, and fails
+// the command.
+func runCommentPlan(ctx context.Context, output io.Writer, args []string, env []string) error {
+	const usage = "usage: bump comment-plan [-pr number] [-forge github|gitlab|bitbucket] [-remote name] [-dry-run] [-- bump flags...]"
+	flagSet := flag.NewFlagSet("comment-plan", flag.ContinueOnError)
+	pr := flagSet.Int("pr", 0, "Pull or merge request to comment on (default: from GITHUB_REF, CI_MERGE_REQUEST_IID or BITBUCKET_PR_ID).")
+	forge := flagSet.String("forge", "", "Forge of the request: github, gitlab or bitbucket (default: gitlab under GitLab CI, bitbucket under Bitbucket Pipelines, else github).")
+	remoteName := flagSet.String("remote", defaultRemote, "Remote whose project has the request.")
+	dryRun := flagSet.Bool("dry-run", false, "Print the comment instead of posting it.")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	bumpArgs := flagSet.Args()
+	if len(bumpArgs) > 0 && len(args) > len(bumpArgs) && args[len(args)-len(bumpArgs)-1] != "--" {
+		return errors.New(usage)
+	}
+	if *pr == 0 {
+		*pr, err = pullRequestFromCI(env)
+		if err != nil {
+			return err
+		}
+	}
+	if *forge == "" {
+		*forge = "github"
+		if getenv(env, "GITLAB_CI") == "true" {
+			*forge = "gitlab"
+		} else if getenv(env, "BITBUCKET_BUILD_NUMBER") != "" {
+			*forge = "bitbucket"
+		}
+	}
+	if *forge != "github" && *forge != "gitlab" && *forge != "bitbucket" {
+		return fmt.Errorf("invalid -forge '%s': must be github, gitlab or bitbucket", *forge)
+	}
+
+	plan, err := planRelease(ctx, bumpArgs, env)
+	if err != nil {
+		return err
+	}
+	body := newRedactor(env).redact(planComment(plan))
+	if *dryRun {
+		_, _ = fmt.Fprint(output, body)
+		return plan.err
+	}
+
+	repo, err := git.PlainOpen(".")
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	switch *forge {
+	case "github":
+		err = postGitHubPlan(ctx, repo, *remoteName, env, output, *pr, body)
+	case "gitlab":
+		err = postGitLabPlan(ctx, repo, *remoteName, env, output, *pr, body)
+	default:
+		err = postBitbucketPlan(ctx, repo, *remoteName, env, output, *pr, body)
+	}
+	if err != nil {
+		return err
+	}
+	if plan.err != nil {
+		return fmt.Errorf("the release plan failed: %w", plan.err)
+	}
+	return nil
+}
+
+// pullRequestFromCI returns the number of the request a CI job runs for.
+func pullRequestFromCI(env []string) (int, error) {
+	if m := pullRequestRef.FindStringSubmatch(getenv(env, "GITHUB_REF")); m != nil {
+		return strconv.Atoi(m[1])
+	}
+	if iid := getenv(env, "CI_MERGE_REQUEST_IID"); iid != "" {
+		return strconv.Atoi(iid)
+	}
+	if id := getenv(env, "BITBUCKET_PR_ID"); id != "" {
+		return strconv.Atoi(id)
+	}
+	return 0, errors.New("comment-plan needs -pr outside a pull or merge request pipeline")
+}
+
+// planRelease dry-runs the bump with its -events stream, which gives the
+// version and the version files; the other files it would change are the
+// ones it prints diffs of.
+func planRelease(ctx context.Context, bumpArgs []string, env []string) (releasePlan, error) {
+	events, err := os.CreateTemp("", "bump-plan-*.jsonl")
+	if err != nil {
+		return releasePlan{}, err
+	}
+	_ = events.Close()
+	defer func() { _ = os.Remove(events.Name()) }()
+
+	var log strings.Builder
+	argv := append(append([]string{}, bumpArgs...), "-dry-run", "-events", events.Name())
+	plan := releasePlan{err: runBump(ctx, &log, argv, env)}
+	plan.log = strings.TrimPrefix(log.String(), fmt.Sprintf("bump %s bumping\n", embeddedVersion))
+
+	content, err := os.ReadFile(events.Name())
+	if err != nil {
+		return releasePlan{}, fmt.Errorf("failed to read the plan's events: %w", err)
+	}
+	seen := make(map[string]bool)
+	addFile := func(file string) {
+		if file != "" && !seen[file] {
+			seen[file] = true
+			plan.files = append(plan.files, file)
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		var event progressEvent
+		if json.Unmarshal([]byte(line), &event) != nil {
+			continue
+		}
+		if event.Phase == phaseValidate && plan.version == "" {
+			plan.version = event.Version
+		}
+		if event.Phase == phaseUpdateFiles {
+			addFile(event.File)
+		}
+	}
+	scanner := bufio.NewScanner(strings.NewReader(plan.log))
+	for scanner.Scan() {
+		if file, ok := strings.CutPrefix(scanner.Text(), "+++ b/"); ok {
+			addFile(file)
+		}
+	}
+	return plan, nil
+}
+
+// planComment renders the comment of a plan in Markdown, with the dry run's
+// output folded away.
+func planComment(plan releasePlan) string {
+	var b strings.Builder
+	b.WriteString(planMarker + "\n### Release plan\n\n")
+	switch {
+	case plan.err != nil:
+		_, _ = fmt.Fprintf(&b, "Releasing after merging this would fail: %v\n", plan.err)
+	case plan.version != "":
+		_, _ = fmt.Fprintf(&b, "Merging this releases **%s**.\n", plan.version)
+	default:
+		b.WriteString("Merging this releases nothing.\n")
+	}
+	if len(plan.files) > 0 {
+		b.WriteString("\nThe release changes:\n\n")
+		for _, file := range plan.files {
+			_, _ = fmt.Fprintf(&b, "- `%s`\n", file)
+		}
+	}
+	if plan.log != "" {
+		fence := "```"
+		for strings.Contains(plan.log, fence) {
+			fence += "`"
+		}
+		_, _ = fmt.Fprintf(&b, "\n<details><summary>Dry run</summary>\n\n%sdiff\n%s\n%s\n\n</details>\n",
+			fence, strings.TrimSuffix(plan.log, "\n"), fence)
+	}
+	return b.String()
+}
+
+// postGitHubPlan creates or updates the plan comment on a pull request.
+func postGitHubPlan(ctx context.Context, repo *git.Repository, remote string, env []string, output io.Writer, pr int, body string) error {
+	project, err := githubRepository(repo, remote, env)
+	if err != nil {
+		return err
+	}
+	gh, err := newGitHubClient(env, output)
+	if err != nil {
+		return fmt.Errorf("comment-plan: %w", err)
+	}
+	var comments []forgeComment
+	err = gh.list(ctx, fmt.Sprintf("/repos/%s/issues/%d/comments", project, pr), &comments)
+	if err != nil {
+		return fmt.Errorf("failed to list the comments of pull request #%d: %w", pr, err)
+	}
+	if id := planCommentID(comments); id != 0 {
+		err = gh.do(ctx, http.MethodPatch, fmt.Sprintf("/repos/%s/issues/comments/%d", project, id), map[string]string{"body": body}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to update the release plan on pull request #%d: %w", pr, err)
+		}
+		_, _ = fmt.Fprintf(output, "Updated the release plan on pull request #%d\n", pr)
+		return nil
+	}
+	err = gh.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/issues/%d/comments", project, pr), map[string]string{"body": body}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to comment on pull request #%d: %w", pr, err)
+	}
+	_, _ = fmt.Fprintf(output, "Posted the release plan on pull request #%d\n", pr)
+	return nil
+}
+
+// postGitLabPlan creates or updates the plan note on a merge request.
+func postGitLabPlan(ctx context.Context, repo *git.Repository, remote string, env []string, output io.Writer, mr int, body string) error {
+	project, err := gitlabProject(repo, remote, env)
+	if err != nil {
+		return err
+	}
+	gl, err := newGitLabClient(env, output)
+	if err != nil {
+		return fmt.Errorf("comment-plan: %w", err)
+	}
+	notes := fmt.Sprintf("/projects/%s/merge_requests/%d/notes", url.PathEscape(project), mr)
+	var comments []forgeComment
+	err = gl.list(ctx, notes, &comments)
+	if err != nil {
+		return fmt.Errorf("failed to list the notes of merge request !%d: %w", mr, err)
+	}
+	if id := planCommentID(comments); id != 0 {
+		err = gl.do(ctx, http.MethodPut, fmt.Sprintf("%s/%d", notes, id), map[string]string{"body": body}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to update the release plan on merge request !%d: %w", mr, err)
+		}
+		_, _ = fmt.Fprintf(output, "Updated the release plan on merge request !%d\n", mr)
+		return nil
+	}
+	err = gl.do(ctx, http.MethodPost, notes, map[string]string{"body": body}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to comment on merge request !%d: %w", mr, err)
+	}
+	_, _ = fmt.Fprintf(output, "Posted the release plan on merge request !%d\n", mr)
+	return nil
+}
+
+// postBitbucketPlan creates or updates the plan comment on a pull request on
+// Bitbucket Cloud or a Bitbucket Server. A server lists comments among the
+// pull request's activities, and updating one takes the version it has.
+func postBitbucketPlan(ctx context.Context, repo *git.Repository, remote string, env []string, output io.Writer, pr int, body string) error {
+	project, err := bitbucketRepository(repo, remote, env)
+	if err != nil {
+		return err
+	}
+	bb, err := newBitbucketClient(env, output)
+	if err != nil {
+		return fmt.Errorf("comment-plan: %w", err)
+	}
+	var comments []forgeComment
+	versions := make(map[int64]int)
+	var path string
+	var post any
+	if bb.server {
+		key, name, _ := strings.Cut(project, "/")
+		path = fmt.Sprintf("/projects/%s/repos/%s/pull-requests/%d", url.PathEscape(key), url.PathEscape(name), pr)
+		var activities []struct {
+			Action  string `json:"action"`
+			Comment struct {
+				ID      int64  `json:"id"`
+				Version int    `json:"version"`
+				Text    string `json:"text"`
+			} `json:"comment"`
+		}
+		err = bb.list(ctx, path+"/activities", &activities)
+		for _, a := range activities {
+			if a.Action == "COMMENTED" {
+				comments = append(comments, forgeComment{ID: a.Comment.ID, Body: a.Comment.Text})
+				versions[a.Comment.ID] = a.Comment.Version
+			}
+		}
+		post = map[string]string{"text": body}
+	} else {
+		path = fmt.Sprintf("/repositories/%s/pullrequests/%d", project, pr)
+		var values []struct {
+			ID      int64 `json:"id"`
+			Deleted bool  `json:"deleted"`
+			Content struct {
+				Raw string `json:"raw"`
+			} `json:"content"`
+		}
+		err = bb.list(ctx, path+"/comments", &values)
+		for _, v := range values {
+			if !v.Deleted {
+				comments = append(comments, forgeComment{ID: v.ID, Body: v.Content.Raw})
+			}
+		}
+		post = map[string]any{"content": map[string]string{"raw": body}}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list the comments of pull request #%d: %w", pr, err)
+	}
+	if id := planCommentID(comments); id != 0 {
+		update := post
+		if bb.server {
+			update = map[string]any{"text": body, "version": versions[id]}
+		}
+		err = bb.do(ctx, http.MethodPut, fmt.Sprintf("%s/comments/%d", path, id), update, nil)
+		if err != nil {
+			return fmt.Errorf("failed to update the release plan on pull request #%d: %w", pr, err)
+		}
+		_, _ = fmt.Fprintf(output, "Updated the release plan on pull request #%d\n", pr)
+		return nil
+	}
+	err = bb.do(ctx, http.MethodPost, path+"/comments", post, nil)
+	if err != nil {
+		return fmt.Errorf("failed to comment on pull request #%d: %w", pr, err)
+	}
+	_, _ = fmt.Fprintf(output, "Posted the release plan on pull request #%d\n", pr)
+	return nil
+}
+
+// planCommentID returns the ID of the earlier plan comment, 0 for none.
+func planCommentID(comments []forgeComment) int64 {
+	for _, c := range comments {
+		if strings.HasPrefix(c.Body, planMarker) {
+			return c.ID
+		}
+	}
+	return 0
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	gitconfig "github.com/go-git/go-git/v5/config"
+)
+
+func TestCommentPlanGitHub(t *testing.T) {
+	var comments []forgeComment
+	var requests []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/acme/app/issues/42/comments":
+			_ = json.NewEncoder(w).Encode(comments)
+		case "POST /repos/acme/app/issues/42/comments":
+			var body forgeComment
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			comments = append(comments, forgeComment{ID: 7, Body: body.Body})
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":7}`))
+		case "PATCH /repos/acme/app/issues/comments/7":
+			var body forgeComment
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			comments[0].Body = body.Body
+			_, _ = w.Write([]byte(`{"id":7}`))
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(api.Close)
+	env := []string{"BUMP_GITHUB_TOKEN=gh-token", "BUMP_GITHUB_API=" + api.URL, "BUMP_GITHUB_REPO=acme/app", "BUMP_API_CACHE=" + t.TempDir(), "GITHUB_REF=refs/pull/42/merge"}
+
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"comment-plan", "--", "-minor"}, env)
+	if err != nil {
+		t.Fatalf("Expected the plan to be posted, got: %v\nOutput: %s", err, output.String())
+	}
+	if len(comments) != 1 || !strings.HasPrefix(comments[0].Body, planMarker) {
+		t.Fatalf("Expected a plan comment, got %v", comments)
+	}
+	for _, want := range []string{"Merging this releases **v1.1.0**.", "- `.version`\n", "+v1.1.0\n"} {
+		if !strings.Contains(comments[0].Body, want) {
+			t.Errorf("Expected the comment to contain %q, got:\n%s", want, comments[0].Body)
+		}
+	}
+	if _, err := repo.Tag("v1.1.0"); err == nil {
+		t.Error("Expected the plan not to tag")
+	}
+
+	output.Reset()
+	err = run(context.Background(), &output, []string{"comment-plan", "--", "-major"}, env)
+	if err != nil {
+		t.Fatalf("Expected the plan to be updated, got: %v\nOutput: %s", err, output.String())
+	}
+	if len(comments) != 1 || !strings.Contains(comments[0].Body, "**v2.0.0**") {
+		t.Errorf("Expected the comment to be updated, got %v", comments)
+	}
+	if !strings.Contains(output.String(), "Updated the release plan on pull request #42\n") {
+		t.Errorf("Expected the update to be reported, got:\n%s", output.String())
+	}
+	if requests[len(requests)-1] != "PATCH /repos/acme/app/issues/comments/7" {
+		t.Errorf("Expected the comment to be patched, got %v", requests)
+	}
+}
+
+func TestCommentPlanGitLabFailure(t *testing.T) {
+	var notes []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.EscapedPath() {
+		case "GET /api/v4/projects/acme%2Fapp/merge_requests/3/notes":
+			_, _ = w.Write([]byte(`[{"id":1,"body":"LGTM"}]`))
+		case "POST /api/v4/projects/acme%2Fapp/merge_requests/3/notes":
+			var body forgeComment
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			notes = append(notes, body.Body)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":2}`))
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(api.Close)
+	env := []string{"CI_JOB_TOKEN=job-token", "CI_API_V4_URL=" + api.URL + "/api/v4", "BUMP_GITLAB_PROJECT=acme/app", "BUMP_API_CACHE=" + t.TempDir(), "GITLAB_CI=true", "CI_MERGE_REQUEST_IID=3"}
+
+	setupTaggedTestRepo(t, "v1.0.0")
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"comment-plan", "--", "-version", "bad"}, env)
+	if err == nil || !strings.Contains(err.Error(), "the release plan failed") {
+		t.Fatalf("Expected the failing plan to fail the command, got: %v\nOutput: %s", err, output.String())
+	}
+	if len(notes) != 1 || !strings.Contains(notes[0], "Releasing after merging this would fail:") {
+		t.Errorf("Expected the failure to be posted, got %v", notes)
+	}
+
+	err = run(context.Background(), &output, []string{"comment-plan", "-pr", "3", "extra"}, env)
+	if err == nil || !strings.Contains(err.Error(), "usage: bump comment-plan") {
+		t.Errorf("Expected bump flags without -- to be refused, got: %v", err)
+	}
+}
+
+func TestCommentPlanBitbucketCloud(t *testing.T) {
+	var posted []string
+	var api *httptest.Server
+	api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /2.0/repositories/acme/app/pullrequests/5/comments":
+			if r.URL.Query().Get("page") == "" {
+				_, _ = w.Write([]byte(`{"values":[{"id":1,"content":{"raw":"LGTM"}}],"next":"` + api.URL + `/2.0/repositories/acme/app/pullrequests/5/comments?page=2"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"values":[{"id":9,"deleted":true,"content":{"raw":"` + planMarker + `"}}]}`))
+		case "POST /2.0/repositories/acme/app/pullrequests/5/comments":
+			if r.Header.Get("Authorization") != "Bearer bb-token" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			var body struct {
+				Content struct {
+					Raw string `json:"raw"`
+				} `json:"content"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			posted = append(posted, body.Content.Raw)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":2}`))
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(api.Close)
+	env := []string{"BUMP_BITBUCKET_TOKEN=bb-token", "BUMP_BITBUCKET_API=" + api.URL + "/2.0", "BITBUCKET_REPO_FULL_NAME=acme/app", "BUMP_API_CACHE=" + t.TempDir(), "BITBUCKET_BUILD_NUMBER=12", "BITBUCKET_PR_ID=5"}
+
+	setupTaggedTestRepo(t, "v1.0.0")
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"comment-plan", "--", "-minor"}, env)
+	if err != nil {
+		t.Fatalf("Expected the plan to be posted, got: %v\nOutput: %s", err, output.String())
+	}
+	if len(posted) != 1 || !strings.Contains(posted[0], "**v1.1.0**") {
+		t.Errorf("Expected a new comment rather than updating the deleted one, got %v", posted)
+	}
+	if !strings.Contains(output.String(), "Posted the release plan on pull request #5\n") {
+		t.Errorf("Expected the comment to be reported, got:\n%s", output.String())
+	}
+}
+
+func TestCommentPlanBitbucketServer(t *testing.T) {
+	var updated map[string]any
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /bitbucket/rest/api/1.0/projects/ACME/repos/app/pull-requests/8/activities":
+			if r.URL.Query().Get("start") == "" {
+				_, _ = w.Write([]byte(`{"values":[{"action":"APPROVED"}],"isLastPage":false,"nextPageStart":1}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"values":[{"action":"COMMENTED","comment":{"id":31,"version":2,"text":"` + planMarker + `\nold"}}],"isLastPage":true}`))
+		case "PUT /bitbucket/rest/api/1.0/projects/ACME/repos/app/pull-requests/8/comments/31":
+			_ = json.NewDecoder(r.Body).Decode(&updated)
+			_, _ = w.Write([]byte(`{"id":31}`))
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(api.Close)
+	env := []string{"BUMP_BITBUCKET_TOKEN=bb-token", "BUMP_BITBUCKET_URL=" + api.URL + "/bitbucket", "BUMP_API_CACHE=" + t.TempDir()}
+
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	_, err := repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{api.URL + "/bitbucket/scm/acme/app.git"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"comment-plan", "-forge", "bitbucket", "-pr", "8", "--", "-patch"}, env)
+	if err != nil {
+		t.Fatalf("Expected the plan to be updated, got: %v\nOutput: %s", err, output.String())
+	}
+	if text, _ := updated["text"].(string); !strings.Contains(text, "**v1.0.1**") || updated["version"] != 2.0 {
+		t.Errorf("Expected the comment to be updated at its version, got %v", updated)
+	}
+	if !strings.Contains(output.String(), "Updated the release plan on pull request #8\n") {
+		t.Errorf("Expected the update to be reported, got:\n%s", output.String())
+	}
+}
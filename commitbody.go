@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// releaseReason explains why the release has its version, for the
+// -commit-body of the release commit. requested is the level asked for on
+// the command line, before -auto-api or -auto.
+func releaseReason(cfg config, requested action, currentVersion string, commits int) string {
+	var reason string
+	switch {
+	case cfg.version != "":
+		reason = "version set with -version"
+	case currentVersion == "":
+		reason = "initial version"
+	case cfg.action == incrementHotfix:
+		reason = "hotfix of " + currentVersion
+	case cfg.autoAPI && requested == noAction:
+		reason = fmt.Sprintf("%s bump chosen by -auto-api from the API changes", cfg.action)
+	case cfg.autoAPI:
+		reason = fmt.Sprintf("%s bump requested, checked against the API changes by -auto-api", cfg.action)
+	case cfg.auto && requested == noAction:
+		reason = fmt.Sprintf("%s bump chosen by -auto from the conventional commits", cfg.action)
+	case cfg.auto:
+		reason = fmt.Sprintf("%s bump requested, checked against the conventional commits by -auto", cfg.action)
+	case cfg.levelSource != "":
+		reason = fmt.Sprintf("%s bump requested by %s", cfg.action, cfg.levelSource)
+	default:
+		reason = fmt.Sprintf("%s bump requested", cfg.action)
+	}
+	since := currentVersion
+	if cfg.since != "" {
+		since = cfg.since
+	}
+	if since != "" {
+		reason += fmt.Sprintf(", %d commit(s) since %s", commits, since)
+	}
+	return reason
+}
+
+// commitBody lists the reason and the updated version files.
+func commitBody(reason string, files []string) string {
+	var body strings.Builder
+	if reason != "" {
+		body.WriteString("Reason: " + reason + "\n\n")
+	}
+	body.WriteString("Updated files:")
+	for _, file := range files {
+		body.WriteString("\n- " + file)
+	}
+	return body.String()
+}
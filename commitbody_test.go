@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestReleaseReason(t *testing.T) {
+	tests := []struct {
+		cfg       config
+		requested action
+		current   string
+		want      string
+	}{
+		{config{action: incrementMinor}, incrementMinor, "v1.2.0", "minor bump requested, 3 commit(s) since v1.2.0"},
+		{config{action: incrementMinor, autoAPI: true}, noAction, "v1.2.0", "minor bump chosen by -auto-api from the API changes, 3 commit(s) since v1.2.0"},
+		{config{action: incrementMajor, autoAPI: true}, incrementMajor, "v1.2.0", "major bump requested, checked against the API changes by -auto-api, 3 commit(s) since v1.2.0"},
+		{config{action: incrementHotfix}, incrementHotfix, "v1.2.0", "hotfix of v1.2.0, 3 commit(s) since v1.2.0"},
+		{config{version: "v2.0.0"}, noAction, "v1.2.0", "version set with -version, 3 commit(s) since v1.2.0"},
+		{config{action: incrementPatch, since: "abc123"}, incrementPatch, "v1.2.0", "patch bump requested, 3 commit(s) since abc123"},
+		{config{action: incrementPatch}, incrementPatch, "", "initial version"},
+	}
+	for _, tt := range tests {
+		if got := releaseReason(tt.cfg, tt.requested, tt.current, 3); got != tt.want {
+			t.Errorf("releaseReason(%+v) = %q, want %q", tt.cfg, got, tt.want)
+		}
+	}
+}
+
+func TestBumpCommitBody(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Add payments", map[string]string{"payments/.version": "v1.0.0"})
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-minor", "-commit-body"}, []string{"BUMP_SKIP_CI=skip-checks"})
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	want := "bump version to v1.1.0\n\nReason: minor bump requested, 2 commit(s) since v1.0.0\n\n" +
+		"Updated files:\n- .version\n- payments/.version\n\nskip-checks: true"
+	if got := commitMessages(t, repo, 1)[0]; got != want {
+		t.Errorf("Got commit message:\n%s\nwant:\n%s", got, want)
+	}
+
+	err = run(context.Background(), &output, []string{"-force", "-commit-body", "-commit-per-module"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "chore(payments): bump to v1.1.1\n\nReason: patch bump requested, 0 commit(s) since v1.1.0\n\nUpdated files:\n- payments/.version"
+	if got := commitMessages(t, repo, 1)[0]; got != want {
+		t.Errorf("Got module commit message:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/go-git/go-git/v5"
+	"golang.org/x/mod/semver"
+)
+
+// compatReport is the JSON form of "bump compat".
+type compatReport struct {
+	Base       string   `json:"base"`
+	Head       string   `json:"head"`
+	Removed    []string `json:"removed"`
+	Changed    []string `json:"changed"`
+	Added      []string `json:"added"`
+	Required   string   `json:"required"`
+	Proposed   string   `json:"proposed,omitempty"`
+	Sufficient *bool    `json:"sufficient,omitempty"`
+}
+
+// compatLevels are the bump levels -level accepts.
+var compatLevels = map[string]action{
+	"patch": incrementPatch,
+	"minor": incrementMinor,
+	"major": incrementMajor,
+}
+
+// runCompat implements "bump compat": it reports the exported Go API changes
+// between two revisions and the bump they call for under semantic
+// versioning. Given a proposed level it fails if that level is too low, so
+// pull request checks can run it.
+func runCompat(_ context.Context, output io.Writer, args []string, _ []string) error {
+	flagSet := flag.NewFlagSet("compat", flag.ContinueOnError)
+	proposed := flagSet.String("level", "", "Proposed bump level to check: patch, minor or major.")
+	format := flagSet.String("format", "text", "Output format: text or json.")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if flagSet.NArg() < 1 || flagSet.NArg() > 2 {
+		return fmt.Errorf("usage: bump compat [-level patch|minor|major] [-format text|json] <base> [head]")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("invalid format '%s': must be text or json", *format)
+	}
+	proposedLevel, ok := compatLevels[*proposed]
+	if *proposed != "" && !ok {
+		return fmt.Errorf("invalid level '%s': must be patch, minor or major", *proposed)
+	}
+	base, head := flagSet.Arg(0), flagSet.Arg(1)
+	if head == "" {
+		head = "HEAD"
+	}
+
+	repo, err := git.PlainOpen(".")
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	changes, err := diffAPI(repo, base, head)
+	if err != nil {
+		return err
+	}
+	required := changes.level(compatVersion(repo, base))
+
+	report := compatReport{
+		Base:     base,
+		Head:     head,
+		Removed:  append([]string{}, changes.removed...),
+		Changed:  append([]string{}, changes.changed...),
+		Added:    append([]string{}, changes.added...),
+		Required: required.String(),
+	}
+	sufficient := proposedLevel >= required
+	if *proposed != "" {
+		report.Proposed = *proposed
+		report.Sufficient = &sufficient
+	}
+	if *format == "json" {
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+		if err != nil {
+			return err
+		}
+	} else {
+		printAPIChanges(output, changes)
+		_, _ = fmt.Fprintf(output, "API changes between %s and %s call for a %s bump\n", base, head, required)
+		if *proposed != "" && sufficient {
+			_, _ = fmt.Fprintf(output, "A %s bump is sufficient\n", *proposed)
+		}
+	}
+	if *proposed != "" && !sufficient {
+		return fmt.Errorf("a %s bump is not sufficient: API changes between %s and %s need a %s bump", *proposed, base, head, required)
+	}
+	return nil
+}
+
+// compatVersion returns the version the API of base was released as, which
+// decides whether incompatible changes need a major bump or, before v1, a
+// minor one. A base that isn't a version is judged by the last version tag.
+func compatVersion(repo *git.Repository, base string) string {
+	if semver.IsValid(normalizeVersion(base)) {
+		return base
+	}
+	version, err := lastTag(repo)
+	if err != nil {
+		return ""
+	}
+	return version
+}
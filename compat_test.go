@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompat(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Add lib", map[string]string{"lib/lib.go": libV1})
+	_, err := repo.CreateTag("v1.3.0", mustHead(t, repo), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, repo, "Change lib", map[string]string{"lib/lib.go": libV1 + "func Listen() {}\n"})
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"compat", "-level", "minor", "v1.3.0"}, nil)
+	if err != nil {
+		t.Fatalf("Expected a minor bump to be sufficient, got: %v", err)
+	}
+	want := "API added: lib.Listen\nAPI changes between v1.3.0 and HEAD call for a minor bump\nA minor bump is sufficient\n"
+	if output.String() != want {
+		t.Errorf("Got output:\n%s\nwant:\n%s", output.String(), want)
+	}
+
+	output.Reset()
+	err = run(context.Background(), &output, []string{"compat", "-level", "patch", "-format", "json", "v1.3.0", "HEAD"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "a patch bump is not sufficient: API changes between v1.3.0 and HEAD need a minor bump") {
+		t.Errorf("Expected a patch bump to be refused, got: %v", err)
+	}
+	var report compatReport
+	err = json.Unmarshal(output.Bytes(), &report)
+	if err != nil {
+		t.Fatalf("Invalid JSON report: %v\n%s", err, output.String())
+	}
+	insufficient := false
+	wantReport := compatReport{Base: "v1.3.0", Head: "HEAD", Removed: []string{}, Changed: []string{}, Added: []string{"lib.Listen"},
+		Required: "minor", Proposed: "patch", Sufficient: &insufficient}
+	if !reflect.DeepEqual(report, wantReport) {
+		t.Errorf("Got report %+v, want %+v", report, wantReport)
+	}
+
+	output.Reset()
+	err = run(context.Background(), &output, []string{"compat", "HEAD", "v1.3.0"}, nil)
+	if err != nil {
+		t.Fatalf("Expected a report without -level to succeed, got: %v", err)
+	}
+	if !strings.Contains(output.String(), "API removed: lib.Listen\nAPI changes between HEAD and v1.3.0 call for a major bump\n") {
+		t.Errorf("Expected a removal to call for a major bump, got:\n%s", output.String())
+	}
+
+	for _, args := range [][]string{{"compat"}, {"compat", "-level", "hotfix", "v1.3.0"}, {"compat", "v9.9.9"}} {
+		err = run(context.Background(), &output, args, nil)
+		if err == nil {
+			t.Errorf("Expected %v to fail", args)
+		}
+	}
+}
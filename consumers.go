@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+)
+
+// runNotifyConsumers implements "bump notify-consumers": it opens a pull
+// request in every downstream repository, updating its go.mod to the release
+// of this module. The repositories are GitHub owner/name pairs, given as
+// arguments or listed in .bumpconsumers.
+func runNotifyConsumers(ctx context.Context, output io.Writer, args []string, env []string) error {
+	flagSet := flag.NewFlagSet("notify-consumers", flag.ContinueOnError)
+	version := flagSet.String("version", "", "Version to update the consumers to (default: the last version tag).")
+	dryRun := flagSet.Bool("dry-run", false, "Report the consumers that would be updated without opening pull requests.")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	consumers := flagSet.Args()
+	if len(consumers) == 0 {
+		consumers, err = loadConsumers(".bumpconsumers")
+		if err != nil {
+			return fmt.Errorf("failed to load .bumpconsumers: %w", err)
+		}
+	}
+	if len(consumers) == 0 {
+		return errors.New("no consumers: pass owner/repo arguments or list them in .bumpconsumers")
+	}
+	module, err := modulePath("go.mod")
+	if err != nil {
+		return err
+	}
+	if *version == "" {
+		repo, err := git.PlainOpen(".")
+		if err != nil {
+			return fmt.Errorf("failed to open repository: %w", err)
+		}
+		*version, err = lastTag(repo)
+		if err != nil {
+			return fmt.Errorf("failed to get last tag: %w", err)
+		}
+	}
+	if !semver.IsValid(*version) {
+		return fmt.Errorf("invalid version '%s'", *version)
+	}
+	gh, err := newGitHubClient(env, output)
+	if err != nil {
+		return err
+	}
+
+	// a failing consumer doesn't keep the others from being updated
+	var failed []string
+	for _, consumer := range consumers {
+		result, err := gh.updateConsumer(ctx, consumer, module, *version, *dryRun)
+		if err != nil {
+			_, _ = fmt.Fprintf(output, "%s: failed: %v\n", consumer, err)
+			failed = append(failed, consumer)
+			continue
+		}
+		_, _ = fmt.Fprintf(output, "%s: %s\n", consumer, result)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to update %d of %d consumers: %s", len(failed), len(consumers), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// loadConsumers reads the owner/repo pairs from path, one per line. Blank
+// lines and # comments are ignored.
+func loadConsumers(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var consumers []string
+	for i, line := range strings.Split(string(content), "\n") {
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		owner, name, ok := strings.Cut(line, "/")
+		if !ok || owner == "" || name == "" || strings.ContainsAny(name, "/ \t") {
+			return nil, fmt.Errorf("line %d: expected owner/repo, got '%s'", i+1, line)
+		}
+		consumers = append(consumers, line)
+	}
+	return consumers, nil
+}
+
+// modulePath returns the module path declared in the go.mod file at path.
+func modulePath(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read go.mod: %w", err)
+	}
+	module := modfile.ModulePath(data)
+	if module == "" {
+		return "", fmt.Errorf("%s has no module directive", path)
+	}
+	return module, nil
+}
+
+// updateRequirement raises the requirement on module in the go.mod content to
+// version. It returns nil if the consumer doesn't require module, or already
+// requires version or later.
+func updateRequirement(content []byte, module, version string) ([]byte, error) {
+	mod, err := modfile.Parse("go.mod", content, nil)
+	if err != nil {
+		return nil, err
+	}
+	required := false
+	for _, req := range mod.Require {
+		if req.Mod.Path != module {
+			continue
+		}
+		if semver.Compare(req.Mod.Version, version) >= 0 {
+			return nil, nil
+		}
+		required = true
+	}
+	if !required {
+		return nil, nil
+	}
+	err = mod.AddRequire(module, version)
+	if err != nil {
+		return nil, err
+	}
+	mod.Cleanup()
+	return mod.Format()
+}
+
+// updateConsumer opens a pull request updating the consumer's go.mod to the
+// version of module, on a branch of its own. It describes the outcome.
+func (gh githubClient) updateConsumer(ctx context.Context, consumer, module, version string, dryRun bool) (string, error) {
+	var repo struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	err := gh.do(ctx, http.MethodGet, "/repos/"+consumer, nil, &repo)
+	if err != nil {
+		return "", err
+	}
+	var file struct {
+		Content string `json:"content"`
+		SHA     string `json:"sha"`
+	}
+	err = gh.do(ctx, http.MethodGet, "/repos/"+consumer+"/contents/go.mod?ref="+repo.DefaultBranch, nil, &file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read go.mod: %w", err)
+	}
+	content, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(file.Content, "\n", ""))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode go.mod: %w", err)
+	}
+	updated, err := updateRequirement(content, module, version)
+	if err != nil {
+		return "", fmt.Errorf("failed to update go.mod: %w", err)
+	}
+	if updated == nil {
+		return fmt.Sprintf("skipped, does not require %s before %s", module, version), nil
+	}
+	branch := "bump/" + module + "-" + version
+	if dryRun {
+		return fmt.Sprintf("would open a pull request from %s", branch), nil
+	}
+
+	var base struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	err = gh.do(ctx, http.MethodGet, "/repos/"+consumer+"/git/ref/heads/"+repo.DefaultBranch, nil, &base)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", repo.DefaultBranch, err)
+	}
+	err = gh.do(ctx, http.MethodPost, "/repos/"+consumer+"/git/refs", map[string]string{
+		"ref": "refs/heads/" + branch,
+		"sha": base.Object.SHA,
+	}, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create branch %s: %w", branch, err)
+	}
+	title := fmt.Sprintf("Update %s to %s", module, version)
+	err = gh.do(ctx, http.MethodPut, "/repos/"+consumer+"/contents/go.mod", map[string]string{
+		"message": title,
+		"content": base64.StdEncoding.EncodeToString(updated),
+		"sha":     file.SHA,
+		"branch":  branch,
+	}, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to commit go.mod: %w", err)
+	}
+	var pull struct {
+		HTMLURL string `json:"html_url"`
+	}
+	err = gh.do(ctx, http.MethodPost, "/repos/"+consumer+"/pulls", map[string]string{
+		"title": title,
+		"head":  branch,
+		"base":  repo.DefaultBranch,
+		"body":  fmt.Sprintf("%s %s has been released. This updates go.mod; run `go mod tidy` to update go.sum.", module, version),
+	}, &pull)
+	if err != nil {
+		return "", fmt.Errorf("failed to open pull request: %w", err)
+	}
+	return "opened " + pull.HTMLURL, nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeGitHub serves the parts of the GitHub API notify-consumers uses, for
+// repositories with a go.mod on their main branch.
+type fakeGitHub struct {
+	*httptest.Server
+	mu       sync.Mutex
+	goMods   map[string]string // repo -> go.mod on main
+	branches map[string]string // repo/branch -> go.mod committed to it
+	pulls    []map[string]string
+}
+
+func newFakeGitHub(t *testing.T, goMods map[string]string) *fakeGitHub {
+	gh := &fakeGitHub{goMods: goMods, branches: make(map[string]string)}
+	gh.Server = httptest.NewServer(http.HandlerFunc(gh.serve))
+	t.Cleanup(gh.Close)
+	return gh
+}
+
+func (gh *fakeGitHub) serve(w http.ResponseWriter, r *http.Request) {
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer gh-token" {
+		http.Error(w, "bad credentials", http.StatusUnauthorized)
+		return
+	}
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/repos/"), "/", 3)
+	repo := parts[0] + "/" + parts[1]
+	goMod, ok := gh.goMods[repo]
+	if !ok {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	endpoint := ""
+	if len(parts) > 2 {
+		endpoint = parts[2]
+	}
+	var body map[string]string
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	reply := func(v any) { _ = json.NewEncoder(w).Encode(v) }
+
+	switch {
+	case r.Method == http.MethodGet && endpoint == "":
+		reply(map[string]string{"default_branch": "main"})
+	case r.Method == http.MethodGet && endpoint == "contents/go.mod":
+		reply(map[string]string{"content": base64.StdEncoding.EncodeToString([]byte(goMod)), "sha": "blob1"})
+	case r.Method == http.MethodGet && endpoint == "git/ref/heads/main":
+		reply(map[string]any{"object": map[string]string{"sha": "commit1"}})
+	case r.Method == http.MethodPost && endpoint == "git/refs":
+		gh.branches[repo+"/"+strings.TrimPrefix(body["ref"], "refs/heads/")] = goMod
+	case r.Method == http.MethodPut && endpoint == "contents/go.mod":
+		content, _ := base64.StdEncoding.DecodeString(body["content"])
+		gh.branches[repo+"/"+body["branch"]] = string(content)
+	case r.Method == http.MethodPost && endpoint == "pulls":
+		gh.pulls = append(gh.pulls, body)
+		reply(map[string]string{"html_url": "https://github.com/" + repo + "/pull/1"})
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+const consumerGoMod = `module example.com/app
+
+go 1.24
+
+require (
+	github.com/perbu/widget v1.1.0
+	golang.org/x/mod v0.28.0
+)
+`
+
+func TestNotifyConsumers(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeTestFile(t, "go.mod", "module github.com/perbu/widget\n\ngo 1.24\n")
+	writeTestFile(t, ".bumpconsumers", "# downstream\nacme/app\nacme/current   # already updated\nacme/unrelated\n")
+	gh := newFakeGitHub(t, map[string]string{
+		"acme/app":       consumerGoMod,
+		"acme/current":   strings.Replace(consumerGoMod, "v1.1.0", "v1.2.0", 1),
+		"acme/unrelated": "module example.com/other\n\ngo 1.24\n",
+	})
+
+	var output bytes.Buffer
+	env := []string{"BUMP_GITHUB_TOKEN=gh-token", "BUMP_GITHUB_API=" + gh.URL}
+	err := run(context.Background(), &output, []string{"notify-consumers", "-version", "v1.2.0"}, env)
+	if err != nil {
+		t.Fatalf("Expected notify-consumers to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	for _, want := range []string{
+		"acme/app: opened https://github.com/acme/app/pull/1\n",
+		"acme/current: skipped, does not require github.com/perbu/widget before v1.2.0\n",
+		"acme/unrelated: skipped",
+	} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output.String())
+		}
+	}
+
+	updated := gh.branches["acme/app/bump/github.com/perbu/widget-v1.2.0"]
+	if !strings.Contains(updated, "github.com/perbu/widget v1.2.0\n") || !strings.Contains(updated, "golang.org/x/mod v0.28.0\n") {
+		t.Errorf("Unexpected go.mod on the branch:\n%s", updated)
+	}
+	if len(gh.pulls) != 1 || gh.pulls[0]["base"] != "main" || gh.pulls[0]["title"] != "Update github.com/perbu/widget to v1.2.0" {
+		t.Errorf("Unexpected pull requests %v", gh.pulls)
+	}
+}
+
+func TestNotifyConsumersFailures(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeTestFile(t, "go.mod", "module github.com/perbu/widget\n")
+	gh := newFakeGitHub(t, map[string]string{"acme/app": consumerGoMod})
+	env := []string{"BUMP_GITHUB_TOKEN=gh-token", "BUMP_GITHUB_API=" + gh.URL}
+
+	// a missing repository doesn't stop the others
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"notify-consumers", "-version", "v1.2.0", "acme/gone", "acme/app"}, env)
+	if err == nil || !strings.Contains(err.Error(), "failed to update 1 of 2 consumers: acme/gone") {
+		t.Errorf("Expected a failure for acme/gone, got: %v", err)
+	}
+	if len(gh.pulls) != 1 {
+		t.Errorf("Expected a pull request for acme/app, got %v", gh.pulls)
+	}
+
+	err = run(context.Background(), &output, []string{"notify-consumers", "-version", "v1.2.0", "acme/app"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "BUMP_GITHUB_TOKEN must be set") {
+		t.Errorf("Expected a missing token error, got: %v", err)
+	}
+	err = run(context.Background(), &output, []string{"notify-consumers", "-version", "v1.2.0"}, env)
+	if err == nil || !strings.Contains(err.Error(), "no consumers") {
+		t.Errorf("Expected a no consumers error, got: %v", err)
+	}
+	err = os.WriteFile(".bumpconsumers", []byte("acme\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = run(context.Background(), &output, []string{"notify-consumers", "-version", "v1.2.0"}, env)
+	if err == nil || !strings.Contains(err.Error(), "line 1: expected owner/repo, got 'acme'") {
+		t.Errorf("Expected a parse error, got: %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// conventionalHeader matches the subject of a Conventional Commit,
+// "type(scope)!: description", capturing the type and the "!".
+var conventionalHeader = regexp.MustCompile(`^(\w+)(?:\([^)]*\))?(!)?: \S`)
+
+// breakingFooter matches the footer of a Conventional Commit announcing a
+// breaking change. Unlike the type, it must be upper case.
+var breakingFooter = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE: `)
+
+// conventionalLevel returns the bump a commit message calls for under
+// Conventional Commits: major for a breaking change, minor for a feat, patch
+// for anything else, including messages that don't follow the convention.
+func conventionalLevel(message string) action {
+	m := conventionalHeader.FindStringSubmatch(commitSubject(message))
+	switch {
+	case m != nil && m[2] == "!", breakingFooter.MatchString(message):
+		return incrementMajor
+	case m != nil && strings.EqualFold(m[1], "feat"):
+		return incrementMinor
+	}
+	return incrementPatch
+}
+
+// commitBumpLevel reads the commits since the last version tag (or -since),
+// selected by -commits, as Conventional Commits and returns the highest bump
+// they call for (-auto). Before v1.0.0 breaking changes call for a minor bump,
+// like -auto-api does. An explicitly requested level below that is refused.
+// Without version tags the -initial-version is released, whatever the
+// commits say, as nextVersion does.
+func commitBumpLevel(repo vcs, cfg config, output io.Writer) (action, error) {
+	gitRepo, err := gitRepository(repo, "-auto")
+	if err != nil {
+		return noAction, err
+	}
+	currentVersion, err := repo.lastTag()
+	if errors.Is(err, errNoVersionTags) && cfg.initialVersion != "" {
+		_, _ = fmt.Fprintf(output, "No version tags yet, releasing the initial version %s\n", cfg.initialVersion)
+		if cfg.action == noAction {
+			return incrementPatch, nil
+		}
+		return cfg.action, nil
+	}
+	if err != nil {
+		return noAction, fmt.Errorf("failed to get last tag: %w", err)
+	}
+	since := cfg.since
+	if since == "" {
+		since, err = existingTag(repo, currentVersion)
+		if err != nil {
+			return noAction, err
+		}
+	}
+	commits, err := commitsSince(gitRepo, since, cfg.commits)
+	if err != nil {
+		return noAction, err
+	}
+	level, reason := incrementPatch, ""
+	for _, c := range commits {
+		if commitLevel := conventionalLevel(c.Message); commitLevel > level {
+			level, reason = commitLevel, commitSubject(c.Message)
+		}
+	}
+	if level == incrementMajor && semver.Major(normalizeVersion(currentVersion)) == "v0" {
+		level = incrementMinor
+	}
+	if reason != "" {
+		_, _ = fmt.Fprintf(output, "Commits since %s call for a %s bump (%s)\n", since, level, reason)
+	} else {
+		_, _ = fmt.Fprintf(output, "Commits since %s call for a %s bump\n", since, level)
+	}
+
+	if cfg.action == noAction {
+		return level, nil
+	}
+	if cfg.action < level && !cfg.forced {
+		return noAction, fmt.Errorf("commits since %s need a %s bump, not %s (use -force to override)", since, level, cfg.action)
+	}
+	return cfg.action, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestConventionalLevel(t *testing.T) {
+	tests := []struct {
+		message string
+		want    action
+	}{
+		{"fix: handle empty input", incrementPatch},
+		{"feat: add widgets", incrementMinor},
+		{"Feat(api): add widgets", incrementMinor},
+		{"feat!: drop widgets", incrementMajor},
+		{"refactor(core)!: rename Client", incrementMajor},
+		{"fix: parse flags\n\nBREAKING CHANGE: -x is gone", incrementMajor},
+		{"fix: parse flags\n\nBREAKING-CHANGE: -x is gone", incrementMajor},
+		{"fix: parse flags\n\nbreaking change: not a footer", incrementPatch},
+		{"docs: feat: is mentioned here", incrementPatch},
+		{"feature: not a type we know", incrementPatch},
+		{"feat:missing space", incrementPatch},
+		{"Merge branch 'main'", incrementPatch},
+	}
+	for _, tt := range tests {
+		if got := conventionalLevel(tt.message); got != tt.want {
+			t.Errorf("conventionalLevel(%q) = %s, want %s", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestBumpAuto(t *testing.T) {
+	tests := []struct {
+		name        string
+		tag         string
+		messages    []string
+		args        []string
+		wantVersion string
+		errContains string
+	}{
+		{name: "fixes", tag: "v1.0.0", messages: []string{"fix: one", "chore: two"}, args: []string{"-auto"}, wantVersion: "v1.0.1"},
+		{name: "feature", tag: "v1.0.0", messages: []string{"fix: one", "feat(ui): widgets", "fix: two"}, args: []string{"-auto"}, wantVersion: "v1.1.0"},
+		{name: "breaking footer", tag: "v1.0.0", messages: []string{"feat: widgets", "fix: flags\n\nBREAKING CHANGE: -x is gone"}, args: []string{"-auto"}, wantVersion: "v2.0.0"},
+		{name: "breaking before v1", tag: "v0.3.0", messages: []string{"feat!: new API"}, args: []string{"-auto"}, wantVersion: "v0.4.0"},
+		{name: "explicit higher level", tag: "v1.0.0", messages: []string{"fix: one"}, args: []string{"-auto", "-minor"}, wantVersion: "v1.1.0"},
+		{name: "explicit lower level", tag: "v1.0.0", messages: []string{"feat: widgets"}, args: []string{"-auto", "-patch"}, errContains: "commits since v1.0.0 need a minor bump, not patch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, repo := setupTaggedTestRepo(t, tt.tag)
+			for i, message := range tt.messages {
+				commitFiles(t, repo, message, map[string]string{"change.txt": strings.Repeat("x", i+1)})
+			}
+
+			var output bytes.Buffer
+			err := run(context.Background(), &output, tt.args, nil)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("Expected error containing %q, got: %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+			}
+			version, err := os.ReadFile(".version")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.TrimSpace(string(version)) != tt.wantVersion {
+				t.Errorf("Expected version %s, got %s\nOutput: %s", tt.wantVersion, version, output.String())
+			}
+		})
+	}
+}
+
+func TestBumpAutoUntagged(t *testing.T) {
+	tempDir, repo := setupTestRepo(t)
+	t.Chdir(tempDir)
+	commitFiles(t, repo, "feat: first feature", map[string]string{".version": ""})
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-auto"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "failed to get last tag") {
+		t.Errorf("Expected -auto without tags or -initial-version to fail, got: %v", err)
+	}
+
+	err = run(context.Background(), &output, []string{"-auto", "-initial-version", "v0.1.0"}, nil)
+	if err != nil {
+		t.Fatalf("Expected the initial release to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	if exists, _ := tagExists(repo, "v0.1.0"); !exists {
+		t.Errorf("Expected the initial version to be tagged, got:\n%s", output.String())
+	}
+}
+
+func TestAutoConflicts(t *testing.T) {
+	for _, args := range [][]string{{"-auto", "-version", "v1.0.0"}, {"-auto", "-auto-api"}, {"-auto", "-hotfix"}} {
+		_, _, err := getConfig(args)
+		if err == nil || !strings.Contains(err.Error(), "-auto") {
+			t.Errorf("Expected %v to conflict, got: %v", args, err)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// tagPlan is a release prepared by -tag-plan: everything a privileged
+// service needs to create the tag bump isn't allowed to create itself.
+type tagPlan struct {
+	Tag      string `json:"tag"`
+	Version  string `json:"version"`
+	Previous string `json:"previous,omitempty"`
+	Commit   string `json:"commit"`
+	Branch   string `json:"branch,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Tag request states reported by the tag service.
+const (
+	tagStatePending = "pending"
+	tagStateCreated = "created"
+	tagStateFailed  = "failed"
+)
+
+// tagRequestStatus is the tag service's answer to a request and to polls.
+type tagRequestStatus struct {
+	State     string `json:"state"`
+	StatusURL string `json:"status_url,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// writeTagPlan writes the plan of the release commit in place of tagging it.
+func writeTagPlan(repo vcs, cfg config, output io.Writer, plan tagPlan) error {
+	_, _ = fmt.Fprintf(output, "Writing tag plan %s for %s\n", cfg.tagPlan, plan.Tag)
+	if cfg.dryRun {
+		return nil
+	}
+	var err error
+	plan.Commit, err = repo.head()
+	if err != nil {
+		return err
+	}
+	plan.Branch, err = repo.branch()
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(cfg.tagPlan, append(content, '\n'), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write tag plan: %w", err)
+	}
+	return nil
+}
+
+// runRequestTag implements "bump request-tag": it hands a tag plan to the
+// release service at BUMP_TAG_SERVICE and waits until the service has
+// created the tag. The release commit must have been pushed first.
+func runRequestTag(ctx context.Context, output io.Writer, args []string, env []string) error {
+	flagSet := flag.NewFlagSet("request-tag", flag.ContinueOnError)
+	interval := flagSet.Duration("interval", 5*time.Second, "How often to poll the service for the tag.")
+	timeout := flagSet.Duration("timeout", 10*time.Minute, "How long to wait for the tag.")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if flagSet.NArg() != 1 {
+		return errors.New("usage: bump request-tag [-interval d] [-timeout d] <plan.json>")
+	}
+	service := getenv(env, "BUMP_TAG_SERVICE")
+	if service == "" {
+		return errors.New("request-tag needs the tag service URL in BUMP_TAG_SERVICE")
+	}
+	content, err := os.ReadFile(flagSet.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to read tag plan: %w", err)
+	}
+	var plan tagPlan
+	err = json.Unmarshal(content, &plan)
+	if err != nil || plan.Tag == "" || plan.Commit == "" {
+		return fmt.Errorf("invalid tag plan %s: needs a tag and a commit", flagSet.Arg(0))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	client := tagService{url: service, token: getenv(env, "BUMP_TAG_SERVICE_TOKEN")}
+	status, err := client.do(ctx, http.MethodPost, service, plan)
+	if err != nil {
+		return fmt.Errorf("failed to request tag %s: %w", plan.Tag, err)
+	}
+	_, _ = fmt.Fprintf(output, "Requested tag %s at %s\n", plan.Tag, shortHash(plan.Commit))
+	for status.State == tagStatePending {
+		if status.StatusURL == "" {
+			return fmt.Errorf("tag service accepted %s without a status_url to poll", plan.Tag)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("tag %s was not created within %s", plan.Tag, *timeout)
+		case <-time.After(*interval):
+		}
+		status, err = client.do(ctx, http.MethodGet, status.StatusURL, nil)
+		if err != nil && ctx.Err() != nil {
+			return fmt.Errorf("tag %s was not created within %s", plan.Tag, *timeout)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to poll tag request %s: %w", plan.Tag, err)
+		}
+	}
+	switch status.State {
+	case tagStateCreated:
+		_, _ = fmt.Fprintf(output, "Tag %s created by the tag service\n", plan.Tag)
+		return nil
+	case tagStateFailed:
+		return fmt.Errorf("tag service failed to create %s: %s", plan.Tag, status.Error)
+	}
+	return fmt.Errorf("tag service reported unknown state '%s' for %s", status.State, plan.Tag)
+}
+
+// tagService is a client of the release service creating tags.
+type tagService struct {
+	url   string
+	token string // sent as a bearer token if set
+}
+
+// do sends a request and decodes the status it answers with. Relative
+// status URLs are resolved against the service URL.
+func (s tagService) do(ctx context.Context, method, endpoint string, payload any) (tagRequestStatus, error) {
+	var body io.Reader
+	if payload != nil {
+		content, err := json.Marshal(payload)
+		if err != nil {
+			return tagRequestStatus{}, fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(content)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return tagRequestStatus{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return tagRequestStatus{}, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return tagRequestStatus{}, fmt.Errorf("unexpected response %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	var status tagRequestStatus
+	err = json.NewDecoder(resp.Body).Decode(&status)
+	if err != nil {
+		return tagRequestStatus{}, fmt.Errorf("invalid response: %w", err)
+	}
+	if status.StatusURL != "" {
+		base, err := url.Parse(s.url)
+		if err != nil {
+			return tagRequestStatus{}, err
+		}
+		ref, err := url.Parse(status.StatusURL)
+		if err != nil {
+			return tagRequestStatus{}, fmt.Errorf("invalid status_url: %w", err)
+		}
+		status.StatusURL = base.ResolveReference(ref).String()
+	}
+	return status, nil
+}
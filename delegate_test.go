@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBumpTagPlan(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	plan := filepath.Join(t.TempDir(), "plan.json")
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-minor", "-tag-plan", plan}, nil)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	if !strings.Contains(output.String(), "Bumped version v1.0.0 --> v1.1.0, tag planned in "+plan) {
+		t.Errorf("Expected the tag to be planned, got:\n%s", output.String())
+	}
+	if exists, _ := tagExists(repo, "v1.1.0"); exists {
+		t.Error("Expected no tag with -tag-plan")
+	}
+	content, err := os.ReadFile(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got tagPlan
+	err = json.Unmarshal(content, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := tagPlan{Tag: "v1.1.0", Version: "v1.1.0", Previous: "v1.0.0", Commit: mustHead(t, repo).String(), Branch: "master", Message: defaultTagMessage}
+	if got != want {
+		t.Errorf("Got plan %+v, want %+v", got, want)
+	}
+
+	_, _, err = getConfig([]string{"-tag-plan", plan, "-announce"})
+	if err == nil {
+		t.Error("Expected -tag-plan and -announce to conflict")
+	}
+}
+
+// fakeTagService accepts tag requests and reports them created after polls
+// pending polls, or failed.
+type fakeTagService struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []string
+	polls    int
+	fail     bool
+}
+
+func newFakeTagService(t *testing.T, polls int, fail bool) *fakeTagService {
+	s := &fakeTagService{polls: polls, fail: fail}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var plan tagPlan
+		_ = json.NewDecoder(r.Body).Decode(&plan)
+		s.requests = append(s.requests, r.Method+" "+r.URL.Path+" "+plan.Tag)
+		status := tagRequestStatus{State: tagStatePending, StatusURL: "/requests/1"}
+		switch {
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusAccepted)
+		case s.polls > 0:
+			s.polls--
+		case s.fail:
+			status = tagRequestStatus{State: tagStateFailed, Error: "tag protected"}
+		default:
+			status = tagRequestStatus{State: tagStateCreated}
+		}
+		_ = json.NewEncoder(w).Encode(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestRequestTag(t *testing.T) {
+	plan := filepath.Join(t.TempDir(), "plan.json")
+	err := os.WriteFile(plan, []byte(`{"tag": "v1.1.0", "version": "v1.1.0", "commit": "0123456789abcdef"}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := newFakeTagService(t, 2, false)
+	env := []string{"BUMP_TAG_SERVICE=" + service.URL + "/requests", "BUMP_TAG_SERVICE_TOKEN=s3cret"}
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"request-tag", "-interval", "1ms", plan}, env)
+	if err != nil {
+		t.Fatalf("Expected the tag to be created, got: %v", err)
+	}
+	want := "Requested tag v1.1.0 at 0123456\nTag v1.1.0 created by the tag service\n"
+	if output.String() != want {
+		t.Errorf("Got output:\n%s\nwant:\n%s", output.String(), want)
+	}
+	wantRequests := []string{"POST /requests v1.1.0", "GET /requests/1 ", "GET /requests/1 ", "GET /requests/1 "}
+	if strings.Join(service.requests, "\n") != strings.Join(wantRequests, "\n") {
+		t.Errorf("Got requests %q, want %q", service.requests, wantRequests)
+	}
+
+	failing := newFakeTagService(t, 0, true)
+	env = []string{"BUMP_TAG_SERVICE=" + failing.URL, "BUMP_TAG_SERVICE_TOKEN=s3cret"}
+	err = run(context.Background(), &output, []string{"request-tag", "-interval", "1ms", plan}, env)
+	if err == nil || !strings.Contains(err.Error(), "tag service failed to create v1.1.0: tag protected") {
+		t.Errorf("Expected the service's failure, got: %v", err)
+	}
+
+	slow := newFakeTagService(t, 1000, false)
+	env = []string{"BUMP_TAG_SERVICE=" + slow.URL, "BUMP_TAG_SERVICE_TOKEN=s3cret"}
+	err = run(context.Background(), &output, []string{"request-tag", "-interval", "1ms", "-timeout", "20ms", plan}, env)
+	if err == nil || !strings.Contains(err.Error(), "tag v1.1.0 was not created within 20ms") {
+		t.Errorf("Expected a timeout, got: %v", err)
+	}
+
+	err = run(context.Background(), &output, []string{"request-tag", plan}, nil)
+	if err == nil || !strings.Contains(err.Error(), "BUMP_TAG_SERVICE") {
+		t.Errorf("Expected a missing service error, got: %v", err)
+	}
+}
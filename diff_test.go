@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{name: "equal", old: "v1.0.0\n", new: "v1.0.0\n", want: ""},
+		{
+			name: "version file",
+			old:  "v1.0.0\n",
+			new:  "v1.0.1\n",
+			want: "--- a/f\n+++ b/f\n@@ -1 +1 @@\n-v1.0.0\n+v1.0.1\n",
+		},
+		{
+			name: "new file",
+			old:  "",
+			new:  "a\nb\n",
+			want: "--- a/f\n+++ b/f\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "missing newline",
+			old:  "v1.0.0",
+			new:  "v1.1.0",
+			want: "--- a/f\n+++ b/f\n@@ -1 +1 @@\n-v1.0.0\n\\ No newline at end of file\n+v1.1.0\n\\ No newline at end of file\n",
+		},
+		{
+			name: "context and separate hunks",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n",
+			new:  "1\nTWO\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n15\n",
+			want: "--- a/f\n+++ b/f\n@@ -1,5 +1,5 @@\n 1\n-2\n+TWO\n 3\n 4\n 5\n@@ -11,5 +11,4 @@\n 11\n 12\n 13\n-14\n 15\n",
+		},
+		{
+			name: "nearby changes share a hunk",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n",
+			new:  "1\nTWO\n3\n4\n5\n6\nSEVEN\n8\n",
+			want: "--- a/f\n+++ b/f\n@@ -1,8 +1,8 @@\n 1\n-2\n+TWO\n 3\n 4\n 5\n 6\n-7\n+SEVEN\n 8\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := unifiedDiff("a/f", "b/f", tt.old, tt.new)
+			if got != tt.want {
+				t.Errorf("Got diff:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDryRunDiff(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Add changelog", map[string]string{
+		changelogFile: "# Changelog\n\n## [Unreleased]\n\n### Fixed\n\n- Crash\n",
+	})
+	setClock(t, "2024-05-01")
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-dry-run"}, nil)
+	if err != nil {
+		t.Fatalf("Expected dry run to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	for _, want := range []string{
+		"--- a/.version\n+++ b/.version\n@@ -1 +1 @@\n-v1.0.0\n\\ No newline at end of file\n+v1.0.1\n",
+		"--- a/CHANGELOG.md\n+++ b/CHANGELOG.md\n",
+		"+## [v1.0.1] - 2024-05-01\n",
+	} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("Expected the dry run to show %q, got:\n%s", want, output.String())
+		}
+	}
+	if content := readFile(t, ".version"); strings.TrimSpace(content) != "v1.0.0" {
+		t.Errorf("Expected the dry run not to write .version, got %q", content)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// defaultRemote is the remote bump uses unless configured otherwise.
+const defaultRemote = "origin"
+
+// exitWouldRelease is the exit status of a -check run that would release.
+const exitWouldRelease = 3
+
+// errNoChanges is wrapped by the error refusing a release without commits
+// since the last one; -check reports it as nothing to release.
+var errNoChanges = errors.New("no changes")
+
+// exitStatus is an outcome of run that isn't a failure but needs an exit
+// status of its own, such as -check's "would release". main exits with code
+// after printing the message, which isn't reported as an error.
+type exitStatus struct {
+	code    int
+	message string
+}
+
+func (e *exitStatus) Error() string {
+	return e.message
+}
+
+// remotes are the remotes a release involves. In a triangular workflow tags
+// are resolved from one remote (e.g. upstream) and published to another (e.g.
+// a fork); usually both are the same.
+type remotes struct {
+	fetch string
+	push  string
+}
+
+// resolveRemotes applies the defaults to the configured remotes. The fetch
+// remote defaults to origin; the push remote to git's remote.pushDefault and
+// then to the fetch remote.
+func resolveRemotes(repo *git.Repository, cfg config) (remotes, error) {
+	r := remotes{fetch: cfg.remote, push: cfg.pushRemote}
+	if r.fetch == "" {
+		r.fetch = defaultRemote
+	}
+	if r.push == "" {
+		repoConfig, err := repo.Config()
+		if err != nil {
+			return remotes{}, fmt.Errorf("failed to read repository config: %w", err)
+		}
+		r.push = repoConfig.Raw.Section("remote").Option("pushDefault")
+	}
+	if r.push == "" {
+		r.push = r.fetch
+	}
+	return r, nil
+}
+
+// simulateRelease performs the read-only checks a dry run can do beyond
+// skipping writes. It asks the remotes whether the tag is already taken, which
+// also verifies that they can be reached with the available credentials.
+func simulateRelease(ctx context.Context, repo *git.Repository, env []string, output io.Writer, tagName string, r remotes) error {
+	err := checkRemoteTag(ctx, repo, env, output, tagName, r.fetch, "remote")
+	if err != nil || r.push == r.fetch {
+		return err
+	}
+	return checkRemoteTag(ctx, repo, env, output, tagName, r.push, "push remote")
+}
+
+// checkRemoteTag fails if the tag exists on the named remote. A remote that
+// is missing or unreachable is reported but not fatal, as the bump itself
+// doesn't need it.
+func checkRemoteTag(ctx context.Context, repo *git.Repository, env []string, output io.Writer, tagName, name, role string) error {
+	remote, err := repo.Remote(name)
+	if errors.Is(err, git.ErrRemoteNotFound) {
+		_, _ = fmt.Fprintf(output, "Dry run: no %s '%s' configured, skipping remote checks\n", role, name)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get %s '%s': %w", role, name, err)
+	}
+
+	auth, err := remoteAuth(remote, env)
+	if err != nil {
+		_, _ = fmt.Fprintf(output, "Dry run: %s '%s' can't be authenticated, publishing would fail: %v\n", role, name, err)
+		return nil
+	}
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: auth})
+	if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		refs, err = nil, nil
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(output, "Dry run: %s '%s' is not reachable, publishing would fail: %v\n", role, name, err)
+		return nil
+	}
+
+	tagRef := plumbing.NewTagReferenceName(tagName)
+	for _, ref := range refs {
+		if ref.Name() == tagRef {
+			return fmt.Errorf("tag '%s' already exists on %s '%s'", tagName, role, name)
+		}
+	}
+	_, _ = fmt.Fprintf(output, "Dry run: tag %s does not exist locally or on %s '%s'\n", tagName, role, name)
+	return nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+)
+
+// addTestRemote creates a separate repository carrying the given tags and
+// registers it as the origin remote of repo
+func addTestRemote(t *testing.T, repo *git.Repository, tags ...string) *git.Repository {
+	return addNamedTestRemote(t, repo, "origin", tags...)
+}
+
+// addNamedTestRemote is addTestRemote for a remote with the given name
+func addNamedTestRemote(t *testing.T, repo *git.Repository, name string, tags ...string) *git.Repository {
+	remoteDir, remoteRepo := setupTestRepo(t)
+	head, err := remoteRepo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tag := range tags {
+		_, err = remoteRepo.CreateTag(tag, head.Hash(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: name, URLs: []string{remoteDir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return remoteRepo
+}
+
+func TestDryRunDetectsRemoteTag(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	addTestRemote(t, repo, "v1.0.1")
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-dry-run"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "already exists on remote 'origin'") {
+		t.Fatalf("Expected dry run to report the remote tag, got: %v\nOutput: %s", err, output.String())
+	}
+}
+
+func TestDryRunChecksRemote(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	addTestRemote(t, repo, "v1.0.0")
+	commitsBefore := countCommits(t, repo)
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-dry-run"}, nil)
+	if err != nil {
+		t.Fatalf("Expected dry run to succeed, got: %v", err)
+	}
+	if !strings.Contains(output.String(), "tag v1.0.1 does not exist locally or on remote 'origin'") {
+		t.Errorf("Expected output to report the remote check, got: %s", output.String())
+	}
+	if countCommits(t, repo) != commitsBefore {
+		t.Errorf("Expected dry run not to create commits")
+	}
+}
+
+func TestDryRunWithoutRemote(t *testing.T) {
+	setupTaggedTestRepo(t, "v1.0.0")
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-dry-run"}, nil)
+	if err != nil {
+		t.Fatalf("Expected dry run to succeed, got: %v", err)
+	}
+	if !strings.Contains(output.String(), "no remote 'origin' configured") {
+		t.Errorf("Expected output to mention the missing remote, got: %s", output.String())
+	}
+}
+
+func TestDryRunRemotes(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		env         []string
+		pushDefault string
+		want        []string
+		errContains string
+	}{
+		{
+			name:        "remote flag",
+			args:        []string{"-remote", "upstream"},
+			errContains: "tag 'v1.0.1' already exists on remote 'upstream'",
+		},
+		{
+			name:        "remote from the environment",
+			env:         []string{"BUMP_REMOTE=upstream"},
+			errContains: "already exists on remote 'upstream'",
+		},
+		{
+			name: "default remote",
+			want: []string{"tag v1.0.1 does not exist locally or on remote 'origin'"},
+		},
+		{
+			name:        "triangular workflow",
+			args:        []string{"-remote", "origin", "-push-remote", "fork"},
+			errContains: "tag 'v1.0.1' already exists on push remote 'fork'",
+		},
+		{
+			name:        "push remote from git config",
+			pushDefault: "fork",
+			errContains: "already exists on push remote 'fork'",
+		},
+		{
+			name: "missing push remote",
+			env:  []string{"BUMP_PUSH_REMOTE=missing"},
+			want: []string{"remote 'origin'", "no push remote 'missing' configured"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, repo := setupTaggedTestRepo(t, "v1.0.0")
+			addNamedTestRemote(t, repo, "origin")
+			addNamedTestRemote(t, repo, "upstream", "v1.0.1")
+			addNamedTestRemote(t, repo, "fork", "v1.0.1")
+			if tt.pushDefault != "" {
+				cfg, err := repo.Config()
+				if err != nil {
+					t.Fatal(err)
+				}
+				cfg.Raw.Section("remote").SetOption("pushDefault", tt.pushDefault)
+				err = repo.SetConfig(cfg)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var output bytes.Buffer
+			err := run(context.Background(), &output, append([]string{"-dry-run"}, tt.args...), tt.env)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("Expected error containing %q, got: %v\nOutput: %s", tt.errContains, err, output.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected dry run to succeed, got: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(output.String(), want) {
+					t.Errorf("Expected output containing %q, got: %s", want, output.String())
+				}
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	head := mustHead(t, repo)
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-minor", "-check"}, nil)
+	var status *exitStatus
+	if !errors.As(err, &status) || status.code != exitWouldRelease {
+		t.Fatalf("Expected exit status %d, got: %v\nOutput: %s", exitWouldRelease, err, output.String())
+	}
+	if mustHead(t, repo) != head {
+		t.Error("Expected -check not to commit")
+	}
+	if exists, _ := tagExists(repo, "v1.1.0"); exists {
+		t.Error("Expected -check not to tag")
+	}
+
+	// nothing since the release: a no-op
+	err = run(context.Background(), &output, []string{"-minor"}, nil)
+	if err != nil {
+		t.Fatalf("bump: %v", err)
+	}
+	output.Reset()
+	err = run(context.Background(), &output, []string{"-check"}, nil)
+	if err != nil {
+		t.Fatalf("Expected nothing to release, got: %v", err)
+	}
+	if !strings.Contains(output.String(), "Nothing to release: no changes since last version tag 'v1.1.0'") {
+		t.Errorf("Expected the reason in the output, got:\n%s", output.String())
+	}
+}
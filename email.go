@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+const defaultEmailSubject = "{{.Project}} {{.Version}} released"
+
+const defaultEmailBody = `{{.Project}} {{.Version}} has been released.
+{{if .Changes}}
+Changes since {{.Previous}}:
+{{range .Changes}}
+  - {{.}}{{end}}
+{{end}}`
+
+// sendMail is swapped out in tests.
+var sendMail = smtp.SendMail
+
+// emailAnnouncer sends the release announcement to a mailing list.
+type emailAnnouncer struct {
+	addr     string
+	username string
+	password string
+	from     string
+	to       []string
+	subject  string
+	body     string
+}
+
+// emailAnnouncerFromEnv configures the email backend from the environment:
+//
+//	BUMP_SMTP_HOST      server as host or host:port (port defaults to 587)
+//	BUMP_SMTP_TO        comma-separated recipients
+//	BUMP_SMTP_FROM      sender, defaults to BUMP_SMTP_USERNAME
+//	BUMP_SMTP_USERNAME  and BUMP_SMTP_PASSWORD for PLAIN authentication
+//	BUMP_SMTP_SUBJECT   subject template
+//	BUMP_SMTP_TEMPLATE  path to a body template
+//
+// It returns nil if neither BUMP_SMTP_HOST nor BUMP_SMTP_TO is set.
+func emailAnnouncerFromEnv(env []string) (*emailAnnouncer, error) {
+	host := getenv(env, "BUMP_SMTP_HOST")
+	to := getenv(env, "BUMP_SMTP_TO")
+	if host == "" && to == "" {
+		return nil, nil
+	}
+	if host == "" || to == "" {
+		return nil, fmt.Errorf("both BUMP_SMTP_HOST and BUMP_SMTP_TO must be set")
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "587")
+	}
+
+	mail := &emailAnnouncer{
+		addr:     host,
+		username: getenv(env, "BUMP_SMTP_USERNAME"),
+		password: getenv(env, "BUMP_SMTP_PASSWORD"),
+		from:     getenv(env, "BUMP_SMTP_FROM"),
+		subject:  getenv(env, "BUMP_SMTP_SUBJECT"),
+	}
+	for _, addr := range strings.Split(to, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			mail.to = append(mail.to, addr)
+		}
+	}
+	if mail.from == "" {
+		mail.from = mail.username
+	}
+	if mail.from == "" {
+		return nil, fmt.Errorf("BUMP_SMTP_FROM or BUMP_SMTP_USERNAME must be set")
+	}
+	if mail.subject == "" {
+		mail.subject = defaultEmailSubject
+	}
+	body, err := templateFromEnv(env, "BUMP_SMTP_TEMPLATE", defaultEmailBody)
+	if err != nil {
+		return nil, err
+	}
+	mail.body = body
+	return mail, nil
+}
+
+func emailBackend(_ config, env []string) (announcer, error) {
+	mail, err := emailAnnouncerFromEnv(env)
+	if err != nil {
+		return nil, fmt.Errorf("email announcer: %w", err)
+	}
+	if mail == nil {
+		return nil, nil
+	}
+	return mail, nil
+}
+
+func (m *emailAnnouncer) name() string {
+	return "email"
+}
+
+func (m *emailAnnouncer) announce(_ context.Context, rel release) error {
+	subject, err := renderTemplate("subject", m.subject, rel)
+	if err != nil {
+		return err
+	}
+	body, err := renderTemplate("body", m.body, rel)
+	if err != nil {
+		return err
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(m.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", strings.TrimSpace(subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if m.username != "" {
+		host, _, _ := net.SplitHostPort(m.addr)
+		auth = smtp.PlainAuth("", m.username, m.password, host)
+	}
+	err = sendMail(m.addr, auth, m.from, m.to, []byte(msg.String()))
+	if err != nil {
+		return fmt.Errorf("failed to send mail via %s: %w", m.addr, err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/smtp"
+	"strings"
+	"testing"
+)
+
+func TestBumpAnnouncesByEmail(t *testing.T) {
+	setupTaggedTestRepo(t, "v1.0.0")
+
+	var sent struct {
+		addr string
+		auth smtp.Auth
+		from string
+		to   []string
+		msg  string
+	}
+	original := sendMail
+	sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		sent.addr, sent.auth, sent.from, sent.to, sent.msg = addr, a, from, to, string(msg)
+		return nil
+	}
+	defer func() { sendMail = original }()
+
+	env := []string{
+		"BUMP_PROJECT=widget",
+		"BUMP_SMTP_HOST=mail.example.com",
+		"BUMP_SMTP_TO=releases@example.com, ops@example.com",
+		"BUMP_SMTP_FROM=bump@example.com",
+	}
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-announce"}, env)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v", err)
+	}
+
+	if sent.addr != "mail.example.com:587" {
+		t.Errorf("Expected default submission port, got %q", sent.addr)
+	}
+	if sent.auth != nil {
+		t.Errorf("Expected no authentication without BUMP_SMTP_USERNAME")
+	}
+	if len(sent.to) != 2 || sent.to[0] != "releases@example.com" || sent.to[1] != "ops@example.com" {
+		t.Errorf("Unexpected recipients: %v", sent.to)
+	}
+	for _, want := range []string{
+		"From: bump@example.com\r\n",
+		"Subject: widget v1.0.1 released\r\n",
+		"widget v1.0.1 has been released.",
+		"Changes since v1.0.0:",
+		"  - Add new feature",
+	} {
+		if !strings.Contains(sent.msg, want) {
+			t.Errorf("Expected message to contain %q, got:\n%s", want, sent.msg)
+		}
+	}
+	if !strings.Contains(output.String(), "Announced v1.0.1 via email") {
+		t.Errorf("Expected output to report the announcement, got: %s", output.String())
+	}
+}
+
+func TestBumpAnnounceWithoutBackend(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitsBefore := countCommits(t, repo)
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-announce"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "no announcement backend is configured") {
+		t.Fatalf("Expected missing backend error, got: %v", err)
+	}
+	if countCommits(t, repo) != commitsBefore {
+		t.Errorf("Expected no commit when announcing is misconfigured")
+	}
+}
+
+func TestEmailAnnouncerFromEnv(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         []string
+		wantNil     bool
+		errContains string
+	}{
+		{name: "not configured", env: nil, wantNil: true},
+		{name: "missing recipients", env: []string{"BUMP_SMTP_HOST=mail"}, errContains: "both BUMP_SMTP_HOST and BUMP_SMTP_TO"},
+		{name: "missing sender", env: []string{"BUMP_SMTP_HOST=mail", "BUMP_SMTP_TO=a@example.com"}, errContains: "BUMP_SMTP_FROM"},
+		{name: "username as sender", env: []string{"BUMP_SMTP_HOST=mail:25", "BUMP_SMTP_TO=a@example.com", "BUMP_SMTP_USERNAME=bot@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mail, err := emailAnnouncerFromEnv(tt.env)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("Expected error containing %q, got: %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if (mail == nil) != tt.wantNil {
+				t.Fatalf("Expected nil announcer: %v, got %+v", tt.wantNil, mail)
+			}
+		})
+	}
+}
+
+func TestGetenv(t *testing.T) {
+	env := []string{"A=1", "B=x=y", "A=2", "EMPTY="}
+	if got := getenv(env, "A"); got != "2" {
+		t.Errorf("Expected later entries to win, got %q", got)
+	}
+	if got := getenv(env, "B"); got != "x=y" {
+		t.Errorf("Expected value containing '=', got %q", got)
+	}
+	if got := getenv(env, "MISSING"); got != "" {
+		t.Errorf("Expected empty value for missing key, got %q", got)
+	}
+}
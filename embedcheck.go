@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// goPackage is what check-embed learns about the Go files in one directory.
+type goPackage struct {
+	dir  string // repository path
+	main bool
+	// embedsVersion is set if a //go:embed directive names the .version file
+	// next to the package
+	embedsVersion bool
+	// readsVersion is set if the code mentions ".version", e.g. to read it at
+	// runtime
+	readsVersion bool
+	// generated is the version in a generated version.go, if there is one
+	generated     string
+	generatedFile string
+}
+
+// embedProblem is a main package whose binary would not report the version
+// in its module's .version file.
+type embedProblem struct {
+	pkg    string
+	reason string
+}
+
+// runCheckEmbed implements "bump check-embed": it verifies that the main
+// packages of every module get their version from the module's .version file,
+// by embedding it, reading it or through a generated version.go that is up to
+// date. Otherwise bumping the file would not change what the binary reports.
+func runCheckEmbed(_ context.Context, output io.Writer, args []string, _ []string) error {
+	flagSet := flag.NewFlagSet("check-embed", flag.ContinueOnError)
+	err := flagSet.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if flagSet.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", flagSet.Args())
+	}
+
+	modules, err := findModules()
+	if err != nil {
+		return err
+	}
+	packages, err := scanGoPackages(".")
+	if err != nil {
+		return err
+	}
+	problems, err := checkEmbed(modules, packages)
+	if err != nil {
+		return err
+	}
+	for _, p := range problems {
+		_, _ = fmt.Fprintf(output, "warning: %s: %s\n", p.pkg, p.reason)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d main package(s) would report a stale or missing version", len(problems))
+	}
+	_, _ = fmt.Fprintln(output, "All main packages report the version from their .version file")
+	return nil
+}
+
+// checkEmbed reports the main packages of the modules that don't get their
+// version from the module's .version file. Modules without main packages are
+// libraries and are not checked.
+func checkEmbed(modules []module, packages []goPackage) ([]embedProblem, error) {
+	byModule := make(map[string][]goPackage)
+	for _, pkg := range packages {
+		m, ok := moduleFor(modules, path.Join(pkg.dir, "x.go"))
+		if ok {
+			byModule[m.Path] = append(byModule[m.Path], pkg)
+		}
+	}
+
+	var problems []embedProblem
+	for _, m := range modules {
+		versionFile := path.Join(m.Path, ".version")
+		content, err := os.ReadFile(filepath.FromSlash(versionFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", versionFile, err)
+		}
+		version := parseVersionFile(content)
+
+		wired, stale := false, ""
+		for _, pkg := range byModule[m.Path] {
+			if (pkg.dir == m.Path && pkg.embedsVersion) || pkg.readsVersion {
+				wired = true
+			}
+			if pkg.generated != "" {
+				if normalizeVersion(pkg.generated) == normalizeVersion(version) {
+					wired = true
+				} else {
+					stale = fmt.Sprintf("%s has version %s but %s is %s", pkg.generatedFile, pkg.generated, versionFile, version)
+				}
+			}
+		}
+		for _, pkg := range byModule[m.Path] {
+			if !pkg.main {
+				continue
+			}
+			switch {
+			case stale != "":
+				problems = append(problems, embedProblem{pkg: pkg.dir, reason: stale})
+			case !wired:
+				problems = append(problems, embedProblem{pkg: pkg.dir,
+					reason: fmt.Sprintf("nothing in the module embeds or reads %s", versionFile)})
+			}
+		}
+	}
+	return problems, nil
+}
+
+// scanGoPackages parses the non-test Go files below root and summarizes them
+// per directory, sorted by directory.
+func scanGoPackages(root string) ([]goPackage, error) {
+	byDir := make(map[string]*goPackage)
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(root, func(osPath string, d os.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to walk directory: %w", err)
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if osPath != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			return nil
+		}
+		file, err := parser.ParseFile(fset, osPath, nil, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", osPath, err)
+		}
+		rel, err := filepath.Rel(root, osPath)
+		if err != nil {
+			return err
+		}
+		dir := path.Dir(repoPath(rel))
+		pkg := byDir[dir]
+		if pkg == nil {
+			pkg = &goPackage{dir: dir}
+			byDir[dir] = pkg
+		}
+		scanGoFile(pkg, path.Join(dir, name), file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	packages := make([]goPackage, 0, len(byDir))
+	for _, pkg := range byDir {
+		packages = append(packages, *pkg)
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].dir < packages[j].dir })
+	return packages, nil
+}
+
+// scanGoFile records how a file relates to the .version file.
+func scanGoFile(pkg *goPackage, repoFile string, file *ast.File) {
+	if file.Name.Name == "main" {
+		pkg.main = true
+	}
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			patterns, ok := strings.CutPrefix(comment.Text, "//go:embed ")
+			if !ok {
+				continue
+			}
+			for _, pattern := range strings.Fields(patterns) {
+				if unquoted, err := strconv.Unquote(pattern); err == nil {
+					pattern = unquoted
+				}
+				if path.Clean(pattern) == ".version" {
+					pkg.embedsVersion = true
+				}
+			}
+		}
+	}
+
+	generated := path.Base(repoFile) == "version.go" && ast.IsGenerated(file)
+	ast.Inspect(file, func(node ast.Node) bool {
+		lit, ok := node.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		value, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return true
+		}
+		switch {
+		case path.Base(value) == ".version":
+			pkg.readsVersion = true
+		case generated && pkg.generated == "" && semver.IsValid(normalizeVersion(value)):
+			pkg.generated, pkg.generatedFile = value, repoFile
+		}
+		return true
+	})
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckEmbed(t *testing.T) {
+	const embedMain = "package main\n\nimport _ \"embed\"\n\n//go:embed .version\nvar version string\n\nfunc main() {}\n"
+	const plainMain = "package main\n\nfunc main() {}\n"
+	const generated = "// Code generated by go generate; DO NOT EDIT.\n\npackage version\n\nconst Version = \"v1.2.0\"\n"
+
+	tests := []struct {
+		name     string
+		files    map[string]string
+		warnings []string
+	}{
+		{
+			name:  "embedded in main",
+			files: map[string]string{".version": "v1.2.0", "main.go": embedMain},
+		},
+		{
+			name: "embedded by a library the commands share",
+			files: map[string]string{
+				".version":         "v1.2.0",
+				"version.go":       "package tool\n\nimport _ \"embed\"\n\n//go:embed .version\nvar Version string\n",
+				"cmd/tool/main.go": plainMain,
+			},
+		},
+		{
+			name:  "read at runtime",
+			files: map[string]string{".version": "v1.2.0", "main.go": "package main\n\nimport \"os\"\n\nfunc main() { os.ReadFile(\".version\") }\n"},
+		},
+		{
+			name:  "generated version.go",
+			files: map[string]string{".version": "v1.2.0\n", "version/version.go": generated, "main.go": plainMain},
+		},
+		{
+			name:     "stale generated version.go",
+			files:    map[string]string{".version": "v1.3.0", "version/version.go": generated, "main.go": plainMain},
+			warnings: []string{"warning: .: version/version.go has version v1.2.0 but .version is v1.3.0"},
+		},
+		{
+			name: "module that never reads its version",
+			files: map[string]string{
+				".version":            "v1.2.0",
+				"main.go":             embedMain,
+				"tools/.version":      "v0.3.0",
+				"tools/cmd/x/main.go": plainMain,
+				"tools/lib/lib.go":    "package lib\n",
+			},
+			warnings: []string{"warning: tools/cmd/x: nothing in the module embeds or reads tools/.version"},
+		},
+		{
+			name:  "library module",
+			files: map[string]string{".version": "v1.2.0", "lib.go": "package lib\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			for name, content := range tt.files {
+				err := os.MkdirAll(filepath.Dir(name), 0755)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = os.WriteFile(name, []byte(content), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var output bytes.Buffer
+			err := run(context.Background(), &output, []string{"check-embed"}, nil)
+			if len(tt.warnings) == 0 {
+				if err != nil {
+					t.Fatalf("Expected check to pass, got: %v\nOutput: %s", err, output.String())
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected check to fail\nOutput: %s", output.String())
+			}
+			got := strings.Split(strings.TrimSpace(output.String()), "\n")
+			if strings.Join(got, "\n") != strings.Join(tt.warnings, "\n") {
+				t.Errorf("Got warnings:\n%s\nwant:\n%s", output.String(), strings.Join(tt.warnings, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"golang.org/x/mod/semver"
+)
+
+// envPinDir holds the pin files of the deployment environments, e.g.
+// deploy/staging.version. Unlike .version files they are never bumped; a
+// version only moves forward through "bump promote-env".
+const envPinDir = "deploy"
+
+// envPinPath returns the repository path of an environment's pin file.
+func envPinPath(name string) string {
+	return path.Join(envPinDir, name+".version")
+}
+
+// readEnvPin returns the version pinned for an environment, or "" if the
+// environment has no pin file yet.
+func readEnvPin(name string) (string, []byte, error) {
+	file := envPinPath(name)
+	content, err := os.ReadFile(filepath.FromSlash(file))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	version := parseVersionFile(content)
+	if !semver.IsValid(normalizeVersion(version)) {
+		return "", nil, fmt.Errorf("invalid version in %s: '%s'", file, version)
+	}
+	return version, content, nil
+}
+
+// runPromoteEnv implements "bump promote-env": it copies the version pinned
+// for one environment to the next one in its own commit, modelling a
+// promotion pipeline in the repository.
+func runPromoteEnv(_ context.Context, output io.Writer, args []string, _ []string) error {
+	flagSet := flag.NewFlagSet("promote-env", flag.ContinueOnError)
+	dryRun := flagSet.Bool("dry-run", false, "Check the promotion without committing it.")
+	force := flagSet.Bool("force", false, "Promote a version that isn't tagged, or one older than the target's.")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if flagSet.NArg() != 2 {
+		return errors.New("usage: bump promote-env [-dry-run] [-force] <from> <to>")
+	}
+	from, to := flagSet.Arg(0), flagSet.Arg(1)
+	for _, name := range []string{from, to} {
+		if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+			return fmt.Errorf("invalid environment name '%s'", name)
+		}
+	}
+	if from == to {
+		return errors.New("cannot promote an environment to itself")
+	}
+
+	version, _, err := readEnvPin(from)
+	if err != nil {
+		return err
+	}
+	if version == "" {
+		return fmt.Errorf("environment %s has no pinned version in %s", from, envPinPath(from))
+	}
+	current, content, err := readEnvPin(to)
+	if err != nil {
+		return err
+	}
+	if current == version {
+		_, _ = fmt.Fprintf(output, "%s is already at %s\n", to, version)
+		return nil
+	}
+
+	repo, err := git.PlainOpen(".")
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	if !*force {
+		released, err := tagExists(repo, version)
+		if err != nil {
+			return err
+		}
+		if !released {
+			return fmt.Errorf("%s pinned in %s is not a released version (use -force to promote it anyway)", version, from)
+		}
+		if current != "" && semver.Compare(normalizeVersion(version), normalizeVersion(current)) < 0 {
+			return fmt.Errorf("%s would downgrade %s from %s (use -force to roll back)", version, to, current)
+		}
+	}
+	dirty, err := (&gitVCS{repo: repo}).dirtyFiles()
+	if err != nil {
+		return err
+	}
+	if len(dirty) > 0 {
+		return fmt.Errorf("repository is not clean:\n%s", strings.Join(sortedDirtyFiles(dirty), "\n"))
+	}
+
+	if *dryRun {
+		_, _ = fmt.Fprintf(output, "Would promote %s from %s to %s\n", version, from, to)
+		return nil
+	}
+	file := envPinPath(to)
+	ending := lineEnding(content)
+	if content == nil {
+		ending = "\n"
+	}
+	err = os.MkdirAll(envPinDir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", envPinDir, err)
+	}
+	err = os.WriteFile(filepath.FromSlash(file), []byte(version+ending), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	err = add(repo, file)
+	if err != nil {
+		return err
+	}
+	err = commit(repo, fmt.Sprintf("promote %s to %s from %s", to, version, from), nil)
+	if err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	_, _ = fmt.Fprintf(output, "Promoted %s from %s to %s\n", version, from, to)
+	return nil
+}
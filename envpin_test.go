@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPromoteEnv(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Pin environments", map[string]string{
+		"deploy/staging.version": "v1.0.0\n",
+		"deploy/prod.version":    "v0.9.0\n",
+	})
+	commitsBefore := countCommits(t, repo)
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"promote-env", "staging", "prod"}, nil)
+	if err != nil {
+		t.Fatalf("Expected the promotion to succeed, got: %v", err)
+	}
+	if output.String() != "Promoted v1.0.0 from staging to prod\n" {
+		t.Errorf("Unexpected output:\n%s", output.String())
+	}
+	if content := readFile(t, "deploy/prod.version"); content != "v1.0.0\n" {
+		t.Errorf("Got prod pin %q, want v1.0.0", content)
+	}
+	if countCommits(t, repo) != commitsBefore+1 {
+		t.Error("Expected the promotion to be committed")
+	}
+	if messages := commitMessages(t, repo, 1); !strings.HasPrefix(messages[0], "promote prod to v1.0.0 from staging") {
+		t.Errorf("Unexpected commit message %q", messages[0])
+	}
+
+	output.Reset()
+	err = run(context.Background(), &output, []string{"promote-env", "staging", "prod"}, nil)
+	if err != nil || output.String() != "prod is already at v1.0.0\n" {
+		t.Errorf("Expected a no-op, got %v:\n%s", err, output.String())
+	}
+
+	// bumping leaves the pins alone
+	err = run(context.Background(), &output, []string{"-minor"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content := readFile(t, "deploy/staging.version"); content != "v1.0.0\n" {
+		t.Errorf("Expected bump not to touch the pins, got %q", content)
+	}
+
+	commitFiles(t, repo, "Pin unreleased", map[string]string{"deploy/staging.version": "v9.9.9\n"})
+	err = run(context.Background(), &output, []string{"promote-env", "staging", "prod"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "not a released version") {
+		t.Errorf("Expected an untagged version to be refused, got: %v", err)
+	}
+	commitFiles(t, repo, "Roll back staging", map[string]string{
+		"deploy/staging.version": "v0.9.0\n",
+		"deploy/qa.version":      "v1.1.0\n",
+	})
+	_, err = repo.CreateTag("v0.9.0", mustHead(t, repo), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = run(context.Background(), &output, []string{"promote-env", "staging", "prod"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "would downgrade prod from v1.0.0") {
+		t.Errorf("Expected a downgrade to be refused, got: %v", err)
+	}
+	err = run(context.Background(), &output, []string{"promote-env", "qa", "../prod"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid environment name") {
+		t.Errorf("Expected a path to be refused as environment, got: %v", err)
+	}
+}
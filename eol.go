@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+)
+
+// utf8BOM is the byte order mark some Windows editors start files with.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// parseVersionFile returns the version in the content of a .version file.
+// Files edited on Windows are tolerated: a byte order mark, CRLF line
+// endings and surrounding whitespace are stripped. Structured files (see
+// versionFileHeader) give their version key.
+func parseVersionFile(content []byte) string {
+	if isStructuredVersionFile(content) {
+		return structuredVersion(content)
+	}
+	return strings.TrimSpace(string(bytes.TrimPrefix(content, utf8BOM)))
+}
+
+// versionFileContent returns what bump writes to a version file: the version
+// and the file's line terminator, which -fix-eol normalizes from CRLF to LF.
+// The eol attribute of .gitattributes has the last word. Structured files
+// keep their metadata, with the release time of the run.
+func versionFileContent(cfg config, rules worktreeRules, relPath string, old []byte, version string) []byte {
+	ending := lineEnding(old)
+	if cfg.fixEOL && ending == "\r\n" {
+		ending = "\n"
+	}
+	if isStructuredVersionFile(old) {
+		releasedAt := cfg.releasedAt
+		if releasedAt.IsZero() {
+			releasedAt = cfg.now()
+		}
+		return rules.encode(relPath, structuredVersionFileContent(old, ending, version, releasedAt))
+	}
+	return rules.encode(relPath, []byte(version+ending))
+}
+
+// needsEOLFix reports whether -fix-eol changes more than the version of a
+// version file: a byte order mark or CRLF line endings.
+func needsEOLFix(content []byte) bool {
+	return bytes.HasPrefix(content, utf8BOM) || bytes.Contains(content, []byte("\r"))
+}
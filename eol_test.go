@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseVersionFile(t *testing.T) {
+	for _, content := range []string{"v1.2.3", "v1.2.3\n", "v1.2.3\r\n", " v1.2.3 \r\n\r\n", "\ufeffv1.2.3\r\n"} {
+		if got := parseVersionFile([]byte(content)); got != "v1.2.3" {
+			t.Errorf("parseVersionFile(%q) = %q, want v1.2.3", content, got)
+		}
+	}
+}
+
+func TestBumpFixEOL(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		want   string
+		output string
+	}{
+		{name: "kept", args: []string{"-minor"}, want: "v1.1.0\r\n"},
+		{name: "fixed", args: []string{"-minor", "-fix-eol"}, want: "v1.1.0\n", output: "Normalizing line endings of .version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, repo := setupTaggedTestRepo(t, "v1.0.0")
+			commitFiles(t, repo, "Edit on Windows", map[string]string{".version": "\ufeffv1.0.0\r\n"})
+
+			var output bytes.Buffer
+			err := run(context.Background(), &output, tt.args, nil)
+			if err != nil {
+				t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+			}
+			if got := readFile(t, ".version"); got != tt.want {
+				t.Errorf("Got .version %q, want %q", got, tt.want)
+			}
+			if !strings.Contains(output.String(), tt.output) {
+				t.Errorf("Expected output containing %q, got:\n%s", tt.output, output.String())
+			}
+		})
+	}
+}
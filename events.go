@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Phases of the -events stream, in the order a bump goes through them.
+const (
+	phaseValidate    = "validate"
+	phaseUpdateFiles = "update_files"
+	phaseCommit      = "commit"
+	phaseTag         = "tag"
+	phasePush        = "push"
+	phaseAnnounce    = "announce"
+	phaseDone        = "done"
+	phaseError       = "error"
+)
+
+// progressEvent is one JSON line of the -events stream.
+type progressEvent struct {
+	Time    time.Time `json:"time"`
+	Phase   string    `json:"phase"`
+	Version string    `json:"version,omitempty"`
+	File    string    `json:"file,omitempty"`
+	Tag     string    `json:"tag,omitempty"`
+	Ref     string    `json:"ref,omitempty"`
+	Pushed  bool      `json:"pushed,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// eventStream writes progress events for GUI wrappers and CI log parsers.
+// A nil stream discards them, so the bump emits unconditionally. Error
+// messages are redacted like the output.
+type eventStream struct {
+	w io.WriteCloser
+	r redactor
+}
+
+// openEvents opens the -events target: "fd:N" for an inherited file
+// descriptor, anything else for a file, which is truncated. It returns nil
+// for no target.
+func openEvents(target string, r redactor) (*eventStream, error) {
+	if target == "" {
+		return nil, nil
+	}
+	if fd, ok := strings.CutPrefix(target, "fd:"); ok {
+		n, err := strconv.Atoi(fd)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid -events file descriptor '%s'", fd)
+		}
+		return &eventStream{w: os.NewFile(uintptr(n), target), r: r}, nil
+	}
+	f, err := os.Create(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open -events: %w", err)
+	}
+	return &eventStream{w: f, r: r}, nil
+}
+
+// emit writes an event. Events are best effort: a wrapper that stops reading
+// must not fail the release.
+func (e *eventStream) emit(event progressEvent) {
+	if e == nil {
+		return
+	}
+	event.Time = now()
+	event.Error = e.r.redact(event.Error)
+	line, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	_, _ = e.w.Write(append(line, '\n'))
+}
+
+func (e *eventStream) close() {
+	if e != nil {
+		_ = e.w.Close()
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readEvents(t *testing.T, file string) []progressEvent {
+	t.Helper()
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []progressEvent
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		var event progressEvent
+		err := json.Unmarshal([]byte(line), &event)
+		if err != nil {
+			t.Fatalf("Invalid event line %q: %v", line, err)
+		}
+		if event.Time.IsZero() {
+			t.Errorf("Event without a time: %q", line)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestProgressEvents(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Add module", map[string]string{"lib/.version": "v1.0.0\n"})
+	file := filepath.Join(t.TempDir(), "events.jsonl")
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-events", file, "-minor"}, nil)
+	if err != nil {
+		t.Fatalf("bump: %v\nOutput: %s", err, output.String())
+	}
+	var got []string
+	for _, event := range readEvents(t, file) {
+		got = append(got, strings.TrimSpace(event.Phase+" "+event.File+" "+event.Tag))
+	}
+	want := []string{"validate", "update_files .version", "update_files lib/.version", "commit", "tag  v1.1.0", "done"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Got events:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// a failing bump ends with an error event, from BUMP_EVENTS
+	err = run(context.Background(), &output, []string{"-version", "v1.1.0"}, []string{"BUMP_EVENTS=" + file})
+	if err == nil {
+		t.Fatal("Expected releasing an existing version to fail")
+	}
+	events := readEvents(t, file)
+	last := events[len(events)-1]
+	if last.Phase != phaseError || !strings.Contains(last.Error, "v1.1.0") {
+		t.Errorf("Expected a final error event, got %+v", last)
+	}
+}
+
+func TestProgressEventsRedacted(t *testing.T) {
+	setupTaggedTestRepo(t, "v1.0.0")
+	file := filepath.Join(t.TempDir(), "events.jsonl")
+	env := []string{"BUMP_EVENTS=" + file, "BUMP_GITHUB_TOKEN=s3cr3t-token"}
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-version", "s3cr3t-token"}, env)
+	if err == nil {
+		t.Fatal("Expected an invalid version to fail")
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "s3cr3t-token") || !strings.Contains(string(content), redacted) {
+		t.Errorf("Expected the token to be redacted from the events, got:\n%s", content)
+	}
+
+	readOnly := filepath.Join(t.TempDir(), "read-only.jsonl")
+	err = run(context.Background(), &output, []string{"-assert-read-only", "-events", readOnly}, nil)
+	if err == nil || !strings.Contains(err.Error(), "-assert-read-only can't write the -events file") {
+		t.Errorf("Expected -events to be refused under -assert-read-only, got: %v", err)
+	}
+	if _, err := os.Stat(readOnly); !os.IsNotExist(err) {
+		t.Errorf("Expected -assert-read-only not to create the events file, got: %v", err)
+	}
+}
+
+func TestOpenEvents(t *testing.T) {
+	for _, target := range []string{"fd:", "fd:x", "fd:-1"} {
+		if _, err := openEvents(target, redactor{}); err == nil {
+			t.Errorf("Expected %s to be rejected", target)
+		}
+	}
+	events, err := openEvents("", redactor{})
+	if err != nil || events != nil {
+		t.Errorf("Expected no stream without a target, got %v, %v", events, err)
+	}
+	// a nil stream discards events
+	events.emit(progressEvent{Phase: phaseDone})
+	events.close()
+}
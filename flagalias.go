@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// flagAliases are short names of the common flags, for users coming from
+// GNU-style tools. They share the flag's value, so either name sets it.
+var flagAliases = map[string]string{
+	"n": "dry-run",
+	"p": "patch",
+	"m": "minor",
+	"M": "major",
+	"f": "force",
+	"h": "help",
+}
+
+// deprecatedFlags are old or misspelt names that keep working with a
+// warning, mapped to the flag to use instead.
+var deprecatedFlags = map[string]string{
+	"dryrun": "dry-run",
+}
+
+// deprecatedValue is the value of a deprecated flag name. Setting it sets
+// the flag it stands for and records a warning.
+type deprecatedValue struct {
+	flag.Value
+	name, replacement string
+	warnings          *[]string
+}
+
+func (v deprecatedValue) Set(s string) error {
+	*v.warnings = append(*v.warnings, fmt.Sprintf("-%s is deprecated, use -%s", v.name, v.replacement))
+	return v.Value.Set(s)
+}
+
+// String is empty, so -help shows no default for a deprecated name; the
+// flag it stands for shows it.
+func (v deprecatedValue) String() string {
+	return ""
+}
+
+// IsBoolFlag lets a deprecated boolean flag be given without a value.
+func (v deprecatedValue) IsBoolFlag() bool {
+	b, ok := v.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// registerFlagAliases adds the aliases and deprecated names to the flag set,
+// after the flags they stand for. Warnings about deprecated names used are
+// appended to warnings when the arguments are parsed.
+func registerFlagAliases(flagSet *flag.FlagSet, warnings *[]string) {
+	for alias, name := range flagAliases {
+		target := flagSet.Lookup(name)
+		flagSet.Var(target.Value, alias, "Short for -"+target.Name+".")
+	}
+	for name, replacement := range deprecatedFlags {
+		target := flagSet.Lookup(replacement)
+		flagSet.Var(deprecatedValue{Value: target.Value, name: name, replacement: target.Name, warnings: warnings}, name, "Deprecated, use -"+target.Name+".")
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestFlagAliases(t *testing.T) {
+	tests := []struct {
+		args []string
+		want action
+	}{
+		{[]string{"-p"}, incrementPatch},
+		{[]string{"-m"}, incrementMinor},
+		{[]string{"--M"}, incrementMajor},
+	}
+	for _, tt := range tests {
+		cfg, _, err := getConfig(tt.args)
+		if err != nil {
+			t.Fatalf("getConfig(%v): %v", tt.args, err)
+		}
+		if cfg.action != tt.want {
+			t.Errorf("getConfig(%v) action = %s, want %s", tt.args, cfg.action, tt.want)
+		}
+	}
+
+	cfg, _, err := getConfig([]string{"-n", "-f"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.dryRun || !cfg.forced || len(cfg.warnings) != 0 {
+		t.Errorf("Expected -n and -f to set -dry-run and -force without warnings, got %+v", cfg)
+	}
+	_, _, err = getConfig([]string{"-m", "-major"})
+	if err == nil || !strings.Contains(err.Error(), "more than one increment flag") {
+		t.Errorf("Expected an alias to count as its flag, got: %v", err)
+	}
+	_, showHelp, err := getConfig([]string{"-h"})
+	if err != nil || !showHelp {
+		t.Errorf("Expected -h to show the help, got %v, %v", showHelp, err)
+	}
+}
+
+func TestDeprecatedFlag(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"--dryrun"}, nil)
+	if err != nil {
+		t.Fatalf("Expected the dry run to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	if !strings.Contains(output.String(), "Warning: -dryrun is deprecated, use -dry-run\n") {
+		t.Errorf("Expected a deprecation warning, got:\n%s", output.String())
+	}
+	if _, err := repo.Tag("v1.0.1"); err == nil {
+		t.Errorf("Expected -dryrun to be a dry run, got a tag:\n%s", output.String())
+	}
+}
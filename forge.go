@@ -0,0 +1,519 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+const defaultGitHubAPI = "https://api.github.com"
+
+const defaultGitLabAPI = "https://gitlab.com/api/v4"
+
+const defaultBitbucketAPI = "https://api.bitbucket.org/2.0"
+
+// Limits of the forge client's retries.
+const (
+	// forgeAttempts is how often a rate limited or failing request is sent
+	forgeAttempts = 4
+	// maxForgeWait is the longest the client waits for a rate limit to
+	// reset; a longer wait fails the request instead of stalling the run
+	maxForgeWait = 5 * time.Minute
+)
+
+// forgeSleep waits for d or until ctx is done. Tests replace it.
+var forgeSleep = func(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// forgeClient is the HTTP client shared by the forge integrations (the
+// GitHub, GitLab and Bitbucket REST APIs). It follows the pagination of list
+// endpoints, revalidates cached GET responses with their ETag, which
+// doesn't count against GitHub's rate limit, and backs off when rate
+// limited. Responses are cached for the run, and across runs in
+// BUMP_API_CACHE if set.
+type forgeClient struct {
+	api      string
+	header   http.Header // sent with every request, e.g. the authentication
+	cacheDir string
+	cache    map[string]cachedResponse
+	log      io.Writer // where waits are reported
+}
+
+// cachedResponse is a GET response kept for revalidation.
+type cachedResponse struct {
+	ETag string `json:"etag"`
+	Link string `json:"link,omitempty"`
+	Body []byte `json:"body"`
+}
+
+// newForgeClient returns a client of the API at the base URL.
+func newForgeClient(api string, header http.Header, env []string, log io.Writer) *forgeClient {
+	return &forgeClient{
+		api:      strings.TrimSuffix(api, "/"),
+		header:   header,
+		cacheDir: getenv(env, "BUMP_API_CACHE"),
+		cache:    make(map[string]cachedResponse),
+		log:      log,
+	}
+}
+
+// do sends a request with the JSON encoding of in, if any, and decodes the
+// response into out, if any.
+func (c *forgeClient) do(ctx context.Context, method, path string, in, out any) error {
+	var body []byte
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+	content, _, err := c.send(ctx, method, c.api+path, "application/json", body)
+	if err != nil || out == nil {
+		return err
+	}
+	err = json.Unmarshal(content, out)
+	if err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// nextLink matches the next page in a Link header, which GitHub and GitLab
+// both send.
+var nextLink = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// list fetches every page of a list endpoint and decodes the concatenated
+// items into out, a pointer to a slice.
+func (c *forgeClient) list(ctx context.Context, path string, out any) error {
+	endpoint := c.api + path
+	if !strings.Contains(path, "per_page=") {
+		sep := "?"
+		if strings.Contains(path, "?") {
+			sep = "&"
+		}
+		endpoint += sep + "per_page=100"
+	}
+	var items []json.RawMessage
+	for endpoint != "" {
+		content, link, err := c.send(ctx, http.MethodGet, endpoint, "", nil)
+		if err != nil {
+			return err
+		}
+		var page []json.RawMessage
+		err = json.Unmarshal(content, &page)
+		if err != nil {
+			return fmt.Errorf("failed to decode page: %w", err)
+		}
+		items = append(items, page...)
+		endpoint = ""
+		if m := nextLink.FindStringSubmatch(link); m != nil {
+			endpoint = m[1]
+		}
+	}
+	all, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(all, out)
+}
+
+// send sends a request, retrying while rate limited or while the server
+// fails (see retryDelay), and returns the body and the Link header of the
+// response.
+func (c *forgeClient) send(ctx context.Context, method, endpoint, contentType string, body []byte) ([]byte, string, error) {
+	key := ""
+	var cached cachedResponse
+	hasCached := false
+	if method == http.MethodGet {
+		key = c.cacheKey(endpoint)
+		cached, hasCached = c.cached(key)
+	}
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to create request: %w", err)
+		}
+		for name, values := range c.header {
+			req.Header[name] = values
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if hasCached {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, "", fmt.Errorf("request failed: %w", err)
+		}
+		content, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read response: %w", err)
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusNotModified && hasCached:
+			return cached.Body, cached.Link, nil
+		case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+			if etag := resp.Header.Get("ETag"); key != "" && etag != "" {
+				c.store(key, cachedResponse{ETag: etag, Link: resp.Header.Get("Link"), Body: content})
+			}
+			return content, resp.Header.Get("Link"), nil
+		}
+		wait, retry := retryDelay(resp, method, attempt)
+		if !retry || attempt == forgeAttempts {
+			detail := strings.TrimSpace(string(content))
+			if len(detail) > 512 {
+				detail = detail[:512]
+			}
+			return nil, "", fmt.Errorf("unexpected response %s: %s", resp.Status, detail)
+		}
+		if wait > maxForgeWait {
+			return nil, "", fmt.Errorf("rate limited by %s until %s", req.URL.Host, now().Add(wait).Format(time.RFC3339))
+		}
+		if c.log != nil {
+			_, _ = fmt.Fprintf(c.log, "%s answered %s, retrying in %s\n", req.URL.Host, resp.Status, wait.Round(time.Second))
+		}
+		err = forgeSleep(ctx, wait)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+}
+
+// retryDelay decides whether a failed request is worth retrying and how long
+// to wait first: as long as Retry-After or the rate limit reset asks for, or
+// with exponential backoff for server errors. A server error may come after
+// a POST or PATCH took effect, so those are only retried when rate limited.
+func retryDelay(resp *http.Response, method string, attempt int) (time.Duration, bool) {
+	remaining := resp.Header.Get("X-RateLimit-Remaining") // GitHub
+	reset := resp.Header.Get("X-RateLimit-Reset")
+	if remaining == "" {
+		remaining = resp.Header.Get("RateLimit-Remaining") // GitLab
+		reset = resp.Header.Get("RateLimit-Reset")
+	}
+	limited := resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusForbidden && remaining == "0")
+	if !limited && (method == http.MethodPost || method == http.MethodPatch) {
+		return 0, false
+	}
+	backoff := time.Duration(1<<(attempt-1)) * time.Second
+	if after := resp.Header.Get("Retry-After"); after != "" {
+		if seconds, err := strconv.Atoi(after); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+		return backoff, true
+	}
+	if limited {
+		if epoch, err := strconv.ParseInt(reset, 10, 64); err == nil {
+			wait := time.Unix(epoch, 0).Sub(now())
+			return max(wait, time.Second), true
+		}
+		return backoff, true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return backoff, true
+	}
+	return 0, false
+}
+
+// cacheKey identifies a GET request. The whole header is part of it, so
+// responses cached for one user's credentials, whichever header carries
+// them, are never served to another.
+func (c *forgeClient) cacheKey(endpoint string) string {
+	names := make([]string, 0, len(c.header))
+	for name := range c.header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	hash := sha256.New()
+	for _, name := range names {
+		for _, value := range c.header[name] {
+			_, _ = fmt.Fprintf(hash, "%s: %s\n", name, value)
+		}
+	}
+	_, _ = io.WriteString(hash, "\n"+endpoint)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (c *forgeClient) cached(key string) (cachedResponse, bool) {
+	if entry, ok := c.cache[key]; ok {
+		return entry, true
+	}
+	if c.cacheDir == "" {
+		return cachedResponse{}, false
+	}
+	content, err := os.ReadFile(filepath.Join(c.cacheDir, key+".json"))
+	if err != nil {
+		return cachedResponse{}, false
+	}
+	var entry cachedResponse
+	if json.Unmarshal(content, &entry) != nil || entry.ETag == "" {
+		return cachedResponse{}, false
+	}
+	c.cache[key] = entry
+	return entry, true
+}
+
+// store caches a response. The disk cache is best effort: failing to write
+// it doesn't fail the request.
+func (c *forgeClient) store(key string, entry cachedResponse) {
+	c.cache[key] = entry
+	if c.cacheDir == "" {
+		return
+	}
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if os.MkdirAll(c.cacheDir, 0700) != nil {
+		return
+	}
+	_ = os.WriteFile(filepath.Join(c.cacheDir, key+".json"), content, 0600)
+}
+
+// githubClient is a client of the GitHub REST API.
+type githubClient struct {
+	*forgeClient
+}
+
+// newGitHubClient authenticates with BUMP_GITHUB_TOKEN against
+// BUMP_GITHUB_API, by default api.github.com.
+func newGitHubClient(env []string, log io.Writer) (githubClient, error) {
+	token := getenv(env, "BUMP_GITHUB_TOKEN")
+	if token == "" {
+		return githubClient{}, errors.New("BUMP_GITHUB_TOKEN must be set")
+	}
+	api := getenv(env, "BUMP_GITHUB_API")
+	if api == "" {
+		api = defaultGitHubAPI
+	}
+	if _, err := url.Parse(api); err != nil {
+		return githubClient{}, fmt.Errorf("invalid BUMP_GITHUB_API: %w", err)
+	}
+	header := http.Header{}
+	header.Set("Accept", "application/vnd.github+json")
+	header.Set("Authorization", "Bearer "+token)
+	return githubClient{newForgeClient(api, header, env, log)}, nil
+}
+
+// githubRepository returns the owner/repo of the repository on GitHub:
+// BUMP_GITHUB_REPO if set, else the project of the remote if it is hosted on
+// github.com, or on any host with BUMP_GITHUB_API pointing to GitHub
+// Enterprise.
+func githubRepository(repo *git.Repository, remoteName string, env []string) (string, error) {
+	if project := getenv(env, "BUMP_GITHUB_REPO"); project != "" {
+		owner, name, ok := strings.Cut(project, "/")
+		if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			return "", fmt.Errorf("invalid BUMP_GITHUB_REPO '%s': expected owner/repo", project)
+		}
+		return project, nil
+	}
+	remote, err := repo.Remote(remoteName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get remote '%s': %w", remoteName, err)
+	}
+	if len(remote.Config().URLs) > 0 {
+		host, project := forgeProject(remote.Config().URLs[0])
+		if project != "" && (host == "github.com" || (host != "" && getenv(env, "BUMP_GITHUB_API") != "")) {
+			return project, nil
+		}
+	}
+	return "", fmt.Errorf("remote '%s' isn't on GitHub: set BUMP_GITHUB_REPO to owner/repo", remoteName)
+}
+
+// gitlabClient is a client of the GitLab REST API.
+type gitlabClient struct {
+	*forgeClient
+}
+
+// newGitLabClient authenticates with the personal, project or group access
+// token in BUMP_GITLAB_TOKEN or, in a GitLab CI job, with CI_JOB_TOKEN,
+// against BUMP_GITLAB_API, by default the job's instance or gitlab.com.
+func newGitLabClient(env []string, log io.Writer) (gitlabClient, error) {
+	header := http.Header{}
+	if token := getenv(env, "BUMP_GITLAB_TOKEN"); token != "" {
+		header.Set("PRIVATE-TOKEN", token)
+	} else if token := getenv(env, "CI_JOB_TOKEN"); token != "" {
+		header.Set("JOB-TOKEN", token)
+	} else {
+		return gitlabClient{}, errors.New("BUMP_GITLAB_TOKEN or CI_JOB_TOKEN must be set")
+	}
+	api := getenv(env, "BUMP_GITLAB_API")
+	if api == "" {
+		api = getenv(env, "CI_API_V4_URL")
+	}
+	if api == "" {
+		api = defaultGitLabAPI
+	}
+	if _, err := url.Parse(api); err != nil {
+		return gitlabClient{}, fmt.Errorf("invalid BUMP_GITLAB_API: %w", err)
+	}
+	return gitlabClient{newForgeClient(api, header, env, log)}, nil
+}
+
+// gitlabProject returns the path of the project on GitLab, e.g.
+// group/subgroup/app: BUMP_GITLAB_PROJECT if set, else the project of the
+// remote if it is hosted on gitlab.com, or on the instance of the CI job or
+// of BUMP_GITLAB_API.
+func gitlabProject(repo *git.Repository, remoteName string, env []string) (string, error) {
+	if project := getenv(env, "BUMP_GITLAB_PROJECT"); project != "" {
+		return project, nil
+	}
+	remote, err := repo.Remote(remoteName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get remote '%s': %w", remoteName, err)
+	}
+	if len(remote.Config().URLs) > 0 {
+		host, project := forgeProject(remote.Config().URLs[0])
+		selfManaged := getenv(env, "BUMP_GITLAB_API") != "" || host == getenv(env, "CI_SERVER_HOST")
+		if project != "" && (host == "gitlab.com" || (host != "" && selfManaged)) {
+			return project, nil
+		}
+	}
+	return "", fmt.Errorf("remote '%s' isn't on GitLab: set BUMP_GITLAB_PROJECT to the project's path", remoteName)
+}
+
+// bitbucketClient is a client of the REST API of Bitbucket Cloud or, with
+// server set, of a Bitbucket Server (Data Center). Their list endpoints
+// page differently from GitHub's and GitLab's, see list.
+type bitbucketClient struct {
+	*forgeClient
+	server bool
+}
+
+// newBitbucketClient authenticates with the access token in
+// BUMP_BITBUCKET_TOKEN or, with BUMP_BITBUCKET_USER set, with that user's
+// app password in it. It talks to the Bitbucket Server at BUMP_BITBUCKET_URL
+// if set, else to BUMP_BITBUCKET_API, by default api.bitbucket.org.
+func newBitbucketClient(env []string, log io.Writer) (bitbucketClient, error) {
+	token := getenv(env, "BUMP_BITBUCKET_TOKEN")
+	if token == "" {
+		return bitbucketClient{}, errors.New("BUMP_BITBUCKET_TOKEN must be set")
+	}
+	header := http.Header{}
+	if user := getenv(env, "BUMP_BITBUCKET_USER"); user != "" {
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user+":"+token)))
+	} else {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	if base := getenv(env, "BUMP_BITBUCKET_URL"); base != "" {
+		if _, err := url.Parse(base); err != nil {
+			return bitbucketClient{}, fmt.Errorf("invalid BUMP_BITBUCKET_URL: %w", err)
+		}
+		return bitbucketClient{newForgeClient(strings.TrimSuffix(base, "/")+"/rest/api/1.0", header, env, log), true}, nil
+	}
+	api := getenv(env, "BUMP_BITBUCKET_API")
+	if api == "" {
+		api = defaultBitbucketAPI
+	}
+	if _, err := url.Parse(api); err != nil {
+		return bitbucketClient{}, fmt.Errorf("invalid BUMP_BITBUCKET_API: %w", err)
+	}
+	return bitbucketClient{newForgeClient(api, header, env, log), false}, nil
+}
+
+// list fetches every page of a list endpoint and decodes the concatenated
+// values into out, a pointer to a slice. Bitbucket Cloud links the next page
+// in the response; a Bitbucket Server gives the start of the next page
+// until the last one.
+func (c bitbucketClient) list(ctx context.Context, path string, out any) error {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	endpoint := c.api + path + sep + "pagelen=100"
+	if c.server {
+		endpoint = c.api + path + sep + "limit=100"
+	}
+	var items []json.RawMessage
+	for endpoint != "" {
+		content, _, err := c.send(ctx, http.MethodGet, endpoint, "", nil)
+		if err != nil {
+			return err
+		}
+		var page struct {
+			Values        []json.RawMessage `json:"values"`
+			Next          string            `json:"next"`
+			IsLastPage    bool              `json:"isLastPage"`
+			NextPageStart int               `json:"nextPageStart"`
+		}
+		err = json.Unmarshal(content, &page)
+		if err != nil {
+			return fmt.Errorf("failed to decode page: %w", err)
+		}
+		items = append(items, page.Values...)
+		endpoint = page.Next
+		if c.server {
+			endpoint = ""
+			if !page.IsLastPage {
+				endpoint = fmt.Sprintf("%s%s%slimit=100&start=%d", c.api, path, sep, page.NextPageStart)
+			}
+		}
+	}
+	all, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(all, out)
+}
+
+// bitbucketRepository returns the repository on Bitbucket:
+// BUMP_BITBUCKET_REPO if set, else in Bitbucket Pipelines the repository
+// being built, else the repository of the remote if it is hosted on
+// bitbucket.org or on the Bitbucket Server at BUMP_BITBUCKET_URL. It is
+// workspace/repo on Bitbucket Cloud and PROJECT/repo, or ~user/repo for a
+// personal repository, on a Bitbucket Server.
+func bitbucketRepository(repo *git.Repository, remoteName string, env []string) (string, error) {
+	if project := getenv(env, "BUMP_BITBUCKET_REPO"); project != "" {
+		owner, name, ok := strings.Cut(project, "/")
+		if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			return "", fmt.Errorf("invalid BUMP_BITBUCKET_REPO '%s': expected workspace/repo or PROJECT/repo", project)
+		}
+		return project, nil
+	}
+	base := getenv(env, "BUMP_BITBUCKET_URL")
+	if project := getenv(env, "BITBUCKET_REPO_FULL_NAME"); project != "" && base == "" {
+		return project, nil
+	}
+	remote, err := repo.Remote(remoteName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get remote '%s': %w", remoteName, err)
+	}
+	if len(remote.Config().URLs) > 0 {
+		host, project := forgeProject(remote.Config().URLs[0])
+		if base == "" && host == "bitbucket.org" && project != "" {
+			return project, nil
+		}
+		if key, name, ok := bitbucketServerRepo(base, host, project); ok {
+			return key + "/" + name, nil
+		}
+	}
+	return "", fmt.Errorf("remote '%s' isn't on Bitbucket: set BUMP_BITBUCKET_REPO to workspace/repo or PROJECT/repo", remoteName)
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gitconfig "github.com/go-git/go-git/v5/config"
+)
+
+// noForgeSleep records the waits of the forge client instead of sleeping.
+func noForgeSleep(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var waits []time.Duration
+	previous := forgeSleep
+	forgeSleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	t.Cleanup(func() { forgeSleep = previous })
+	return &waits
+}
+
+func TestForgeClientPagination(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("per_page") != "100" {
+			http.Error(w, "expected per_page=100", http.StatusBadRequest)
+			return
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 2 {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/items?per_page=100&page=2>; rel="next", <%s/items?per_page=100&page=2>; rel="last"`, server.URL, server.URL))
+			_, _ = fmt.Fprint(w, `[1, 2]`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `[3]`)
+	}))
+	defer server.Close()
+
+	client := newForgeClient(server.URL, http.Header{}, nil, nil)
+	var items []int
+	err := client.list(context.Background(), "/items", &items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(items) != "[1 2 3]" {
+		t.Errorf("Expected the items of both pages, got %v", items)
+	}
+}
+
+func TestForgeClientETagCache(t *testing.T) {
+	var full, revalidated atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidated.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = fmt.Fprint(w, `{"name": "bump"}`)
+	}))
+	defer server.Close()
+
+	env := []string{"BUMP_API_CACHE=" + t.TempDir()}
+	var repo struct{ Name string }
+	for range 2 {
+		// a new client per run, sharing the cache directory
+		client := newForgeClient(server.URL, http.Header{"Authorization": {"Bearer token"}}, env, nil)
+		repo.Name = ""
+		err := client.do(context.Background(), http.MethodGet, "/repo", nil, &repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if repo.Name != "bump" {
+			t.Errorf("Expected the cached body, got %+v", repo)
+		}
+	}
+	if full.Load() != 1 || revalidated.Load() != 1 {
+		t.Errorf("Expected 1 full and 1 conditional request, got %d and %d", full.Load(), revalidated.Load())
+	}
+
+	// another user doesn't get the cached response
+	client := newForgeClient(server.URL, http.Header{"Authorization": {"Bearer other"}}, env, nil)
+	err := client.do(context.Background(), http.MethodGet, "/repo", nil, &repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if full.Load() != 2 {
+		t.Errorf("Expected the cache to be per credentials, got %d full requests", full.Load())
+	}
+
+	// nor does another GitLab CI job, whose token isn't an Authorization
+	for i, token := range []string{"job-1", "job-2"} {
+		header := http.Header{}
+		header.Set("JOB-TOKEN", token)
+		client := newForgeClient(server.URL, header, env, nil)
+		err := client.do(context.Background(), http.MethodGet, "/repo", nil, &repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if full.Load() != int32(3+i) {
+			t.Errorf("Expected the cache to be per job token, got %d full requests", full.Load())
+		}
+	}
+}
+
+func TestForgeClientRateLimit(t *testing.T) {
+	waits := noForgeSleep(t)
+	setClock(t, "2024-05-01")
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch requests.Add(1) {
+		case 1:
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(now().Add(30*time.Second).Unix(), 10))
+			http.Error(w, "API rate limit exceeded", http.StatusForbidden)
+		case 2:
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+		default:
+			_, _ = fmt.Fprint(w, `{}`)
+		}
+	}))
+	defer server.Close()
+
+	var log bytes.Buffer
+	client := newForgeClient(server.URL, http.Header{}, nil, &log)
+	err := client.do(context.Background(), http.MethodPut, "/releases", map[string]string{"tag_name": "v1.0.0"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(*waits) != "[30s 2s]" {
+		t.Errorf("Expected to wait for the reset and then back off, got %v", *waits)
+	}
+	if !strings.Contains(log.String(), "answered 403 Forbidden, retrying in 30s") {
+		t.Errorf("Expected the wait to be reported, got: %s", log.String())
+	}
+}
+
+func TestForgeClientNoRetryAfterPost(t *testing.T) {
+	waits := noForgeSleep(t)
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 && r.URL.Path == "/limited" {
+			w.Header().Set("Retry-After", "5")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		if r.URL.Path == "/limited" {
+			_, _ = fmt.Fprint(w, `{}`)
+			return
+		}
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := newForgeClient(server.URL, http.Header{}, nil, nil)
+	err := client.do(context.Background(), http.MethodPost, "/limited", map[string]string{"tag_name": "v1.0.0"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests.Load() != 2 || fmt.Sprint(*waits) != "[5s]" {
+		t.Errorf("Expected a rate limited POST to be retried, got %d requests and waits %v", requests.Load(), *waits)
+	}
+
+	requests.Store(0)
+	err = client.do(context.Background(), http.MethodPost, "/releases", map[string]string{"tag_name": "v1.0.0"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "unexpected response 502") {
+		t.Errorf("Expected the bad gateway to fail the request, got: %v", err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("Expected a POST answered with 502 to be sent once, got %d requests", requests.Load())
+	}
+}
+
+func TestForgeClientGivesUp(t *testing.T) {
+	noForgeSleep(t)
+	setClock(t, "2024-05-01")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/later" {
+			w.Header().Set("Retry-After", "3600")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := newForgeClient(server.URL, http.Header{}, nil, nil)
+	err := client.do(context.Background(), http.MethodGet, "/later", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "rate limited by") {
+		t.Errorf("Expected a long rate limit to fail the request, got: %v", err)
+	}
+	err = client.do(context.Background(), http.MethodGet, "/down", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "unexpected response 503") {
+		t.Errorf("Expected the request to fail after %d attempts, got: %v", forgeAttempts, err)
+	}
+}
+
+func TestGitLabProject(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	for name, u := range map[string]string{"origin": "git@gitlab.com:acme/tools/app.git", "company": "https://git.example.com/acme/app.git"} {
+		_, err := repo.CreateRemote(&gitconfig.RemoteConfig{Name: name, URLs: []string{u}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		remote string
+		env    []string
+		want   string
+		err    string
+	}{
+		{remote: "origin", want: "acme/tools/app"},
+		{remote: "company", err: "remote 'company' isn't on GitLab"},
+		{remote: "company", env: []string{"CI_SERVER_HOST=git.example.com"}, want: "acme/app"},
+		{remote: "company", env: []string{"BUMP_GITLAB_API=https://git.example.com/api/v4"}, want: "acme/app"},
+		{remote: "company", env: []string{"BUMP_GITLAB_PROJECT=acme/other"}, want: "acme/other"},
+	}
+	for _, tt := range tests {
+		got, err := gitlabProject(repo, tt.remote, tt.env)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("gitlabProject(%s, %v): expected error %q, got %v", tt.remote, tt.env, tt.err, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("gitlabProject(%s, %v) = %q, %v, want %q", tt.remote, tt.env, got, err, tt.want)
+		}
+	}
+
+	_, err := newGitLabClient(nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "BUMP_GITLAB_TOKEN or CI_JOB_TOKEN must be set") {
+		t.Errorf("Expected a missing token to be refused, got: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// generatedFile is a file rendered from a template at bump time and committed
+// with the release, such as a version.json served by the application.
+type generatedFile struct {
+	template string // repository path of the template
+	path     string // repository path of the rendered file
+	content  []byte
+}
+
+// loadGenerated reads the files to generate. The format is line based, a
+// template and the file it renders to:
+//
+//	templates/version.json.tmpl  public/version.json
+//	templates/RELEASE.tmpl       RELEASE
+func loadGenerated(file string) ([]generatedFile, error) {
+	content, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []generatedFile
+	for i, line := range strings.Split(string(content), "\n") {
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected '<template> <file>'", i+1)
+		}
+		f := generatedFile{template: path.Clean(fields[0]), path: path.Clean(fields[1])}
+		for _, p := range []string{f.template, f.path} {
+			if p == "." || path.IsAbs(p) || p == ".." || strings.HasPrefix(p, "../") {
+				return nil, fmt.Errorf("line %d: %s is not a file in the repository", i+1, p)
+			}
+		}
+		for _, other := range files {
+			if other.path == f.path {
+				return nil, fmt.Errorf("line %d: %s is already generated from %s", i+1, f.path, other.template)
+			}
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// renderGenerated renders the files of .bumpgenerate for the release. It runs
+// before anything is written, so a broken template aborts the bump. The files
+// are part of the release commit, so the commit they see is the one the
+// release is cut from.
+func renderGenerated(repo vcs, rel release) ([]generatedFile, error) {
+	files, err := loadGenerated(".bumpgenerate")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load .bumpgenerate: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+	rel.Commit, err = repo.head()
+	if err != nil {
+		return nil, err
+	}
+	for i, f := range files {
+		text, err := os.ReadFile(filepath.FromSlash(f.template))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template for %s: %w", f.path, err)
+		}
+		rendered, err := renderTemplate(f.template, string(text), rel)
+		if err != nil {
+			return nil, err
+		}
+		files[i].content = []byte(rendered)
+	}
+	return files, nil
+}
+
+// writeGenerated writes the rendered files into the worktree and stages them,
+// so they are part of the release commit.
+func writeGenerated(repo vcs, cfg config, output io.Writer, files []generatedFile) error {
+	for _, f := range files {
+		_, _ = fmt.Fprintf(output, "Generating %s from %s\n", f.path, f.template)
+		if cfg.dryRun {
+			err := previewFile(output, f.path, f.content)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		err := writeReleaseFile(repo, f.path, f.content)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeReleaseFile writes a file of the release commit and stages it.
+func writeReleaseFile(repo vcs, file string, content []byte) error {
+	osPath := filepath.FromSlash(file)
+	err := os.MkdirAll(filepath.Dir(osPath), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", file, err)
+	}
+	err = os.WriteFile(osPath, content, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	err = repo.add(file)
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", file, err)
+	}
+	return nil
+}
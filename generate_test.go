@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadGenerated(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []generatedFile
+		errText string
+	}{
+		{
+			name:    "files",
+			content: "# served by the app\ntemplates/version.json.tmpl public/version.json\nRELEASE.tmpl ./RELEASE\n",
+			want:    []generatedFile{{template: "templates/version.json.tmpl", path: "public/version.json"}, {template: "RELEASE.tmpl", path: "RELEASE"}},
+		},
+		{name: "syntax", content: "RELEASE.tmpl\n", errText: "line 1: expected '<template> <file>'"},
+		{name: "outside", content: "RELEASE.tmpl ../RELEASE\n", errText: "line 1: ../RELEASE is not a file in the repository"},
+		{name: "absolute", content: "/etc/passwd RELEASE\n", errText: "line 1: /etc/passwd is not a file in the repository"},
+		{name: "duplicate", content: "a.tmpl RELEASE\nb.tmpl RELEASE\n", errText: "line 2: RELEASE is already generated from a.tmpl"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), ".bumpgenerate")
+			err := os.WriteFile(file, []byte(tt.content), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files, err := loadGenerated(file)
+			if tt.errText != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errText) {
+					t.Fatalf("Expected error containing %q, got: %v", tt.errText, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != len(tt.want) {
+				t.Fatalf("Got files %+v, want %+v", files, tt.want)
+			}
+			for i := range files {
+				if files[i].template != tt.want[i].template || files[i].path != tt.want[i].path {
+					t.Errorf("Got file %+v, want %+v", files[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBumpGenerated(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Add version endpoint", map[string]string{
+		".bumpgenerate":     "version.json.tmpl public/version.json\n",
+		"version.json.tmpl": `{"version": "{{.Version}}", "previous": "{{.Previous}}", "commit": "{{shortHash .Commit}}"}` + "\n",
+	})
+	source := mustHead(t, repo).String()
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-dry-run"}, nil)
+	if err != nil {
+		t.Fatalf("Expected the dry run to succeed, got: %v", err)
+	}
+	if !strings.Contains(output.String(), "Generating public/version.json from version.json.tmpl") {
+		t.Errorf("Expected the dry run to report the generated file, got:\n%s", output.String())
+	}
+	if _, err := os.Stat(filepath.Join("public", "version.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected the dry run not to write the file, got: %v", err)
+	}
+
+	err = run(context.Background(), &output, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	commit, err := tagCommit(repo, "v1.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := commit.File("public/version.json")
+	if err != nil {
+		t.Fatalf("Expected the generated file in the release commit: %v", err)
+	}
+	content, err := file.Contents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"version": "v1.0.1", "previous": "v1.0.0", "commit": "` + source[:7] + `"}` + "\n"
+	if content != want {
+		t.Errorf("Got generated file %q, want %q", content, want)
+	}
+}
+
+func TestBumpGeneratedBrokenTemplate(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Add release file", map[string]string{
+		".bumpgenerate": "RELEASE.tmpl RELEASE\n",
+		"RELEASE.tmpl":  "{{.Version\n",
+	})
+	commits := countCommits(t, repo)
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "failed to parse RELEASE.tmpl template") {
+		t.Errorf("Expected a template error, got: %v", err)
+	}
+	if countCommits(t, repo) != commits {
+		t.Error("Expected no commit with a broken template")
+	}
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/mod/semver"
+)
+
+// releaseHistory is the release history of a repository as written by
+// "bump export-history".
+type releaseHistory struct {
+	Releases []historyRelease `json:"releases"`
+}
+
+// historyRelease is a version tag and enough about its commit to find the
+// commit again once the history was rewritten.
+type historyRelease struct {
+	Tag      string    `json:"tag"`
+	Version  string    `json:"version"`
+	Date     time.Time `json:"date"`
+	Commit   string    `json:"commit"`
+	Subject  string    `json:"subject"`
+	Author   string    `json:"author"`
+	Authored time.Time `json:"authored"`
+	// Tagger and Message are set for annotated tags, Tagger as "Name <email>"
+	Tagger  string `json:"tagger,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// runExportHistory implements "bump export-history": it writes the release
+// history as JSON, for "bump import-history" to recreate the tags after a
+// history rewrite or a migration.
+func runExportHistory(_ context.Context, output io.Writer, args []string, _ []string) error {
+	flagSet := flag.NewFlagSet("export-history", flag.ContinueOnError)
+	err := flagSet.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if flagSet.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", flagSet.Args())
+	}
+	repo, err := git.PlainOpen(".")
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	history, err := exportHistory(repo)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(output)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(history)
+}
+
+// versionOfTag returns the version a tag stands for: its name, or the last
+// component of a prefixed name such as cli/v1.2.0 or stable/v1.2.0.
+func versionOfTag(name string) (string, bool) {
+	version := path.Base(name)
+	return version, semver.IsValid(normalizeVersion(version))
+}
+
+// exportHistory collects the version tags, ordered by version.
+func exportHistory(repo *git.Repository) (releaseHistory, error) {
+	history := releaseHistory{Releases: []historyRelease{}}
+	err := forEachTag(repo, func(ref *plumbing.Reference) error {
+		name := ref.Name().Short()
+		version, ok := versionOfTag(name)
+		if !ok {
+			return nil
+		}
+		commit, err := peelTag(repo, ref.Hash())
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		release := historyRelease{
+			Tag:      name,
+			Version:  version,
+			Date:     commit.Committer.When,
+			Commit:   commit.Hash.String(),
+			Subject:  commitSubject(commit.Message),
+			Author:   commit.Author.Email,
+			Authored: commit.Author.When,
+		}
+		if tag, err := repo.TagObject(ref.Hash()); err == nil {
+			release.Date = tag.Tagger.When
+			release.Tagger = fmt.Sprintf("%s <%s>", tag.Tagger.Name, tag.Tagger.Email)
+			release.Message = tag.Message
+		}
+		history.Releases = append(history.Releases, release)
+		return nil
+	})
+	if err != nil {
+		return history, err
+	}
+	sort.Slice(history.Releases, func(i, j int) bool {
+		a, b := history.Releases[i], history.Releases[j]
+		if c := semver.Compare(normalizeVersion(a.Version), normalizeVersion(b.Version)); c != 0 {
+			return c < 0
+		}
+		return a.Tag < b.Tag
+	})
+	return history, nil
+}
+
+// runImportHistory implements "bump import-history": it recreates the tags
+// of an exported history. A release's commit is found by its id, through
+// the -map of a history rewrite (git filter-repo's commit-map, BFG's
+// object-id-map), or else by its author, author date and subject. Nothing
+// is tagged unless every release is placed, or -skip-missing is given.
+func runImportHistory(_ context.Context, output io.Writer, args []string, _ []string) error {
+	flagSet := flag.NewFlagSet("import-history", flag.ContinueOnError)
+	dryRun := flagSet.Bool("dry-run", false, "Show the tags that would be created.")
+	mapFile := flagSet.String("map", "", "Commit map of the rewrite: lines of old and new commit id.")
+	skipMissing := flagSet.Bool("skip-missing", false, "Tag the releases that can be placed and skip the others.")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if flagSet.NArg() != 1 {
+		return errors.New("usage: bump import-history [-dry-run] [-map file] [-skip-missing] <history.json|->")
+	}
+	history, err := readHistory(flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
+	commitMap := map[string]string{}
+	if *mapFile != "" {
+		commitMap, err = loadCommitMap(*mapFile)
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", *mapFile, err)
+		}
+	}
+	repo, err := git.PlainOpen(".")
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	placed, missing, err := placeReleases(repo, history, commitMap)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 && !*skipMissing {
+		return fmt.Errorf("no commit found for %d release(s), nothing was tagged (use -map or -skip-missing):\n  %s",
+			len(missing), strings.Join(missing, "\n  "))
+	}
+	for _, tag := range missing {
+		_, _ = fmt.Fprintf(output, "Skipping %s: no commit found\n", tag)
+	}
+	var create []placedRelease
+	for _, p := range placed {
+		ref, err := repo.Tag(p.release.Tag)
+		if errors.Is(err, git.ErrTagNotFound) {
+			create = append(create, p)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to look up tag %s: %w", p.release.Tag, err)
+		}
+		commit, err := peelTag(repo, ref.Hash())
+		if err != nil {
+			return fmt.Errorf("%s: %w", p.release.Tag, err)
+		}
+		if commit.Hash != p.commit {
+			return fmt.Errorf("tag '%s' already exists on %s, not on %s", p.release.Tag, shortHash(commit.Hash.String()), shortHash(p.commit.String()))
+		}
+		_, _ = fmt.Fprintf(output, "Tag %s already exists\n", p.release.Tag)
+	}
+	for _, p := range create {
+		_, _ = fmt.Fprintf(output, "Tagging %s on %s (was %s)\n", p.release.Tag, shortHash(p.commit.String()), shortHash(p.release.Commit))
+		if *dryRun {
+			continue
+		}
+		err = recreateTag(repo, p.release, p.commit)
+		if err != nil {
+			return err
+		}
+	}
+	if !*dryRun {
+		_, _ = fmt.Fprintf(output, "Created %d tag(s)\n", len(create))
+	}
+	return nil
+}
+
+// readHistory reads an exported history from the file, or stdin for "-".
+func readHistory(file string) (releaseHistory, error) {
+	var history releaseHistory
+	var content []byte
+	var err error
+	if file == "-" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return history, fmt.Errorf("failed to read history: %w", err)
+	}
+	err = json.Unmarshal(content, &history)
+	if err != nil {
+		return history, fmt.Errorf("invalid history: %w", err)
+	}
+	return history, nil
+}
+
+// loadCommitMap reads the old and new commit ids of a history rewrite, one
+// pair per line. Header lines and removed commits (a new id of zeros) are
+// skipped.
+func loadCommitMap(file string) (map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	commitMap := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || !plumbing.IsHash(fields[0]) || !plumbing.IsHash(fields[1]) {
+			continue
+		}
+		if plumbing.NewHash(fields[1]).IsZero() {
+			continue
+		}
+		commitMap[fields[0]] = fields[1]
+	}
+	return commitMap, scanner.Err()
+}
+
+// placedRelease is a release and the commit it is tagged on in this
+// repository.
+type placedRelease struct {
+	release historyRelease
+	commit  plumbing.Hash
+}
+
+// commitKey identifies a commit across a history rewrite that keeps its
+// author, date and subject.
+type commitKey struct {
+	author  string
+	date    int64
+	subject string
+}
+
+// placeReleases finds the commit of every release on the branches,
+// returning the tags of the releases it can't place. Commits that are only
+// left over in the object database from before a rewrite don't count.
+func placeReleases(repo *git.Repository, history releaseHistory, commitMap map[string]string) ([]placedRelease, []string, error) {
+	onBranches, byKey, err := indexCommits(repo)
+	if err != nil {
+		return nil, nil, err
+	}
+	var placed []placedRelease
+	var missing []string
+	for _, release := range history.Releases {
+		if release.Tag == "" || !plumbing.IsHash(release.Commit) {
+			return nil, nil, fmt.Errorf("invalid history entry for '%s'", release.Tag)
+		}
+		hash := plumbing.NewHash(release.Commit)
+		if mapped, ok := commitMap[release.Commit]; ok {
+			hash = plumbing.NewHash(mapped)
+		}
+		if onBranches[hash] {
+			placed = append(placed, placedRelease{release: release, commit: hash})
+			continue
+		}
+		candidates := byKey[commitKey{author: release.Author, date: release.Authored.Unix(), subject: release.Subject}]
+		switch len(candidates) {
+		case 1:
+			placed = append(placed, placedRelease{release: release, commit: candidates[0]})
+		case 0:
+			missing = append(missing, release.Tag)
+		default:
+			missing = append(missing, fmt.Sprintf("%s (%d commits match)", release.Tag, len(candidates)))
+		}
+	}
+	return placed, missing, nil
+}
+
+// indexCommits collects the commits reachable from the branches, and
+// indexes them by commitKey.
+func indexCommits(repo *git.Repository) (map[plumbing.Hash]bool, map[commitKey][]plumbing.Hash, error) {
+	branches, err := repo.Branches()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	seen := make(map[plumbing.Hash]bool)
+	byKey := make(map[commitKey][]plumbing.Hash)
+	err = branches.ForEach(func(ref *plumbing.Reference) error {
+		tip, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			return fmt.Errorf("failed to get the tip of %s: %w", ref.Name().Short(), err)
+		}
+		return object.NewCommitPreorderIter(tip, seen, nil).ForEach(func(c *object.Commit) error {
+			seen[c.Hash] = true
+			key := commitKey{author: c.Author.Email, date: c.Author.When.Unix(), subject: commitSubject(c.Message)}
+			byKey[key] = append(byKey[key], c.Hash)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to walk the branches: %w", err)
+	}
+	return seen, byKey, nil
+}
+
+// recreateTag tags the commit like the release was tagged: annotated with
+// the original tagger, date and message, or lightweight.
+func recreateTag(repo *git.Repository, release historyRelease, commit plumbing.Hash) error {
+	var opts *git.CreateTagOptions
+	if release.Message != "" {
+		name, email, _ := strings.Cut(release.Tagger, " <")
+		opts = &git.CreateTagOptions{
+			Tagger:  &object.Signature{Name: name, Email: strings.TrimSuffix(email, ">"), When: release.Date},
+			Message: release.Message,
+		}
+	}
+	_, err := repo.CreateTag(release.Tag, commit, opts)
+	if err != nil {
+		return fmt.Errorf("failed to create tag %s: %w", release.Tag, err)
+	}
+	return nil
+}
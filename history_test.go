@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// rewriteHistory drops README.md from every commit of master, like a
+// filter-repo run, and deletes the tags. It returns the old and new ids.
+func rewriteHistory(t *testing.T, repo *git.Repository) map[plumbing.Hash]plumbing.Hash {
+	t.Helper()
+	iter, err := repo.Log(&git.LogOptions{From: mustHead(t, repo)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var commits []*object.Commit
+	err = iter.ForEach(func(c *object.Commit) error {
+		commits = append([]*object.Commit{c}, commits...)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rewritten := make(map[plumbing.Hash]plumbing.Hash)
+	var last plumbing.Hash
+	for _, c := range commits {
+		tree, err := c.Tree()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var entries []object.TreeEntry
+		for _, e := range tree.Entries {
+			if e.Name != "README.md" {
+				entries = append(entries, e)
+			}
+		}
+		treeHash, err := storeObject(repo, plumbing.TreeObject, (&object.Tree{Entries: entries}).Encode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		commit := &object.Commit{Author: c.Author, Committer: c.Committer, Message: c.Message, TreeHash: treeHash}
+		for _, parent := range c.ParentHashes {
+			commit.ParentHashes = append(commit.ParentHashes, rewritten[parent])
+		}
+		last, err = storeObject(repo, plumbing.CommitObject, commit.Encode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rewritten[c.Hash] = last
+	}
+	err = repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("master"), last))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags, err := repo.Tags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tags.ForEach(func(ref *plumbing.Reference) error {
+		return repo.Storer.RemoveReference(ref.Name())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rewritten
+}
+
+func exportTestHistory(t *testing.T) string {
+	t.Helper()
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"export-history"}, nil)
+	if err != nil {
+		t.Fatalf("export-history: %v", err)
+	}
+	err = os.WriteFile("history.json", output.Bytes(), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return output.String()
+}
+
+func TestExportImportHistory(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-minor"}, nil)
+	if err != nil {
+		t.Fatalf("bump: %v", err)
+	}
+	released := mustHead(t, repo)
+
+	exported := exportTestHistory(t)
+	var history releaseHistory
+	err = json.Unmarshal([]byte(exported), &history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.Releases) != 2 || history.Releases[0].Tag != "v1.0.0" || history.Releases[1].Tag != "v1.1.0" {
+		t.Fatalf("Expected v1.0.0 and v1.1.0 in version order, got %+v", history.Releases)
+	}
+	if history.Releases[0].Message != "" || history.Releases[1].Subject != "bump version to v1.1.0" {
+		t.Errorf("Unexpected release details: %+v", history.Releases)
+	}
+
+	rewritten := rewriteHistory(t, repo)
+	output.Reset()
+	err = run(context.Background(), &output, []string{"import-history", "history.json"}, nil)
+	if err != nil {
+		t.Fatalf("import-history: %v\nOutput: %s", err, output.String())
+	}
+	if !strings.Contains(output.String(), "Created 2 tag(s)") {
+		t.Errorf("Unexpected output: %s", output.String())
+	}
+	commit, err := tagCommit(repo, "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.Hash != rewritten[released] {
+		t.Errorf("Expected v1.1.0 on the rewritten release commit %s, got %s", rewritten[released], commit.Hash)
+	}
+	ref, err := repo.Tag("v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag, err := repo.TagObject(ref.Hash())
+	if err != nil {
+		t.Fatal("Expected v1.1.0 to be recreated as an annotated tag")
+	}
+	if tag.Message != history.Releases[1].Message || !tag.Tagger.When.Equal(history.Releases[1].Date) {
+		t.Errorf("Expected the original annotation and date, got %q at %s", tag.Message, tag.Tagger.When)
+	}
+
+	// importing again changes nothing
+	output.Reset()
+	err = run(context.Background(), &output, []string{"import-history", "history.json"}, nil)
+	if err != nil || !strings.Contains(output.String(), "Created 0 tag(s)") {
+		t.Errorf("Expected a repeated import to be a no-op, got %v: %s", err, output.String())
+	}
+}
+
+func TestImportHistoryWithCommitMap(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	tagged, err := tagCommit(repo, "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exportTestHistory(t)
+	rewritten := rewriteHistory(t, repo)
+
+	// a history entry whose commit can't be matched by its metadata
+	content, err := os.ReadFile("history.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile("history.json", bytes.ReplaceAll(content, []byte("Add initial version file"), []byte("reworded")), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"import-history", "history.json"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "no commit found for 1 release(s), nothing was tagged") {
+		t.Fatalf("Expected the unplaced release to abort, got: %v", err)
+	}
+
+	commitMap := fmt.Sprintf("old                                      new\n%s %s\n", tagged.Hash, rewritten[tagged.Hash])
+	err = os.WriteFile("commit-map", []byte(commitMap), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = run(context.Background(), &output, []string{"import-history", "-map", "commit-map", "history.json"}, nil)
+	if err != nil {
+		t.Fatalf("import-history: %v", err)
+	}
+	commit, err := tagCommit(repo, "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.Hash != rewritten[tagged.Hash] {
+		t.Errorf("Expected v1.0.0 on the mapped commit, got %s", commit.Hash)
+	}
+}
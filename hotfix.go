@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/mod/semver"
+)
+
+// hotfixPrefix starts the prerelease of hotfix versions such as
+// v1.4.2-hotfix.20240610. As a prerelease of the next patch, a hotfix sorts
+// above the release it fixes and below the next real patch, so it doesn't
+// consume the patch number.
+const hotfixPrefix = "-hotfix."
+
+// hotfixBase returns the release a hotfix version precedes, e.g. v1.4.2 for
+// v1.4.2-hotfix.20240610. It returns false for other versions.
+func hotfixBase(version string) (string, bool) {
+	prerelease := semver.Prerelease(normalizeVersion(version))
+	if !strings.HasPrefix(prerelease, hotfixPrefix) {
+		return version, false
+	}
+	return strings.TrimSuffix(version, prerelease), true
+}
+
+// nextHotfix determines the current and the hotfix version. Hotfixes are cut
+// from maintenance branches, so the current version is the highest version
+// tag reachable from HEAD rather than the highest in the repository. A second
+// hotfix on the same day gets a counter: v1.4.2-hotfix.20240610.2.
+func nextHotfix(repo vcs, runConfig config) (string, string, error) {
+	gitRepo, err := gitRepository(repo, "-hotfix")
+	if err != nil {
+		return "", "", err
+	}
+	currentVersion, err := lastReachableTag(gitRepo, keepTags(repo))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get last tag: %w", err)
+	}
+	err = checkNotEmpty(repo, runConfig, currentVersion)
+	if err != nil {
+		return "", "", err
+	}
+
+	// the next patch, or the release the current hotfix precedes
+	base, err := incrementVersion(currentVersion, config{action: incrementPatch})
+	if err != nil {
+		return "", "", fmt.Errorf("incrementVersion: %w", err)
+	}
+	stamp := base + hotfixPrefix + runConfig.now().Format("20060102")
+	newVersion := stamp
+	for n := 2; ; n++ {
+		exists, err := repo.tagExists(newVersion)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to check if tag exists: %w", err)
+		}
+		if !exists {
+			return currentVersion, newVersion, nil
+		}
+		newVersion = fmt.Sprintf("%s.%d", stamp, n)
+	}
+}
+
+// lastReachableTag returns the highest version tag on a commit that is an
+// ancestor of HEAD, considering only the tags keep accepts.
+func lastReachableTag(repo *git.Repository, keep func(*plumbing.Reference) bool) (string, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	history := make(map[plumbing.Hash]bool)
+	iter, err := repo.Log(&git.LogOptions{From: head.Hash()})
+	if err != nil {
+		return "", fmt.Errorf("failed to walk history: %w", err)
+	}
+	err = iter.ForEach(func(c *object.Commit) error {
+		history[c.Hash] = true
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to walk history: %w", err)
+	}
+
+	var tagNames []string
+	err = forEachTag(repo, func(t *plumbing.Reference) error {
+		if !keep(t) {
+			return nil
+		}
+		// the ref is at hand, so peel it instead of looking the tag up by
+		// name, which would scan every tag again
+		commit, err := peelTag(repo, t.Hash())
+		if err == nil && history[commit.Hash] {
+			tagNames = append(tagNames, t.Name().Short())
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return highestVersion(tagNames)
+}
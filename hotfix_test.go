@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestHotfixBase(t *testing.T) {
+	tests := []struct {
+		version string
+		base    string
+		hotfix  bool
+	}{
+		{version: "v1.4.2-hotfix.20240610", base: "v1.4.2", hotfix: true},
+		{version: "1.4.2-hotfix.20240610.2", base: "1.4.2", hotfix: true},
+		{version: "v1.4.2-rc.1", base: "v1.4.2-rc.1"},
+		{version: "v1.4.2", base: "v1.4.2"},
+	}
+	for _, tt := range tests {
+		base, hotfix := hotfixBase(tt.version)
+		if base != tt.base || hotfix != tt.hotfix {
+			t.Errorf("hotfixBase(%q) = %q, %v, want %q, %v", tt.version, base, hotfix, tt.base, tt.hotfix)
+		}
+	}
+}
+
+func TestHotfixSortsBelowNextPatch(t *testing.T) {
+	got, err := highestVersion([]string{"v1.4.1", "v1.4.2-hotfix.20240610", "v1.4.2-hotfix.20240610.2"})
+	if err != nil || got != "v1.4.2-hotfix.20240610.2" {
+		t.Errorf("Expected the latest hotfix to be the highest version, got %q, %v", got, err)
+	}
+	got, err = highestVersion([]string{"v1.4.2-hotfix.20240610", "v1.4.2"})
+	if err != nil || got != "v1.4.2" {
+		t.Errorf("Expected the patch release to sort above its hotfix, got %q, %v", got, err)
+	}
+}
+
+func TestBumpHotfix(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.4.1")
+	setClock(t, "2024-06-10")
+
+	steps := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"-hotfix"}, want: "v1.4.2-hotfix.20240610"},
+		{args: []string{"-hotfix"}, want: "v1.4.2-hotfix.20240610.2"},
+		{args: []string{"-patch"}, want: "v1.4.2"},
+	}
+	for i, step := range steps {
+		if i > 0 {
+			commitFiles(t, repo, "Fix something", map[string]string{"fix.txt": step.want})
+		}
+		var output bytes.Buffer
+		err := run(context.Background(), &output, step.args, nil)
+		if err != nil {
+			t.Fatalf("step %d: expected bump to succeed, got: %v\nOutput: %s", i, err, output.String())
+		}
+		version, err := os.ReadFile(".version")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(version) != step.want {
+			t.Errorf("step %d: expected version %s, got %s", i, step.want, version)
+		}
+	}
+}
+
+func TestBumpHotfixOnMaintenanceBranch(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.4.1")
+	setClock(t, "2024-06-10")
+	maintenance, err := tagCommit(repo, "v1.4.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// main has moved on to v2
+	_, err = repo.CreateTag("v2.0.0", mustHead(t, repo), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Checkout(&git.CheckoutOptions{Hash: maintenance.Hash, Branch: plumbing.NewBranchReferenceName("release-1.4"), Create: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, repo, "Fix security issue", map[string]string{"fix.txt": "fixed"})
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-hotfix"}, nil)
+	if err != nil {
+		t.Fatalf("Expected hotfix to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	exists, err := tagExists(repo, "v1.4.2-hotfix.20240610")
+	if err != nil || !exists {
+		t.Errorf("Expected tag v1.4.2-hotfix.20240610, got %v, %v\nOutput: %s", exists, err, output.String())
+	}
+}
+
+func TestHotfixFlagConflicts(t *testing.T) {
+	for _, args := range [][]string{{"-hotfix", "-patch"}, {"-hotfix", "-version", "v1.0.0"}, {"-hotfix", "-auto-api"}} {
+		_, _, err := getConfig(args)
+		if err == nil {
+			t.Errorf("Expected %v to be rejected", args)
+		}
+	}
+}
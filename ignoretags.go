@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// tagKeeper is implemented by the wrappers that exclude tags from the
+// version tags, so the features finding tags on their own (-hotfix,
+// -tag-template) exclude the same ones.
+type tagKeeper interface {
+	keep(*plumbing.Reference) bool
+}
+
+// keepTags returns the tag filter of repo, which keeps every tag unless repo
+// is a tagKeeper.
+func keepTags(repo vcs) func(*plumbing.Reference) bool {
+	if keeper, ok := repo.(tagKeeper); ok {
+		return keeper.keep
+	}
+	return func(*plumbing.Reference) bool { return true }
+}
+
+// loadIgnoredTags reads the tag patterns of .bumpignoretags, one glob per
+// line matched against the tag name:
+//
+//	v9.9.9         # test tag pushed by accident
+//	v2.0.0-bad*    # broken release candidates
+func loadIgnoredTags(file string) ([]string, error) {
+	content, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns []string
+	for i, line := range strings.Split(string(content), "\n") {
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		pattern := strings.TrimSpace(line)
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("line %d: invalid pattern '%s'", i+1, pattern)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// ignoreTagsVCS never uses the ignored tags as the current version, so
+// known-bad or experimental tags can stay in the shared history.
+type ignoreTagsVCS struct {
+	vcs
+	repo     *git.Repository
+	patterns []string
+	// inner is the tag filter of the wrapped repository, e.g. -own-tags
+	inner func(*plumbing.Reference) bool
+}
+
+// withIgnoredTags wraps repo for the patterns of .bumpignoretags.
+func withIgnoredTags(repo vcs, patterns []string) (vcs, error) {
+	gitRepo, err := gitRepository(repo, ".bumpignoretags")
+	if err != nil {
+		return nil, err
+	}
+	return ignoreTagsVCS{vcs: repo, repo: gitRepo, patterns: patterns, inner: keepTags(repo)}, nil
+}
+
+func (i ignoreTagsVCS) goGit() *git.Repository {
+	return i.repo
+}
+
+// ignored reports whether a tag name matches one of the patterns.
+func (i ignoreTagsVCS) ignored(name string) bool {
+	for _, pattern := range i.patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (i ignoreTagsVCS) keep(ref *plumbing.Reference) bool {
+	return !i.ignored(ref.Name().Short()) && i.inner(ref)
+}
+
+// versionTags returns the version tags that aren't ignored.
+func (i ignoreTagsVCS) versionTags() ([]versionTag, error) {
+	tags, err := allVersionTags(i.repo)
+	if err != nil {
+		return nil, err
+	}
+	var kept []versionTag
+	for _, tag := range tags {
+		if i.keep(tag.ref) {
+			kept = append(kept, tag)
+		}
+	}
+	return kept, nil
+}
+
+// lastTag returns the highest version tag that isn't ignored.
+func (i ignoreTagsVCS) lastTag() (string, error) {
+	tags, err := i.versionTags()
+	if err != nil {
+		return "", err
+	}
+	return highestVersion(tagVersions(tags))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoadIgnoredTags(t *testing.T) {
+	file := t.TempDir() + "/.bumpignoretags"
+	err := os.WriteFile(file, []byte("# known bad\nv9.9.9\n\nv2.0.0-bad*   # broken candidates\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patterns, err := loadIgnoredTags(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(patterns, ",") != "v9.9.9,v2.0.0-bad*" {
+		t.Errorf("Got patterns %q", patterns)
+	}
+
+	err = os.WriteFile(file, []byte("v1.0.0\nv[1-\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = loadIgnoredTags(file)
+	if err == nil || !strings.Contains(err.Error(), "line 2: invalid pattern") {
+		t.Errorf("Expected an invalid pattern error, got: %v", err)
+	}
+}
+
+func TestBumpIgnoredTags(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	for _, tag := range []string{"v9.9.9", "v2.0.0-bad1", "v2.0.0-bad2"} {
+		_, err := repo.CreateTag(tag, mustHead(t, repo), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	commitFiles(t, repo, "Ignore bad tags", map[string]string{".bumpignoretags": "v9.9.9\nv2.0.0-bad*\n"})
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-minor"}, nil)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	if !strings.Contains(output.String(), "Bumped version v1.0.0 --> v1.1.0") {
+		t.Errorf("Expected the ignored tags to be skipped, got:\n%s", output.String())
+	}
+	if exists, _ := tagExists(repo, "v9.9.9"); !exists {
+		t.Error("Expected the ignored tag to stay")
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// manifestMediaTypes are the manifest formats accepted from a registry. Index
+// formats come first, so a multi-arch image is pinned as a whole.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// imageRef is a container image reference split into its parts.
+type imageRef struct {
+	name       string // the reference without tag or digest, as given
+	registry   string // host of the registry API
+	repository string
+	reference  string // tag or digest
+}
+
+// pinnedImage is a pushed image and the digest its tag resolved to.
+type pinnedImage struct {
+	ref    string // the reference as given, e.g. ghcr.io/shop/app:v1.5.0
+	pinned string // name@sha256:...
+}
+
+// parseImageRef splits a reference like ghcr.io/shop/app:v1.5.0. Like
+// docker, a first component without a dot or port is a Docker Hub
+// repository, and single-component names are official images.
+func parseImageRef(ref string) (imageRef, error) {
+	name, reference := ref, "latest"
+	if at := strings.Index(ref, "@"); at >= 0 {
+		name, reference = ref[:at], ref[at+1:]
+	} else if colon := strings.LastIndex(ref, ":"); colon > strings.LastIndex(ref, "/") {
+		name, reference = ref[:colon], ref[colon+1:]
+	}
+	if name == "" || reference == "" || strings.ContainsAny(ref, " \t") {
+		return imageRef{}, fmt.Errorf("invalid image reference '%s'", ref)
+	}
+	r := imageRef{name: name, registry: "registry-1.docker.io", repository: name, reference: reference}
+	first, rest, ok := strings.Cut(name, "/")
+	if ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		r.registry, r.repository = first, rest
+	} else if !ok {
+		r.repository = "library/" + name
+	}
+	return r, nil
+}
+
+// resolveImages resolves the digests of the -image references. It runs
+// before anything is written, so an image that wasn't pushed aborts the
+// bump.
+func resolveImages(ctx context.Context, cfg config, env []string) ([]pinnedImage, error) {
+	var images []pinnedImage
+	for _, ref := range cfg.images {
+		r, err := parseImageRef(ref)
+		if err != nil {
+			return nil, err
+		}
+		digest, err := registryClient{env: env}.digest(ctx, r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve the digest of %s: %w", ref, err)
+		}
+		images = append(images, pinnedImage{ref: ref, pinned: r.name + "@" + digest})
+	}
+	return images, nil
+}
+
+// imageManifest renders the deployment manifest of the pinned images: the
+// reference each was pushed as and the immutable reference to deploy.
+func imageManifest(images []pinnedImage) []byte {
+	var b strings.Builder
+	for _, image := range images {
+		_, _ = fmt.Fprintf(&b, "%s %s\n", image.ref, image.pinned)
+	}
+	return []byte(b.String())
+}
+
+// writeImageManifest commits the pinned images to -image-manifest.
+func writeImageManifest(repo vcs, cfg config, output io.Writer, images []pinnedImage) error {
+	if cfg.imageManifest == "" || len(images) == 0 {
+		return nil
+	}
+	file := repoPath(cfg.imageManifest)
+	content := imageManifest(images)
+	_, _ = fmt.Fprintf(output, "Pinning %d image(s) in %s\n", len(images), file)
+	if cfg.dryRun {
+		return previewFile(output, file, content)
+	}
+	return writeReleaseFile(repo, file, content)
+}
+
+// registryClient talks to the registry API (the OCI distribution spec). It
+// authenticates with BUMP_REGISTRY_USER and BUMP_REGISTRY_PASSWORD if set,
+// exchanging them for a bearer token where the registry asks for one.
+type registryClient struct {
+	env []string
+}
+
+// digest returns the digest of the manifest the reference points to.
+func (c registryClient) digest(ctx context.Context, r imageRef) (string, error) {
+	if strings.HasPrefix(r.reference, "sha256:") {
+		return r.reference, nil
+	}
+	scheme := "https"
+	if host, _, _ := strings.Cut(r.registry, ":"); host == "localhost" || host == "127.0.0.1" {
+		// like docker, local registries are spoken to in plain HTTP
+		scheme = "http"
+	}
+	endpoint := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", scheme, r.registry, r.repository, r.reference)
+
+	resp, err := c.get(ctx, endpoint, "")
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		_ = resp.Body.Close()
+		auth, err := c.authorize(ctx, challenge, r.repository)
+		if err != nil {
+			return "", err
+		}
+		resp, err = c.get(ctx, endpoint, auth)
+		if err != nil {
+			return "", err
+		}
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry answered %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read manifest: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	if header := resp.Header.Get("Docker-Content-Digest"); header != "" && header != digest {
+		return "", fmt.Errorf("registry reports digest %s but the manifest hashes to %s", header, digest)
+	}
+	return digest, nil
+}
+
+func (c registryClient) get(ctx context.Context, endpoint, auth string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// authorize answers a WWW-Authenticate challenge with an Authorization
+// header: basic credentials, or a bearer token pulled from the token service.
+func (c registryClient) authorize(ctx context.Context, challenge, repository string) (string, error) {
+	user, password := getenv(c.env, "BUMP_REGISTRY_USER"), getenv(c.env, "BUMP_REGISTRY_PASSWORD")
+	scheme, params, _ := strings.Cut(challenge, " ")
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if user == "" {
+			return "", errors.New("registry needs credentials: set BUMP_REGISTRY_USER and BUMP_REGISTRY_PASSWORD")
+		}
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(user, password)
+		return req.Header.Get("Authorization"), nil
+	case "bearer":
+	default:
+		return "", fmt.Errorf("unsupported registry authentication '%s'", challenge)
+	}
+
+	fields := challengeParams(params)
+	if fields["realm"] == "" {
+		return "", fmt.Errorf("registry challenge without a realm: '%s'", challenge)
+	}
+	query := url.Values{}
+	if fields["service"] != "" {
+		query.Set("service", fields["service"])
+	}
+	scope := fields["scope"]
+	if scope == "" {
+		scope = "repository:" + repository + ":pull"
+	}
+	query.Set("scope", scope)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fields["realm"]+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	if user != "" {
+		req.SetBasicAuth(user, password)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token service answered %s", resp.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return "", fmt.Errorf("invalid token response: %w", err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if token.Token == "" {
+		return "", errors.New("token service returned no token")
+	}
+	return "Bearer " + token.Token, nil
+}
+
+// challengeParams parses the key="value" parameters of a WWW-Authenticate
+// challenge.
+func challengeParams(params string) map[string]string {
+	fields := make(map[string]string)
+	for params != "" {
+		var key, value string
+		key, params, _ = strings.Cut(strings.TrimLeft(params, ", "), "=")
+		if strings.HasPrefix(params, `"`) {
+			value, params, _ = strings.Cut(params[1:], `"`)
+		} else {
+			value, params, _ = strings.Cut(params, ",")
+		}
+		fields[strings.TrimSpace(key)] = value
+	}
+	return fields
+}
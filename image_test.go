@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseImageRef(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want imageRef
+	}{
+		{"nginx", imageRef{name: "nginx", registry: "registry-1.docker.io", repository: "library/nginx", reference: "latest"}},
+		{"shop/app:v1.5.0", imageRef{name: "shop/app", registry: "registry-1.docker.io", repository: "shop/app", reference: "v1.5.0"}},
+		{"ghcr.io/shop/app:v1.5.0", imageRef{name: "ghcr.io/shop/app", registry: "ghcr.io", repository: "shop/app", reference: "v1.5.0"}},
+		{"localhost:5000/app", imageRef{name: "localhost:5000/app", registry: "localhost:5000", repository: "app", reference: "latest"}},
+		{"ghcr.io/app@sha256:abc", imageRef{name: "ghcr.io/app", registry: "ghcr.io", repository: "app", reference: "sha256:abc"}},
+	}
+	for _, tt := range tests {
+		got, err := parseImageRef(tt.ref)
+		if err != nil || got != tt.want {
+			t.Errorf("parseImageRef(%s) = %+v, %v, want %+v", tt.ref, got, err, tt.want)
+		}
+	}
+	for _, ref := range []string{":v1", "app:", "app@", "my app"} {
+		if _, err := parseImageRef(ref); err == nil {
+			t.Errorf("Expected %q to be rejected", ref)
+		}
+	}
+}
+
+// testRegistry serves one manifest for shop/app:v1.1.0 behind a bearer token
+// challenge, as Docker Hub and ghcr.io do.
+func testRegistry(t *testing.T) (host string, digest string) {
+	t.Helper()
+	manifest := []byte(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[]}`)
+	sum := sha256.Sum256(manifest)
+	digest = "sha256:" + hex.EncodeToString(sum[:])
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if r.URL.Query().Get("scope") != "repository:shop/app:pull" {
+				http.Error(w, "bad scope", http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"token":"registry-token"}`))
+		case "/v2/shop/app/manifests/v1.1.0":
+			if r.Header.Get("Authorization") != "Bearer registry-token" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="test"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if !strings.Contains(r.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json") {
+				http.Error(w, "unsupported media type", http.StatusNotAcceptable)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", digest)
+			_, _ = w.Write(manifest)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://"), digest
+}
+
+func TestBumpPinsImages(t *testing.T) {
+	host, digest := testRegistry(t)
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+
+	image := host + "/shop/app:v1.1.0"
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-minor", "-image", image, "-image-manifest", "deploy/images.txt"}, nil)
+	if err != nil {
+		t.Fatalf("bump: %v\nOutput: %s", err, output.String())
+	}
+	pinned := host + "/shop/app@" + digest
+	if got := readFile(t, "deploy/images.txt"); got != image+" "+pinned+"\n" {
+		t.Errorf("Unexpected image manifest %q", got)
+	}
+	ref, err := repo.Tag("v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag, err := repo.TagObject(ref.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(tag.Message, "\nImage: "+pinned+"\n") {
+		t.Errorf("Expected the tag to pin the image, got:\n%s", tag.Message)
+	}
+	commit, err := tagCommit(repo, "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := commit.File("deploy/images.txt"); err != nil {
+		t.Errorf("Expected the image manifest in the release commit: %v", err)
+	}
+
+	// an image that wasn't pushed aborts the bump before anything is written
+	commits := countCommits(t, repo)
+	err = run(context.Background(), &output, []string{"-minor", "-force", "-image", host + "/shop/app:v1.2.0"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "failed to resolve the digest of "+host+"/shop/app:v1.2.0: registry answered 404") {
+		t.Errorf("Expected an unresolved image to fail, got: %v", err)
+	}
+	if countCommits(t, repo) != commits {
+		t.Error("A failed bump committed")
+	}
+}
+
+func TestImageManifestNeedsImages(t *testing.T) {
+	_, _, err := getConfig([]string{"-image-manifest", "deploy/images.txt"})
+	if err == nil {
+		t.Error("Expected -image-manifest without -image to fail")
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/mod/semver"
+)
+
+// Pre-1.0 policies (-pre1-major) decide what -major does before v1.0.0.
+// Semantic versioning allows breaking changes in minor releases of v0, so
+// some projects stay below v1 until they declare it explicitly.
+const (
+	pre1Major = "major" // -major releases v1.0.0
+	pre1Minor = "minor" // -major bumps the minor version; v1.0.0 takes -version
+)
+
+// checkVersionConventions validates the initial version and the pre-1.0
+// policy, which may come from the environment.
+func checkVersionConventions(cfg config) error {
+	if cfg.initialVersion != "" && !semver.IsValid(normalizeVersion(cfg.initialVersion)) {
+		return fmt.Errorf("invalid initial version '%s'", cfg.initialVersion)
+	}
+	switch cfg.pre1Major {
+	case "", pre1Major, pre1Minor:
+		return nil
+	}
+	return fmt.Errorf("invalid -pre1-major '%s': must be %s or %s", cfg.pre1Major, pre1Major, pre1Minor)
+}
+
+// effectiveAction returns the increment to apply to a version, after the
+// pre-1.0 policy.
+func effectiveAction(cfg config, major int) action {
+	if cfg.action == incrementMajor && major == 0 && cfg.pre1Major == pre1Minor {
+		return incrementMinor
+	}
+	return cfg.action
+}
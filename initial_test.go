@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBumpInitialVersion(t *testing.T) {
+	tempDir, repo := setupTestRepo(t)
+	t.Chdir(tempDir)
+	commitFiles(t, repo, "Add version file", map[string]string{".version": ""})
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "failed to get last tag") {
+		t.Errorf("Expected a repository without tags to fail, got: %v", err)
+	}
+
+	err = run(context.Background(), &output, nil, []string{"BUMP_INITIAL_VERSION=v0.1.0"})
+	if err != nil {
+		t.Fatalf("Expected the initial release to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	if !strings.Contains(output.String(), "Set version v0.1.0, tag=") {
+		t.Errorf("Expected the initial version to be set, got:\n%s", output.String())
+	}
+	if exists, _ := tagExists(repo, "v0.1.0"); !exists {
+		t.Error("Expected tag v0.1.0")
+	}
+
+	// with a tag the initial version no longer applies
+	err = run(context.Background(), &output, []string{"-force", "-initial-version", "v1.0.0"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, err := os.ReadFile(".version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(version)) != "v0.1.1" {
+		t.Errorf("Expected v0.1.1 after the initial release, got %s", version)
+	}
+
+	err = run(context.Background(), &output, []string{"-initial-version", "one"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid initial version 'one'") {
+		t.Errorf("Expected an invalid initial version to be refused, got: %v", err)
+	}
+}
+
+// brokenTagsVCS fails to list its tags.
+type brokenTagsVCS struct {
+	*fakeVCS
+}
+
+func (brokenTagsVCS) lastTag() (string, error) {
+	return "", errors.New("corrupt packfile")
+}
+
+func TestFirstReleaseNeedsNoTags(t *testing.T) {
+	for _, cfg := range []config{{version: "v1.0.0"}, {action: incrementPatch, initialVersion: "v0.1.0"}} {
+		_, version, err := nextVersion(&fakeVCS{tags: map[string]string{}}, cfg)
+		if err != nil || version != cfg.version+cfg.initialVersion {
+			t.Errorf("nextVersion(%+v) without tags = %s, %v, want the first release", cfg, version, err)
+		}
+		_, _, err = nextVersion(brokenTagsVCS{&fakeVCS{}}, cfg)
+		if err == nil || !strings.Contains(err.Error(), "corrupt packfile") {
+			t.Errorf("nextVersion(%+v) with broken tags = %v, want the error", cfg, err)
+		}
+	}
+}
+
+func TestPre1Major(t *testing.T) {
+	tests := []struct {
+		current string
+		policy  string
+		want    string
+	}{
+		{current: "v0.3.2", policy: "", want: "v1.0.0"},
+		{current: "v0.3.2", policy: pre1Major, want: "v1.0.0"},
+		{current: "v0.3.2", policy: pre1Minor, want: "v0.4.0"},
+		{current: "v1.3.2", policy: pre1Minor, want: "v2.0.0"},
+	}
+	for _, tt := range tests {
+		got, err := incrementVersion(tt.current, config{action: incrementMajor, pre1Major: tt.policy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("-major of %s with policy %q = %s, want %s", tt.current, tt.policy, got, tt.want)
+		}
+	}
+
+	_, _ = setupTaggedTestRepo(t, "v0.3.0")
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-major"}, []string{"BUMP_PRE1_MAJOR=minor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output.String(), "Bumped version v0.3.0 --> v0.4.0") {
+		t.Errorf("Expected BUMP_PRE1_MAJOR to keep -major below v1, got:\n%s", output.String())
+	}
+	err = run(context.Background(), &output, []string{"-pre1-major", "patch"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid -pre1-major 'patch'") {
+		t.Errorf("Expected an invalid policy to be refused, got: %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"debug/buildinfo"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// binaryReport is what inspect-binary found in a binary and how it compares
+// to the release.
+type binaryReport struct {
+	Binary        string   `json:"binary"`
+	Module        string   `json:"module"`
+	ModuleVersion string   `json:"module_version"`
+	Revision      string   `json:"revision,omitempty"`
+	Modified      bool     `json:"modified"`
+	Version       string   `json:"version"`
+	LatestTag     string   `json:"latest_tag,omitempty"`
+	TagCommit     string   `json:"tag_commit,omitempty"`
+	Problems      []string `json:"problems"`
+}
+
+// runInspectBinary implements "bump inspect-binary": it reads the Go build
+// information of a compiled binary and checks that it was built from the
+// current release, catching binaries built before the bump.
+func runInspectBinary(_ context.Context, output io.Writer, args []string, _ []string) error {
+	flagSet := flag.NewFlagSet("inspect-binary", flag.ContinueOnError)
+	dir := flagSet.String("module", ".", "Directory of the module whose .version the binary should report.")
+	format := flagSet.String("format", "text", "Output format: text or json.")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if flagSet.NArg() != 1 {
+		return errors.New("usage: bump inspect-binary [-module dir] [-format text|json] <binary>")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("invalid format '%s': must be text or json", *format)
+	}
+
+	binary := flagSet.Arg(0)
+	info, err := buildinfo.ReadFile(binary)
+	if err != nil {
+		return fmt.Errorf("failed to read build information of %s: %w", binary, err)
+	}
+	versionFile := path.Join(repoPath(*dir), ".version")
+	content, err := os.ReadFile(filepath.FromSlash(versionFile))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", versionFile, err)
+	}
+	report := binaryReport{Binary: binary, Version: parseVersionFile(content)}
+
+	repo, err := git.PlainOpen(".")
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	report.LatestTag, err = lastTag(repo)
+	if err == nil {
+		commit, err := tagCommit(repo, report.LatestTag)
+		if err != nil {
+			return err
+		}
+		report.TagCommit = commit.Hash.String()
+	}
+	inspectBinary(&report, info)
+
+	if *format == "json" {
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+		if err != nil {
+			return err
+		}
+	} else {
+		printBinaryReport(output, report)
+	}
+	if len(report.Problems) > 0 {
+		return fmt.Errorf("%s was not built from release %s: %d problem(s)", binary, report.Version, len(report.Problems))
+	}
+	return nil
+}
+
+// inspectBinary fills in the build information and checks it against the
+// .version file and the latest tag.
+func inspectBinary(report *binaryReport, info *debug.BuildInfo) {
+	report.Problems = []string{}
+	report.Module, report.ModuleVersion = info.Main.Path, info.Main.Version
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			report.Revision = setting.Value
+		case "vcs.modified":
+			report.Modified = setting.Value == "true"
+		}
+	}
+
+	switch {
+	case report.ModuleVersion == "" || report.ModuleVersion == "(devel)":
+		report.Problems = append(report.Problems, "no module version stamped in the binary")
+	case normalizeVersion(strings.TrimSuffix(report.ModuleVersion, "+dirty")) != normalizeVersion(report.Version):
+		report.Problems = append(report.Problems, fmt.Sprintf("binary reports %s but .version is %s", report.ModuleVersion, report.Version))
+	}
+	switch {
+	case report.Revision == "":
+		report.Problems = append(report.Problems, "no vcs.revision stamped in the binary")
+	case report.TagCommit == "":
+		report.Problems = append(report.Problems, "no version tag to compare the revision with")
+	case report.Revision != report.TagCommit:
+		report.Problems = append(report.Problems, fmt.Sprintf("built from %s but %s is at %s",
+			shortHash(report.Revision), report.LatestTag, shortHash(report.TagCommit)))
+	}
+	if report.Modified {
+		report.Problems = append(report.Problems, "built from a modified worktree")
+	}
+}
+
+func printBinaryReport(output io.Writer, report binaryReport) {
+	_, _ = fmt.Fprintf(output, "%s: %s %s", report.Binary, report.Module, report.ModuleVersion)
+	if report.Revision != "" {
+		_, _ = fmt.Fprintf(output, " (%s)", shortHash(report.Revision))
+	}
+	_, _ = fmt.Fprintln(output)
+	for _, problem := range report.Problems {
+		_, _ = fmt.Fprintf(output, "warning: %s\n", problem)
+	}
+	if len(report.Problems) == 0 {
+		_, _ = fmt.Fprintf(output, "Built from release %s\n", report.LatestTag)
+	}
+}
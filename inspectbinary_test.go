@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestInspectBinary(t *testing.T) {
+	release := "0123456789abcdef0123456789abcdef01234567"
+	tests := []struct {
+		name     string
+		version  string
+		settings []debug.BuildSetting
+		want     []string
+	}{
+		{
+			name:     "release build",
+			version:  "v1.2.0",
+			settings: []debug.BuildSetting{{Key: "vcs.revision", Value: release}, {Key: "vcs.modified", Value: "false"}},
+		},
+		{
+			name:     "built before the bump",
+			version:  "v1.1.1-0.20240501120000-fedcba987654",
+			settings: []debug.BuildSetting{{Key: "vcs.revision", Value: "fedcba9876543210fedcba9876543210fedcba98"}},
+			want:     []string{"binary reports v1.1.1-0.20240501120000-fedcba987654 but .version is v1.2.0", "built from fedcba9 but v1.2.0 is at 0123456"},
+		},
+		{
+			name:     "dirty",
+			version:  "v1.2.0+dirty",
+			settings: []debug.BuildSetting{{Key: "vcs.revision", Value: release}, {Key: "vcs.modified", Value: "true"}},
+			want:     []string{"built from a modified worktree"},
+		},
+		{
+			name:    "unstamped",
+			version: "(devel)",
+			want:    []string{"no module version stamped in the binary", "no vcs.revision stamped in the binary"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := binaryReport{Version: "v1.2.0", LatestTag: "v1.2.0", TagCommit: release}
+			info := &debug.BuildInfo{Main: debug.Module{Path: "example.com/app", Version: tt.version}, Settings: tt.settings}
+			inspectBinary(&report, info)
+			if strings.Join(report.Problems, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Got problems %q, want %q", report.Problems, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunInspectBinary(t *testing.T) {
+	// the test binary itself is a Go binary without a stamped version
+	binary, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	setupTaggedTestRepo(t, "v1.0.0")
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"inspect-binary", binary}, nil)
+	if err == nil || !strings.Contains(err.Error(), "was not built from release v1.0.0") {
+		t.Fatalf("Expected the test binary to fail the check, got: %v\nOutput: %s", err, output.String())
+	}
+	if !strings.Contains(output.String(), "warning: no module version stamped in the binary") {
+		t.Errorf("Expected the missing version to be reported, got:\n%s", output.String())
+	}
+
+	err = run(context.Background(), &output, []string{"inspect-binary", "missing-binary"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "failed to read build information") {
+		t.Errorf("Expected a missing binary to fail, got: %v", err)
+	}
+}
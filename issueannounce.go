@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+const defaultIssueMessage = `{{.Project}} {{.Version}} is out.
+{{if .Notes}}
+{{.Notes}}{{else if .Changes}}
+Changes since {{.Previous}}:
+{{range .Changes}}
+- {{.}}{{end}}{{end}}`
+
+// issueAnnouncer announces a release on GitHub, as a new issue titled
+// "<version> released", optionally pinned, or as a comment on an existing
+// issue such as a pinned releases thread.
+type issueAnnouncer struct {
+	gh       githubClient
+	project  string
+	issue    int // the issue to comment on, 0 for a new issue per release
+	pin      bool
+	labels   []string
+	template string
+}
+
+// githubIssueBackend is configured by BUMP_GITHUB_ISSUE: "new" for an issue
+// per release, or the number of the issue to comment on. The repository is
+// BUMP_GITHUB_REPO or the push remote's, the token BUMP_GITHUB_TOKEN.
+func githubIssueBackend(cfg config, env []string) (announcer, error) {
+	target := getenv(env, "BUMP_GITHUB_ISSUE")
+	if target == "" {
+		return nil, nil
+	}
+	a := &issueAnnouncer{pin: getenv(env, "BUMP_GITHUB_ISSUE_PIN") == "true"}
+	if target != "new" {
+		var err error
+		a.issue, err = strconv.Atoi(strings.TrimPrefix(target, "#"))
+		if err != nil || a.issue <= 0 {
+			return nil, fmt.Errorf("github announcer: invalid BUMP_GITHUB_ISSUE '%s': must be new or an issue number", target)
+		}
+		if a.pin {
+			return nil, fmt.Errorf("github announcer: BUMP_GITHUB_ISSUE_PIN pins new issues, pin issue #%d on GitHub instead", a.issue)
+		}
+	}
+	for _, label := range strings.Split(getenv(env, "BUMP_GITHUB_ISSUE_LABELS"), ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			a.labels = append(a.labels, label)
+		}
+	}
+	var err error
+	a.template, err = notificationTemplate(env, "github")
+	if err != nil {
+		return nil, fmt.Errorf("github announcer: %w", err)
+	}
+	if a.template == defaultNotificationMessage {
+		a.template = defaultIssueMessage
+	}
+	repo, err := git.PlainOpen(".")
+	if err != nil {
+		return nil, fmt.Errorf("github announcer: failed to open repository: %w", err)
+	}
+	r, err := resolveRemotes(repo, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("github announcer: %w", err)
+	}
+	a.project, err = githubRepository(repo, r.push, env)
+	if err != nil {
+		return nil, fmt.Errorf("github announcer: %w", err)
+	}
+	a.gh, err = newGitHubClient(env, io.Discard)
+	if err != nil {
+		return nil, fmt.Errorf("github announcer: %w", err)
+	}
+	return a, nil
+}
+
+func (a *issueAnnouncer) name() string {
+	return "github"
+}
+
+func (a *issueAnnouncer) announce(ctx context.Context, rel release) error {
+	text, err := renderTemplate("github", a.template, rel)
+	if err != nil {
+		return err
+	}
+	body := strings.TrimSpace(text)
+	if a.issue != 0 {
+		return a.gh.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/issues/%d/comments", a.project, a.issue), map[string]string{"body": body}, nil)
+	}
+	request := map[string]any{"title": rel.Version + " released", "body": body}
+	if len(a.labels) > 0 {
+		request["labels"] = a.labels
+	}
+	var issue struct {
+		NodeID string `json:"node_id"`
+	}
+	err = a.gh.do(ctx, http.MethodPost, "/repos/"+a.project+"/issues", request, &issue)
+	if err != nil {
+		return err
+	}
+	if !a.pin {
+		return nil
+	}
+	// pinning is only in the GraphQL API
+	query, err := json.Marshal(map[string]any{
+		"query":     "mutation($id: ID!) { pinIssue(input: {issueId: $id}) { issue { number } } }",
+		"variables": map[string]string{"id": issue.NodeID},
+	})
+	if err != nil {
+		return err
+	}
+	content, _, err := a.gh.send(ctx, http.MethodPost, graphQLEndpoint(a.gh.api), "application/json", query)
+	if err != nil {
+		return fmt.Errorf("failed to pin the issue: %w", err)
+	}
+	var result struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(content, &result) == nil && len(result.Errors) > 0 {
+		return fmt.Errorf("failed to pin the issue: %s", result.Errors[0].Message)
+	}
+	return nil
+}
+
+// graphQLEndpoint returns the GraphQL endpoint next to a REST API base:
+// api.github.com/graphql, or /api/graphql on GitHub Enterprise Server.
+func graphQLEndpoint(api string) string {
+	if base, ok := strings.CutSuffix(api, "/api/v3"); ok {
+		return base + "/api/graphql"
+	}
+	return api + "/graphql"
+}
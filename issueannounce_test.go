@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	gitconfig "github.com/go-git/go-git/v5/config"
+)
+
+func TestBumpAnnouncesOnGitHubIssue(t *testing.T) {
+	var issues, comments, queries []map[string]any
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		switch r.Method + " " + r.URL.Path {
+		case "POST /repos/acme/app/issues":
+			issues = append(issues, body)
+			_, _ = w.Write([]byte(`{"number":12,"node_id":"I_12"}`))
+		case "POST /repos/acme/app/issues/3/comments":
+			comments = append(comments, body)
+			_, _ = w.Write([]byte(`{}`))
+		case "POST /graphql":
+			queries = append(queries, body)
+			_, _ = w.Write([]byte(`{"data":{"pinIssue":{"issue":{"number":12}}}}`))
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(api.Close)
+	env := []string{"BUMP_GITHUB_TOKEN=gh-token", "BUMP_GITHUB_API=" + api.URL, "BUMP_GITHUB_REPO=acme/app", "BUMP_PROJECT=app",
+		"BUMP_GITHUB_ISSUE=new", "BUMP_GITHUB_ISSUE_PIN=true", "BUMP_GITHUB_ISSUE_LABELS=release, announcement"}
+
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-announce"}, env)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	if len(issues) != 1 || issues[0]["title"] != "v1.0.1 released" || !strings.Contains(issues[0]["body"].(string), "app v1.0.1 is out.\n\nChanges since v1.0.0:\n\n- Add new feature") {
+		t.Fatalf("Expected an issue announcing v1.0.1, got %v", issues)
+	}
+	if labels := issues[0]["labels"].([]any); len(labels) != 2 || labels[1] != "announcement" {
+		t.Errorf("Expected the labels on the issue, got %v", labels)
+	}
+	if len(queries) != 1 || queries[0]["variables"].(map[string]any)["id"] != "I_12" {
+		t.Errorf("Expected the issue to be pinned, got %v", queries)
+	}
+	if !strings.Contains(output.String(), "Announced v1.0.1 via github") {
+		t.Errorf("Expected the announcement in the output, got:\n%s", output.String())
+	}
+
+	commitFiles(t, repo, "Fix crash", map[string]string{"main.go": "package main\n"})
+	env = append(env, "BUMP_GITHUB_ISSUE=#3", "BUMP_GITHUB_ISSUE_PIN=")
+	err = run(context.Background(), &output, []string{"-announce"}, env)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	if len(comments) != 1 || !strings.Contains(comments[0]["body"].(string), "app v1.0.2 is out.") || len(issues) != 1 {
+		t.Errorf("Expected a comment on issue #3, got comments %v and issues %v", comments, issues)
+	}
+}
+
+func TestGitHubIssueBackendConfig(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	for _, tt := range []struct {
+		env []string
+		err string
+	}{
+		{[]string{"BUMP_GITHUB_ISSUE=latest"}, "invalid BUMP_GITHUB_ISSUE 'latest'"},
+		{[]string{"BUMP_GITHUB_ISSUE=3", "BUMP_GITHUB_ISSUE_PIN=true"}, "pin issue #3 on GitHub instead"},
+		{[]string{"BUMP_GITHUB_ISSUE=new", "BUMP_GITHUB_REPO=acme/app"}, "BUMP_GITHUB_TOKEN must be set"},
+	} {
+		_, err := githubIssueBackend(config{}, tt.env)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("githubIssueBackend(%v): expected error %q, got %v", tt.env, tt.err, err)
+		}
+	}
+	if a, err := githubIssueBackend(config{}, nil); a != nil || err != nil {
+		t.Errorf("Expected no backend without BUMP_GITHUB_ISSUE, got %v, %v", a, err)
+	}
+
+	for name, url := range map[string]string{"origin": "git@github.com:me/app.git", "upstream": "git@github.com:acme/app.git"} {
+		_, err := repo.CreateRemote(&gitconfig.RemoteConfig{Name: name, URLs: []string{url}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, err := githubIssueBackend(config{remote: "upstream"}, []string{"BUMP_GITHUB_ISSUE=new", "BUMP_GITHUB_TOKEN=gh-token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project := a.(*issueAnnouncer).project; project != "acme/app" {
+		t.Errorf("Expected the issue in -remote's repository, got %s", project)
+	}
+}
+
+func TestGraphQLEndpoint(t *testing.T) {
+	for api, want := range map[string]string{
+		defaultGitHubAPI:                 "https://api.github.com/graphql",
+		"https://git.example.com/api/v3": "https://git.example.com/api/graphql",
+	} {
+		if got := graphQLEndpoint(api); got != want {
+			t.Errorf("graphQLEndpoint(%s) = %s, want %s", api, got, want)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// defaultKubeAnnotation is the annotation -k8s-annotate sets to the version.
+const defaultKubeAnnotation = "bump/version"
+
+// serviceAccountDir holds the credentials of a pod's service account.
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// kubeResources map the kinds -k8s-annotate takes, with kubectl's plurals and
+// short names, to their API paths.
+var kubeResources = map[string]string{
+	"deployment":  "apis/apps/v1/namespaces/%s/deployments/%s",
+	"statefulset": "apis/apps/v1/namespaces/%s/statefulsets/%s",
+	"daemonset":   "apis/apps/v1/namespaces/%s/daemonsets/%s",
+	"configmap":   "api/v1/namespaces/%s/configmaps/%s",
+}
+
+var kubeKindAliases = map[string]string{
+	"deployments": "deployment", "deploy": "deployment",
+	"statefulsets": "statefulset", "sts": "statefulset",
+	"daemonsets": "daemonset", "ds": "daemonset",
+	"configmaps": "configmap", "cm": "configmap",
+}
+
+// kubeTarget is a resource -k8s-annotate annotates, "[namespace/]kind/name".
+type kubeTarget struct {
+	namespace string
+	kind      string
+	name      string
+}
+
+func (t kubeTarget) String() string {
+	return t.kind + "/" + t.name
+}
+
+// parseKubeTargets parses the comma-separated -k8s-annotate resources.
+func parseKubeTargets(value string) ([]kubeTarget, error) {
+	var targets []kubeTarget
+	for _, spec := range strings.Split(value, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		parts := strings.Split(spec, "/")
+		var t kubeTarget
+		switch len(parts) {
+		case 2:
+			t = kubeTarget{kind: parts[0], name: parts[1]}
+		case 3:
+			t = kubeTarget{namespace: parts[0], kind: parts[1], name: parts[2]}
+		default:
+			return nil, fmt.Errorf("invalid -k8s-annotate resource '%s': expected [namespace/]kind/name", spec)
+		}
+		t.kind = strings.ToLower(t.kind)
+		if kind, ok := kubeKindAliases[t.kind]; ok {
+			t.kind = kind
+		}
+		if _, ok := kubeResources[t.kind]; !ok || t.name == "" {
+			return nil, fmt.Errorf("invalid -k8s-annotate resource '%s': kind must be deployment, statefulset, daemonset or configmap", spec)
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// kubeConfig is the part of a kubeconfig bump reads, in the JSON form of
+// "kubectl config view --minify --flatten -o json".
+type kubeConfig struct {
+	CurrentContext string `json:"current-context"`
+	Contexts       []struct {
+		Name    string `json:"name"`
+		Context struct {
+			Cluster   string `json:"cluster"`
+			User      string `json:"user"`
+			Namespace string `json:"namespace"`
+		} `json:"context"`
+	} `json:"contexts"`
+	Clusters []struct {
+		Name    string `json:"name"`
+		Cluster struct {
+			Server                   string `json:"server"`
+			CertificateAuthorityData string `json:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `json:"insecure-skip-tls-verify"`
+		} `json:"cluster"`
+	} `json:"clusters"`
+	Users []struct {
+		Name string `json:"name"`
+		User struct {
+			Token                 string          `json:"token"`
+			ClientCertificateData string          `json:"client-certificate-data"`
+			ClientKeyData         string          `json:"client-key-data"`
+			Exec                  json.RawMessage `json:"exec"`
+		} `json:"user"`
+	} `json:"users"`
+}
+
+// kubeAnnotator annotates Kubernetes resources with the released version
+// (-k8s-annotate), for deployments that treat the cluster as the record of
+// what runs where.
+type kubeAnnotator struct {
+	server    string
+	token     string
+	namespace string
+	client    *http.Client
+	targets   []kubeTarget
+	key       string
+}
+
+// newKubeAnnotator prepares -k8s-annotate before the release is made, so
+// missing cluster credentials can't fail it halfway. The cluster is the
+// current context of the kubeconfig at BUMP_KUBECONFIG or, without it, the
+// one bump runs in. It returns nil without -k8s-annotate.
+func newKubeAnnotator(cfg config, env []string) (*kubeAnnotator, error) {
+	if len(cfg.kubeTargets) == 0 {
+		return nil, nil
+	}
+	var a *kubeAnnotator
+	var err error
+	if file := getenv(env, "BUMP_KUBECONFIG"); file != "" {
+		a, err = kubeAnnotatorFromConfig(file)
+	} else if host := getenv(env, "KUBERNETES_SERVICE_HOST"); host != "" {
+		a, err = kubeAnnotatorInCluster(net.JoinHostPort(host, getenv(env, "KUBERNETES_SERVICE_PORT")))
+	} else {
+		err = errors.New("set BUMP_KUBECONFIG to a kubeconfig, e.g. from 'kubectl config view --minify --flatten -o json', or run in the cluster")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("-k8s-annotate: %w", err)
+	}
+	a.targets, a.key = cfg.kubeTargets, cfg.kubeAnnotation
+	return a, nil
+}
+
+// kubeAnnotatorFromConfig authenticates as the current context of a JSON
+// kubeconfig, with a token or a client certificate. Exec plugins aren't run.
+func kubeAnnotatorFromConfig(file string) (*kubeAnnotator, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read BUMP_KUBECONFIG: %w", err)
+	}
+	var kc kubeConfig
+	err = json.Unmarshal(content, &kc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse BUMP_KUBECONFIG, which must be JSON: %w", err)
+	}
+	a := &kubeAnnotator{namespace: "default"}
+	tlsConfig := &tls.Config{}
+	found := 0
+	for _, c := range kc.Contexts {
+		if c.Name != kc.CurrentContext {
+			continue
+		}
+		if c.Context.Namespace != "" {
+			a.namespace = c.Context.Namespace
+		}
+		for _, cluster := range kc.Clusters {
+			if cluster.Name != c.Context.Cluster {
+				continue
+			}
+			a.server = cluster.Cluster.Server
+			tlsConfig.InsecureSkipVerify = cluster.Cluster.InsecureSkipTLSVerify
+			if cluster.Cluster.CertificateAuthorityData != "" {
+				ca, err := base64.StdEncoding.DecodeString(cluster.Cluster.CertificateAuthorityData)
+				if err != nil {
+					return nil, fmt.Errorf("invalid certificate-authority-data of cluster %s: %w", cluster.Name, err)
+				}
+				tlsConfig.RootCAs = x509.NewCertPool()
+				if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+					return nil, fmt.Errorf("invalid certificate-authority-data of cluster %s", cluster.Name)
+				}
+			}
+			found++
+		}
+		for _, user := range kc.Users {
+			if user.Name != c.Context.User {
+				continue
+			}
+			switch {
+			case user.User.Token != "":
+				a.token = user.User.Token
+			case user.User.ClientCertificateData != "":
+				cert, err := base64.StdEncoding.DecodeString(user.User.ClientCertificateData)
+				if err != nil {
+					return nil, fmt.Errorf("invalid client-certificate-data of user %s: %w", user.Name, err)
+				}
+				key, err := base64.StdEncoding.DecodeString(user.User.ClientKeyData)
+				if err != nil {
+					return nil, fmt.Errorf("invalid client-key-data of user %s: %w", user.Name, err)
+				}
+				pair, err := tls.X509KeyPair(cert, key)
+				if err != nil {
+					return nil, fmt.Errorf("invalid client certificate of user %s: %w", user.Name, err)
+				}
+				tlsConfig.Certificates = []tls.Certificate{pair}
+			case len(user.User.Exec) > 0:
+				return nil, fmt.Errorf("user %s authenticates with an exec plugin, which bump doesn't run: use a service account token", user.Name)
+			}
+			found++
+		}
+	}
+	if found != 2 || a.server == "" {
+		return nil, fmt.Errorf("BUMP_KUBECONFIG has no complete current context '%s'", kc.CurrentContext)
+	}
+	a.client = &http.Client{Timeout: 30 * time.Second, Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	return a, nil
+}
+
+// kubeAnnotatorInCluster authenticates as the pod's service account.
+func kubeAnnotatorInCluster(host string) (*kubeAnnotator, error) {
+	token, err := os.ReadFile(path.Join(serviceAccountDir, "token"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the service account token: %w", err)
+	}
+	ca, err := os.ReadFile(path.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the cluster CA: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(ca) {
+		return nil, errors.New("invalid cluster CA")
+	}
+	a := &kubeAnnotator{server: "https://" + host, token: strings.TrimSpace(string(token)), namespace: "default"}
+	if namespace, err := os.ReadFile(path.Join(serviceAccountDir, "namespace")); err == nil {
+		a.namespace = strings.TrimSpace(string(namespace))
+	}
+	a.client = &http.Client{Timeout: 30 * time.Second, Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	return a, nil
+}
+
+// annotate sets the annotation of every target to the version with a merge
+// patch of its metadata. The pod template isn't touched, so annotating a
+// Deployment doesn't roll it out.
+func (a *kubeAnnotator) annotate(ctx context.Context, cfg config, output io.Writer, version string) error {
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{"annotations": map[string]string{a.key: version}},
+	})
+	if err != nil {
+		return err
+	}
+	for _, t := range a.targets {
+		namespace := t.namespace
+		if namespace == "" {
+			namespace = a.namespace
+		}
+		if cfg.dryRun {
+			_, _ = fmt.Fprintf(output, "Would annotate %s in namespace %s with %s=%s\n", t, namespace, a.key, version)
+			continue
+		}
+		endpoint := strings.TrimSuffix(a.server, "/") + "/" + fmt.Sprintf(kubeResources[t.kind], namespace, t.name)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPatch, endpoint, bytes.NewReader(patch))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		if a.token != "" {
+			req.Header.Set("Authorization", "Bearer "+a.token)
+		}
+		resp, err := a.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to annotate %s: %w", t, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			var status struct {
+				Message string `json:"message"`
+			}
+			if json.Unmarshal(body, &status) != nil || status.Message == "" {
+				status.Message = strings.TrimSpace(string(body))
+			}
+			return fmt.Errorf("failed to annotate %s in namespace %s: %s: %s", t, namespace, resp.Status, status.Message)
+		}
+		_, _ = fmt.Fprintf(output, "Annotated %s in namespace %s with %s=%s\n", t, namespace, a.key, version)
+	}
+	return nil
+}
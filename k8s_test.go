@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseKubeTargets(t *testing.T) {
+	targets, err := parseKubeTargets("deploy/app, prod/configmap/app-version,sts/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []kubeTarget{{kind: "deployment", name: "app"}, {namespace: "prod", kind: "configmap", name: "app-version"}, {kind: "statefulset", name: "db"}}
+	if len(targets) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, targets)
+	}
+	for i := range want {
+		if targets[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want[i], targets[i])
+		}
+	}
+	for _, invalid := range []string{"app", "pod/app", "a/b/c/d", "deployment/"} {
+		_, err := parseKubeTargets(invalid)
+		if err == nil {
+			t.Errorf("Expected %q to be refused", invalid)
+		}
+	}
+}
+
+func TestBumpKubeAnnotate(t *testing.T) {
+	var patches []string
+	api := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Authorization") != "Bearer kube-token" || r.Header.Get("Content-Type") != "application/merge-patch+json" {
+			http.Error(w, `{"message":"bad request headers"}`, http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/apis/apps/v1/namespaces/shop/deployments/missing" {
+			http.Error(w, `{"kind":"Status","message":"deployments.apps \"missing\" not found"}`, http.StatusNotFound)
+			return
+		}
+		patches = append(patches, r.Method+" "+r.URL.Path+" "+string(body))
+	}))
+	t.Cleanup(api.Close)
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: api.Certificate().Raw})
+	kubeconfig, err := json.Marshal(map[string]any{
+		"current-context": "prod",
+		"contexts":        []any{map[string]any{"name": "prod", "context": map[string]string{"cluster": "c", "user": "u", "namespace": "shop"}}},
+		"clusters":        []any{map[string]any{"name": "c", "cluster": map[string]string{"server": api.URL, "certificate-authority-data": base64.StdEncoding.EncodeToString(ca)}}},
+		"users":           []any{map[string]any{"name": "u", "user": map[string]string{"token": "kube-token"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kubeFile := filepath.Join(t.TempDir(), "kubeconfig.json")
+	err = os.WriteFile(kubeFile, kubeconfig, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-k8s-annotate", "deployment/app"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "-k8s-annotate: set BUMP_KUBECONFIG") {
+		t.Errorf("Expected a missing cluster to fail the bump, got: %v", err)
+	}
+
+	env := []string{"BUMP_KUBECONFIG=" + kubeFile}
+	err = run(context.Background(), &output, []string{"-k8s-annotate", "deployment/app,ops/cm/versions"}, env)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	want := []string{
+		`PATCH /apis/apps/v1/namespaces/shop/deployments/app {"metadata":{"annotations":{"bump/version":"v1.0.1"}}}`,
+		`PATCH /api/v1/namespaces/ops/configmaps/versions {"metadata":{"annotations":{"bump/version":"v1.0.1"}}}`,
+	}
+	if strings.Join(patches, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected patches:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(patches, "\n"))
+	}
+	if !strings.Contains(output.String(), "Annotated deployment/app in namespace shop with bump/version=v1.0.1") {
+		t.Errorf("Expected the annotation in the output, got:\n%s", output.String())
+	}
+
+	patches = nil
+	orphanBranch(t, repo, "releases", map[string]string{".version": "v1.0.1\n"})
+	err = run(context.Background(), &output, []string{"-minor", "-branch", "releases", "-k8s-annotate", "deployment/app"}, env)
+	if err != nil {
+		t.Fatalf("Expected the -branch bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	if len(patches) != 1 || !strings.Contains(patches[0], `"bump/version":"v1.1.0"`) {
+		t.Errorf("Expected the -branch release to be annotated, got %v", patches)
+	}
+
+	commitFiles(t, repo, "Fix", map[string]string{"fix.txt": "fix\n"})
+	err = run(context.Background(), &output, []string{"-k8s-annotate", "deploy/missing", "-k8s-annotation", "example.com/release"}, env)
+	if err == nil || !strings.Contains(err.Error(), "release v1.1.1 was created but failed to annotate deployment/missing in namespace shop: 404 Not Found: deployments.apps \"missing\" not found") {
+		t.Errorf("Expected the API's error, got: %v", err)
+	}
+}
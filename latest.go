@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"golang.org/x/mod/semver"
+)
+
+// Strategies for the current version (-latest-strategy).
+const (
+	// latestSemver takes the highest version
+	latestSemver = "semver"
+	// latestTagDate takes the most recently created tag
+	latestTagDate = "tag-date"
+	// latestCommitDate takes the tag of the most recently committed release
+	latestCommitDate = "commit-date"
+)
+
+const latestStrategiesHelp = latestSemver + ", " + latestTagDate + " or " + latestCommitDate
+
+// checkLatestStrategy validates -latest-strategy.
+func checkLatestStrategy(strategy string) error {
+	switch strategy {
+	case latestSemver, latestTagDate, latestCommitDate:
+		return nil
+	}
+	return fmt.Errorf("invalid -latest-strategy '%s': must be %s", strategy, latestStrategiesHelp)
+}
+
+// versionTag is a tag and the version it stands for.
+type versionTag struct {
+	version string
+	ref     *plumbing.Reference
+}
+
+// versionTagLister is implemented by the wrappers that narrow down or rename
+// the version tags, so a date-based strategy picks among the same tags.
+type versionTagLister interface {
+	versionTags() ([]versionTag, error)
+}
+
+// tagVersions returns the versions of the tags.
+func tagVersions(tags []versionTag) []string {
+	versions := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		versions = append(versions, tag.version)
+	}
+	return versions
+}
+
+// allVersionTags returns the tags whose names are versions.
+func allVersionTags(repo *git.Repository) ([]versionTag, error) {
+	var tags []versionTag
+	err := forEachTag(repo, func(ref *plumbing.Reference) error {
+		if name := ref.Name().Short(); semver.IsValid(normalizeVersion(name)) {
+			tags = append(tags, versionTag{version: name, ref: ref})
+		}
+		return nil
+	})
+	return tags, err
+}
+
+// latestVCS resolves the current version by date instead of by version, for
+// repositories with erratic tags such as a v9.9.9 test tag.
+type latestVCS struct {
+	vcs
+	repo     *git.Repository
+	strategy string
+}
+
+// withLatestStrategy wraps repo for a -latest-strategy other than semver.
+func withLatestStrategy(repo vcs, strategy string) (vcs, error) {
+	gitRepo, err := gitRepository(repo, "-latest-strategy "+strategy)
+	if err != nil {
+		return nil, err
+	}
+	return latestVCS{vcs: repo, repo: gitRepo, strategy: strategy}, nil
+}
+
+func (l latestVCS) goGit() *git.Repository {
+	return l.repo
+}
+
+// versionTags returns the version tags of the wrapped vcs.
+func (l latestVCS) versionTags() ([]versionTag, error) {
+	if lister, ok := l.vcs.(versionTagLister); ok {
+		return lister.versionTags()
+	}
+	return allVersionTags(l.repo)
+}
+
+// lastTag returns the version of the newest version tag. Tags of the same
+// date are ordered by version.
+func (l latestVCS) lastTag() (string, error) {
+	tags, err := l.versionTags()
+	if err != nil {
+		return "", err
+	}
+	var latest string
+	var latestDate time.Time
+	for _, tag := range tags {
+		date, err := l.date(tag.ref)
+		if err != nil {
+			return "", err
+		}
+		if latest == "" || date.After(latestDate) ||
+			(date.Equal(latestDate) && semver.Compare(normalizeVersion(tag.version), normalizeVersion(latest)) > 0) {
+			latest, latestDate = tag.version, date
+		}
+	}
+	if latest == "" {
+		return "", errNoVersionTags
+	}
+	return latest, nil
+}
+
+// date returns the date of a tag for the strategy. Lightweight tags have no
+// date of their own; their commit's date stands in.
+func (l latestVCS) date(ref *plumbing.Reference) (time.Time, error) {
+	if l.strategy == latestTagDate {
+		tag, err := l.repo.TagObject(ref.Hash())
+		if err == nil {
+			return tag.Tagger.When, nil
+		}
+	}
+	commit, err := peelTag(l.repo, ref.Hash())
+	if err != nil {
+		return time.Time{}, err
+	}
+	return commit.Committer.When, nil
+}
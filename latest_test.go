@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// commitAt commits a change to file with the given commit date.
+func commitAt(t *testing.T, repo *git.Repository, file string, when time.Time) plumbing.Hash {
+	t.Helper()
+	err := os.WriteFile(file, []byte(when.String()), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = w.Add(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := &object.Signature{Name: "Test", Email: "test@example.com", When: when}
+	hash, err := w.Commit("Change "+file, &git.CommitOptions{Author: signature, Committer: signature})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestLatestStrategy(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	// after the tagged v1.0.0, which is committed now
+	day := time.Now().AddDate(0, 0, 1).Truncate(time.Second)
+	// an erratic v9.9.9 test tag, created late on an old commit
+	old := commitAt(t, repo, "old.txt", day)
+	recent := commitAt(t, repo, "recent.txt", day.AddDate(0, 0, 1))
+	tag := func(name string, hash plumbing.Hash, when time.Time) {
+		_, err := repo.CreateTag(name, hash, &git.CreateTagOptions{
+			Tagger:  &object.Signature{Name: "Test", Email: "test@example.com", When: when},
+			Message: "release",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	tag("v1.1.0", recent, day.AddDate(0, 0, 2))
+	tag("v9.9.9", old, day.AddDate(0, 0, 3))
+	commitAt(t, repo, "fix.txt", day.AddDate(0, 0, 4))
+
+	tests := []struct {
+		strategy string
+		want     string
+	}{
+		{latestSemver, "Bumped version v9.9.9 --> v9.9.10"},
+		{latestTagDate, "Bumped version v9.9.9 --> v9.9.10"},
+		{latestCommitDate, "Bumped version v1.1.0 --> v1.1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			var output bytes.Buffer
+			err := run(context.Background(), &output, []string{"-dry-run", "-latest-strategy", tt.strategy}, nil)
+			if err != nil {
+				t.Fatalf("Expected the dry run to succeed, got: %v\nOutput: %s", err, output.String())
+			}
+			if !strings.Contains(output.String(), tt.want) {
+				t.Errorf("Expected %q, got:\n%s", tt.want, output.String())
+			}
+		})
+	}
+
+	// retagging v1.1.0 later makes it the latest by tag date
+	err := repo.DeleteTag("v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag("v1.1.0", recent, day.AddDate(0, 0, 5))
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-dry-run"}, []string{"BUMP_LATEST_STRATEGY=tag-date"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output.String(), "Bumped version v1.1.0 --> v1.1.1") {
+		t.Errorf("Expected the most recent tag, got:\n%s", output.String())
+	}
+
+	err = run(context.Background(), &output, []string{"-latest-strategy", "newest"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid -latest-strategy") {
+		t.Errorf("Expected an invalid strategy to fail, got: %v", err)
+	}
+}
@@ -9,13 +9,15 @@ import (
 	"io"
 	"os"
 	"os/signal"
+	"path"
 	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
-	"golang.org/x/mod/semver"
+	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
 //go:embed .version
@@ -28,20 +30,188 @@ const (
 	incrementPatch
 	incrementMinor
 	incrementMajor
+	// incrementHotfix creates a date-stamped prerelease of the next patch
+	incrementHotfix
 )
 
 type config struct {
 	version string
 	action  action
 	dryRun  bool
-	forced  bool
+	// check is a dry run whose exit status tells whether it would release
+	// (see exitWouldRelease)
+	check  bool
+	forced bool
+	// commitPerModule creates one commit per directory holding a .version file
+	commitPerModule bool
+	// autostash stashes uncommitted changes around the bump instead of failing
+	autostash bool
+	// announce sends release announcements after a successful bump
+	announce bool
+	// tagMetadata is the format of the metadata block in the tag message
+	tagMetadata string
+	// tagType is annotated or lightweight; tagMessage is the template of the
+	// annotation's first paragraph, empty for defaultTagMessage
+	tagType    string
+	tagMessage string
+	// sign signs the version tags with the OpenPGP key signingKey names, or
+	// the tags and release commits with the SSH key sshKey names (see
+	// withSignedTags)
+	sign       bool
+	signingKey string
+	sshKey     string
+	// noVCS skips all repository operations and only rewrites version files
+	noVCS bool
+	// backend selects the git implementation: go-git, cli or auto
+	backend string
+	// offTrain allows releasing a level outside its release train schedule
+	offTrain bool
+	// autoAPI derives the bump level from the changes to the exported Go API
+	autoAPI bool
+	// auto derives the bump level from the Conventional Commits since the
+	// last tag (see commitBumpLevel)
+	auto bool
+	// warnings are about the command line, e.g. deprecated flag names, and
+	// printed before the bump
+	warnings []string
+	// fromConfig are the flags only a config file set, which the BUMP_*
+	// variables override (see envDefault)
+	fromConfig map[string]bool
+	// defaultLevel is set when no flag chose the bump, leaving it to
+	// BUMP_LEVEL (see levelFromCI); levelSource is what BUMP_LEVEL took the
+	// level from
+	defaultLevel bool
+	levelSource  string
+	// ownTags ignores version tags that weren't created by bump
+	ownTags bool
+	// timeout bounds the runtime of the bump, zero for no limit
+	timeout time.Duration
+	// remote is the remote tags are resolved from, pushRemote the one releases
+	// are published to; empty for the defaults (see resolveRemotes)
+	remote     string
+	pushRemote string
+	// push publishes the release commit and tag to the push remote, sending
+	// the pushOptions with both (see pushRelease)
+	push        bool
+	pushOptions []string
+	// githubRelease creates the GitHub release of the pushed tag, uploading
+	// the files matching the assets globs to it (see releaseCreator)
+	githubRelease bool
+	assets        []string
+	// gitlabRelease creates the GitLab release of the pushed tag, with the
+	// releaseLinks as its assets (see gitlabReleaseCreator)
+	gitlabRelease bool
+	releaseLinks  []releaseLink
+	// backMerge opens a pull request merging a release made on a release
+	// branch back into the default branch (see backMerger)
+	backMerge bool
+	// kubeTargets are annotated with the version in kubeAnnotation after the
+	// release (see kubeAnnotator)
+	kubeTargets    []kubeTarget
+	kubeAnnotation string
+	// since overrides the last tag as the base the changes and -auto-api
+	// compare against: a tag or commit
+	since string
+	// commits is the commit strategy for collecting changes (see commitsAll)
+	commits string
+	// provenance adds signed trailers chaining the release commit to the
+	// previous release (see provenance.go)
+	provenance bool
+	// stream is the version stream to bump, empty for the default stream;
+	// streams are the streams of .bumpstreams
+	stream  string
+	streams []versionStream
+	// skipCI marks the release commit to skip CI (see skipCISkipCI), empty
+	// for no marker
+	skipCI string
+	// initialVersion is released when there are no version tags yet
+	initialVersion string
+	// pre1Major is the pre-1.0 policy (see pre1Major), empty for the default
+	pre1Major string
+	// timezone is the time zone of the dates and timestamps bump writes,
+	// dateFormat the layout of its dates
+	timezone   string
+	dateFormat string
+	// tagPlan is the file the tag is planned in for a tag service to create,
+	// instead of creating it (see runRequestTag)
+	tagPlan string
+	// tagTemplate names the version tags (see tagFormat), empty for the
+	// stream's prefix and the version
+	tagTemplate string
+	// fixEOL normalizes CRLF line endings of the version files it writes
+	fixEOL bool
+	// releasedAt is the time of the release, recorded in structured version
+	// files (see versionFileHeader)
+	releasedAt time.Time
+	// location is the time zone of -timezone, nil for the local one
+	location *time.Location
+	// latestStrategy selects the current version among the version tags
+	// (see latestSemver)
+	latestStrategy string
+	// changelog adds the commits since the last release to the Unreleased
+	// section of the changelog before it is released, through
+	// changelogTemplate if set (see addCommitEntries)
+	changelog         bool
+	changelogTemplate string
+	// releaseNotes is the file the aggregated release notes of the released
+	// changelogs are written to
+	releaseNotes string
+	// assertReadOnly guarantees that nothing is written: it implies dryRun
+	// and refuses repository writes (see readOnlyVCS)
+	assertReadOnly bool
+	// commitBody explains the release commit with the bump reason and the
+	// updated files
+	commitBody bool
+	// sbom is the path the release's SBOM is committed to, empty for none
+	sbom string
+	// artifacts are the globs of the release artifacts listed in the checksum
+	// manifest written to checksums after tagging; none for no manifest
+	artifacts []string
+	checksums string
+	// buildArgs is the file the VERSION and VCS_REF build args of the release
+	// are written to after tagging, empty for none
+	buildArgs string
+	// events is where the progress events go: a file or fd:N (see
+	// openEvents); eventSink is the opened stream
+	events    string
+	eventSink *eventStream
+	// images are the pushed container images whose digests the release
+	// pins, imageManifest the file they are committed to, empty for the tag
+	// annotation only
+	images        []string
+	imageManifest string
+	// allowEmptyRelease releases even without commits since the last release
+	allowEmptyRelease bool
+	// allowLargeRelease downgrades the max-commits and max-age rules of
+	// .bumppolicy to warnings
+	allowLargeRelease bool
+	// scanSecrets refuses releases whose changes add lines that look like
+	// secrets (see scanSecrets)
+	scanSecrets bool
+	// scheme names the version scheme (see versionSchemes), empty for the
+	// default
+	scheme string
+	// branch is the branch to release instead of the checked-out one, empty
+	// for HEAD (see branchVCS)
+	branch string
+	// sandbox rehearses the bump in a temporary clone (see runSandboxed)
+	sandbox bool
+	// include are globs of changed files committed with the version files;
+	// stagedOnly commits the staged changes with them (see releasedChange)
+	include    []string
+	stagedOnly bool
 }
 
 type ignoreRule struct {
 	pattern  string
 	anchored bool // true if starts with /
+	negated  bool // true if starts with !, re-including what earlier rules ignore
 }
 
+// defaultIgnoreRules come before the rules of .bumpignore: test fixtures are
+// never bumped unless .bumpignore re-includes them with !testdata.
+var defaultIgnoreRules = []ignoreRule{{pattern: "testdata"}}
+
 func loadIgnoreRules(path string) ([]ignoreRule, error) {
 	content, err := os.ReadFile(path)
 	if errors.Is(err, os.ErrNotExist) {
@@ -57,43 +227,107 @@ func loadIgnoreRules(path string) ([]ignoreRule, error) {
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
+		negated := strings.HasPrefix(line, "!")
+		line = strings.TrimPrefix(line, "!")
 		if strings.HasPrefix(line, "/") {
-			rules = append(rules, ignoreRule{pattern: line[1:], anchored: true})
+			rules = append(rules, ignoreRule{pattern: line[1:], anchored: true, negated: negated})
 		} else {
-			rules = append(rules, ignoreRule{pattern: line, anchored: false})
+			rules = append(rules, ignoreRule{pattern: line, anchored: false, negated: negated})
 		}
 	}
 	return rules, nil
 }
 
+// shouldIgnore reports whether the directory is ignored. The last matching
+// rule decides, so a negated rule re-includes what earlier rules ignore.
 func shouldIgnore(path string, dirName string, rules []ignoreRule) bool {
+	ignored := false
 	for _, rule := range rules {
+		var match bool
 		if rule.anchored {
 			// Anchored: path must be exactly the pattern (from repo root)
-			if path == rule.pattern {
-				return true
-			}
+			match = path == rule.pattern
 		} else {
 			// Unanchored: match directory name at any level
-			if dirName == rule.pattern {
-				return true
-			}
+			match = dirName == rule.pattern
+		}
+		if match {
+			ignored = !rule.negated
 		}
 	}
-	return false
+	return ignored
 }
 
 func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 	err := run(ctx, os.Stdout, os.Args[1:], os.Environ())
+	var status *exitStatus
+	if errors.As(err, &status) {
+		fmt.Println(err)
+		os.Exit(status.code)
+	}
 	if err != nil {
 		fmt.Println("error:", err)
 		os.Exit(1)
 	}
 }
 
+// commands are the subcommands of bump. Without a subcommand bump bumps.
+var commands = map[string]func(ctx context.Context, output io.Writer, args []string, env []string) error{
+	"affected":         runAffected,
+	"changelog":        runChangelog,
+	"channel":          runChannel,
+	"check-embed":      runCheckEmbed,
+	"comment-plan":     runCommentPlan,
+	"compat":           runCompat,
+	"export-history":   runExportHistory,
+	"import-history":   runImportHistory,
+	"inspect-binary":   runInspectBinary,
+	"notify-consumers": runNotifyConsumers,
+	"promote-env":      runPromoteEnv,
+	"push":             runPush,
+	"reconcile":        runReconcile,
+	"release":          runRelease,
+	"request-tag":      runRequestTag,
+	"set":              runSet,
+	"show":             runShow,
+	"since":            runSince,
+	"status":           runStatus,
+	"train":            runTrain,
+	"verify-chain":     runVerifyChain,
+	"verify-release":   runVerifyRelease,
+	"watch":            runWatch,
+}
+
+// run executes bump. Credentials from the environment and in URLs are
+// redacted from all output and from the returned error.
 func run(ctx context.Context, output io.Writer, argv []string, env []string) error {
+	r := newRedactor(env)
+	err := runCommand(ctx, redactingWriter{w: output, r: r}, argv, env)
+	return r.redactError(err)
+}
+
+func runCommand(ctx context.Context, output io.Writer, argv []string, env []string) error {
+	if len(argv) > 1 && isAssertReadOnly(argv[0]) {
+		if _, ok := commands[argv[1]]; ok {
+			err := checkReadOnlyCommand(argv[1])
+			if err != nil {
+				return err
+			}
+			argv = argv[1:]
+		}
+	}
+	if len(argv) > 0 {
+		if command, ok := commands[argv[0]]; ok {
+			return command(ctx, output, argv[1:], env)
+		}
+	}
+	return runBump(ctx, output, argv, env)
+}
+
+// runBump bumps the version as configured by the flags in argv.
+func runBump(ctx context.Context, output io.Writer, argv []string, env []string) (err error) {
 	_, _ = fmt.Fprintf(output, "bump %s bumping\n", embeddedVersion)
 	runConfig, showHelp, err := getConfig(argv)
 	if err != nil {
@@ -102,177 +336,663 @@ func run(ctx context.Context, output io.Writer, argv []string, env []string) err
 	if showHelp {
 		return nil
 	}
-
-	repo, err := git.PlainOpen(".")
+	for _, warning := range runConfig.warnings {
+		_, _ = fmt.Fprintf(output, "Warning: %s\n", warning)
+	}
+	if runConfig.sandbox {
+		return runSandboxed(ctx, output, argv, env, runConfig)
+	}
+	runConfig.events = runConfig.envDefault("events", runConfig.events, env, "BUMP_EVENTS")
+	if runConfig.assertReadOnly && runConfig.events != "" && !strings.HasPrefix(runConfig.events, "fd:") {
+		return fmt.Errorf("-assert-read-only can't write the -events file %s: use -events fd:N", runConfig.events)
+	}
+	runConfig.eventSink, err = openEvents(runConfig.events, newRedactor(env))
 	if err != nil {
-		return fmt.Errorf("failed to open repository: %w", err)
+		return err
 	}
-	// check that the repository is clean
-	w, err := repo.Worktree()
+	defer func() {
+		// -check turns the outcome of the dry run into the exit status: a
+		// refused empty release is nothing to do, a complete run would release
+		if runConfig.check && errors.Is(err, errNoChanges) {
+			_, _ = fmt.Fprintf(output, "Nothing to release: %v\n", err)
+			err = nil
+		} else if runConfig.check && err == nil {
+			err = &exitStatus{code: exitWouldRelease, message: "A release would be made"}
+		}
+		if err != nil && !errors.As(err, new(*exitStatus)) {
+			runConfig.eventSink.emit(progressEvent{Phase: phaseError, Error: err.Error()})
+		}
+		runConfig.eventSink.close()
+	}()
+	runConfig.remote = runConfig.envDefault("remote", runConfig.remote, env, "BUMP_REMOTE")
+	runConfig.pushRemote = runConfig.envDefault("push-remote", runConfig.pushRemote, env, "BUMP_PUSH_REMOTE")
+	runConfig.skipCI = runConfig.envDefault("skip-ci", runConfig.skipCI, env, "BUMP_SKIP_CI")
+	err = checkSkipCI(runConfig.skipCI)
 	if err != nil {
-		return fmt.Errorf("repo.Worktree: %w", err)
+		return err
+	}
+	runConfig.initialVersion = runConfig.envDefault("initial-version", runConfig.initialVersion, env, "BUMP_INITIAL_VERSION")
+	runConfig.pre1Major = runConfig.envDefault("pre1-major", runConfig.pre1Major, env, "BUMP_PRE1_MAJOR")
+	err = checkVersionConventions(runConfig)
+	if err != nil {
+		return err
+	}
+	runConfig.timezone = runConfig.envDefault("timezone", runConfig.timezone, env, "BUMP_TIMEZONE")
+	runConfig.dateFormat = runConfig.envDefault("date-format", runConfig.dateFormat, env, "BUMP_DATE_FORMAT")
+	if runConfig.dateFormat == "" {
+		runConfig.dateFormat = defaultDateFormat
+	}
+	runConfig.tagTemplate = runConfig.envDefault("tag-template", runConfig.tagTemplate, env, "BUMP_TAG_TEMPLATE")
+	runConfig.latestStrategy = runConfig.envDefault("latest-strategy", runConfig.latestStrategy, env, "BUMP_LATEST_STRATEGY")
+	if runConfig.latestStrategy == "" {
+		runConfig.latestStrategy = latestSemver
 	}
-	status, err := w.Status()
+	err = checkLatestStrategy(runConfig.latestStrategy)
 	if err != nil {
-		return fmt.Errorf("worktree.Status: %w", err)
+		return err
 	}
+	if runConfig.latestStrategy != latestSemver && runConfig.action == incrementHotfix {
+		return fmt.Errorf("-latest-strategy can't be combined with -hotfix, which takes the highest tag reachable from HEAD")
+	}
+	if runConfig.tagTemplate != "" && (runConfig.action == incrementHotfix || runConfig.provenance || runConfig.noVCS) {
+		return fmt.Errorf("a tag template can't be combined with -hotfix, -provenance or -no-vcs")
+	}
+	runConfig.scheme = runConfig.envDefault("scheme", runConfig.scheme, env, "BUMP_SCHEME")
+	if runConfig.scheme == "" {
+		runConfig.scheme = defaultScheme
+	}
+	versionScheme, err := lookupScheme(runConfig.scheme)
+	if err != nil {
+		return err
+	}
+	defer useScheme(versionScheme)()
+	runConfig.location, err = loadTimezone(runConfig.timezone)
+	if err != nil {
+		return err
+	}
+	if runConfig.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, runConfig.timeout)
+		defer cancel()
+	}
+	defer useBudget(runBudget{ctx: ctx, output: output, timeout: runConfig.timeout})()
 
-	// Filter out files that shouldn't block bumping
-	// go-git's Status() can report files that native git doesn't consider dirty:
-	// - Ignored files (untracked in both worktree and staging)
-	// - Files with only metadata changes (permissions) when filemode=false
-	// - Line ending differences when autocrlf is configured
-	//
-	// The approach: if a file is Modified in worktree but Unmodified in staging,
-	// it's likely a go-git quirk. We trust native git's behavior over go-git.
-	cleanStatus := make(git.Status)
-	for file, fileStatus := range status {
-		// Skip untracked files (includes ignored files)
-		if fileStatus.Worktree == git.Untracked && fileStatus.Staging == git.Untracked {
-			continue
+	var repo vcs = fsVCS{}
+	if !runConfig.noVCS {
+		repo, err = openVCS(".", runConfig.backend, runConfig.location)
+		if err != nil {
+			return err
 		}
-		// Skip files that show as Modified/Unmodified - this is a go-git quirk
-		// where it detects changes that git itself doesn't consider dirty
-		// (e.g., filemode, line endings with autocrlf, etc.)
-		if fileStatus.Worktree == git.Modified && fileStatus.Staging == git.Unmodified {
-			continue
+	}
+	if runConfig.branch != "" {
+		repo, err = withBranch(repo, runConfig.branch, runConfig.location)
+		if err != nil {
+			return err
 		}
-		cleanStatus[file] = fileStatus
 	}
-
-	if !cleanStatus.IsClean() && !runConfig.forced {
-		// Provide detailed information about what's dirty
-		var reasons []string
-		for file, fileStatus := range cleanStatus {
-			reasons = append(reasons, fmt.Sprintf("  %s: worktree=%v staging=%v", file, fileStatus.Worktree, fileStatus.Staging))
+	if runConfig.sign {
+		repo, err = withSignedTags(repo, runConfig.signingKey, runConfig.sshKey, runConfig.location, env)
+		if err != nil {
+			return err
+		}
+	}
+	if runConfig.assertReadOnly {
+		repo = withReadOnly(repo)
+	}
+	if runConfig.ownTags {
+		repo, err = withOwnTags(repo, env)
+		if err != nil {
+			return err
+		}
+	}
+	ignoredTags, err := loadIgnoredTags(".bumpignoretags")
+	if err != nil {
+		return fmt.Errorf("failed to load .bumpignoretags: %w", err)
+	}
+	if len(ignoredTags) > 0 && !runConfig.noVCS {
+		repo, err = withIgnoredTags(repo, ignoredTags)
+		if err != nil {
+			return err
+		}
+	}
+	runConfig.streams, err = loadStreams(".bumpstreams")
+	if err != nil {
+		return fmt.Errorf("failed to load .bumpstreams: %w", err)
+	}
+	format, err := releaseTagFormat(runConfig)
+	if err != nil {
+		return err
+	}
+	if format != nil {
+		repo, err = withTagFormat(repo, format)
+		if err != nil {
+			return err
 		}
-		if len(reasons) > 0 {
-			return fmt.Errorf("repository is not clean (use -force to override):\n%s", strings.Join(reasons, "\n"))
+	}
+	if runConfig.latestStrategy != latestSemver {
+		repo, err = withLatestStrategy(repo, runConfig.latestStrategy)
+		if err != nil {
+			return err
 		}
-		return fmt.Errorf("repository is not clean (use -force to override)")
+	}
+	runConfig, err = levelFromCI(runConfig, repo, env, output)
+	if err != nil {
+		return err
+	}
+	if runConfig.branch != "" {
+		// the worktree isn't touched, so it needn't be clean
+		return bumpBranch(ctx, repo, runConfig, env, output)
+	}
+	// check that the repository is clean
+	dirty, err := repo.dirtyFiles()
+	if err != nil {
+		return err
+	}
+	dirty, err = withoutReleasedChanges(repo, runConfig, dirty)
+	if err != nil {
+		return err
 	}
 
-	if runConfig.version != "" {
-		// Normalize version for validation (semver requires "v" prefix)
-		normalizedVersion := normalizeVersion(runConfig.version)
-		if !semver.IsValid(normalizedVersion) {
-			return fmt.Errorf("invalid semantic version string: '%s'", runConfig.version)
+	if len(dirty) > 0 && !runConfig.forced {
+		if runConfig.autostash {
+			return bumpWithAutostash(ctx, repo, runConfig, env, output)
 		}
+		// Provide detailed information about what's dirty
+		return fmt.Errorf("repository is not clean (use -force or -autostash to override):\n%s", strings.Join(sortedDirtyFiles(dirty), "\n"))
+	}
+
+	return bump(ctx, repo, runConfig, env, output)
+}
 
-		// Check if tag already exists before making any changes
-		exists, err := tagExists(repo, runConfig.version)
+// bump sets or increments the version, updates the version files and tags the
+// result. The repository is expected to have passed the cleanliness check.
+func bump(ctx context.Context, repo vcs, runConfig config, env []string, output io.Writer) error {
+	requested := runConfig.action
+	if runConfig.autoAPI {
+		level, err := apiBumpLevel(repo, runConfig, output)
 		if err != nil {
-			return fmt.Errorf("failed to check if tag exists: %w", err)
+			return err
 		}
-		if exists {
-			return fmt.Errorf("tag '%s' already exists", runConfig.version)
+		runConfig.action = level
+	}
+	if runConfig.auto {
+		level, err := commitBumpLevel(repo, runConfig, output)
+		if err != nil {
+			return err
 		}
+		runConfig.action = level
+	}
+	currentVersion, newVersion, err := nextVersion(repo, runConfig)
+	if err != nil {
+		return err
+	}
+	announcers, err := configuredAnnouncers(runConfig, env)
+	if err != nil {
+		return err
+	}
+	runConfig.releasedAt = runConfig.now()
+	backMerge, err := newBackMerger(repo, runConfig, env, output)
+	if err != nil {
+		return err
+	}
+	githubRelease, err := newReleaseCreator(repo, runConfig, env, output)
+	if err != nil {
+		return err
+	}
+	gitlabRelease, err := newGitLabReleaseCreator(repo, runConfig, env, output)
+	if err != nil {
+		return err
+	}
+	kube, err := newKubeAnnotator(runConfig, env)
+	if err != nil {
+		return err
+	}
+	train, err := releaseTrain(runConfig, runConfig.now())
+	if err != nil {
+		return err
+	}
+
+	// Validate the release before making any changes
+	runConfig.eventSink.emit(progressEvent{Phase: phaseValidate, Version: newVersion})
+	err = validateRelease(ctx, repo, runConfig, env, output, newVersion)
+	if err != nil {
+		return err
+	}
+	since := currentVersion
+	if runConfig.since != "" {
+		since = runConfig.since
+	}
+	changes, err := repo.changesSince(since, runConfig.commits)
+	if err != nil {
+		return fmt.Errorf("failed to collect changes: %w", err)
+	}
+
+	bom, err := generateSBOM(ctx, runConfig, env, newVersion)
+	if err != nil {
+		return err
+	}
+	checksumKey, err := signingKey(runConfig, env)
+	if err != nil {
+		return err
+	}
+	trailers, err := provenanceTrailers(repo, runConfig, env, currentVersion, newVersion)
+	if err != nil {
+		return err
+	}
+	images, err := resolveImages(ctx, runConfig, env)
+	if err != nil {
+		return err
+	}
 
-		err = updateVersionFiles(repo, runConfig, output, runConfig.version)
+	meta := newTagMetadata(runConfig, currentVersion, newVersion, changes)
+	meta.Train = train
+	if bom != nil {
+		meta.SBOMSHA256 = bom.digest
+	}
+	var pinned []string
+	for _, image := range images {
+		pinned = append(pinned, image.pinned)
+	}
+	message, err := tagMessage(runConfig.tagMessage, runConfig.tagMetadata, meta, pinned)
+	if err != nil {
+		return err
+	}
+	if runConfig.tagType == tagTypeLightweight {
+		message = "" // see vcs.createTag
+	}
+	rel := release{
+		Project:  projectName(env),
+		Previous: currentVersion,
+		Version:  newVersion,
+		Changes:  changes,
+		Date:     runConfig.now(),
+		env:      env,
+	}
+	changelogs, err := rollChangelogs(runConfig, rel)
+	if err != nil {
+		return err
+	}
+	notes := releaseNotes(rel.Project, newVersion, changelogs)
+	rel.Notes = notes
+	generated, err := renderGenerated(repo, rel)
+	if err != nil {
+		return err
+	}
+
+	packages, err := updatePackages(newVersion)
+	if err != nil {
+		return err
+	}
+
+	// Last chance for a clean abort: past this point the release is written
+	err = budget.err()
+	if err != nil {
+		return err
+	}
+	err = writeSBOM(repo, runConfig, output, bom)
+	if err != nil {
+		return err
+	}
+	err = writeGenerated(repo, runConfig, output, generated)
+	if err != nil {
+		return err
+	}
+	err = writePackages(repo, runConfig, output, packages)
+	if err != nil {
+		return err
+	}
+	err = writeImageManifest(repo, runConfig, output, images)
+	if err != nil {
+		return err
+	}
+	err = writeChangelogs(repo, runConfig, output, changelogs, newVersion)
+	if err != nil {
+		return err
+	}
+	err = writeReleaseNotes(repo, runConfig, output, notes)
+	if err != nil {
+		return err
+	}
+	reason := ""
+	if runConfig.commitBody {
+		reason = releaseReason(runConfig, requested, currentVersion, len(changes))
+	}
+	err = updateVersionFiles(repo, runConfig, output, newVersion, reason, trailers)
+	if err != nil {
+		return fmt.Errorf("updateVersionFiles: %w", err)
+	}
+	tagInfo := ""
+	if runConfig.tagPlan != "" {
+		err = writeTagPlan(repo, runConfig, output, tagPlan{
+			Tag:      releaseTag(runConfig, newVersion),
+			Version:  newVersion,
+			Previous: currentVersion,
+			Message:  message,
+		})
 		if err != nil {
-			return fmt.Errorf("updateVersionFiles: %w", err)
+			return err
 		}
-		hash, err := tagVersion(repo, runConfig, runConfig.version)
+		tagInfo = ", tag planned in " + runConfig.tagPlan
+	} else {
+		runConfig.eventSink.emit(progressEvent{Phase: phaseTag, Tag: releaseTag(runConfig, newVersion)})
+		tag, err := tagVersion(repo, runConfig, newVersion, message)
 		if err != nil {
 			return fmt.Errorf("tagVersion: %w", err)
 		}
-		_, _ = fmt.Fprintf(output, "Set version %s, tag=%s\n", runConfig.version, hash)
+		if tag != "" { // without a vcs nothing is tagged
+			tagInfo = ", tag=" + tag
+		}
+	}
+	if runConfig.version != "" || currentVersion == "" {
+		_, _ = fmt.Fprintf(output, "Set version %s%s\n", newVersion, tagInfo)
+	} else {
+		_, _ = fmt.Fprintf(output, "Bumped version %s --> %s%s\n", currentVersion,
+			newVersion, tagInfo)
+	}
+	err = writeChecksums(runConfig, output, checksumKey)
+	if err != nil {
+		return fmt.Errorf("release %s was created but writing the checksum manifest failed: %w", newVersion, err)
+	}
+	err = writeBuildArgs(repo, runConfig, output, newVersion)
+	if err != nil {
+		return fmt.Errorf("release %s was created but writing the build args failed: %w", newVersion, err)
+	}
+	if runConfig.push {
+		err = pushRelease(ctx, repo, runConfig, env, output, releaseTag(runConfig, newVersion))
+		if err != nil {
+			return fmt.Errorf("release %s was created but %w", newVersion, err)
+		}
+	}
+	if githubRelease != nil {
+		err = githubRelease.create(ctx, runConfig, output, releaseTag(runConfig, newVersion), newVersion, notes)
+		if err != nil {
+			return fmt.Errorf("release %s was pushed but %w", newVersion, err)
+		}
+	}
+	if gitlabRelease != nil {
+		err = gitlabRelease.create(ctx, runConfig, output, releaseTag(runConfig, newVersion), rel)
+		if err != nil {
+			return fmt.Errorf("release %s was pushed but %w", newVersion, err)
+		}
+	}
+	if backMerge != nil {
+		err = backMerge.open(ctx, repo, runConfig, output, releaseTag(runConfig, newVersion))
+		if err != nil {
+			return fmt.Errorf("release %s was pushed but the back-merge failed: %w", newVersion, err)
+		}
+	}
+	if kube != nil {
+		err = kube.annotate(ctx, runConfig, output, newVersion)
+		if err != nil {
+			return fmt.Errorf("release %s was created but %w", newVersion, err)
+		}
+	}
+
+	if runConfig.dryRun || runConfig.tagPlan != "" {
+		runConfig.eventSink.emit(progressEvent{Phase: phaseDone, Version: newVersion})
 		return nil
 	}
-	// increment version
-	currentVersion, err := lastTag(repo)
+	commit, err := repo.head()
 	if err != nil {
-		return fmt.Errorf("failed to get last tag: %w", err)
+		return fmt.Errorf("release %s was created but resolving its commit failed: %w", newVersion, err)
 	}
+	if len(announcers) > 0 {
+		runConfig.eventSink.emit(progressEvent{Phase: phaseAnnounce, Version: newVersion})
+	}
+	err = announce(ctx, announcers, output, release{
+		Project:  projectName(env),
+		Previous: currentVersion,
+		Version:  newVersion,
+		Changes:  changes,
+		Notes:    notes,
+		Commit:   commit,
+		Date:     runConfig.now(),
+		env:      env,
+	})
+	if err != nil {
+		return err
+	}
+	runConfig.eventSink.emit(progressEvent{Phase: phaseDone, Version: newVersion})
+	return nil
+}
 
-	// Check if there are changes since the last tag
-	hasChanges, err := hasChangesSinceTag(repo, currentVersion)
+// checkNotEmpty refuses a release without commits since the last one: it
+// would mint a new version of the same content, unless -allow-empty-release
+// asks for exactly that. -force, which overrides every check, still does too.
+func checkNotEmpty(repo vcs, cfg config, currentVersion string) error {
+	hasChanges, err := repo.hasChangesSince(currentVersion)
 	if err != nil {
 		return fmt.Errorf("failed to check for changes since last tag: %w", err)
 	}
-	if !hasChanges && !runConfig.forced {
-		return fmt.Errorf("no changes since last version tag '%s' (use -force to override)", currentVersion)
+	if !hasChanges && !cfg.allowEmptyRelease && !cfg.forced {
+		return fmt.Errorf("%w since last version tag '%s' (use -allow-empty-release to release anyway)", errNoChanges, currentVersion)
+	}
+	return nil
+}
+
+// nextVersion determines the current and the new version. With an explicit
+// -version the current version is the last tag, if any.
+func nextVersion(repo vcs, runConfig config) (string, string, error) {
+	if runConfig.version != "" {
+		_, err := scheme.parse(runConfig.version)
+		if err != nil {
+			return "", "", err
+		}
+		currentVersion, err := repo.lastTag()
+		if errors.Is(err, errNoVersionTags) {
+			return "", runConfig.version, nil // setting the initial version
+		}
+		if err != nil {
+			return "", "", fmt.Errorf("failed to get last tag: %w", err)
+		}
+		err = checkNotEmpty(repo, runConfig, currentVersion)
+		if err != nil {
+			return "", "", err
+		}
+		return currentVersion, runConfig.version, nil
+	}
+	if runConfig.action == incrementHotfix {
+		return nextHotfix(repo, runConfig)
+	}
+	// increment version
+	currentVersion, err := repo.lastTag()
+	if errors.Is(err, errNoVersionTags) && runConfig.initialVersion != "" {
+		return "", runConfig.initialVersion, nil // the first release
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get last tag: %w", err)
+	}
+
+	err = checkNotEmpty(repo, runConfig, currentVersion)
+	if err != nil {
+		return "", "", err
 	}
 
 	newVersion, err := incrementVersion(currentVersion, runConfig)
 	if err != nil {
-		return fmt.Errorf("incrementVersion: %w", err)
+		return "", "", fmt.Errorf("incrementVersion: %w", err)
 	}
+	return currentVersion, newVersion, nil
+}
 
-	// Check if the target tag already exists before making any changes
-	exists, err := tagExists(repo, newVersion)
+// validateRelease runs every check that must pass before creating version:
+// the release policy, the tag not existing yet and, in dry-run mode, the
+// simulated publishing.
+func validateRelease(ctx context.Context, repo vcs, cfg config, env []string, output io.Writer, version string) error {
+	policy, err := loadPolicy(".bumppolicy")
 	if err != nil {
-		return fmt.Errorf("failed to check if tag exists: %w", err)
+		return fmt.Errorf("failed to load .bumppolicy: %w", err)
 	}
-	if exists {
-		return fmt.Errorf("tag '%s' already exists", newVersion)
+	err = checkPolicy(repo, policy, version)
+	if err != nil {
+		return err
+	}
+	err = checkReleaseSize(repo, policy, cfg, output)
+	if err != nil {
+		return err
+	}
+	if cfg.scanSecrets {
+		err = scanSecrets(ctx, repo, cfg, env, output)
+		if err != nil {
+			return err
+		}
 	}
 
-	err = updateVersionFiles(repo, runConfig, output, newVersion)
+	exists, err := repo.tagExists(version)
 	if err != nil {
-		return fmt.Errorf("updateVersionFiles: %w", err)
+		return fmt.Errorf("failed to check if tag exists: %w", err)
+	}
+	tag := releaseTag(cfg, version)
+	if exists {
+		return fmt.Errorf("tag '%s' already exists", tag)
 	}
-	tag, err := tagVersion(repo, runConfig, newVersion)
+	protected, err := loadProtectedPaths(".bumpprotect")
 	if err != nil {
-		return fmt.Errorf("tagVersion: %w", err)
+		return fmt.Errorf("failed to load .bumpprotect: %w", err)
+	}
+	if len(protected) > 0 && cfg.branch == "" { // bumpBranch checks the branch's files
+		modules, err := bumpedModules(cfg)
+		if err != nil {
+			return err
+		}
+		var versionFiles []string
+		for _, m := range modules {
+			versionFiles = append(versionFiles, path.Join(m.Path, ".version"))
+		}
+		err = checkProtected(protected, versionFiles)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cfg.push {
+		err = checkPush(repo, cfg, env)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cfg.dryRun {
+		gitRepo, err := gitRepository(repo, "simulating the release")
+		if err != nil {
+			_, _ = fmt.Fprintf(output, "Dry run: %v, skipping remote checks\n", err)
+			return nil
+		}
+		r, err := resolveRemotes(gitRepo, cfg)
+		if err != nil {
+			return err
+		}
+		return simulateRelease(ctx, gitRepo, env, output, tag, r)
 	}
-	_, _ = fmt.Fprintf(output, "Bumped version %s --> %s, tag=%s\n", currentVersion,
-		newVersion, tag)
 	return nil
 }
 
+// releaseTag returns the tag of version in the stream being bumped.
+func releaseTag(cfg config, version string) string {
+	format, err := releaseTagFormat(cfg)
+	if err != nil || format == nil {
+		return version
+	}
+	return format.tag(version)
+}
+
 func lastTag(repo *git.Repository) (string, error) {
-	// Get the list of tags
+	var tagNames []string
+	err := forEachTag(repo, func(t *plumbing.Reference) error {
+		tagNames = append(tagNames, t.Name().Short())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return highestVersion(tagNames)
+}
+
+// forEachTag calls fn for every tag of the repository. Repositories can have
+// tens of thousands of tags, so the scan reports its progress and stops when
+// the -timeout budget runs out.
+func forEachTag(repo *git.Repository, fn func(*plumbing.Reference) error) error {
 	tagRefs, err := repo.Tags()
 	if err != nil {
-		return "", fmt.Errorf("failed to get tags: %w", err)
+		return fmt.Errorf("failed to get tags: %w", err)
 	}
+	var refs []*plumbing.Reference
+	err = tagRefs.ForEach(func(t *plumbing.Reference) error {
+		refs = append(refs, t)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to iterate over tags: %w", err)
+	}
+	scan := budget.startScan("tags", len(refs))
+	for _, ref := range refs {
+		err = scan.step()
+		if err != nil {
+			return err
+		}
+		err = fn(ref)
+		if err != nil {
+			return err
+		}
+	}
+	scan.done()
+	return nil
+}
+
+// errNoVersionTags is returned by lastTag when there is no version yet, so a
+// first release sets one (-version, -initial-version). Messages naming where
+// no version was found wrap it with noVersionError.
+var errNoVersionTags = errors.New("no version tags found in the repository")
+
+// noVersionError is errNoVersionTags with a more specific message.
+type noVersionError string
+
+func (e noVersionError) Error() string {
+	return string(e)
+}
+
+func (e noVersionError) Is(target error) bool {
+	return target == errNoVersionTags
+}
+
+// highestVersion returns the highest semantic version among the tag names,
+// ignoring names that aren't versions.
+func highestVersion(tagNames []string) (string, error) {
 	var tags []string
 	// Map to track original format for each normalized tag
 	originalFormat := make(map[string]string)
-	err = tagRefs.ForEach(func(t *plumbing.Reference) error {
-		tagName := t.Name().Short()
-		// Normalize for validation (semver requires "v" prefix)
-		normalizedTag := normalizeVersion(tagName)
-		// check that the tag matches the semver format
-		if !semver.IsValid(normalizedTag) {
-			return nil
+	for _, tagName := range tagNames {
+		// check that the tag is a version of the scheme
+		normalizedTag, err := scheme.parse(tagName)
+		if err != nil {
+			continue
 		}
 		tags = append(tags, normalizedTag)
 		// Store original format (prefer the one without "v" if we encounter duplicates)
 		if _, exists := originalFormat[normalizedTag]; !exists || !hasVPrefix(tagName) {
 			originalFormat[normalizedTag] = tagName
 		}
-		return nil
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to iterate over tags: %w", err)
 	}
 	if len(tags) == 0 {
-		return "", errors.New("no version tags found in the repository")
+		return "", errNoVersionTags
 	}
 	// sort the normalized tags
-	semver.Sort(tags)
+	sortVersions(scheme, tags)
 	// return the highest tag in its original format
 	highestNormalized := tags[len(tags)-1]
 	return originalFormat[highestNormalized], nil
 }
 
 func tagExists(repo *git.Repository, tagName string) (bool, error) {
-	tagRefs, err := repo.Tags()
-	if err != nil {
-		return false, fmt.Errorf("failed to get tags: %w", err)
-	}
-
 	exists := false
-	err = tagRefs.ForEach(func(t *plumbing.Reference) error {
+	err := forEachTag(repo, func(t *plumbing.Reference) error {
 		if t.Name().Short() == tagName {
 			exists = true
 		}
 		return nil
 	})
 	if err != nil {
-		return false, fmt.Errorf("failed to iterate over tags: %w", err)
+		return false, err
 	}
 
 	return exists, nil
@@ -280,15 +1000,27 @@ func tagExists(repo *git.Repository, tagName string) (bool, error) {
 
 // hasChangesSinceTag checks if there are any commits since the given tag
 func hasChangesSinceTag(repo *git.Repository, tagName string) (bool, error) {
-	// Get all tags and find the one we're looking for
-	tagRefs, err := repo.Tags()
+	commit, err := tagCommit(repo, tagName)
 	if err != nil {
-		return false, fmt.Errorf("failed to get tags: %w", err)
+		return false, err
+	}
+
+	// Get the current HEAD
+	head, err := repo.Head()
+	if err != nil {
+		return false, fmt.Errorf("failed to get HEAD: %w", err)
 	}
 
+	// If HEAD is the same as the tag commit, there are no changes
+	return head.Hash() != commit.Hash, nil
+}
+
+// tagCommit returns the commit the named tag points to
+func tagCommit(repo *git.Repository, tagName string) (*object.Commit, error) {
+	// Get all tags and find the one we're looking for
 	var tagHash plumbing.Hash
 	found := false
-	err = tagRefs.ForEach(func(t *plumbing.Reference) error {
+	err := forEachTag(repo, func(t *plumbing.Reference) error {
 		if t.Name().Short() == tagName {
 			tagHash = t.Hash()
 			found = true
@@ -296,35 +1028,146 @@ func hasChangesSinceTag(repo *git.Repository, tagName string) (bool, error) {
 		return nil
 	})
 	if err != nil {
-		return false, fmt.Errorf("failed to iterate tags: %w", err)
+		return nil, err
 	}
 	if !found {
-		return false, fmt.Errorf("tag not found: %s", tagName)
+		return nil, fmt.Errorf("tag not found: %s", tagName)
 	}
 
-	// Try to get as commit object first (for lightweight tags)
-	commit, err := repo.CommitObject(tagHash)
-	if err != nil {
-		// If that fails, it might be an annotated tag
-		tagObj, err := repo.TagObject(tagHash)
+	return peelTag(repo, tagHash)
+}
+
+// maxTagChain bounds the tag objects peeled to reach a commit, guarding
+// against cycles in corrupt repositories.
+const maxTagChain = 16
+
+// peelTag returns the commit a tag ref points to. Lightweight tags point to
+// the commit itself, annotated tags to a tag object, which in some
+// historical repositories points to another tag object in turn.
+func peelTag(repo *git.Repository, hash plumbing.Hash) (*object.Commit, error) {
+	for range maxTagChain {
+		obj, err := repo.Object(plumbing.AnyObject, hash)
 		if err != nil {
-			return false, fmt.Errorf("failed to get tag or commit object: %w", err)
+			return nil, fmt.Errorf("failed to get tag or commit object: %w", err)
 		}
-		// Get the commit the tag points to
-		commit, err = repo.CommitObject(tagObj.Target)
-		if err != nil {
-			return false, fmt.Errorf("failed to get commit from tag: %w", err)
+		switch o := obj.(type) {
+		case *object.Commit:
+			return o, nil
+		case *object.Tag:
+			hash = o.Target
+		default:
+			return nil, fmt.Errorf("tag points to a %s, not a commit", obj.Type())
 		}
 	}
+	return nil, fmt.Errorf("tag chain longer than %d tags", maxTagChain)
+}
 
-	// Get the current HEAD
+// Commit strategies select the commits whose subjects are the changes of a
+// release (-commits).
+const (
+	// commitsAll takes every commit since the base
+	commitsAll = "all"
+	// commitsFirstParent follows only the first parent of merges, so a
+	// branch's commits are represented by its merge or squash commit
+	commitsFirstParent = "first-parent"
+	// commitsMergesOnly takes only merge commits, e.g. for merge trains
+	commitsMergesOnly = "merges-only"
+)
+
+const commitStrategiesHelp = commitsAll + ", " + commitsFirstParent + " or " + commitsMergesOnly
+
+// changesSince returns the subject lines of the commits reachable from HEAD
+// but not from the given tag or commit, newest first, selected by the commit
+// strategy. Without a base there is nothing to compare against and no
+// changes are returned.
+func changesSince(repo *git.Repository, rev, strategy string) ([]string, error) {
+	if rev == "" {
+		return nil, nil
+	}
+	commits, err := commitsSince(repo, rev, strategy)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]string, 0, len(commits))
+	for _, c := range commits {
+		changes = append(changes, commitSubject(c.Message))
+	}
+	return changes, nil
+}
+
+// commitSubject returns the first line of a commit message.
+func commitSubject(message string) string {
+	subject, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return subject
+}
+
+// commitsSince returns the commits reachable from HEAD but not from the given
+// tag or commit, newest first, selected by the commit strategy
+func commitsSince(repo *git.Repository, rev, strategy string) ([]*object.Commit, error) {
 	head, err := repo.Head()
 	if err != nil {
-		return false, fmt.Errorf("failed to get HEAD: %w", err)
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
 	}
+	return commitsBetween(repo, head.Hash(), rev, strategy)
+}
 
-	// If HEAD is the same as the tag commit, there are no changes
-	return head.Hash() != commit.Hash, nil
+// commitsBetween returns the commits reachable from the commit from but not
+// from the given tag or commit, like commitsSince does for HEAD.
+func commitsBetween(repo *git.Repository, from plumbing.Hash, rev, strategy string) ([]*object.Commit, error) {
+	base, err := resolveCommit(repo, rev)
+	if err != nil {
+		return nil, err
+	}
+	released := make(map[plumbing.Hash]bool)
+	baseIter, err := repo.Log(&git.LogOptions{From: base.Hash})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk history of %s: %w", rev, err)
+	}
+	err = baseIter.ForEach(func(c *object.Commit) error {
+		released[c.Hash] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk history of %s: %w", rev, err)
+	}
+
+	if strategy == commitsFirstParent {
+		return firstParentsSince(repo, from, released)
+	}
+	headIter, err := repo.Log(&git.LogOptions{From: from})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk history: %w", err)
+	}
+	var commits []*object.Commit
+	err = headIter.ForEach(func(c *object.Commit) error {
+		if released[c.Hash] || (strategy == commitsMergesOnly && c.NumParents() < 2) {
+			return nil
+		}
+		commits = append(commits, c)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk history: %w", err)
+	}
+	return commits, nil
+}
+
+// firstParentsSince walks the first parents from the commit until it reaches
+// a released commit.
+func firstParentsSince(repo *git.Repository, from plumbing.Hash, released map[plumbing.Hash]bool) ([]*object.Commit, error) {
+	var commits []*object.Commit
+	for hash := from; !released[hash]; {
+		c, err := repo.CommitObject(hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk history: %w", err)
+		}
+		commits = append(commits, c)
+		if c.NumParents() == 0 {
+			break
+		}
+		hash = c.ParentHashes[0]
+	}
+	return commits, nil
 }
 
 // hasVPrefix checks if a version string starts with "v"
@@ -350,21 +1193,98 @@ func stripVPrefix(version string) string {
 
 func getConfig(args []string) (config, bool, error) {
 	var cfg config
-	var showhelp, patchFlag, minorFlag, majorFlag bool
+	var showhelp, patchFlag, minorFlag, majorFlag, hotfixFlag bool
+	var artifactsFlag, assetsFlag, imagesFlag, includeFlag, kubeFlag, linksFlag, pushOptionsFlag string
 
 	flagSet := flag.NewFlagSet("version", flag.ContinueOnError)
 	flagSet.StringVar(&cfg.version, "version", "", "Initial version number.")
 	flagSet.BoolVar(&patchFlag, "patch", false, "Increase patch version.")
 	flagSet.BoolVar(&minorFlag, "minor", false, "Increase minor version.")
 	flagSet.BoolVar(&majorFlag, "major", false, "Increase major version.")
+	flagSet.BoolVar(&hotfixFlag, "hotfix", false, "Create a date-stamped hotfix of the next patch version, e.g. v1.4.2-hotfix.20240610.")
 	flagSet.BoolVar(&cfg.dryRun, "dry-run", false, "Do not write changes to the repository.")
+	flagSet.BoolVar(&cfg.check, "check", false, "Dry run that exits 0 if there is nothing to release and 3 if a release would be made.")
+	flagSet.BoolVar(&cfg.assertReadOnly, "assert-read-only", false, "Guarantee that nothing is written to disk, refs or the network; implies -dry-run. Also accepted before read-only subcommands.")
 	flagSet.BoolVar(&cfg.forced, "force", false, "Force the action despite the repository being dirty.")
+	flagSet.BoolVar(&cfg.announce, "announce", false, "Announce the release via the configured backends.")
+	flagSet.BoolVar(&cfg.autostash, "autostash", false, "Stash uncommitted changes before bumping and restore them afterwards.")
+	flagSet.StringVar(&cfg.tagType, "tag-type", tagTypeAnnotated, "Type of the version tag: "+tagTypeAnnotated+" or "+tagTypeLightweight+".")
+	flagSet.StringVar(&cfg.tagMessage, "tag-message", "", "Go template of the tag annotation over .Version, .Previous and .Level (default: \""+defaultTagMessage+"\").")
+	flagSet.BoolVar(&cfg.sign, "sign", false, "Sign the version tag with an OpenPGP key (from BUMP_SIGNING_KEY or the GnuPG secret keyring).")
+	flagSet.StringVar(&cfg.signingKey, "signing-key", "", "Key id, fingerprint or email of the -sign key (default: git config user.signingkey).")
+	flagSet.StringVar(&cfg.sshKey, "ssh-key", "", "SSH private key, or public key of an agent key, to sign the release commit and tag with; implies -sign.")
+	flagSet.StringVar(&cfg.tagMetadata, "tag-metadata", "", "Embed release metadata in the tag message ("+tagMetadataFormatsHelp+").")
+	flagSet.BoolVar(&cfg.noVCS, "no-vcs", false, "Only rewrite the version files, based on the root .version file, without any repository operations.")
+	flagSet.StringVar(&cfg.backend, "backend", backendGoGit, "Git implementation: go-git, cli (system git binary) or auto (cli if git is installed).")
+	flagSet.BoolVar(&cfg.offTrain, "off-train", false, "Release even if the level's release train does not depart today.")
+	flagSet.BoolVar(&cfg.autoAPI, "auto-api", false, "Choose the bump level from the exported Go API changes since the last tag; refuse lower levels.")
+	flagSet.BoolVar(&cfg.auto, "auto", false, "Choose the bump level from the Conventional Commits since the last tag (feat, fix, BREAKING CHANGE); refuse lower levels.")
+	flagSet.BoolVar(&cfg.ownTags, "own-tags", false, "Only consider version tags created by bump (or by BUMP_TAGGER), ignoring tags imported from forks and mirrors.")
+	flagSet.DurationVar(&cfg.timeout, "timeout", 0, "Abort cleanly if the bump takes longer than this, e.g. 30s (default: no limit).")
+	flagSet.StringVar(&cfg.remote, "remote", "", "Remote to resolve tags from (default: BUMP_REMOTE or origin).")
+	flagSet.BoolVar(&cfg.push, "push", false, "Push the release commit and tag to the push remote.")
+	flagSet.StringVar(&pushOptionsFlag, "push-option", "", "Comma-separated git push options sent with -push, e.g. ci.skip or merge_request.create.")
+	flagSet.BoolVar(&cfg.githubRelease, "github-release", false, "After -push, create the GitHub release of the tag, with the release notes or notes generated by GitHub.")
+	flagSet.StringVar(&assetsFlag, "assets", "", "Comma-separated globs of files uploaded in parallel to the -github-release release, with a checksums.txt of them.")
+	flagSet.BoolVar(&cfg.gitlabRelease, "gitlab-release", false, "After -push, create the GitLab release of the tag (token from BUMP_GITLAB_TOKEN or CI_JOB_TOKEN).")
+	flagSet.StringVar(&linksFlag, "gitlab-release-links", "", "Comma-separated name=url asset links of the -gitlab-release; URLs are templates, e.g. https://example.com/app-{{.Version}}.tar.gz.")
+	flagSet.BoolVar(&cfg.backMerge, "back-merge", false, "After -push, open a GitHub pull request merging a release branch's release into the default branch.")
+	flagSet.StringVar(&kubeFlag, "k8s-annotate", "", "Comma-separated [namespace/]kind/name Kubernetes resources to annotate with the version after the release (cluster from BUMP_KUBECONFIG).")
+	flagSet.StringVar(&cfg.kubeAnnotation, "k8s-annotation", defaultKubeAnnotation, "Annotation -k8s-annotate sets to the version.")
+	flagSet.StringVar(&cfg.pushRemote, "push-remote", "", "Remote to publish to, if different (default: BUMP_PUSH_REMOTE, remote.pushDefault or -remote).")
+	flagSet.StringVar(&cfg.since, "since", "", "Tag or commit to collect changes and compare the API against (default: the last version tag).")
+	flagSet.StringVar(&cfg.commits, "commits", commitsAll, "Commits whose subjects are the release's changes: "+commitStrategiesHelp+".")
+	flagSet.BoolVar(&cfg.provenance, "provenance", false, "Sign the release commit's .version digest and previous release into trailers (key in BUMP_PROVENANCE_KEY).")
+	flagSet.StringVar(&cfg.stream, "stream", "", "Bump the named version stream of .bumpstreams instead of the default one.")
+	flagSet.StringVar(&cfg.initialVersion, "initial-version", "", "Version to release when there are no version tags yet (default: BUMP_INITIAL_VERSION).")
+	flagSet.StringVar(&cfg.pre1Major, "pre1-major", "", "What -major does before v1.0.0: "+pre1Major+" or "+pre1Minor+" (default: BUMP_PRE1_MAJOR, else major).")
+	flagSet.StringVar(&cfg.timezone, "timezone", "", "Time zone of dates, commits and tags, e.g. UTC (default: BUMP_TIMEZONE, else local).")
+	flagSet.StringVar(&cfg.dateFormat, "date-format", "", "Go layout of written dates such as changelog headings (default: BUMP_DATE_FORMAT, else "+defaultDateFormat+").")
+	flagSet.BoolVar(&cfg.fixEOL, "fix-eol", false, "Normalize CRLF line endings and byte order marks of the .version files in the bump commit.")
+	flagSet.StringVar(&cfg.latestStrategy, "latest-strategy", "", "How to find the current version: "+latestStrategiesHelp+" (default: BUMP_LATEST_STRATEGY, else semver).")
+	flagSet.StringVar(&cfg.tagTemplate, "tag-template", "", "Template of tag names, e.g. 'release/{{.Date}}/{{.Version}}' (default: BUMP_TAG_TEMPLATE, else "+defaultTagTemplate+").")
+	flagSet.StringVar(&cfg.tagPlan, "tag-plan", "", "Don't tag: write the tag to this plan file for 'bump request-tag'.")
+	flagSet.BoolVar(&cfg.changelog, "changelog", false, "Add the commits since the last release to CHANGELOG.md, grouped by Conventional Commit type, and release them with the version.")
+	flagSet.StringVar(&cfg.changelogTemplate, "changelog-template", "", "Template rendering the changelog entries of -changelog from the release's changes (implies -changelog).")
+	flagSet.StringVar(&cfg.releaseNotes, "release-notes", "", "Write the released changelog entries of all bumped modules to this file, committed with the release.")
+	flagSet.BoolVar(&cfg.commitBody, "commit-body", false, "Give the release commit a body with the bump reason and the updated files.")
+	flagSet.StringVar(&cfg.skipCI, "skip-ci", "", "Mark the release commit to skip CI: "+skipCIStylesHelp+" (default: BUMP_SKIP_CI).")
+	flagSet.StringVar(&cfg.sbom, "sbom", "", "Generate an SBOM, commit it to this path with the release and record its digest in the tag.")
+	flagSet.StringVar(&artifactsFlag, "artifacts", "", "Comma-separated globs of release artifacts to write a checksum manifest of after tagging (signed if BUMP_CHECKSUMS_KEY is set).")
+	flagSet.StringVar(&cfg.checksums, "checksums", "checksums.txt", "Path of the checksum manifest written for -artifacts.")
+	flagSet.StringVar(&cfg.buildArgs, "build-args", "", "Write the VERSION and VCS_REF build args of the release to this file after tagging.")
+	flagSet.StringVar(&imagesFlag, "image", "", "Comma-separated container images, pushed before the bump, whose digests the release tag pins.")
+	flagSet.StringVar(&cfg.imageManifest, "image-manifest", "", "Also commit the pinned -image references to this file with the release.")
+	flagSet.StringVar(&cfg.events, "events", "", "Write progress events as JSON lines to this file, or to fd:N (default: BUMP_EVENTS).")
+	flagSet.BoolVar(&cfg.allowEmptyRelease, "allow-empty-release", false, "Release even if there are no commits since the last release.")
+	flagSet.BoolVar(&cfg.allowLargeRelease, "allow-large-release", false, "Only warn when the release trips the max-commits or max-age rules of .bumppolicy.")
+	flagSet.BoolVar(&cfg.scanSecrets, "scan-secrets", false, "Refuse to tag if the changes since the last tag add lines that look like secrets (also runs BUMP_SECRET_SCAN_COMMAND).")
+	flagSet.StringVar(&cfg.scheme, "scheme", "", "Version scheme (default: BUMP_SCHEME, else "+defaultScheme+").")
+	flagSet.StringVar(&cfg.branch, "branch", "", "Release this branch instead of the checked-out one, without switching to it.")
+	flagSet.StringVar(&includeFlag, "include", "", "Comma-separated globs of changed files to commit with the version files.")
+	flagSet.BoolVar(&cfg.stagedOnly, "staged-only", false, "Commit the staged changes with the version files; only unstaged changes block the bump.")
+	flagSet.BoolVar(&cfg.sandbox, "sandbox", false, "Rehearse the bump for real in a temporary clone, leaving the repository untouched.")
+	flagSet.BoolVar(&cfg.commitPerModule, "commit-per-module", false, "Commit each module's version file separately.")
 	flagSet.BoolVar(&showhelp, "help", false, "Show help message.")
+	registerFlagAliases(flagSet, &cfg.warnings)
 
+	cfg.fromConfig = make(map[string]bool)
+	for _, file := range []string{userConfigFile(), projectConfigFile} {
+		if file == "" {
+			continue
+		}
+		err := applyConfigFile(flagSet, file, cfg.fromConfig)
+		if err != nil {
+			return config{}, false, err
+		}
+	}
 	err := flagSet.Parse(args)
 	if err != nil {
 		return config{}, false, fmt.Errorf("failed to parse flags: %w", err)
 	}
+	flagSet.Visit(func(f *flag.Flag) {
+		delete(cfg.fromConfig, f.Name)
+	})
 	if showhelp {
 		flagSet.Usage()
 		return config{}, true, nil
@@ -375,13 +1295,162 @@ func getConfig(args []string) (config, bool, error) {
 	}
 
 	// if both version and increment flags are set, return an error
-	if cfg.version != "" && (patchFlag || minorFlag || majorFlag) {
+	if cfg.version != "" && (patchFlag || minorFlag || majorFlag || hotfixFlag) {
 		return config{}, false, fmt.Errorf("cannot set version and increment flags at the same time")
 	}
+	if cfg.version != "" && cfg.autoAPI {
+		return config{}, false, fmt.Errorf("cannot set version and -auto-api at the same time")
+	}
+	if cfg.version != "" && cfg.auto {
+		return config{}, false, fmt.Errorf("cannot set version and -auto at the same time")
+	}
+	if cfg.auto && cfg.autoAPI {
+		return config{}, false, fmt.Errorf("cannot set -auto and -auto-api at the same time")
+	}
+	cfg.assets, err = splitGlobs(assetsFlag, "-assets")
+	if err != nil {
+		return config{}, false, err
+	}
+	cfg.artifacts, err = artifactGlobs(artifactsFlag)
+	if err != nil {
+		return config{}, false, err
+	}
+	cfg.include, err = splitGlobs(includeFlag, "-include")
+	if err != nil {
+		return config{}, false, err
+	}
+	cfg.kubeTargets, err = parseKubeTargets(kubeFlag)
+	if err != nil {
+		return config{}, false, err
+	}
+	cfg.releaseLinks, err = parseReleaseLinks(linksFlag)
+	if err != nil {
+		return config{}, false, err
+	}
+	cfg.pushOptions, err = parsePushOptions(pushOptionsFlag)
+	if err != nil {
+		return config{}, false, err
+	}
+	for _, image := range strings.Split(imagesFlag, ",") {
+		if image = strings.TrimSpace(image); image != "" {
+			cfg.images = append(cfg.images, image)
+		}
+	}
+	if cfg.imageManifest != "" && len(cfg.images) == 0 {
+		return config{}, false, fmt.Errorf("-image-manifest needs the images to pin in -image")
+	}
+	if cfg.check {
+		cfg.dryRun = true
+	}
+	if cfg.assertReadOnly {
+		if cfg.autostash || cfg.announce || cfg.tagPlan != "" {
+			return config{}, false, fmt.Errorf("-assert-read-only can't be combined with -autostash, -announce or -tag-plan")
+		}
+		cfg.dryRun = true
+	}
+	if cfg.tagPlan != "" && (cfg.announce || cfg.noVCS) {
+		return config{}, false, fmt.Errorf("-tag-plan can't be combined with -announce or -no-vcs: the release isn't tagged yet")
+	}
+	if len(cfg.kubeTargets) > 0 && cfg.tagPlan != "" {
+		return config{}, false, fmt.Errorf("-k8s-annotate can't be combined with -tag-plan: the release isn't tagged yet")
+	}
+	if cfg.githubRelease && !cfg.push {
+		return config{}, false, fmt.Errorf("-github-release needs -push: the release is created for the pushed tag")
+	}
+	if cfg.gitlabRelease && !cfg.push {
+		return config{}, false, fmt.Errorf("-gitlab-release needs -push: the release is created for the pushed tag")
+	}
+	if len(cfg.assets) > 0 && !cfg.githubRelease {
+		return config{}, false, fmt.Errorf("-assets needs -github-release: the files are uploaded to the release")
+	}
+	if len(cfg.releaseLinks) > 0 && !cfg.gitlabRelease {
+		return config{}, false, fmt.Errorf("-gitlab-release-links needs -gitlab-release")
+	}
+	if len(cfg.pushOptions) > 0 && !cfg.push {
+		return config{}, false, fmt.Errorf("-push-option needs -push")
+	}
+	if cfg.backMerge && !cfg.push {
+		return config{}, false, fmt.Errorf("-back-merge needs -push: the pull request is opened from the pushed release")
+	}
+	if cfg.changelogTemplate != "" {
+		cfg.changelog = true
+	}
+	if cfg.sshKey != "" {
+		if cfg.signingKey != "" {
+			return config{}, false, fmt.Errorf("-ssh-key can't be combined with -signing-key: one names an SSH key, the other an OpenPGP key")
+		}
+		cfg.sign = true
+	}
+	if cfg.sign && (cfg.tagPlan != "" || cfg.noVCS) {
+		return config{}, false, fmt.Errorf("-sign can't be combined with -tag-plan or -no-vcs: bump doesn't create the tag")
+	}
+	if cfg.push && (cfg.tagPlan != "" || cfg.noVCS) {
+		return config{}, false, fmt.Errorf("-push can't be combined with -tag-plan or -no-vcs: there is no tag to push")
+	}
+	if cfg.stream != "" && (hotfixFlag || cfg.provenance || cfg.noVCS) {
+		return config{}, false, fmt.Errorf("-stream can't be combined with -hotfix, -provenance or -no-vcs")
+	}
+	if cfg.branch != "" && (cfg.noVCS || cfg.autostash || cfg.commitPerModule || hotfixFlag || cfg.autoAPI || cfg.auto || cfg.provenance ||
+		cfg.tagPlan != "" || cfg.announce || cfg.sbom != "" || cfg.releaseNotes != "" || cfg.changelog || cfg.imageManifest != "" || len(cfg.images) > 0 ||
+		cfg.githubRelease || cfg.gitlabRelease || cfg.backMerge) {
+		return config{}, false, fmt.Errorf("-branch can't be combined with -no-vcs, -autostash, -commit-per-module, -hotfix, -auto-api, -auto, -provenance, -tag-plan, -announce, -sbom, -release-notes, -changelog, -image, -github-release, -gitlab-release or -back-merge: they work on the checked-out branch")
+	}
+	if cfg.provenance && cfg.commitPerModule {
+		return config{}, false, fmt.Errorf("cannot set -provenance and -commit-per-module at the same time")
+	}
+	if (len(cfg.include) > 0 || cfg.stagedOnly) && (cfg.noVCS || cfg.branch != "" || cfg.commitPerModule) {
+		return config{}, false, fmt.Errorf("-include and -staged-only can't be combined with -no-vcs, -branch or -commit-per-module")
+	}
+	if cfg.scanSecrets && (cfg.noVCS || cfg.branch != "") {
+		return config{}, false, fmt.Errorf("-scan-secrets can't be combined with -no-vcs or -branch: it scans the checked-out branch")
+	}
+	if strings.HasPrefix(cfg.since, "-") {
+		return config{}, false, fmt.Errorf("invalid -since '%s': must be a tag or commit", cfg.since)
+	}
+	if cfg.sandbox && (cfg.dryRun || cfg.assertReadOnly || cfg.noVCS || cfg.announce || cfg.backMerge || len(cfg.kubeTargets) > 0 || cfg.githubRelease || cfg.gitlabRelease) {
+		return config{}, false, fmt.Errorf("-sandbox can't be combined with -dry-run, -assert-read-only, -no-vcs, -announce, -back-merge, -k8s-annotate, -github-release or -gitlab-release")
+	}
+	switch cfg.commits {
+	case commitsAll, commitsFirstParent, commitsMergesOnly:
+	default:
+		return config{}, false, fmt.Errorf("invalid -commits '%s': must be %s", cfg.commits, commitStrategiesHelp)
+	}
+	switch cfg.tagType {
+	case tagTypeAnnotated:
+	case tagTypeLightweight:
+		if cfg.tagMetadata != "" || cfg.tagMessage != "" || cfg.sbom != "" || len(cfg.images) > 0 || cfg.ownTags || cfg.sign {
+			return config{}, false, fmt.Errorf("-tag-type lightweight can't be combined with -tag-metadata, -tag-message, -sbom, -image, -own-tags or -sign: they need the annotation")
+		}
+	default:
+		return config{}, false, fmt.Errorf("invalid -tag-type '%s': must be %s or %s", cfg.tagType, tagTypeAnnotated, tagTypeLightweight)
+	}
+	if cfg.tagMessage != "" {
+		_, err := parseTagMessage(cfg.tagMessage)
+		if err != nil {
+			return config{}, false, err
+		}
+	}
+	switch cfg.tagMetadata {
+	case "", tagMetadataFormatJSON, tagMetadataFormatYAML:
+	default:
+		return config{}, false, fmt.Errorf("invalid -tag-metadata '%s': must be %s", cfg.tagMetadata, tagMetadataFormatsHelp)
+	}
 	// check that not more than one flag is set:
-	if (patchFlag && minorFlag) || (patchFlag && majorFlag) || (minorFlag && majorFlag) {
+	levels := 0
+	for _, set := range []bool{patchFlag, minorFlag, majorFlag, hotfixFlag} {
+		if set {
+			levels++
+		}
+	}
+	if levels > 1 {
 		return config{}, false, fmt.Errorf("cannot set more than one increment flag at the same time")
 	}
+	if hotfixFlag && cfg.autoAPI {
+		return config{}, false, fmt.Errorf("cannot set -hotfix and -auto-api at the same time")
+	}
+	if hotfixFlag && cfg.auto {
+		return config{}, false, fmt.Errorf("cannot set -hotfix and -auto at the same time")
+	}
 	if patchFlag {
 		cfg.action = incrementPatch
 	}
@@ -391,36 +1460,47 @@ func getConfig(args []string) (config, bool, error) {
 	if majorFlag {
 		cfg.action = incrementMajor
 	}
-	// no action not version given: increment patch
-	if cfg.action == noAction && cfg.version == "" {
+	if hotfixFlag {
+		cfg.action = incrementHotfix
+	}
+	// no action not version given: increment patch, unless the API or the
+	// commits decide
+	if cfg.action == noAction && cfg.version == "" && !cfg.autoAPI && !cfg.auto {
 		cfg.action = incrementPatch
+		cfg.defaultLevel = true
 	}
 	return cfg, false, nil
 }
 
-func updateVersionFiles(repo *git.Repository, cfg config, output io.Writer, newVersion string) error {
+// findVersionFiles returns the repository paths of all .version files,
+// skipping .git and directories excluded by .bumpignore
+func findVersionFiles() ([]string, error) {
 	// Load ignore rules
 	rules, err := loadIgnoreRules(".bumpignore")
 	if err != nil {
-		return fmt.Errorf("failed to load .bumpignore: %w", err)
+		return nil, fmt.Errorf("failed to load .bumpignore: %w", err)
 	}
+	rules = append(append([]ignoreRule{}, defaultIgnoreRules...), rules...)
 
-	// Track if any files were updated
-	filesUpdated := 0
-
+	var files []string
+	scan := budget.startScan("files", 0)
 	// find all the files name ".version"
 	err = filepath.WalkDir(".", func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return fmt.Errorf("failed to walk directory: %w", err)
 		}
+		err = scan.step()
+		if err != nil {
+			return err
+		}
 		if d.IsDir() {
 			// Always skip .git
 			if d.Name() == ".git" {
 				return filepath.SkipDir
 			}
 			// Check user-defined ignore rules
-			// path needs to be cleaned (remove leading ./)
-			cleanPath := strings.TrimPrefix(path, "./")
+			// path needs to be in repository form to match anchored rules
+			cleanPath := repoPath(path)
 			if cleanPath == "." {
 				return nil
 			}
@@ -432,106 +1512,163 @@ func updateVersionFiles(repo *git.Repository, cfg config, output io.Writer, newV
 		if d.Name() != ".version" {
 			return nil
 		}
+		// go-git and the output both want the slash-separated repository path
+		files = append(files, repoPath(path))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory: %w", err)
+	}
+	scan.done()
+	return files, nil
+}
+
+// updateVersionFiles writes the new version to the version files and commits
+// them. trailers, if any, are appended to the commit message.
+func updateVersionFiles(repo vcs, cfg config, output io.Writer, newVersion, reason, trailers string) error {
+	versionFiles, err := findVersionFiles()
+	if err != nil {
+		return err
+	}
+	writeRules, err := loadWorktreeRules()
+	if err != nil {
+		return err
+	}
+
+	// Track which files were updated
+	var updated []string
+
+	for _, relPath := range versionFiles {
+		if writeRules.excluded(relPath) || streamOf(cfg.streams, relPath) != cfg.stream {
+			continue
+		}
+		path := filepath.FromSlash(relPath)
 		// read the content of the file
 		content, err := os.ReadFile(path)
 		if err != nil {
 			return fmt.Errorf("failed to read file: %w", err)
 		}
-		// content must either by empty or a valid semver, if not we return an error
-		trimmedContent := strings.TrimSpace(string(content))
-		if len(trimmedContent) > 0 && !semver.IsValid(normalizeVersion(trimmedContent)) {
-			return fmt.Errorf("invalid version in file %s: '%s'", path, trimmedContent)
+		// content must either by empty or a valid version, if not we return an error
+		trimmedContent := parseVersionFile(content)
+		if _, err := scheme.parse(trimmedContent); len(trimmedContent) > 0 && err != nil {
+			return fmt.Errorf("invalid version in file %s: '%s'", relPath, trimmedContent)
 		}
 		// print the action to the output.
-		_, _ = fmt.Fprintf(output, "Updating version in file %s to %s\n", path, newVersion)
-
-		filesUpdated++
+		_, _ = fmt.Fprintf(output, "Updating version in file %s to %s\n", relPath, newVersion)
+		cfg.eventSink.emit(progressEvent{Phase: phaseUpdateFiles, Version: newVersion, File: relPath})
+		if cfg.fixEOL && needsEOLFix(content) {
+			_, _ = fmt.Fprintf(output, "Normalizing line endings of %s\n", relPath)
+		}
 
+		newContent := versionFileContent(cfg, writeRules, relPath, content, newVersion)
 		if cfg.dryRun {
-			return nil // return early if we are in dry-run mode
+			// skip writing if we are in dry-run mode, showing the change instead
+			err = previewFile(output, relPath, newContent)
+			if err != nil {
+				return err
+			}
+			continue
 		}
-		// write the new version to the file
-		err = os.WriteFile(path, []byte(newVersion), 0644)
+		err = os.WriteFile(path, newContent, 0644)
 		if err != nil {
 			return fmt.Errorf("failed to write file: %w", err)
 		}
-		// add the file to the repository
-		err = add(repo, path)
-		if err != nil {
-			return fmt.Errorf("failed to add file: %w", err)
-		}
-		return nil
-	})
+		updated = append(updated, relPath)
+	}
+
+	included, err := includedFiles(repo, cfg, updated)
 	if err != nil {
-		return fmt.Errorf("failed to walk directory: %w", err)
+		return err
+	}
+	for _, file := range included {
+		_, _ = fmt.Fprintf(output, "Including %s in the release commit\n", file)
 	}
 
 	// Only commit if not in dry-run mode and files were actually updated
-	if !cfg.dryRun && filesUpdated > 0 {
-		// commit the changes
-		err = commit(repo, fmt.Sprintf("bump version to %s", newVersion))
+	if cfg.dryRun || len(updated)+len(included) == 0 {
+		return nil
+	}
+	cfg.eventSink.emit(progressEvent{Phase: phaseCommit, Version: newVersion})
+	if cfg.commitPerModule {
+		return commitModules(repo, cfg, updated, newVersion, reason)
+	}
+	updated = append(updated, included...)
+	for _, path := range updated {
+		// add the file to the repository
+		err = repo.add(path)
 		if err != nil {
-			return fmt.Errorf("commit: %w", err)
+			return fmt.Errorf("failed to add file: %w", err)
 		}
 	}
+	// commit the changes
+	body := ""
+	if cfg.commitBody {
+		body = commitBody(reason, updated)
+	}
+	err = repo.commit(commitMessage(cfg.skipCI, fmt.Sprintf("bump version to %s", newVersion), body, trailers))
+	if err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
 	return nil
 }
 
-func incrementVersion(currentVersion string, cfg config) (string, error) {
-	// Detect if the current version uses "v" prefix
-	useVPrefix := hasVPrefix(currentVersion)
-
-	// Strip "v" prefix for parsing
-	versionToParse := stripVPrefix(currentVersion)
-
-	parts := strings.Split(versionToParse, ".")
-	if len(parts) != 3 {
-		return "", fmt.Errorf("invalid version format: %s", currentVersion)
+// moduleName returns the name of the module owning the given .version file,
+// which is the name of the directory it lives in. The root module has no name.
+func moduleName(versionFile string) string {
+	dir := path.Dir(versionFile)
+	if dir == "." {
+		return ""
 	}
+	return path.Base(dir)
+}
 
-	var major, minor, patch int
-	_, err := fmt.Sscanf(versionToParse, "%d.%d.%d", &major, &minor, &patch)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse current version('%s'): %w", currentVersion, err)
-	}
-	switch cfg.action {
-	case incrementPatch:
-		patch++
-	case incrementMinor:
-		minor++
-		patch = 0
-	case incrementMajor:
-		major++
-		minor = 0
-		patch = 0
-	default:
-		return "", fmt.Errorf("invalid action: %d", cfg.action)
+// commitModules creates a separate commit for every updated version file so
+// each module in a monorepo gets its own history entry.
+func commitModules(repo vcs, cfg config, updated []string, newVersion, reason string) error {
+	for _, path := range updated {
+		err := repo.add(path)
+		if err != nil {
+			return fmt.Errorf("failed to add file: %w", err)
+		}
+		message := fmt.Sprintf("bump version to %s", newVersion)
+		if name := moduleName(path); name != "" {
+			message = fmt.Sprintf("chore(%s): bump to %s", name, newVersion)
+		}
+		body := ""
+		if cfg.commitBody {
+			body = commitBody(reason, []string{path})
+		}
+		err = repo.commit(commitMessage(cfg.skipCI, message, body, ""))
+		if err != nil {
+			return fmt.Errorf("commit %s: %w", path, err)
+		}
 	}
+	return nil
+}
 
-	// Return version in the same format as input
-	if useVPrefix {
-		return fmt.Sprintf("v%d.%d.%d", major, minor, patch), nil
+func incrementVersion(currentVersion string, cfg config) (string, error) {
+	// A hotfix doesn't consume the patch number it is a prerelease of
+	if base, ok := hotfixBase(currentVersion); ok {
+		if cfg.action == incrementPatch {
+			return base, nil
+		}
+		currentVersion = base
 	}
-	return fmt.Sprintf("%d.%d.%d", major, minor, patch), nil
+	return scheme.increment(currentVersion, cfg)
 }
 
-func tagVersion(repo *git.Repository, cfg config, version string) (string, error) {
-	// find the current commit
-	head, err := repo.Head()
-	if err != nil {
-		return "", fmt.Errorf("failed to get HEAD: %w", err)
-	}
-	opts := &git.CreateTagOptions{
-		Message: "tag created by bump",
-	}
+func tagVersion(repo vcs, cfg config, version, message string) (string, error) {
 	if cfg.dryRun {
-		return head.Hash().String(), nil
-	}
-	ref, err := repo.CreateTag(version, head.Hash(), opts)
-	if err != nil {
-		return "", fmt.Errorf("failed to create tag: %w", err)
+		// find the current commit
+		return repo.head()
 	}
-	return ref.Hash().String(), nil
+	return repo.createTag(version, message)
+}
+
+// repoPath converts an OS-specific path relative to the repository root into
+// the slash-separated form used by git and go-git's index.
+func repoPath(path string) string {
+	return filepath.ToSlash(filepath.Clean(path))
 }
 
 // add adds the file at the given path to the repository
@@ -547,12 +1684,17 @@ func add(repo *git.Repository, path string) error {
 	return nil
 }
 
-func commit(repo *git.Repository, message string) error {
+// commit commits the staged changes, dated now in loc (see signatures).
+func commit(repo *git.Repository, message string, loc *time.Location) error {
 	w, err := repo.Worktree()
 	if err != nil {
 		return fmt.Errorf("repo.Worktree: %w", err)
 	}
-	_, err = w.Commit(message, &git.CommitOptions{})
+	author, committer, err := signatures(repo, loc)
+	if err != nil {
+		return err
+	}
+	_, err = w.Commit(message, &git.CommitOptions{Author: author, Committer: committer})
 	if err != nil {
 		return fmt.Errorf("worktree.Commit: %w", err)
 	}
@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
@@ -381,6 +383,49 @@ func TestLastTag(t *testing.T) {
 	}
 }
 
+func TestNestedTags(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	released := mustHead(t, repo)
+	opts := &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+		Message: "release",
+	}
+	inner, err := repo.CreateTag("release-candidate", released, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// v1.1.0 is a tag object pointing to the tag object of release-candidate
+	_, err = repo.CreateTag("v1.1.0", inner.Hash(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, repo, "Fix a bug", map[string]string{"fix.txt": "fixed"})
+
+	commit, err := tagCommit(repo, "v1.1.0")
+	if err != nil {
+		t.Fatalf("Expected the tag chain to be peeled, got: %v", err)
+	}
+	if commit.Hash != released {
+		t.Errorf("Got commit %s, want %s", commit.Hash, released)
+	}
+	commits, err := commitsSince(repo, "v1.1.0", commitsAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 1 {
+		t.Errorf("Expected 1 commit since the nested tag, got %d", len(commits))
+	}
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{}, nil)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	if !strings.Contains(output.String(), "Bumped version v1.1.0 --> v1.1.1") {
+		t.Errorf("Expected a bump from the nested tag, got:\n%s", output.String())
+	}
+}
+
 func TestIncrementVersion(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -1227,7 +1272,7 @@ func TestUpdateVersionFiles(t *testing.T) {
 			// Call updateVersionFiles
 			var output bytes.Buffer
 			cfg := config{dryRun: tt.dryRun}
-			err = updateVersionFiles(repo, cfg, &output, tt.newVersion)
+			err = updateVersionFiles(&gitVCS{repo: repo}, cfg, &output, tt.newVersion, "", "")
 
 			// Check error
 			if (err != nil) != tt.wantErr {
@@ -1274,3 +1319,301 @@ func TestUpdateVersionFiles(t *testing.T) {
 		})
 	}
 }
+
+func TestRepoPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "root file", path: ".version", want: ".version"},
+		{name: "leading dot", path: filepath.Join(".", ".version"), want: ".version"},
+		{name: "nested file", path: filepath.Join("foo", "bar", ".version"), want: "foo/bar/.version"},
+		{name: "root directory", path: ".", want: "."},
+		{name: "nested directory", path: filepath.Join("vendor", "pkg"), want: "vendor/pkg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := repoPath(tt.path); got != tt.want {
+				t.Errorf("repoPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateVersionFilesCommitPerModule(t *testing.T) {
+	tempDir, repo := setupTestRepo(t)
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(originalDir)
+
+	err = os.Chdir(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{".version", "ledger/.version", "services/payments/.version"} {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, []byte("v2.0.0"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	commitsBefore := countCommits(t, repo)
+
+	var output bytes.Buffer
+	cfg := config{commitPerModule: true}
+	err = updateVersionFiles(&gitVCS{repo: repo}, cfg, &output, "v2.1.0", "", "")
+	if err != nil {
+		t.Fatalf("updateVersionFiles() error = %v", err)
+	}
+
+	commitsAfter := countCommits(t, repo)
+	if commitsAfter != commitsBefore+3 {
+		t.Fatalf("Expected 3 new commits, but commit count changed from %d to %d", commitsBefore, commitsAfter)
+	}
+
+	// Messages are listed newest first
+	want := []string{
+		"chore(payments): bump to v2.1.0",
+		"chore(ledger): bump to v2.1.0",
+		"bump version to v2.1.0",
+	}
+	got := commitMessages(t, repo, len(want))
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("commit %d: got message %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	// Every commit must only carry its own module's file
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := headCommit.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].Name != "services/payments/.version" {
+		t.Errorf("Expected HEAD to only change services/payments/.version, got %v", stats)
+	}
+}
+
+func TestModuleName(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: ".version", want: ""},
+		{path: "payments/.version", want: "payments"},
+		{path: "services/payments/.version", want: "payments"},
+	}
+
+	for _, tt := range tests {
+		if got := moduleName(tt.path); got != tt.want {
+			t.Errorf("moduleName(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+// commitMessages returns the first n commit messages reachable from HEAD,
+// newest first, with trailing whitespace removed
+func commitMessages(t *testing.T, repo *git.Repository, n int) []string {
+	ref, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cIter, err := repo.Log(&git.LogOptions{From: ref.Hash()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var messages []string
+	for len(messages) < n {
+		c, err := cIter.Next()
+		if err != nil {
+			break
+		}
+		messages = append(messages, strings.TrimSpace(c.Message))
+	}
+	return messages
+}
+
+// setupTaggedTestRepo creates a test repository with a committed .version
+// file, tags it with the given version and adds one more commit on top so
+// there is something to release. The working directory is changed to the
+// repository for the duration of the test.
+func setupTaggedTestRepo(t *testing.T, version string) (string, *git.Repository) {
+	tempDir, repo := setupTestRepo(t)
+	t.Chdir(tempDir)
+
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := &object.Signature{
+		Name:  "Test User",
+		Email: "test@example.com",
+		When:  time.Now(),
+	}
+
+	err = os.WriteFile(".version", []byte(version), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = w.Add(".version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := w.Commit("Add initial version file", &git.CommitOptions{Author: signature})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.CreateTag(version, hash, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile("feature.txt", []byte("new feature"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = w.Add("feature.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = w.Commit("Add new feature", &git.CommitOptions{Author: signature})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tempDir, repo
+}
+
+// mustHead returns the hash of the current commit
+func mustHead(t *testing.T, repo *git.Repository) plumbing.Hash {
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return head.Hash()
+}
+
+func TestBumpSince(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	base := mustHead(t, repo)
+	commitFiles(t, repo, "Add second feature", map[string]string{"second.txt": "second"})
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-minor", "-tag-metadata", "json", "-since", base.String()}, nil)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v", err)
+	}
+	meta, _, err := parseTagMetadata(tagMessageOf(t, repo, "v1.1.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := newTagMetadata(config{action: incrementMinor}, "v1.0.0", "v1.1.0", []string{"Add second feature"})
+	if meta != want {
+		t.Errorf("Expected only the changes since %s, got metadata %+v, want %+v", base, meta, want)
+	}
+
+	err = run(context.Background(), &output, []string{"-force", "-since", "no-such-rev"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "failed to resolve 'no-such-rev'") {
+		t.Errorf("Expected an unknown -since to fail, got: %v", err)
+	}
+}
+
+func TestCommitStrategies(t *testing.T) {
+	requireGit(t)
+	tempDir, repo := setupTaggedTestRepo(t, "v1.0.0")
+	feature := mustHead(t, repo)
+	commitFiles(t, repo, "Add knobs", map[string]string{"knobs.txt": "knobs"})
+	knobs := mustHead(t, repo)
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Reset(&git.ResetOptions{Commit: feature, Mode: git.HardReset})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, repo, "Fix docs", map[string]string{"docs.txt": "docs"})
+	_, err = w.Commit("Merge branch 'knobs'", &git.CommitOptions{
+		Author:            &object.Signature{Name: "Test User", Email: "test@example.com", When: time.Now()},
+		Parents:           []plumbing.Hash{mustHead(t, repo), knobs},
+		AllowEmptyCommits: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cli, err := openGitBackend(tempDir, backendCLI, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		commitsAll:         "Add knobs|Add new feature|Fix docs|Merge branch 'knobs'",
+		commitsFirstParent: "Add new feature|Fix docs|Merge branch 'knobs'",
+		commitsMergesOnly:  "Merge branch 'knobs'",
+	}
+	for _, backend := range []vcs{&gitVCS{repo: repo}, cli} {
+		for strategy, changes := range want {
+			got, err := backend.changesSince("v1.0.0", strategy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(got)
+			if strings.Join(got, "|") != changes {
+				t.Errorf("%s, %s: got changes %q, want %q", backend.name(), strategy, strings.Join(got, "|"), changes)
+			}
+		}
+	}
+
+	_, _, err = getConfig([]string{"-commits", "squash"})
+	if err == nil || !strings.Contains(err.Error(), "invalid -commits 'squash'") {
+		t.Errorf("Expected an invalid strategy to be refused, got: %v", err)
+	}
+}
+
+func TestBumpEmptyRelease(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	var output bytes.Buffer
+	err := run(context.Background(), &output, nil, nil)
+	if err != nil {
+		t.Fatalf("bump: %v", err)
+	}
+	commits := countCommits(t, repo)
+
+	// nothing was committed since v1.0.1, explicit versions included
+	for _, args := range [][]string{nil, {"-version", "v2.0.0"}} {
+		err = run(context.Background(), &output, args, nil)
+		if err == nil || !strings.Contains(err.Error(), "no changes since last version tag 'v1.0.1' (use -allow-empty-release") {
+			t.Fatalf("%v: expected an empty release to be refused, got: %v", args, err)
+		}
+	}
+	if countCommits(t, repo) != commits {
+		t.Fatal("A refused release was committed")
+	}
+
+	err = run(context.Background(), &output, []string{"-version", "v2.0.0", "-allow-empty-release"}, nil)
+	if err != nil {
+		t.Fatalf("Expected -allow-empty-release to release, got: %v", err)
+	}
+	if exists, _ := tagExists(repo, "v2.0.0"); !exists {
+		t.Error("Expected tag v2.0.0")
+	}
+}
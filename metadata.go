@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const (
+	defaultTagMessage      = "tag created by bump"
+	metadataBegin          = "-----BEGIN BUMP METADATA-----"
+	metadataEnd            = "-----END BUMP METADATA-----"
+	tagMetadataFormatJSON  = "json"
+	tagMetadataFormatYAML  = "yaml"
+	tagMetadataFormatsHelp = "json or yaml"
+	tagTypeAnnotated       = "annotated"
+	tagTypeLightweight     = "lightweight"
+)
+
+// ownTagTrailer marks the tags bump annotated with a -tag-message, which
+// don't start with defaultTagMessage, for -own-tags.
+const ownTagTrailer = "Tagged-By: bump"
+
+// tagMetadata is the structured release information bump can embed in the
+// annotated tag message, so tooling can reconstruct a release from the tag
+// object alone.
+type tagMetadata struct {
+	Version     string `json:"version"`
+	Previous    string `json:"previous,omitempty"`
+	Level       string `json:"level"`
+	Train       string `json:"train,omitempty"`
+	SBOMSHA256  string `json:"sbom_sha256,omitempty"`
+	NotesSHA256 string `json:"notes_sha256"`
+	Tool        string `json:"tool"`
+	ToolVersion string `json:"tool_version"`
+}
+
+// String returns the bump level the action stands for.
+func (a action) String() string {
+	switch a {
+	case incrementPatch:
+		return "patch"
+	case incrementMinor:
+		return "minor"
+	case incrementMajor:
+		return "major"
+	case incrementHotfix:
+		return "hotfix"
+	}
+	return "set"
+}
+
+// newTagMetadata describes a release. The notes hash covers the change list
+// that announcements and changelogs are generated from.
+func newTagMetadata(cfg config, previous, version string, changes []string) tagMetadata {
+	level := cfg.action.String()
+	if cfg.version != "" {
+		level = "set"
+	}
+	notes := sha256.Sum256([]byte(strings.Join(changes, "\n")))
+	return tagMetadata{
+		Version:     version,
+		Previous:    previous,
+		Level:       level,
+		NotesSHA256: hex.EncodeToString(notes[:]),
+		Tool:        "bump",
+		ToolVersion: strings.TrimSpace(embeddedVersion),
+	}
+}
+
+// tagMessage returns the annotation for the tag, with the metadata appended
+// as a delimited block in the requested format if one is set. The release
+// train, the SBOM digest and the pinned container images are recorded as
+// trailers in any case; the images only there, as the flat YAML block can't
+// hold a list. The annotation starts with defaultTagMessage, or with the
+// -tag-message template rendered against the metadata and followed by the
+// ownTagTrailer.
+func tagMessage(text, format string, meta tagMetadata, images []string) (string, error) {
+	var trailers []string
+	if meta.Train != "" {
+		trailers = append(trailers, "Release-Train: "+meta.Train)
+	}
+	if meta.SBOMSHA256 != "" {
+		trailers = append(trailers, "SBOM-SHA256: "+meta.SBOMSHA256)
+	}
+	for _, image := range images {
+		trailers = append(trailers, "Image: "+image)
+	}
+	header := defaultTagMessage
+	if text != "" {
+		trailers = append(trailers, ownTagTrailer)
+		tmpl, err := parseTagMessage(text)
+		if err != nil {
+			return "", err
+		}
+		var buf bytes.Buffer
+		err = tmpl.Execute(&buf, meta)
+		if err != nil {
+			return "", fmt.Errorf("failed to render -tag-message: %w", err)
+		}
+		header = strings.TrimSpace(buf.String())
+	}
+	if len(trailers) > 0 {
+		header += "\n\n" + strings.Join(trailers, "\n")
+	}
+	var block string
+	switch format {
+	case "":
+		return header, nil
+	case tagMetadataFormatJSON:
+		encoded, err := json.MarshalIndent(meta, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to encode tag metadata: %w", err)
+		}
+		block = string(encoded)
+	case tagMetadataFormatYAML:
+		block = metadataYAML(meta)
+	default:
+		return "", fmt.Errorf("invalid tag metadata format '%s': must be %s", format, tagMetadataFormatsHelp)
+	}
+	return fmt.Sprintf("%s\n\n%s\n%s\n%s\n", header, metadataBegin, block, metadataEnd), nil
+}
+
+// parseTagMessage parses the -tag-message template.
+func parseTagMessage(text string) (*template.Template, error) {
+	tmpl, err := template.New("tag message").Funcs(templateFuncs(nil)).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -tag-message: %w", err)
+	}
+	return tmpl, nil
+}
+
+// metadataYAML renders the metadata as a flat YAML mapping using the same keys
+// as the JSON encoding.
+func metadataYAML(meta tagMetadata) string {
+	encoded, _ := json.Marshal(meta)
+	var fields map[string]string
+	_ = json.Unmarshal(encoded, &fields)
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		// JSON strings are valid YAML double-quoted scalars
+		value, _ := json.Marshal(fields[key])
+		lines = append(lines, fmt.Sprintf("%s: %s", key, value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseTagMetadata extracts the metadata block from a tag message. It returns
+// false if the message carries no metadata.
+func parseTagMetadata(message string) (tagMetadata, bool, error) {
+	start := strings.Index(message, metadataBegin)
+	end := strings.Index(message, metadataEnd)
+	if start < 0 || end < start {
+		return tagMetadata{}, false, nil
+	}
+	block := strings.TrimSpace(message[start+len(metadataBegin) : end])
+
+	var meta tagMetadata
+	if strings.HasPrefix(block, "{") {
+		err := json.Unmarshal([]byte(block), &meta)
+		if err != nil {
+			return tagMetadata{}, true, fmt.Errorf("invalid JSON metadata: %w", err)
+		}
+		return meta, true, nil
+	}
+
+	fields := make(map[string]string)
+	for _, line := range strings.Split(block, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return tagMetadata{}, true, fmt.Errorf("invalid YAML metadata line '%s'", line)
+		}
+		var decoded string
+		err := json.Unmarshal([]byte(strings.TrimSpace(value)), &decoded)
+		if err != nil {
+			decoded = strings.TrimSpace(value)
+		}
+		fields[strings.TrimSpace(key)] = decoded
+	}
+	encoded, _ := json.Marshal(fields)
+	err := json.Unmarshal(encoded, &meta)
+	if err != nil {
+		return tagMetadata{}, true, fmt.Errorf("invalid YAML metadata: %w", err)
+	}
+	return meta, true, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// tagMessageOf returns the annotation of the named tag
+func tagMessageOf(t *testing.T, repo *git.Repository, name string) string {
+	ref, err := repo.Tag(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagObj, err := repo.TagObject(ref.Hash())
+	if err != nil {
+		t.Fatalf("Expected %s to be an annotated tag: %v", name, err)
+	}
+	return tagObj.Message
+}
+
+func TestBumpWithTagMetadata(t *testing.T) {
+	for _, format := range []string{"json", "yaml"} {
+		t.Run(format, func(t *testing.T) {
+			_, repo := setupTaggedTestRepo(t, "v1.0.0")
+
+			var output bytes.Buffer
+			err := run(context.Background(), &output, []string{"-minor", "-tag-metadata", format}, nil)
+			if err != nil {
+				t.Fatalf("Expected bump to succeed, got: %v", err)
+			}
+
+			message := tagMessageOf(t, repo, "v1.1.0")
+			if !strings.HasPrefix(message, defaultTagMessage) {
+				t.Errorf("Expected message to start with %q, got %q", defaultTagMessage, message)
+			}
+			meta, ok, err := parseTagMetadata(message)
+			if err != nil || !ok {
+				t.Fatalf("parseTagMetadata() = %v, %v for message:\n%s", ok, err, message)
+			}
+			want := newTagMetadata(config{action: incrementMinor}, "v1.0.0", "v1.1.0", []string{"Add new feature"})
+			if meta != want {
+				t.Errorf("Got metadata %+v, want %+v", meta, want)
+			}
+		})
+	}
+}
+
+func TestTagMessageWithoutMetadata(t *testing.T) {
+	message, err := tagMessage("", "", tagMetadata{Version: "v1.0.0"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message != defaultTagMessage {
+		t.Errorf("Expected plain message, got %q", message)
+	}
+	_, ok, err := parseTagMetadata(message)
+	if ok || err != nil {
+		t.Errorf("Expected no metadata in plain message, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestInvalidTagMetadataFormat(t *testing.T) {
+	_, _, err := getConfig([]string{"-tag-metadata", "xml"})
+	if err == nil || !strings.Contains(err.Error(), "invalid -tag-metadata 'xml'") {
+		t.Errorf("Expected invalid format error, got: %v", err)
+	}
+}
+
+func TestBumpTagType(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-minor", "-tag-message", "Release {{.Version}} ({{.Level}} after {{.Previous}})", "-tag-metadata", "json"}, nil)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v", err)
+	}
+	message := tagMessageOf(t, repo, "v1.1.0")
+	if !strings.HasPrefix(message, "Release v1.1.0 (minor after v1.0.0)\n\n"+ownTagTrailer+"\n\n"+metadataBegin) {
+		t.Errorf("Expected the custom message before the metadata, got %q", message)
+	}
+
+	commitFiles(t, repo, "Fix", map[string]string{"fix.txt": "fix"})
+	err = run(context.Background(), &output, []string{"-tag-type", "lightweight"}, nil)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v", err)
+	}
+	ref, err := repo.Tag("v1.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref.Hash() != mustHead(t, repo) {
+		t.Errorf("Expected a lightweight tag pointing at the release commit, got %s", ref.Hash())
+	}
+
+	for args, want := range map[string]string{
+		"-tag-type signed":                         "invalid -tag-type 'signed'",
+		"-tag-type lightweight -tag-metadata json": "-tag-type lightweight can't be combined",
+		"-tag-message {{.Version":                  "invalid -tag-message",
+	} {
+		err = run(context.Background(), &output, strings.Fields(args), nil)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected error %q, got %v", args, want, err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// module is a directory holding a .version file. In a monorepo every module
+// is versioned by its own .version file; the root module is the repository
+// itself.
+type module struct {
+	Name string `json:"name"` // directory name, empty for the root module
+	Path string `json:"path"` // repository path of the directory, "." for the root module
+}
+
+// findModules returns the modules of the repository, sorted by path.
+func findModules() ([]module, error) {
+	files, err := findVersionFiles()
+	if err != nil {
+		return nil, err
+	}
+	modules := make([]module, 0, len(files))
+	for _, file := range files {
+		modules = append(modules, module{Name: moduleName(file), Path: path.Dir(file)})
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Path < modules[j].Path })
+	return modules, nil
+}
+
+// moduleFor returns the innermost module containing the repository path.
+func moduleFor(modules []module, file string) (module, bool) {
+	var owner module
+	found := false
+	for _, m := range modules {
+		if m.Path != "." && file != m.Path && !strings.HasPrefix(file, m.Path+"/") {
+			continue
+		}
+		if !found || owner.Path == "." || len(m.Path) > len(owner.Path) {
+			owner, found = m, true
+		}
+	}
+	return owner, found
+}
+
+// resolveCommit resolves a tag name or any other revision to a commit.
+func resolveCommit(repo *git.Repository, rev string) (*object.Commit, error) {
+	commit, err := tagCommit(repo, rev)
+	if err == nil {
+		return commit, nil
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve '%s': %w", rev, err)
+	}
+	return repo.CommitObject(*hash)
+}
+
+// changedFiles returns the repository paths that differ between the base
+// commit and HEAD, sorted.
+func changedFiles(repo *git.Repository, base *object.Commit) ([]string, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+	baseTree, err := base.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree of %s: %w", base.Hash, err)
+	}
+	headTree, err := headCommit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree of HEAD: %w", err)
+	}
+	changes, err := object.DiffTree(baseTree, headTree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff trees: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	for _, change := range changes {
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			if name != "" && !seen[name] {
+				seen[name] = true
+				files = append(files, name)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
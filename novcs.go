@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/mod/semver"
+)
+
+// fsVCS is the vcs used with -no-vcs. There is no repository: the current
+// version is read from the root .version file and all repository operations
+// are no-ops, so only the version files are rewritten.
+type fsVCS struct{}
+
+func (fsVCS) name() string {
+	return "no-vcs"
+}
+
+func (fsVCS) dirtyFiles() (map[string]string, error) {
+	return nil, nil
+}
+
+func (fsVCS) branch() (string, error) {
+	return "", nil
+}
+
+// lastTag returns the version in the root .version file.
+func (fsVCS) lastTag() (string, error) {
+	content, err := os.ReadFile(".version")
+	if errors.Is(err, os.ErrNotExist) {
+		return "", noVersionError("no .version file found in the current directory")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read .version: %w", err)
+	}
+	version := parseVersionFile(content)
+	if version == "" {
+		return "", noVersionError(".version is empty, use -version to set the initial version")
+	}
+	if !semver.IsValid(normalizeVersion(version)) {
+		return "", fmt.Errorf("invalid version in file .version: '%s'", version)
+	}
+	return version, nil
+}
+
+func (fsVCS) tagExists(string) (bool, error) {
+	return false, nil
+}
+
+// hasChangesSince can't know about changes without history and assumes there
+// are some.
+func (fsVCS) hasChangesSince(string) (bool, error) {
+	return true, nil
+}
+
+func (fsVCS) changesSince(string, string) ([]string, error) {
+	return nil, nil
+}
+
+func (fsVCS) add(string) error {
+	return nil
+}
+
+func (fsVCS) commit(string) error {
+	return nil
+}
+
+func (fsVCS) createTag(string, string) (string, error) {
+	return "", nil
+}
+
+func (fsVCS) head() (string, error) {
+	return "", nil
+}
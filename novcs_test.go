@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBumpWithoutVCS(t *testing.T) {
+	t.Chdir(t.TempDir())
+	files := map[string]string{
+		".version":     "1.4.2\n",
+		"api/.version": "1.4.2\n",
+	}
+	err := os.MkdirAll("api", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range files {
+		err = os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-no-vcs", "-minor"}, nil)
+	if err != nil {
+		t.Fatalf("Expected bump without vcs to succeed, got: %v", err)
+	}
+	for path := range files {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != "1.5.0\n" {
+			t.Errorf("File %s: got %q, want %q", path, string(content), "1.5.0\n")
+		}
+	}
+	if !strings.Contains(output.String(), "Bumped version 1.4.2 --> 1.5.0\n") {
+		t.Errorf("Unexpected output: %s", output.String())
+	}
+	_, err = os.Stat(".git")
+	if !os.IsNotExist(err) {
+		t.Errorf("Expected no repository to be created")
+	}
+}
+
+func TestBumpWithoutVCSNeedsVersionFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-no-vcs"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "no .version file found") {
+		t.Fatalf("Expected missing .version error, got: %v", err)
+	}
+
+	// An explicit version works without a current one
+	err = os.WriteFile(".version", []byte(""), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = run(context.Background(), &output, []string{"-no-vcs", "-version", "v0.1.0"}, nil)
+	if err != nil {
+		t.Fatalf("Expected -version to work without vcs, got: %v", err)
+	}
+	content, err := os.ReadFile(".version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "v0.1.0" {
+		t.Errorf("Expected .version to be v0.1.0, got %q", string(content))
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// ownTagsVCS restricts the version stream to tags created by bump, so version
+// tags imported from an upstream fork or mirror can't hijack it. A tag is
+// bump's if it is annotated with bump's message or its ownTagTrailer, or if
+// it was made by the configured tagger.
+type ownTagsVCS struct {
+	vcs
+	repo   *git.Repository
+	tagger string // email address, optional
+}
+
+// withOwnTags wraps repo for -own-tags. The tagger comes from BUMP_TAGGER.
+func withOwnTags(repo vcs, env []string) (vcs, error) {
+	gitRepo, err := gitRepository(repo, "-own-tags")
+	if err != nil {
+		return nil, err
+	}
+	return ownTagsVCS{vcs: repo, repo: gitRepo, tagger: getenv(env, "BUMP_TAGGER")}, nil
+}
+
+func (o ownTagsVCS) goGit() *git.Repository {
+	return o.repo
+}
+
+// keep reports whether the tag was created by bump. Lightweight tags carry no
+// message or tagger and never are.
+func (o ownTagsVCS) keep(ref *plumbing.Reference) bool {
+	tag, err := o.repo.TagObject(ref.Hash())
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(tag.Message, defaultTagMessage) || slices.Contains(strings.Split(tag.Message, "\n"), ownTagTrailer) {
+		return true
+	}
+	return o.tagger != "" && strings.EqualFold(tag.Tagger.Email, o.tagger)
+}
+
+// versionTags returns the version tags created by bump.
+func (o ownTagsVCS) versionTags() ([]versionTag, error) {
+	tags, err := allVersionTags(o.repo)
+	if err != nil {
+		return nil, err
+	}
+	var own []versionTag
+	for _, tag := range tags {
+		if o.keep(tag.ref) {
+			own = append(own, tag)
+		}
+	}
+	return own, nil
+}
+
+// lastTag returns the highest version tag created by bump.
+func (o ownTagsVCS) lastTag() (string, error) {
+	tags, err := o.versionTags()
+	if err != nil {
+		return "", err
+	}
+	return highestVersion(tagVersions(tags))
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestOwnTags(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		env         []string
+		ownTag      bool
+		want        string
+		errContains string
+	}{
+		{name: "all tags", args: []string{}, ownTag: true, want: "v3.1.1"},
+		{name: "own tags", args: []string{"-own-tags"}, ownTag: true, want: "v1.2.1"},
+		{name: "own tags with tagger", args: []string{"-own-tags"}, env: []string{"BUMP_TAGGER=Upstream@example.com"}, ownTag: true, want: "v3.1.1"},
+		{name: "no own tags", args: []string{"-own-tags"}, errContains: "no version tags found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// v1.0.0 is a lightweight tag, v3.1.0 was imported from upstream
+			_, repo := setupTaggedTestRepo(t, "v1.0.0")
+			_, err := repo.CreateTag("v3.1.0", mustHead(t, repo), &git.CreateTagOptions{
+				Message: "Release 3.1.0",
+				Tagger:  &object.Signature{Name: "Upstream", Email: "upstream@example.com", When: time.Now()},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.ownTag {
+				_, err = repo.CreateTag("v1.2.0", mustHead(t, repo), &git.CreateTagOptions{Message: defaultTagMessage})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			commitFiles(t, repo, "Fix bug", map[string]string{"fix.txt": "fixed"})
+
+			var output bytes.Buffer
+			err = run(context.Background(), &output, tt.args, tt.env)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("Expected error containing %q, got: %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+			}
+			version, err := os.ReadFile(".version")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(version) != tt.want {
+				t.Errorf("Expected version %s, got %s", tt.want, version)
+			}
+		})
+	}
+}
+
+func TestOwnTagsNeedsGit(t *testing.T) {
+	t.Chdir(t.TempDir())
+	err := os.WriteFile(".version", []byte("v1.0.0"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-no-vcs", "-own-tags"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "-own-tags is only supported for git") {
+		t.Errorf("Expected unsupported error, got: %v", err)
+	}
+}
+
+func TestOwnTagsWithTagMessage(t *testing.T) {
+	dir, repo := setupTestRepo(t)
+	t.Chdir(dir)
+	args := []string{"-own-tags", "-tag-message", "Release {{.Version}}"}
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, append([]string{"-version", "v1.0.0"}, args...), nil)
+	if err != nil {
+		t.Fatalf("Expected the first release to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	commitFiles(t, repo, "Fix bug", map[string]string{"fix.txt": "fixed"})
+	err = run(context.Background(), &output, append([]string{"-patch"}, args...), nil)
+	if err != nil {
+		t.Fatalf("Expected the tag with a custom message to be bump's, got: %v\nOutput: %s", err, output.String())
+	}
+	if message := tagMessageOf(t, repo, "v1.0.1"); message != "Release v1.0.1\n\n"+ownTagTrailer+"\n" {
+		t.Errorf("Expected the message with the trailer, got %q", message)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// packageUpdater rewrites the version of a distro packaging file in place,
+// leaving everything else as it is.
+type packageUpdater func(content []byte, version string) ([]byte, error)
+
+// packageUpdaters are the updaters .bumppackages can select.
+var packageUpdaters = map[string]packageUpdater{
+	"apkbuild": updateAPKBUILD,
+	"pkgbuild": updatePKGBUILD,
+}
+
+// packageFile is a packaging file updated with the release.
+type packageFile struct {
+	updater string
+	path    string // repository path
+	content []byte
+}
+
+// loadPackages reads the packaging files to update. The format is line
+// based, an updater and the file it updates:
+//
+//	apkbuild  packaging/alpine/APKBUILD
+//	pkgbuild  packaging/arch/PKGBUILD
+func loadPackages(file string) ([]packageFile, error) {
+	content, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []packageFile
+	for i, line := range strings.Split(string(content), "\n") {
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected '<updater> <file>'", i+1)
+		}
+		f := packageFile{updater: fields[0], path: path.Clean(fields[1])}
+		if _, ok := packageUpdaters[f.updater]; !ok {
+			return nil, fmt.Errorf("line %d: unknown updater '%s': must be apkbuild or pkgbuild", i+1, f.updater)
+		}
+		if f.path == "." || path.IsAbs(f.path) || f.path == ".." || strings.HasPrefix(f.path, "../") {
+			return nil, fmt.Errorf("line %d: %s is not a file in the repository", i+1, f.path)
+		}
+		for _, other := range files {
+			if other.path == f.path {
+				return nil, fmt.Errorf("line %d: %s is already listed", i+1, f.path)
+			}
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// updatePackages computes the packaging files of .bumppackages for the
+// release. Like renderGenerated it runs before anything is written, so a
+// version a package format can't express aborts the bump.
+func updatePackages(version string) ([]packageFile, error) {
+	files, err := loadPackages(".bumppackages")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load .bumppackages: %w", err)
+	}
+	for i, f := range files {
+		content, err := os.ReadFile(filepath.FromSlash(f.path))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.path, err)
+		}
+		files[i].content, err = packageUpdaters[f.updater](content, version)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.path, err)
+		}
+	}
+	return files, nil
+}
+
+// writePackages writes the updated packaging files into the worktree and
+// stages them with the release.
+func writePackages(repo vcs, cfg config, output io.Writer, files []packageFile) error {
+	for _, f := range files {
+		_, _ = fmt.Fprintf(output, "Updating %s package %s\n", f.updater, f.path)
+		if cfg.dryRun {
+			err := previewFile(output, f.path, f.content)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		err := writeReleaseFile(repo, f.path, f.content)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shellAssignments match the top-level NAME=value lines of a shell-style
+// packaging file, with the value as the third group.
+var shellAssignments = map[string]*regexp.Regexp{
+	"pkgver": regexp.MustCompile(`(?m)^(pkgver=)(["']?)([^"'\s#]*)`),
+	"pkgrel": regexp.MustCompile(`(?m)^(pkgrel=)(["']?)([^"'\s#]*)`),
+}
+
+// setShellVariable replaces the value of the first assignment of the
+// variable, keeping its quotes, comments and line endings.
+func setShellVariable(content []byte, name, value string) ([]byte, error) {
+	loc := shellAssignments[name].FindSubmatchIndex(content)
+	if loc == nil {
+		return nil, fmt.Errorf("no %s= line", name)
+	}
+	updated := append([]byte{}, content[:loc[6]]...)
+	updated = append(updated, value...)
+	return append(updated, content[loc[7]:]...), nil
+}
+
+// updatePKGBUILD sets pkgver of an Arch PKGBUILD and resets pkgrel to 1.
+// pkgver can't hold hyphens, so a prerelease is joined with an underscore:
+// 1.2.0_rc.1.
+func updatePKGBUILD(content []byte, version string) ([]byte, error) {
+	pkgver := stripVPrefix(version)
+	if strings.Contains(pkgver, "+") {
+		return nil, fmt.Errorf("pkgver can't hold the build metadata of %s", version)
+	}
+	pkgver = strings.ReplaceAll(pkgver, "-", "_")
+	content, err := setShellVariable(content, "pkgver", pkgver)
+	if err != nil {
+		return nil, err
+	}
+	return setShellVariable(content, "pkgrel", "1")
+}
+
+// apkSuffix matches the semver prereleases Alpine's version suffixes can
+// express, e.g. rc.1 as _rc1.
+var apkSuffix = regexp.MustCompile(`^(alpha|beta|pre|rc)\.?([0-9]*)$`)
+
+// updateAPKBUILD sets pkgver of an Alpine APKBUILD and resets pkgrel to 0,
+// where Alpine's releases of a version start.
+func updateAPKBUILD(content []byte, version string) ([]byte, error) {
+	pkgver, prerelease, _ := strings.Cut(stripVPrefix(version), "-")
+	if strings.Contains(version, "+") {
+		return nil, fmt.Errorf("pkgver can't hold the build metadata of %s", version)
+	}
+	if prerelease != "" {
+		m := apkSuffix.FindStringSubmatch(prerelease)
+		if m == nil {
+			return nil, fmt.Errorf("pkgver can't express the prerelease of %s: must be alpha, beta, pre or rc with an optional number", version)
+		}
+		pkgver += "_" + m[1] + m[2]
+	}
+	content, err := setShellVariable(content, "pkgver", pkgver)
+	if err != nil {
+		return nil, err
+	}
+	return setShellVariable(content, "pkgrel", "0")
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPKGBUILD = `# Maintainer: Shop Team <shop@example.com>
+pkgname=shop
+pkgver=1.0.0 # kept in sync by bump
+pkgrel=3
+source=("https://example.com/shop-$pkgver.tar.gz")
+`
+
+const testAPKBUILD = "# Contributor: Shop Team <shop@example.com>\r\npkgname=shop\r\npkgver=\"1.0.0\"\r\npkgrel=2\r\n"
+
+func TestUpdatePKGBUILD(t *testing.T) {
+	got, err := updatePKGBUILD([]byte(testPKGBUILD), "v1.2.0-rc.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Replace(strings.Replace(testPKGBUILD, "pkgver=1.0.0", "pkgver=1.2.0_rc.1", 1), "pkgrel=3", "pkgrel=1", 1)
+	if string(got) != want {
+		t.Errorf("Got:\n%s\nwant:\n%s", got, want)
+	}
+	if _, err := updatePKGBUILD([]byte(testPKGBUILD), "v1.2.0+build.5"); err == nil {
+		t.Error("Expected build metadata to be rejected")
+	}
+	if _, err := updatePKGBUILD([]byte("pkgname=shop\n"), "v1.2.0"); err == nil || err.Error() != "no pkgver= line" {
+		t.Errorf("Expected a missing pkgver error, got: %v", err)
+	}
+}
+
+func TestUpdateAPKBUILD(t *testing.T) {
+	tests := []struct {
+		version string
+		pkgver  string
+		wantErr bool
+	}{
+		{version: "v1.2.0", pkgver: "1.2.0"},
+		{version: "1.2.0-rc.1", pkgver: "1.2.0_rc1"},
+		{version: "v2.0.0-beta", pkgver: "2.0.0_beta"},
+		{version: "v2.0.0-hotfix.20250304", wantErr: true},
+		{version: "v2.0.0+meta", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := updateAPKBUILD([]byte(testAPKBUILD), tt.version)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.version)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.version, err)
+			continue
+		}
+		want := strings.Replace(strings.Replace(testAPKBUILD, `pkgver="1.0.0"`, `pkgver="`+tt.pkgver+`"`, 1), "pkgrel=2", "pkgrel=0", 1)
+		if string(got) != want {
+			t.Errorf("%s: got %q, want %q", tt.version, got, want)
+		}
+	}
+}
+
+func TestBumpPackages(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Add packaging", map[string]string{
+		".bumppackages":             "pkgbuild packaging/arch/PKGBUILD  # Arch\napkbuild packaging/alpine/APKBUILD\n",
+		"packaging/arch/PKGBUILD":   testPKGBUILD,
+		"packaging/alpine/APKBUILD": testAPKBUILD,
+	})
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-minor"}, nil)
+	if err != nil {
+		t.Fatalf("bump: %v\nOutput: %s", err, output.String())
+	}
+	if got := readFile(t, "packaging/arch/PKGBUILD"); !strings.Contains(got, "pkgver=1.1.0 # kept in sync by bump\npkgrel=1\n") {
+		t.Errorf("Unexpected PKGBUILD:\n%s", got)
+	}
+	if got := readFile(t, "packaging/alpine/APKBUILD"); !strings.Contains(got, "pkgver=\"1.1.0\"\r\npkgrel=0\r\n") {
+		t.Errorf("Unexpected APKBUILD:\n%q", got)
+	}
+	commit, err := tagCommit(repo, "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := commit.File("packaging/arch/PKGBUILD"); err != nil {
+		t.Errorf("Expected the PKGBUILD in the release commit: %v", err)
+	}
+
+	// a version the APKBUILD can't express aborts before anything is written
+	commits := countCommits(t, repo)
+	err = run(context.Background(), &output, []string{"-version", "v1.2.0-nightly", "-force"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "packaging/alpine/APKBUILD: pkgver can't express the prerelease") {
+		t.Errorf("Expected an APKBUILD error, got: %v", err)
+	}
+	if countCommits(t, repo) != commits {
+		t.Error("A failed bump committed")
+	}
+}
+
+func TestLoadPackages(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".bumppackages")
+	for content, want := range map[string]string{
+		"deb debian/changelog\n":                   "line 1: unknown updater 'deb': must be apkbuild or pkgbuild",
+		"pkgbuild\n":                               "line 1: expected '<updater> <file>'",
+		"pkgbuild ../PKGBUILD\n":                   "line 1: ../PKGBUILD is not a file in the repository",
+		"pkgbuild PKGBUILD\napkbuild ./PKGBUILD\n": "line 2: PKGBUILD is already listed",
+	} {
+		err := os.WriteFile(file, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = loadPackages(file)
+		if err == nil || err.Error() != want {
+			t.Errorf("%q: expected error %q, got: %v", content, want, err)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestRepoPathWindows(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: `foo\.version`, want: "foo/.version"},
+		{path: `.\foo\bar\.version`, want: "foo/bar/.version"},
+		{path: `vendor\pkg`, want: "vendor/pkg"},
+	}
+
+	for _, tt := range tests {
+		if got := repoPath(tt.path); got != tt.want {
+			t.Errorf("repoPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	// Anchored ignore rules are written with forward slashes and must match
+	// the backslash paths produced by filepath.WalkDir.
+	rules := []ignoreRule{{pattern: "vendor/pkg", anchored: true}}
+	if !shouldIgnore(repoPath(`vendor\pkg`), "pkg", rules) {
+		t.Errorf("expected anchored rule to match backslash path")
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// loadPrivateKey loads the armored OpenPGP private key from the file named by
+// the environment variable, decrypting it with the passphrase in
+// <variable>_PASSPHRASE.
+func loadPrivateKey(env []string, variable string) (*openpgp.Entity, error) {
+	keys, err := loadKeyRing(getenv(env, variable), variable)
+	if err != nil {
+		return nil, err
+	}
+	key := keys[0]
+	if key.PrivateKey == nil {
+		return nil, fmt.Errorf("%s holds no private key", variable)
+	}
+	if key.PrivateKey.Encrypted {
+		err = key.DecryptPrivateKeys([]byte(getenv(env, variable+"_PASSPHRASE")))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt %s: %w", variable, err)
+		}
+	}
+	return key, nil
+}
+
+// loadKeyRing reads the armored OpenPGP keys in path. source names where the
+// path came from in errors.
+func loadKeyRing(path, source string) (openpgp.EntityList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", source, err)
+	}
+	defer func() { _ = f.Close() }()
+	keys, err := openpgp.ReadArmoredKeyRing(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", source, err)
+	}
+	return keys, nil
+}
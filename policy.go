@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// policyRule is a single release policy from .bumppolicy. A rule either
+// protects a range of versions from being (re)created, forbids prerelease
+// versions on a branch, or guards against unexpectedly large releases.
+type policyRule struct {
+	line int
+	// protect rules
+	op      string // one of <, <=, =, >=, >
+	version string // normalized version, or "latest"
+	// no-prerelease rules
+	branch string
+	// max-commits and max-age rules: the first-parent commits since the last
+	// release, and the days since it
+	maxCommits int
+	maxAge     int
+}
+
+// loadPolicy reads release policy rules. The format is line based:
+//
+//	protect <= latest     # never recreate anything at or below the newest tag
+//	protect < v1.0.0      # comparison against a fixed version
+//	protect v2.3.4        # a single version
+//	no-prerelease main    # no prereleases while on branch main
+//	max-commits 500       # more commits since the last release is suspicious
+//	max-age 90d           # so is a last release older than 90 days
+func loadPolicy(path string) ([]policyRule, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil // No policy file is fine
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []policyRule
+	for i, line := range strings.Split(string(content), "\n") {
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		rule := policyRule{line: i + 1}
+		switch {
+		case fields[0] == "protect" && len(fields) == 2:
+			rule.op, rule.version = "=", fields[1]
+		case fields[0] == "protect" && len(fields) == 3:
+			rule.op, rule.version = fields[1], fields[2]
+		case fields[0] == "no-prerelease" && len(fields) == 2:
+			rule.branch = fields[1]
+		case fields[0] == "max-commits" && len(fields) == 2:
+			rule.maxCommits, err = strconv.Atoi(fields[1])
+			if err != nil || rule.maxCommits <= 0 {
+				return nil, fmt.Errorf("line %d: invalid commit count '%s'", rule.line, fields[1])
+			}
+		case fields[0] == "max-age" && len(fields) == 2:
+			rule.maxAge, err = strconv.Atoi(strings.TrimSuffix(fields[1], "d"))
+			if err != nil || rule.maxAge <= 0 {
+				return nil, fmt.Errorf("line %d: invalid age '%s': must be a number of days like 90d", rule.line, fields[1])
+			}
+		default:
+			return nil, fmt.Errorf("line %d: invalid rule '%s'", rule.line, strings.TrimSpace(line))
+		}
+		if rule.op != "" {
+			switch rule.op {
+			case "<", "<=", "=", ">=", ">":
+			default:
+				return nil, fmt.Errorf("line %d: invalid operator '%s'", rule.line, rule.op)
+			}
+			if rule.version != "latest" {
+				rule.version = normalizeVersion(rule.version)
+				if !semver.IsValid(rule.version) {
+					return nil, fmt.Errorf("line %d: invalid version '%s'", rule.line, fields[len(fields)-1])
+				}
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// checkPolicy returns an error if creating version violates any of the rules.
+// It only reads from the repository and is meant to run before any mutation.
+func checkPolicy(repo vcs, rules []policyRule, version string) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	normalized := normalizeVersion(version)
+
+	for _, rule := range rules {
+		if rule.maxCommits > 0 || rule.maxAge > 0 {
+			continue // see checkReleaseSize
+		}
+		if rule.branch != "" {
+			if semver.Prerelease(normalized) == "" {
+				continue
+			}
+			branch, err := repo.branch()
+			if err != nil {
+				return err
+			}
+			if branch == rule.branch {
+				return fmt.Errorf("policy forbids prerelease versions on branch '%s' (.bumppolicy line %d)", rule.branch, rule.line)
+			}
+			continue
+		}
+
+		bound := rule.version
+		if bound == "latest" {
+			latest, err := repo.lastTag()
+			if err != nil {
+				continue // nothing published yet, nothing to protect
+			}
+			bound = normalizeVersion(latest)
+		}
+		if compareMatches(semver.Compare(normalized, bound), rule.op) {
+			return fmt.Errorf("policy protects version %s: matches 'protect %s %s' (.bumppolicy line %d)", version, rule.op, rule.version, rule.line)
+		}
+	}
+	return nil
+}
+
+// compareMatches reports whether a semver.Compare result satisfies op.
+func compareMatches(cmp int, op string) bool {
+	switch op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "=":
+		return cmp == 0
+	case ">=":
+		return cmp >= 0
+	case ">":
+		return cmp > 0
+	}
+	return false
+}
+
+// checkReleaseSize applies the max-commits and max-age rules, a cheap
+// heuristic for releasing the wrong branch: a release far larger or a last
+// release far older than usual. A tripped guard aborts the bump, unless
+// -allow-large-release turns it into a warning.
+func checkReleaseSize(repo vcs, rules []policyRule, cfg config, output io.Writer) error {
+	latest := ""
+	for _, rule := range rules {
+		if rule.maxCommits == 0 && rule.maxAge == 0 {
+			continue
+		}
+		if latest == "" {
+			var err error
+			latest, err = repo.lastTag()
+			if err != nil {
+				return nil // the first release has nothing to compare against
+			}
+		}
+		var problem string
+		if rule.maxCommits > 0 {
+			changes, err := repo.changesSince(latest, commitsFirstParent)
+			if err != nil {
+				return fmt.Errorf("failed to count the commits since %s: %w", latest, err)
+			}
+			if len(changes) > rule.maxCommits {
+				problem = fmt.Sprintf("%d commits since %s exceed 'max-commits %d'", len(changes), latest, rule.maxCommits)
+			}
+		} else {
+			gitRepo, err := gitRepository(repo, "the max-age rule")
+			if err != nil {
+				return err
+			}
+			tag, err := existingTag(repo, latest)
+			if err != nil {
+				return err
+			}
+			commit, err := tagCommit(gitRepo, tag)
+			if err != nil {
+				return err
+			}
+			days := int(now().Sub(commit.Committer.When).Hours() / 24)
+			if days > rule.maxAge {
+				problem = fmt.Sprintf("last release %s is %d days old, exceeding 'max-age %dd'", latest, days, rule.maxAge)
+			}
+		}
+		if problem == "" {
+			continue
+		}
+		if !cfg.allowLargeRelease {
+			return fmt.Errorf("%s (.bumppolicy line %d): check that this is the right branch, or pass -allow-large-release", problem, rule.line)
+		}
+		_, _ = fmt.Fprintf(output, "Warning: %s (.bumppolicy line %d)\n", problem, rule.line)
+	}
+	return nil
+}
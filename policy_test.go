@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		want        []policyRule
+		errContains string
+	}{
+		{
+			name: "all rule kinds",
+			content: `# release policy
+protect <= latest
+protect < 1.0.0   # pre-1.0 is gone
+protect v2.3.4
+no-prerelease main
+max-commits 500
+max-age 90d
+`,
+			want: []policyRule{
+				{line: 2, op: "<=", version: "latest"},
+				{line: 3, op: "<", version: "v1.0.0"},
+				{line: 4, op: "=", version: "v2.3.4"},
+				{line: 5, branch: "main"},
+				{line: 6, maxCommits: 500},
+				{line: 7, maxAge: 90},
+			},
+		},
+		{
+			name:        "invalid age",
+			content:     "max-age 3w\n",
+			errContains: "line 1: invalid age '3w'",
+		},
+		{
+			name:        "unknown rule",
+			content:     "allow everything\n",
+			errContains: "line 1: invalid rule",
+		},
+		{
+			name:        "invalid operator",
+			content:     "protect != v1.0.0\n",
+			errContains: "invalid operator",
+		},
+		{
+			name:        "invalid version",
+			content:     "\nprotect < banana\n",
+			errContains: "line 2: invalid version 'banana'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".bumppolicy")
+			err := os.WriteFile(path, []byte(tt.content), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadPolicy(path)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("loadPolicy() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadPolicy() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("loadPolicy() returned %d rules, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("rule %d: got %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadPolicyNoFile(t *testing.T) {
+	rules, err := loadPolicy(filepath.Join(t.TempDir(), ".bumppolicy"))
+	if err != nil {
+		t.Fatalf("Expected no error for missing file, got: %v", err)
+	}
+	if rules != nil {
+		t.Errorf("Expected nil rules for missing file, got: %v", rules)
+	}
+}
+
+func TestBumpWithPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		errContains string
+	}{
+		{name: "recreating an older version", args: []string{"-version", "v0.9.0"}, errContains: "policy protects version v0.9.0"},
+		{name: "explicitly protected version", args: []string{"-version", "v1.5.0"}, errContains: "'protect = v1.5.0'"},
+		{name: "prerelease on protected branch", args: []string{"-version", "v1.1.0-rc.1"}, errContains: "forbids prerelease versions on branch 'master'"},
+		{name: "regular increment", args: []string{"-minor"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, repo := setupTaggedTestRepo(t, "v1.0.0")
+			policy := "protect <= latest\nprotect v1.5.0\nno-prerelease master\n"
+			err := os.WriteFile(".bumppolicy", []byte(policy), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			commitsBefore := countCommits(t, repo)
+
+			var output bytes.Buffer
+			err = run(context.Background(), &output, tt.args, nil)
+			if tt.errContains == "" {
+				if err != nil {
+					t.Fatalf("Expected bump to succeed, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Fatalf("Expected error containing %q, got: %v", tt.errContains, err)
+			}
+			if countCommits(t, repo) != commitsBefore {
+				t.Errorf("Expected no commit when the policy rejects the version")
+			}
+		})
+	}
+}
+
+func TestReleaseSizeGuard(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Add policy", map[string]string{".bumppolicy": "max-commits 3\nmax-age 30d\n"})
+	commitFiles(t, repo, "Add feature", map[string]string{"other.txt": "feature"})
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-dry-run"}, nil)
+	if err != nil {
+		t.Fatalf("Expected three commits to pass the guard, got: %v", err)
+	}
+
+	commitFiles(t, repo, "Add another feature", map[string]string{"other.txt": "other"})
+	err = run(context.Background(), &output, []string{"-dry-run"}, nil)
+	want := "4 commits since v1.0.0 exceed 'max-commits 3' (.bumppolicy line 1)"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("Expected the guard to trip, got: %v", err)
+	}
+
+	output.Reset()
+	err = run(context.Background(), &output, []string{"-allow-large-release"}, nil)
+	if err != nil {
+		t.Fatalf("Expected -allow-large-release to override the guard, got: %v", err)
+	}
+	if !strings.Contains(output.String(), "Warning: "+want) {
+		t.Errorf("Expected a warning, got: %s", output.String())
+	}
+
+	// the last release is now v1.0.1, with no commits since
+	now = func() time.Time { return time.Now().AddDate(0, 0, 45) }
+	t.Cleanup(func() { now = time.Now })
+	err = run(context.Background(), &output, []string{"-force", "-dry-run"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "last release v1.0.1 is 45 days old, exceeding 'max-age 30d' (.bumppolicy line 2)") {
+		t.Fatalf("Expected the age guard to trip, got: %v", err)
+	}
+}
+
+func TestReleaseSizeGuardStream(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Add server", map[string]string{
+		".bumpstreams":    "server server\n",
+		".bumppolicy":     "max-age 3650d\n",
+		"server/.version": "v0.3.0\n",
+	})
+	_, err := repo.CreateTag("server/v0.3.0", mustHead(t, repo), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, repo, "Improve server", map[string]string{"server/main.go": "package main"})
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-stream", "server", "-dry-run"}, nil)
+	if err != nil {
+		t.Fatalf("Expected the age of server/v0.3.0 to pass the guard, got: %v\nOutput: %s", err, output.String())
+	}
+}
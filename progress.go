@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// progressDelay is how long a scan runs silently before it reports progress.
+var progressDelay = 2 * time.Second
+
+// progressInterval is the minimum time between two progress reports.
+const progressInterval = time.Second
+
+// runBudget bounds the runtime of an invocation (-timeout) and receives the
+// progress reports of long scans. The tag and file scans sit below the vcs
+// interface, out of reach of run's context and output, so run installs the
+// budget for the duration of the invocation. The default never expires and
+// reports nothing.
+type runBudget struct {
+	ctx     context.Context
+	output  io.Writer
+	timeout time.Duration
+}
+
+var budget = runBudget{ctx: context.Background(), output: io.Discard}
+
+// useBudget installs the budget for a run and returns the function restoring
+// the previous one.
+func useBudget(b runBudget) func() {
+	previous := budget
+	budget = b
+	return func() { budget = previous }
+}
+
+// err returns a clean abort error once the budget has run out.
+func (b runBudget) err() error {
+	if b.ctx.Err() == nil {
+		return nil
+	}
+	if b.timeout > 0 {
+		return fmt.Errorf("aborted: -timeout %s exceeded: %w", b.timeout, b.ctx.Err())
+	}
+	return fmt.Errorf("aborted: %w", b.ctx.Err())
+}
+
+// scanProgress counts the items of one scan, e.g. the tags of a repository.
+type scanProgress struct {
+	budget   runBudget
+	noun     string
+	total    int // 0 if unknown
+	count    int
+	start    time.Time
+	reported time.Time // zero until the first report
+}
+
+func (b runBudget) startScan(noun string, total int) *scanProgress {
+	return &scanProgress{budget: b, noun: noun, total: total, start: time.Now()}
+}
+
+// step counts an item. It reports progress once the scan has taken longer
+// than progressDelay, and fails once the budget has run out.
+func (p *scanProgress) step() error {
+	err := p.budget.err()
+	if err != nil {
+		return fmt.Errorf("%w (scanned %s)", err, p.counter())
+	}
+	p.count++
+	now := time.Now()
+	if now.Sub(p.start) >= progressDelay && now.Sub(p.reported) >= progressInterval {
+		p.report()
+		p.reported = now
+	}
+	return nil
+}
+
+// done reports the final count if progress was reported at all, so the last
+// line doesn't leave the scan looking unfinished.
+func (p *scanProgress) done() {
+	if !p.reported.IsZero() {
+		p.report()
+	}
+}
+
+func (p *scanProgress) report() {
+	_, _ = fmt.Fprintf(p.budget.output, "scanned %s\n", p.counter())
+}
+
+// counter formats the progress as "12,000/48,000 tags", or "12,000 files" if
+// the total isn't known.
+func (p *scanProgress) counter() string {
+	if p.total > 0 {
+		return fmt.Sprintf("%s/%s %s", thousands(p.count), thousands(p.total), p.noun)
+	}
+	return fmt.Sprintf("%s %s", thousands(p.count), p.noun)
+}
+
+// thousands formats n with comma separators.
+func thousands(n int) string {
+	digits := strconv.Itoa(n)
+	for i := len(digits) - 3; i > 0; i -= 3 {
+		digits = digits[:i] + "," + digits[i:]
+	}
+	return digits
+}
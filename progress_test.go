@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThousands(t *testing.T) {
+	tests := map[int]string{0: "0", 999: "999", 1000: "1,000", 48000: "48,000", 1234567: "1,234,567"}
+	for n, want := range tests {
+		if got := thousands(n); got != want {
+			t.Errorf("thousands(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+// reportImmediately makes scans report progress from the first item
+func reportImmediately(t *testing.T) {
+	delay := progressDelay
+	progressDelay = 0
+	t.Cleanup(func() { progressDelay = delay })
+}
+
+func TestScanProgress(t *testing.T) {
+	reportImmediately(t)
+	var output bytes.Buffer
+	b := runBudget{ctx: context.Background(), output: &output}
+
+	scan := b.startScan("tags", 12000)
+	for range 12000 {
+		err := scan.step()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	scan.done()
+	// the first step reports, the rest fall within progressInterval
+	want := "scanned 1/12,000 tags\nscanned 12,000/12,000 tags\n"
+	if output.String() != want {
+		t.Errorf("Got progress:\n%s\nwant:\n%s", output.String(), want)
+	}
+}
+
+func TestFastScanIsSilent(t *testing.T) {
+	var output bytes.Buffer
+	scan := runBudget{ctx: context.Background(), output: &output}.startScan("files", 0)
+	_ = scan.step()
+	scan.done()
+	if output.Len() != 0 {
+		t.Errorf("Expected a fast scan to be silent, got %q", output.String())
+	}
+}
+
+func TestScanAbortsWhenBudgetRunsOut(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	scan := runBudget{ctx: ctx, output: &bytes.Buffer{}, timeout: time.Nanosecond}.startScan("files", 0)
+	err := scan.step()
+	if err == nil || !strings.Contains(err.Error(), "aborted: -timeout 1ns exceeded") || !strings.Contains(err.Error(), "(scanned 0 files)") {
+		t.Errorf("Expected timeout error, got: %v", err)
+	}
+}
+
+func TestBumpTimeout(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commits := countCommits(t, repo)
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-timeout", "1ns"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "-timeout 1ns exceeded") {
+		t.Fatalf("Expected timeout error, got: %v", err)
+	}
+	if countCommits(t, repo) != commits {
+		t.Error("Expected no commit after a timeout")
+	}
+	exists, err := tagExists(repo, "v1.0.1")
+	if err != nil || exists {
+		t.Errorf("Expected no tag after a timeout, got %v, %v", exists, err)
+	}
+}
+
+func TestBumpReportsProgress(t *testing.T) {
+	reportImmediately(t)
+	setupTaggedTestRepo(t, "v1.0.0")
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-timeout", "1m"}, nil)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v", err)
+	}
+	for _, want := range []string{"scanned 1/1 tags", "files\n"} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("Expected progress output containing %q, got:\n%s", want, output.String())
+		}
+	}
+}
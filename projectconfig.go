@@ -0,0 +1,394 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// projectConfigFile holds the project's defaults for the bump flags, at the
+// root of the repository. They override the user's defaults in
+// userConfigFile.
+const projectConfigFile = ".bump.yaml"
+
+// configBaseTimeout bounds fetching a config extended from a URL.
+const configBaseTimeout = 30 * time.Second
+
+// userConfigFile returns the user's defaults for the bump flags,
+// $XDG_CONFIG_HOME/bump/config.yaml or ~/.config/bump/config.yaml, or "" if
+// there's no home directory.
+func userConfigFile() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "bump", "config.yaml")
+}
+
+// projectConfigFlags are the flags a config file can set: how the project
+// releases. The flags picking what one run does, such as the level, -force,
+// -since or -dry-run, are left to the command line, so a committed
+// .bump.yaml can't change what a checkout of it runs.
+var projectConfigFlags = map[string]bool{
+	"allow-empty-release":  true,
+	"announce":             true,
+	"artifacts":            true,
+	"assets":               true,
+	"auto":                 true,
+	"auto-api":             true,
+	"back-merge":           true,
+	"backend":              true,
+	"build-args":           true,
+	"changelog":            true,
+	"changelog-template":   true,
+	"checksums":            true,
+	"commit-body":          true,
+	"commit-per-module":    true,
+	"commits":              true,
+	"date-format":          true,
+	"fix-eol":              true,
+	"github-release":       true,
+	"gitlab-release":       true,
+	"gitlab-release-links": true,
+	"image":                true,
+	"image-manifest":       true,
+	"include":              true,
+	"initial-version":      true,
+	"k8s-annotate":         true,
+	"k8s-annotation":       true,
+	"latest-strategy":      true,
+	"own-tags":             true,
+	"pre1-major":           true,
+	"provenance":           true,
+	"push":                 true,
+	"push-option":          true,
+	"push-remote":          true,
+	"release-notes":        true,
+	"remote":               true,
+	"sbom":                 true,
+	"scan-secrets":         true,
+	"scheme":               true,
+	"sign":                 true,
+	"signing-key":          true,
+	"skip-ci":              true,
+	"ssh-key":              true,
+	"staged-only":          true,
+	"tag-message":          true,
+	"tag-metadata":         true,
+	"tag-template":         true,
+	"tag-type":             true,
+	"timeout":              true,
+	"timezone":             true,
+}
+
+// configSetting is a flag set by a config file, with the line setting it.
+type configSetting struct {
+	line  int
+	name  string
+	value string
+}
+
+// loadProjectConfig reads the flag defaults of a config file, a flat YAML
+// mapping from flag names to values, e.g. "tag-template: v{{.Version}}".
+// Lists, as block sequences or "[a, b]", give the comma-separated flags.
+// Nested mappings, anchors and multi-line strings aren't supported. A
+// missing file sets nothing.
+func loadProjectConfig(file string) ([]configSetting, error) {
+	content, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseProjectConfig(content)
+}
+
+// parseProjectConfig parses the flag defaults of a config file, see
+// loadProjectConfig.
+func parseProjectConfig(content []byte) ([]configSetting, error) {
+	var err error
+	var settings []configSetting
+	var list *configSetting
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimRight(stripYAMLComment(line), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if item, ok := strings.CutPrefix(trimmed, "- "); ok {
+			if list == nil {
+				return nil, fmt.Errorf("line %d: list item outside a list", i+1)
+			}
+			value, err := yamlScalar(strings.TrimSpace(item))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			if list.value != "" {
+				list.value += ","
+			}
+			list.value += value
+			continue
+		}
+		if line != trimmed {
+			return nil, fmt.Errorf("line %d: nested mappings aren't supported, use the flag names as keys", i+1)
+		}
+		name, raw, ok := strings.Cut(line, ":")
+		if !ok || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("line %d: expected 'flag: value'", i+1)
+		}
+		raw = strings.TrimSpace(raw)
+		for _, s := range settings {
+			if s.name == name {
+				return nil, fmt.Errorf("line %d: %s is already set on line %d", i+1, name, s.line)
+			}
+		}
+		settings = append(settings, configSetting{line: i + 1, name: name})
+		list = nil
+		setting := &settings[len(settings)-1]
+		switch {
+		case raw == "":
+			list = setting
+		case strings.HasPrefix(raw, "[") && strings.HasSuffix(raw, "]"):
+			var values []string
+			for _, item := range strings.Split(raw[1:len(raw)-1], ",") {
+				if item = strings.TrimSpace(item); item == "" {
+					continue
+				}
+				value, err := yamlScalar(item)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", i+1, err)
+				}
+				values = append(values, value)
+			}
+			setting.value = strings.Join(values, ",")
+		default:
+			setting.value, err = yamlScalar(raw)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+		}
+	}
+	return settings, nil
+}
+
+// stripYAMLComment removes a comment from a line: a "#" at its start or
+// after a space, outside quotes.
+func stripYAMLComment(line string) string {
+	var quote rune
+	for i, c := range line {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// yamlScalar returns the value of a plain, single- or double-quoted scalar.
+// YAML's yes, no, on and off are the booleans they spell.
+func yamlScalar(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		value, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid double-quoted value %s", raw)
+		}
+		return value, nil
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") {
+			return "", fmt.Errorf("invalid single-quoted value %s", raw)
+		}
+		return strings.ReplaceAll(raw[1:len(raw)-1], "''", "'"), nil
+	}
+	switch strings.ToLower(raw) {
+	case "yes", "on":
+		return "true", nil
+	case "no", "off":
+		return "false", nil
+	}
+	return raw, nil
+}
+
+// applyConfigFile applies the settings of a config file. A config can
+// "extends:" a shared one, such as an organization's baseline: a file,
+// relative to the extending config, an http(s) URL of a .yaml or .yml file,
+// or the URL of a git repository with a .bump.yaml at its root, optionally
+// at a branch or tag given as "#ref". The base's settings are applied
+// first, so the extending config overrides them. Bases can extend further
+// ones, but a config fetched from a URL can only extend other URLs.
+func applyConfigFile(flagSet *flag.FlagSet, file string, set map[string]bool) error {
+	settings, err := loadProjectConfig(file)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return applyExtendedConfig(flagSet, file, settings, set, map[string]bool{file: true})
+}
+
+// applyExtendedConfig applies the base a config extends, if any, then the
+// config's own settings. seen holds the configs of the chain, to refuse
+// cycles.
+func applyExtendedConfig(flagSet *flag.FlagSet, file string, settings []configSetting, set, seen map[string]bool) error {
+	var own []configSetting
+	for _, s := range settings {
+		if s.name != "extends" {
+			own = append(own, s)
+			continue
+		}
+		base := s.value
+		switch {
+		case isConfigURL(base):
+		case isConfigURL(file):
+			return fmt.Errorf("%s line %d: a config fetched from a URL can only extend URLs, not %s", file, s.line, base)
+		case !filepath.IsAbs(base):
+			base = filepath.Join(filepath.Dir(file), base)
+		}
+		if seen[base] {
+			return fmt.Errorf("%s line %d: %s extends itself", file, s.line, base)
+		}
+		seen[base] = true
+		content, err := fetchConfigBase(base)
+		if err != nil {
+			return fmt.Errorf("%s line %d: failed to fetch %s: %w", file, s.line, base, err)
+		}
+		baseSettings, err := parseProjectConfig(content)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", base, err)
+		}
+		err = applyExtendedConfig(flagSet, base, baseSettings, set, seen)
+		if err != nil {
+			return err
+		}
+	}
+	return applyConfigSettings(flagSet, file, own, set)
+}
+
+// isConfigURL reports whether an extended config is fetched rather than read
+// from a file: a URL, or the user@host:path form of SSH remotes.
+func isConfigURL(base string) bool {
+	if strings.Contains(base, "://") {
+		return true
+	}
+	at, _, ok := strings.Cut(base, ":")
+	return ok && strings.Contains(at, "@") && !strings.Contains(at, "/")
+}
+
+// fetchConfigBase returns the content of an extended config. A git
+// repository is cloned shallowly into memory, using the credentials in the
+// URL or the SSH agent like the remotes do.
+func fetchConfigBase(base string) ([]byte, error) {
+	if !isConfigURL(base) {
+		return os.ReadFile(base)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), configBaseTimeout)
+	defer cancel()
+	if u, err := url.Parse(base); err == nil && (u.Scheme == "https" || u.Scheme == "http") && (path.Ext(u.Path) == ".yaml" || path.Ext(u.Path) == ".yml") {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, base, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected response %s", resp.Status)
+		}
+		return io.ReadAll(resp.Body)
+	}
+
+	repoURL, ref, _ := strings.Cut(base, "#")
+	refs := []plumbing.ReferenceName{""}
+	if ref != "" {
+		refs = []plumbing.ReferenceName{plumbing.NewTagReferenceName(ref), plumbing.NewBranchReferenceName(ref)}
+	}
+	var repo *git.Repository
+	var err error
+	for _, name := range refs {
+		repo, err = git.CloneContext(ctx, memory.NewStorage(), nil, &git.CloneOptions{URL: repoURL, ReferenceName: name, SingleBranch: true, Depth: 1, Tags: git.NoTags})
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+	f, err := commit.File(projectConfigFile)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", projectConfigFile, err)
+	}
+	content, err := f.Contents()
+	return []byte(content), err
+}
+
+// applyConfigSettings sets the flags of a config file before the arguments
+// are parsed, so the arguments override them, and records their names in
+// set. Only the full names of flags are taken.
+func applyConfigSettings(flagSet *flag.FlagSet, file string, settings []configSetting, set map[string]bool) error {
+	for _, s := range settings {
+		f := flagSet.Lookup(s.name)
+		_, alias := flagAliases[s.name]
+		_, deprecated := deprecatedFlags[s.name]
+		switch {
+		case f == nil:
+			return fmt.Errorf("%s line %d: unknown flag %s", file, s.line, s.name)
+		case alias || deprecated:
+			return fmt.Errorf("%s line %d: use the flag's full name instead of %s", file, s.line, s.name)
+		case !projectConfigFlags[s.name]:
+			return fmt.Errorf("%s line %d: -%s can only be given on the command line", file, s.line, s.name)
+		}
+		err := f.Value.Set(s.value)
+		if err != nil {
+			return fmt.Errorf("%s line %d: invalid value for %s: %w", file, s.line, s.name, err)
+		}
+		set[s.name] = true
+	}
+	return nil
+}
+
+// envDefault returns the value of the flag name from the environment
+// variable key if the flag is empty, or if only a config file set it: the
+// BUMP_* variables override the config files, and the command line
+// overrides both.
+func (cfg config) envDefault(name, value string, env []string, key string) string {
+	if value != "" && !cfg.fromConfig[name] {
+		return value
+	}
+	if v := getenv(env, key); v != "" {
+		return v
+	}
+	return value
+}
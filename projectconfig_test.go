@@ -0,0 +1,255 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// TestMain points XDG_CONFIG_HOME to an empty directory, so the tests don't
+// pick up the defaults of the user running them.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "bump-config-")
+	if err != nil {
+		panic(err)
+	}
+	_ = os.Setenv("XDG_CONFIG_HOME", dir)
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestLoadProjectConfig(t *testing.T) {
+	file := t.TempDir() + "/.bump.yaml"
+	err := os.WriteFile(file, []byte(`---
+# release settings
+tag-template: "v{{.Version}}"
+push: yes
+push-remote: upstream # the fork's parent
+include:
+  - go.mod
+  - 'docs/*.md'
+artifacts: [dist/app, dist/app.sha256]
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings, err := loadProjectConfig(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []configSetting{
+		{3, "tag-template", "v{{.Version}}"},
+		{4, "push", "true"},
+		{5, "push-remote", "upstream"},
+		{6, "include", "go.mod,docs/*.md"},
+		{9, "artifacts", "dist/app,dist/app.sha256"},
+	}
+	if !reflect.DeepEqual(settings, want) {
+		t.Errorf("loadProjectConfig() = %+v, want %+v", settings, want)
+	}
+
+	settings, err = loadProjectConfig(t.TempDir() + "/.bump.yaml")
+	if err != nil || settings != nil {
+		t.Errorf("Expected a missing config to set nothing, got %v, %v", settings, err)
+	}
+
+	for content, want := range map[string]string{
+		"changelog:\n  template: x.md\n": "line 2: nested mappings",
+		"- go.mod\n":                     "line 1: list item outside a list",
+		"push: true\npush: false\n":      "line 2: push is already set on line 1",
+		"tag template: x\n":              "line 1: expected 'flag: value'",
+	} {
+		err := os.WriteFile(file, []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = loadProjectConfig(file)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("loadProjectConfig(%q) error = %v, want %s", content, err, want)
+		}
+	}
+}
+
+func TestProjectConfigDefaults(t *testing.T) {
+	t.Chdir(t.TempDir())
+	err := os.WriteFile(projectConfigFile, []byte("tag-template: release-{{.Version}}\npush: true\ninclude: [go.mod]\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, _, err := getConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.tagTemplate != "release-{{.Version}}" || !cfg.push || !reflect.DeepEqual(cfg.include, []string{"go.mod"}) {
+		t.Errorf("Expected the config's defaults, got %+v", cfg)
+	}
+	cfg, _, err = getConfig([]string{"-push=false", "-tag-template", "v{{.Version}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.tagTemplate != "v{{.Version}}" || cfg.push {
+		t.Errorf("Expected the flags to override the config, got %+v", cfg)
+	}
+
+	for content, want := range map[string]string{
+		"hooks: [make]\n":          ".bump.yaml line 1: unknown flag hooks",
+		"n: true\n":                "use the flag's full name instead of n",
+		"minor: true\n":            "-minor can only be given on the command line",
+		"push: maybe\n":            "invalid value for push",
+		"force: true\n":            "-force can only be given on the command line",
+		"since: --output=/tmp/x\n": "-since can only be given on the command line",
+	} {
+		err := os.WriteFile(projectConfigFile, []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = getConfig(nil)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("getConfig() with %q error = %v, want %s", content, err, want)
+		}
+	}
+}
+
+func TestProjectConfigExtends(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	err := os.MkdirAll("ci", 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join("ci", "base.yaml"), []byte("tag-template: release-{{.Version}}\npush: true\nremote: upstream\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(projectConfigFile, []byte("extends: ci/base.yaml\nremote: origin\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, _, err := getConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.tagTemplate != "release-{{.Version}}" || !cfg.push || cfg.remote != "origin" || !cfg.fromConfig["tag-template"] {
+		t.Errorf("Expected the base's defaults under the config's, got %+v", cfg)
+	}
+
+	orgDir, orgRepo := setupTestRepo(t)
+	t.Chdir(orgDir)
+	commitFiles(t, orgRepo, "Add the baseline", map[string]string{projectConfigFile: "extends: ci/base.yaml\n"})
+	err = orgRepo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("v1"), mustHead(t, orgRepo)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, orgRepo, "Sign releases", map[string]string{projectConfigFile: "tag-template: org-{{.Version}}\nsign: true\n"})
+	t.Chdir(dir)
+	baseline := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bump.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("extends: file://" + orgDir + "\ntag-template: web-{{.Version}}\n"))
+	}))
+	t.Cleanup(baseline.Close)
+	err = os.WriteFile(projectConfigFile, []byte("extends: "+baseline.URL+"/bump.yaml\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, _, err = getConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.tagTemplate != "web-{{.Version}}" || !cfg.sign {
+		t.Errorf("Expected the fetched bases' defaults, got %+v", cfg)
+	}
+
+	for content, want := range map[string]string{
+		"extends: file://" + orgDir + "#v1\n":    "a config fetched from a URL can only extend URLs",
+		"extends: .bump.yaml\n":                  "extends itself",
+		"extends: ci/missing.yaml\n":             "failed to fetch",
+		"extends: " + baseline.URL + "/x.yaml\n": "404 Not Found",
+	} {
+		err := os.WriteFile(projectConfigFile, []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = getConfig(nil)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("getConfig() with %q error = %v, want %s", content, err, want)
+		}
+	}
+}
+
+func TestProjectConfigEnvPrecedence(t *testing.T) {
+	t.Chdir(t.TempDir())
+	err := os.WriteFile(projectConfigFile, []byte("remote: upstream\ntimezone: UTC\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := []string{"BUMP_REMOTE=origin", "BUMP_TAG_TEMPLATE=release-{{.Version}}"}
+	cfg, _, err := getConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remote := cfg.envDefault("remote", cfg.remote, env, "BUMP_REMOTE"); remote != "origin" {
+		t.Errorf("Expected BUMP_REMOTE to override the config, got %s", remote)
+	}
+	if timezone := cfg.envDefault("timezone", cfg.timezone, env, "BUMP_TIMEZONE"); timezone != "UTC" {
+		t.Errorf("Expected the config to apply without BUMP_TIMEZONE, got %s", timezone)
+	}
+	if template := cfg.envDefault("tag-template", cfg.tagTemplate, env, "BUMP_TAG_TEMPLATE"); template != "release-{{.Version}}" {
+		t.Errorf("Expected BUMP_TAG_TEMPLATE to fill in the unset flag, got %s", template)
+	}
+	cfg, _, err = getConfig([]string{"-remote", "mirror"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remote := cfg.envDefault("remote", cfg.remote, env, "BUMP_REMOTE"); remote != "mirror" {
+		t.Errorf("Expected the flag to override BUMP_REMOTE, got %s", remote)
+	}
+
+	_, _, err = getConfig([]string{"-since", "--output=/tmp/x"})
+	if err == nil || !strings.Contains(err.Error(), "invalid -since") {
+		t.Errorf("Expected an option as -since to be refused, got: %v", err)
+	}
+}
+
+func TestUserConfigDefaults(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	err := os.Mkdir(filepath.Join(home, "bump"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(home, "bump", "config.yaml"), []byte("signing-key: me@example.com\nremote: upstream\npush-remote: fork\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(t.TempDir())
+	err = os.WriteFile(projectConfigFile, []byte("remote: origin\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, _, err := getConfig([]string{"-push-remote", "mirror"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.signingKey != "me@example.com" || cfg.remote != "origin" || cfg.pushRemote != "mirror" {
+		t.Errorf("Expected the project config over the user's and the flags over both, got %+v", cfg)
+	}
+
+	err = os.WriteFile(filepath.Join(home, "bump", "config.yaml"), []byte("major: true\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = getConfig(nil)
+	if err == nil || !strings.Contains(err.Error(), filepath.Join(home, "bump", "config.yaml")+" line 1") {
+		t.Errorf("Expected the error to name the user config, got: %v", err)
+	}
+}
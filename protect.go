@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// loadProtectedPaths reads the patterns of .bumpprotect, one glob per line
+// matched against the repository path of a .version file and each of its
+// directories:
+//
+//	internal/fixtures    # golden files of the parser tests
+//	*/legacy/.version
+func loadProtectedPaths(file string) ([]string, error) {
+	content, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns []string
+	for i, line := range strings.Split(string(content), "\n") {
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		pattern := strings.Trim(strings.TrimSpace(line), "/")
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("line %d: invalid pattern '%s'", i+1, pattern)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// protectedBy returns the pattern protecting the version file, or "" if it
+// isn't protected.
+func protectedBy(patterns []string, versionFile string) string {
+	for p := versionFile; p != "." && p != "/"; p = path.Dir(p) {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, p); ok {
+				return pattern
+			}
+		}
+	}
+	return ""
+}
+
+// checkProtected fails if one of the version files about to be written is
+// protected by .bumpprotect, so fixtures that must never change abort the
+// bump instead of silently breaking tests.
+func checkProtected(patterns, versionFiles []string) error {
+	for _, file := range versionFiles {
+		if pattern := protectedBy(patterns, file); pattern != "" {
+			return fmt.Errorf("refusing to bump protected version file %s (matches '%s' in .bumpprotect)", file, pattern)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTestdataIsNotBumped(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Add fixtures", map[string]string{
+		"parser/testdata/old/.version": "v0.1.0\n",
+		"parser/.version":              "v1.0.0\n",
+	})
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-minor"}, nil)
+	if err != nil {
+		t.Fatalf("bump: %v\nOutput: %s", err, output.String())
+	}
+	if got := readFile(t, "parser/testdata/old/.version"); got != "v0.1.0\n" {
+		t.Errorf("Expected the fixture to stay untouched, got %q", got)
+	}
+	if got := readFile(t, "parser/.version"); got != "v1.1.0\n" {
+		t.Errorf("Expected parser/.version to be bumped, got %q", got)
+	}
+
+	// !testdata in .bumpignore re-includes the fixtures
+	commitFiles(t, repo, "Version fixtures", map[string]string{".bumpignore": "!testdata\n"})
+	err = run(context.Background(), &output, []string{"-minor"}, nil)
+	if err != nil {
+		t.Fatalf("bump: %v\nOutput: %s", err, output.String())
+	}
+	if got := readFile(t, "parser/testdata/old/.version"); got != "v1.2.0\n" {
+		t.Errorf("Expected !testdata to re-include the fixture, got %q", got)
+	}
+}
+
+func TestBumpProtect(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Add fixtures", map[string]string{
+		".bumpprotect":                "# golden files\ninternal/golden/\n",
+		"internal/golden/v1/.version": "v0.9.0\n",
+	})
+	commits := countCommits(t, repo)
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-minor"}, nil)
+	want := "refusing to bump protected version file internal/golden/v1/.version (matches 'internal/golden' in .bumpprotect)"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("Expected the bump to abort, got: %v", err)
+	}
+	if countCommits(t, repo) != commits || readFile(t, ".version") != "v1.0.0" {
+		t.Error("An aborted bump wrote the release")
+	}
+	if exists, _ := tagExists(repo, "v1.1.0"); exists {
+		t.Error("An aborted bump created the tag")
+	}
+}
+
+func TestLoadProtectedPaths(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".bumpprotect")
+	err := os.WriteFile(file, []byte("/fixtures/  # leading and trailing slashes\n\n*/legacy/.version\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patterns, err := loadProtectedPaths(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"fixtures/a/.version":   "fixtures",
+		"app/legacy/.version":   "*/legacy/.version",
+		"app/fixtures/.version": "",
+		".version":              "",
+	}
+	for file, want := range tests {
+		if got := protectedBy(patterns, file); got != want {
+			t.Errorf("protectedBy(%s) = %q, want %q", file, got, want)
+		}
+	}
+
+	err = os.WriteFile(file, []byte("ok\n[bad\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = loadProtectedPaths(file)
+	if err == nil || err.Error() != "line 2: invalid pattern '[bad'" {
+		t.Errorf("Expected an invalid pattern error, got: %v", err)
+	}
+}
+
+func TestShouldIgnoreNegated(t *testing.T) {
+	rules := []ignoreRule{{pattern: "testdata"}, {pattern: "testdata", negated: true}}
+	if shouldIgnore("a/testdata", "testdata", rules) {
+		t.Error("Expected a later negated rule to re-include the directory")
+	}
+	if !shouldIgnore("a/testdata", "testdata", rules[:1]) {
+		t.Error("Expected the directory to be ignored")
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5"
+)
+
+// Trailers of a release commit made with -provenance. Together they chain
+// every release to the previous one: the signature covers the release's
+// version, the digest of its .version file and the previous release's tag
+// and commit.
+const (
+	versionDigestTrailer       = "Version-SHA256"
+	previousReleaseTrailer     = "Previous-Release"
+	provenanceSignatureTrailer = "Provenance-Signature"
+)
+
+// noPreviousRelease is the Previous-Release of the first release.
+const noPreviousRelease = "none"
+
+// provenance is the signed statement of a release.
+type provenance struct {
+	version  string
+	digest   string // hex SHA-256 of the root .version (see versionDigest)
+	previous string // "<tag> <commit>" of the previous release, or noPreviousRelease
+}
+
+func (p provenance) statement() []byte {
+	return []byte(fmt.Sprintf("version: %s\nversion-sha256: %s\nprevious: %s\n", p.version, p.digest, p.previous))
+}
+
+// versionDigest hashes .version content. Line endings are normalized, since
+// git may convert them between the worktree and the repository.
+func versionDigest(content []byte) string {
+	digest := sha256.Sum256(bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n")))
+	return hex.EncodeToString(digest[:])
+}
+
+// provenanceTrailers returns the signed provenance trailers for the release
+// commit of newVersion, or "" without -provenance. The signing key comes
+// from BUMP_PROVENANCE_KEY.
+func provenanceTrailers(repo vcs, cfg config, env []string, currentVersion, newVersion string) (string, error) {
+	if !cfg.provenance {
+		return "", nil
+	}
+	gitRepo, err := gitRepository(repo, "-provenance")
+	if err != nil {
+		return "", err
+	}
+	if getenv(env, "BUMP_PROVENANCE_KEY") == "" {
+		return "", errors.New("-provenance needs the signing key in BUMP_PROVENANCE_KEY")
+	}
+	key, err := loadPrivateKey(env, "BUMP_PROVENANCE_KEY")
+	if err != nil {
+		return "", err
+	}
+	rules, err := loadWorktreeRules()
+	if err != nil {
+		return "", err
+	}
+	content, err := os.ReadFile(".version")
+	if err != nil || rules.excluded(".version") {
+		return "", errors.New("-provenance needs a root .version file that bump updates")
+	}
+
+	p := provenance{
+		version:  newVersion,
+		digest:   versionDigest(versionFileContent(cfg, rules, ".version", content, newVersion)),
+		previous: noPreviousRelease,
+	}
+	if currentVersion != "" {
+		commit, err := tagCommit(gitRepo, currentVersion)
+		if err != nil {
+			return "", err
+		}
+		p.previous = currentVersion + " " + commit.Hash.String()
+	}
+	var signature bytes.Buffer
+	err = openpgp.DetachSign(&signature, key, bytes.NewReader(p.statement()), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign provenance: %w", err)
+	}
+	return fmt.Sprintf("%s: %s\n%s: %s\n%s: %s",
+		versionDigestTrailer, p.digest,
+		previousReleaseTrailer, p.previous,
+		provenanceSignatureTrailer, base64.StdEncoding.EncodeToString(signature.Bytes())), nil
+}
+
+// commitTrailers returns the "Key: value" trailers of the last paragraph of
+// a commit message.
+func commitTrailers(message string) map[string]string {
+	paragraphs := strings.Split(strings.TrimSpace(message), "\n\n")
+	trailers := make(map[string]string)
+	for _, line := range strings.Split(paragraphs[len(paragraphs)-1], "\n") {
+		key, value, ok := strings.Cut(line, ": ")
+		if ok && !strings.ContainsAny(key, " \t") {
+			trailers[key] = strings.TrimSpace(value)
+		}
+	}
+	return trailers
+}
+
+// runVerifyChain implements "bump verify-chain": it walks the provenance
+// chain back from a release, the last version tag by default, and verifies
+// every release on it.
+func runVerifyChain(_ context.Context, output io.Writer, args []string, env []string) error {
+	flagSet := flag.NewFlagSet("verify-chain", flag.ContinueOnError)
+	keyFile := flagSet.String("key", "", "Armored OpenPGP public key(s) to verify with (default: BUMP_PROVENANCE_PUBKEY).")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if flagSet.NArg() > 1 {
+		return fmt.Errorf("unexpected arguments: %s", flagSet.Args()[1:])
+	}
+	source := "-key"
+	if *keyFile == "" {
+		*keyFile, source = getenv(env, "BUMP_PROVENANCE_PUBKEY"), "BUMP_PROVENANCE_PUBKEY"
+	}
+	if *keyFile == "" {
+		return errors.New("no key to verify with: use -key or set BUMP_PROVENANCE_PUBKEY")
+	}
+	keys, err := loadKeyRing(*keyFile, source)
+	if err != nil {
+		return err
+	}
+
+	repo, err := git.PlainOpen(".")
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	release := flagSet.Arg(0)
+	if release == "" {
+		release, err = lastTag(repo)
+		if err != nil {
+			return fmt.Errorf("failed to get last tag: %w", err)
+		}
+	}
+
+	verified := 0
+	seen := make(map[string]bool)
+	for release != "" {
+		if seen[release] {
+			return fmt.Errorf("%s: the chain loops", release)
+		}
+		seen[release] = true
+		previous, signed, err := verifyRelease(repo, keys, release)
+		if err != nil {
+			return fmt.Errorf("%s: %w", release, err)
+		}
+		if !signed {
+			if verified == 0 {
+				return fmt.Errorf("%s: release has no provenance", release)
+			}
+			_, _ = fmt.Fprintf(output, "%s: chain starts after this release\n", release)
+			break
+		}
+		_, _ = fmt.Fprintf(output, "%s: ok\n", release)
+		verified++
+		release = previous
+	}
+	_, _ = fmt.Fprintf(output, "Verified the provenance of %d release(s)\n", verified)
+	return nil
+}
+
+// verifyRelease verifies the provenance of the release commit of a tag and
+// returns the previous release on the chain, "" for none. signed is false if
+// the commit carries no provenance.
+func verifyRelease(repo *git.Repository, keys openpgp.EntityList, tag string) (previous string, signed bool, err error) {
+	commit, err := tagCommit(repo, tag)
+	if err != nil {
+		return "", false, err
+	}
+	trailers := commitTrailers(commit.Message)
+	encoded, ok := trailers[provenanceSignatureTrailer]
+	if !ok {
+		return "", false, nil
+	}
+	p := provenance{version: tag, digest: trailers[versionDigestTrailer], previous: trailers[previousReleaseTrailer]}
+
+	signature, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", true, fmt.Errorf("malformed %s: %w", provenanceSignatureTrailer, err)
+	}
+	_, err = openpgp.CheckDetachedSignature(keys, bytes.NewReader(p.statement()), bytes.NewReader(signature), nil)
+	if err != nil {
+		return "", true, fmt.Errorf("invalid signature: %w", err)
+	}
+
+	file, err := commit.File(".version")
+	if err != nil {
+		return "", true, fmt.Errorf("release commit %s has no .version: %w", commit.Hash, err)
+	}
+	content, err := file.Contents()
+	if err != nil {
+		return "", true, err
+	}
+	if versionDigest([]byte(content)) != p.digest {
+		return "", true, fmt.Errorf(".version of %s doesn't match its signed digest", commit.Hash)
+	}
+
+	if p.previous == noPreviousRelease {
+		return "", true, nil
+	}
+	previousTag, previousHash, ok := strings.Cut(p.previous, " ")
+	if !ok {
+		return "", true, fmt.Errorf("malformed %s '%s'", previousReleaseTrailer, p.previous)
+	}
+	previousCommit, err := tagCommit(repo, previousTag)
+	if err != nil {
+		return "", true, fmt.Errorf("previous release: %w", err)
+	}
+	if previousCommit.Hash.String() != previousHash {
+		return "", true, fmt.Errorf("previous release %s was signed at %s but is tagged at %s", previousTag, previousHash, previousCommit.Hash)
+	}
+	return previousTag, true, nil
+}
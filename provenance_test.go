@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+func TestCommitTrailers(t *testing.T) {
+	trailers := commitTrailers("bump version to v1.0.1\n\nSome: body text here\n\nVersion-SHA256: abc\nPrevious-Release: v1.0.0 123\n")
+	if len(trailers) != 2 || trailers["Version-SHA256"] != "abc" || trailers["Previous-Release"] != "v1.0.0 123" {
+		t.Errorf("Unexpected trailers %v", trailers)
+	}
+	if trailers := commitTrailers("bump version to v1.0.1"); len(trailers) != 0 {
+		t.Errorf("Expected no trailers, got %v", trailers)
+	}
+}
+
+func TestProvenanceChain(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	key, keyFile := testSigningKey(t)
+	pubKey := testPublicKey(t, key)
+	env := []string{"BUMP_PROVENANCE_KEY=" + keyFile, "BUMP_PROVENANCE_KEY_PASSPHRASE=secret"}
+
+	var output bytes.Buffer
+	for i, args := range [][]string{{"-provenance"}, {"-provenance", "-force", "-minor"}} {
+		err := run(context.Background(), &output, args, env)
+		if err != nil {
+			t.Fatalf("Expected bump %d to succeed, got: %v\nOutput: %s", i+1, err, output.String())
+		}
+	}
+	commit, err := tagCommit(repo, "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous, err := tagCommit(repo, "v1.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trailers := commitTrailers(commit.Message)
+	if trailers[previousReleaseTrailer] != "v1.0.1 "+previous.Hash.String() || trailers[versionDigestTrailer] != versionDigest([]byte("v1.1.0")) {
+		t.Errorf("Unexpected trailers in the release commit:\n%s", commit.Message)
+	}
+
+	output.Reset()
+	err = run(context.Background(), &output, []string{"verify-chain", "-key", pubKey}, nil)
+	if err != nil {
+		t.Fatalf("Expected the chain to verify, got: %v\nOutput: %s", err, output.String())
+	}
+	want := "v1.1.0: ok\nv1.0.1: ok\nv1.0.0: chain starts after this release\nVerified the provenance of 2 release(s)\n"
+	if output.String() != want {
+		t.Errorf("Got output:\n%s\nwant:\n%s", output.String(), want)
+	}
+
+	// another key doesn't verify the chain
+	other, _ := testSigningKey(t)
+	err = run(context.Background(), &output, []string{"verify-chain", "-key", testPublicKey(t, other)}, nil)
+	if err == nil || !strings.Contains(err.Error(), "v1.1.0: invalid signature") {
+		t.Errorf("Expected a signature error, got: %v", err)
+	}
+
+	// neither does moving a release tag in the middle of the chain
+	err = repo.DeleteTag("v1.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.CreateTag("v1.0.1", commit.Hash, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = run(context.Background(), &output, []string{"verify-chain", "-key", pubKey}, nil)
+	if err == nil || !strings.Contains(err.Error(), "v1.1.0: previous release v1.0.1 was signed at "+previous.Hash.String()) {
+		t.Errorf("Expected a moved tag to break the chain, got: %v", err)
+	}
+	err = run(context.Background(), &output, []string{"verify-chain", "-key", pubKey, "v1.0.0"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "v1.0.0: release has no provenance") {
+		t.Errorf("Expected an unsigned release to fail, got: %v", err)
+	}
+}
+
+func TestProvenanceNeedsKey(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commits := countCommits(t, repo)
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-provenance"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "-provenance needs the signing key in BUMP_PROVENANCE_KEY") {
+		t.Errorf("Expected a missing key error, got: %v", err)
+	}
+	if countCommits(t, repo) != commits {
+		t.Error("Expected no commit without a signing key")
+	}
+	_, _, err = getConfig([]string{"-provenance", "-commit-per-module"})
+	if err == nil {
+		t.Error("Expected -provenance and -commit-per-module to conflict")
+	}
+}
+
+// testPublicKey writes the public part of key to an armored key file.
+func testPublicKey(t *testing.T, key *openpgp.Entity) string {
+	t.Helper()
+	var armored bytes.Buffer
+	w, err := armor.Encode(&armored, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = key.Serialize(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = w.Close()
+	keyFile := filepath.Join(t.TempDir(), "release.pub.asc")
+	err = os.WriteFile(keyFile, armored.Bytes(), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyFile
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// checkPush fails the bump before anything is written if -push can't work:
+// the release commit must be on a branch and the push remote must exist, with
+// credentials if its host needs them (see remoteAuth).
+func checkPush(repo vcs, cfg config, env []string) error {
+	gitRepo, err := gitRepository(repo, "-push")
+	if err != nil {
+		return err
+	}
+	branch, err := repo.branch()
+	if err != nil {
+		return err
+	}
+	if branch == "" {
+		return errors.New("-push needs a branch to push, HEAD is detached")
+	}
+	r, err := resolveRemotes(gitRepo, cfg)
+	if err != nil {
+		return err
+	}
+	remote, err := gitRepo.Remote(r.push)
+	if err != nil {
+		return fmt.Errorf("failed to get push remote '%s': %w", r.push, err)
+	}
+	_, err = remoteAuth(remote, env)
+	return err
+}
+
+// protectedTagMessages are what forges answer when a tag push is refused
+// because the tag is protected: GitLab's protected tags and GitHub's tag
+// rulesets.
+var protectedTagMessages = []string{"protected", "creations being restricted"}
+
+// pushRelease publishes the release (-push) to the push remote: the branch
+// first, then the tag, so the remote never has a tag of a commit its branch
+// doesn't have. The -push-option options go with both pushes; go-git drops
+// them for a remote that doesn't support push options. Each push emits a push
+// event before and after it, or with the error when it fails. Nothing is
+// undone when a push fails: the local release is complete and the error says
+// how to finish publishing it: with "bump push --retry" or git push.
+func pushRelease(ctx context.Context, repo vcs, cfg config, env []string, output io.Writer, tag string) error {
+	gitRepo, err := gitRepository(repo, "-push")
+	if err != nil {
+		return err
+	}
+	r, err := resolveRemotes(gitRepo, cfg)
+	if err != nil {
+		return err
+	}
+	branch, err := repo.branch()
+	if err != nil {
+		return err
+	}
+	remote, err := gitRepo.Remote(r.push)
+	if err != nil {
+		return fmt.Errorf("failed to get push remote '%s': %w", r.push, err)
+	}
+	refs := []plumbing.ReferenceName{plumbing.NewBranchReferenceName(branch), plumbing.NewTagReferenceName(tag)}
+	var options map[string]string
+	for _, option := range cfg.pushOptions {
+		if options == nil {
+			options = make(map[string]string)
+		}
+		// go-git sends a key without a value as "key=", which GitLab reads
+		// like the key alone
+		key, value, _ := strings.Cut(option, "=")
+		options[key] = value
+	}
+	if cfg.dryRun {
+		_, _ = fmt.Fprintf(output, "Would push branch %s and tag %s to %s\n", branch, tag, r.push)
+		return nil
+	}
+	for i, ref := range refs {
+		_, _ = fmt.Fprintf(output, "Pushing %s to %s\n", ref.Short(), r.push)
+		cfg.eventSink.emit(progressEvent{Phase: phasePush, Ref: ref.Short()})
+		// CodeCommit signatures expire, so each push gets fresh credentials
+		auth, err := remoteAuth(remote, env)
+		var messages bytes.Buffer // the remote's, e.g. why a hook refused the push
+		if err == nil {
+			err = gitRepo.PushContext(ctx, &git.PushOptions{
+				RemoteName: r.push,
+				RefSpecs:   []gitconfig.RefSpec{gitconfig.RefSpec(ref + ":" + ref)},
+				Auth:       auth,
+				Options:    options,
+				Progress:   &messages,
+			})
+		}
+		if errors.Is(err, git.NoErrAlreadyUpToDate) {
+			err = nil
+		}
+		if err == nil {
+			cfg.eventSink.emit(progressEvent{Phase: phasePush, Ref: ref.Short(), Pushed: true})
+			continue
+		}
+		cfg.eventSink.emit(progressEvent{Phase: phasePush, Ref: ref.Short(), Error: err.Error()})
+		if ref.IsTag() && protectedTagRejection(err, messages.String()) {
+			return fmt.Errorf("tag %s is protected on %s, and the push was refused: %w\nGive the pushing user permission to create it, such as the Maintainer role on GitLab or a bypass of the tag ruleset on GitHub. The release is complete locally; publish it with: %s", tag, r.push, err, retryCommands(r.push, cfg, tag, refs[i:]))
+		}
+		return fmt.Errorf("failed to push %s to %s: %w\nThe release is complete locally; publish it with: %s", ref.Short(), r.push, err, retryCommands(r.push, cfg, tag, refs[i:]))
+	}
+	return nil
+}
+
+// protectedTagRejection reports whether a failed tag push was refused
+// because the tag is protected, from the error or the remote's messages.
+func protectedTagRejection(err error, messages string) bool {
+	text := strings.ToLower(err.Error() + "\n" + messages)
+	for _, m := range protectedTagMessages {
+		if strings.Contains(text, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryCommands are the commands publishing what a failed push left: bump's
+// retry, which pushes the branch and the tag again, and the git push of the
+// remaining refs.
+func retryCommands(remote string, cfg config, tag string, refs []plumbing.ReferenceName) string {
+	args := []string{"bump", "push", "--retry", "-push-remote", remote}
+	if cfg.branch != "" {
+		args = append(args, "-branch", cfg.branch)
+	}
+	if len(cfg.pushOptions) > 0 {
+		args = append(args, "-push-option", strings.Join(cfg.pushOptions, ","))
+	}
+	args = append(args, tag)
+	return strings.Join(args, " ") + "\nor: " + pushCommand(remote, cfg.pushOptions, refs)
+}
+
+// runPush implements "bump push --retry": publishing a release whose -push
+// failed, by pushing the branch and the existing tag again with pushRelease.
+// Nothing is recomputed or re-tagged, so the tag must still be on the tip of
+// the branch; the tag defaults to the last version tag.
+func runPush(ctx context.Context, output io.Writer, args []string, env []string) error {
+	const usage = "usage: bump push --retry [-remote name] [-push-remote name] [-branch name] [-push-option options] [-dry-run] [tag]"
+	flagSet := flag.NewFlagSet("push", flag.ContinueOnError)
+	retry := flagSet.Bool("retry", false, "Push the branch and the tag of a release whose push failed.")
+	var cfg config
+	var pushOptions string
+	flagSet.StringVar(&cfg.remote, "remote", getenv(env, "BUMP_REMOTE"), "Remote tags are resolved from (default: BUMP_REMOTE or origin).")
+	flagSet.StringVar(&cfg.pushRemote, "push-remote", getenv(env, "BUMP_PUSH_REMOTE"), "Remote to push to (default: BUMP_PUSH_REMOTE, remote.pushDefault or -remote).")
+	flagSet.StringVar(&cfg.branch, "branch", "", "Branch the release was made on with -branch (default: the checked-out branch).")
+	flagSet.StringVar(&pushOptions, "push-option", "", "Comma-separated git push options sent with both pushes.")
+	flagSet.BoolVar(&cfg.dryRun, "dry-run", false, "Print what would be pushed without pushing.")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if !*retry || flagSet.NArg() > 1 {
+		return errors.New(usage)
+	}
+	cfg.pushOptions, err = parsePushOptions(pushOptions)
+	if err != nil {
+		return err
+	}
+	gitRepo, err := git.PlainOpen(".")
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	var repo vcs = &gitVCS{repo: gitRepo}
+	if cfg.branch != "" {
+		repo, err = withBranch(repo, cfg.branch, nil)
+		if err != nil {
+			return err
+		}
+	}
+	tag := flagSet.Arg(0)
+	if tag == "" {
+		tag, err = lastTag(gitRepo)
+		if err != nil {
+			return err
+		}
+	}
+	commit, err := tagCommit(gitRepo, tag)
+	if err != nil {
+		return err
+	}
+	head, err := repo.head()
+	if err != nil {
+		return err
+	}
+	if commit.Hash.String() != head {
+		return fmt.Errorf("tag %s is not on the tip of the branch: the branch has moved on since the release, push it by hand", tag)
+	}
+	err = checkPush(repo, cfg, env)
+	if err != nil {
+		return err
+	}
+	return pushRelease(ctx, repo, cfg, env, output, tag)
+}
+
+// pushCommand is the git command publishing the refs by hand.
+func pushCommand(remote string, options []string, refs []plumbing.ReferenceName) string {
+	args := []string{"git", "push", "--atomic"}
+	for _, option := range options {
+		args = append(args, "-o", option)
+	}
+	args = append(args, remote)
+	for _, ref := range refs {
+		args = append(args, ref.Short())
+	}
+	return strings.Join(args, " ")
+}
+
+// parsePushOptions parses the comma-separated -push-option options, each a
+// key or key=value. A key can only be given once.
+func parsePushOptions(value string) ([]string, error) {
+	var options []string
+	seen := make(map[string]bool)
+	for _, option := range strings.Split(value, ",") {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			continue
+		}
+		key, _, _ := strings.Cut(option, "=")
+		if key == "" || strings.ContainsAny(option, "\n\x00") {
+			return nil, fmt.Errorf("invalid -push-option '%s': expected key or key=value", option)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("invalid -push-option '%s': %s is already given", option, key)
+		}
+		seen[key] = true
+		options = append(options, option)
+	}
+	return options, nil
+}
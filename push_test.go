@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestBumpPush(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	remoteDir := t.TempDir()
+	remote, err := git.PlainInit(remoteDir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{remoteDir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := filepath.Join(t.TempDir(), "events.jsonl")
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-minor", "-push", "-events", events}, nil)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	branch, err := remote.Reference(plumbing.NewBranchReferenceName("master"), true)
+	if err != nil || branch.Hash() != mustHead(t, repo) {
+		t.Errorf("Expected the release commit to be pushed to master, got %v (%v)", branch, err)
+	}
+	if exists, _ := tagExists(remote, "v1.1.0"); !exists {
+		t.Error("Expected the tag to be pushed")
+	}
+	if !strings.Contains(output.String(), "Pushing master to origin\nPushing v1.1.0 to origin\n") {
+		t.Errorf("Expected the branch to be pushed before the tag, got:\n%s", output.String())
+	}
+	var got []string
+	for _, event := range readEvents(t, events) {
+		if event.Phase == phasePush {
+			got = append(got, fmt.Sprintf("%s %t", event.Ref, event.Pushed))
+		}
+	}
+	want := []string{"master false", "master true", "v1.1.0 false", "v1.1.0 true"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Got push events %v, want %v", got, want)
+	}
+}
+
+func TestBumpPushRejected(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	// the fork rejects pushes to its checked-out master
+	fork := addNamedTestRemote(t, repo, "fork")
+	events := filepath.Join(t.TempDir(), "events.jsonl")
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-minor", "-push", "-push-remote", "fork", "-events", events}, nil)
+	if err == nil || !strings.Contains(err.Error(), "failed to push master to fork: command error on refs/heads/master") ||
+		!strings.Contains(err.Error(), "publish it with: bump push --retry -push-remote fork v1.1.0\nor: git push --atomic fork master v1.1.0") {
+		t.Fatalf("Expected a rejected push with recovery instructions, got: %v\nOutput: %s", err, output.String())
+	}
+	if exists, _ := tagExists(repo, "v1.1.0"); !exists {
+		t.Error("Expected the local release to be kept")
+	}
+	if exists, _ := tagExists(fork, "v1.1.0"); exists {
+		t.Error("Expected the tag not to be pushed after the branch was rejected")
+	}
+	got := readEvents(t, events)
+	failed := got[len(got)-2]
+	if failed.Phase != phasePush || failed.Ref != "master" || failed.Pushed || !strings.Contains(failed.Error, "command error") {
+		t.Errorf("Expected a push event with the error before the final error event, got %+v", failed)
+	}
+}
+
+func TestPushRetry(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	fork := addNamedTestRemote(t, repo, "fork")
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-minor", "-push", "-push-remote", "fork"}, nil)
+	if err == nil {
+		t.Fatal("Expected the push to the fork's checked-out branch to be rejected")
+	}
+	// the cause is fixed: the fork accepts pushes to its checked-out branch
+	forkConfig, err := fork.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	forkConfig.Raw.Section("receive").SetOption("denyCurrentBranch", "ignore")
+	err = fork.SetConfig(forkConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output.Reset()
+	err = run(context.Background(), &output, []string{"push", "--retry", "-push-remote", "fork"}, nil)
+	if err != nil {
+		t.Fatalf("Expected the retry to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	branch, err := fork.Reference(plumbing.NewBranchReferenceName("master"), true)
+	if err != nil || branch.Hash() != mustHead(t, repo) {
+		t.Errorf("Expected the release commit to be pushed to master, got %v (%v)", branch, err)
+	}
+	if exists, _ := tagExists(fork, "v1.1.0"); !exists {
+		t.Error("Expected the existing tag to be pushed")
+	}
+	if exists, _ := tagExists(repo, "v1.2.0"); exists {
+		t.Error("Expected the retry not to release again")
+	}
+
+	commitFiles(t, repo, "After the release", map[string]string{"new.txt": "new\n"})
+	err = run(context.Background(), &output, []string{"push", "--retry", "-push-remote", "fork", "v1.1.0"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "tag v1.1.0 is not on the tip of the branch") {
+		t.Errorf("Expected a retry after new commits to fail, got: %v", err)
+	}
+	err = run(context.Background(), &output, []string{"push"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "usage: bump push --retry") {
+		t.Errorf("Expected usage without --retry, got: %v", err)
+	}
+}
+
+func TestBumpPushDetachedHead(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Checkout(&git.CheckoutOptions{Hash: mustHead(t, repo)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-minor", "-push"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "HEAD is detached") {
+		t.Fatalf("Expected -push to refuse a detached HEAD, got: %v", err)
+	}
+	if exists, _ := tagExists(repo, "v1.1.0"); exists {
+		t.Error("Expected nothing to be released")
+	}
+}
+
+func TestBumpPushOptionsAndProtectedTag(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	remoteDir := t.TempDir()
+	remote, err := git.PlainInit(remoteDir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := remote.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Raw.Section("receive").SetOption("advertisePushOptions", "true")
+	err = remote.SetConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the hook records the push options and, like GitLab, refuses protected tags
+	hook := `#!/bin/sh
+for i in $(seq 0 $((${GIT_PUSH_OPTION_COUNT:-0} - 1))); do
+	eval "echo \$GIT_PUSH_OPTION_$i" >> "$GIT_DIR/push-options"
+done
+while read old new ref; do
+	case "$ref" in refs/tags/v2.*)
+		echo "GitLab: You are not allowed to create this tag as it is protected." >&2
+		exit 1
+	esac
+done
+`
+	err = os.MkdirAll(filepath.Join(remoteDir, "hooks"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(remoteDir, "hooks", "pre-receive"), []byte(hook), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{remoteDir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-minor", "-push", "-push-option", "ci.skip,merge_request.label=release"}, nil)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	sent, err := os.ReadFile(filepath.Join(remoteDir, "push-options"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(sent), "merge_request.label=release\n"); got != 2 || strings.Count(string(sent), "ci.skip") != 2 {
+		t.Errorf("Expected the options with both pushes, got:\n%s", sent)
+	}
+
+	output.Reset()
+	err = run(context.Background(), &output, []string{"-major", "-allow-empty-release", "-push", "-push-option", "ci.skip"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "tag v2.0.0 is protected on origin") ||
+		!strings.Contains(err.Error(), "publish it with: bump push --retry -push-remote origin -push-option ci.skip v2.0.0\nor: git push --atomic -o ci.skip origin v2.0.0") {
+		t.Fatalf("Expected a protected tag error, got: %v\nOutput: %s", err, output.String())
+	}
+
+	for want, args := range map[string][]string{
+		"-push-option needs -push":  {"-push-option", "ci.skip"},
+		"ci.skip is already given":  {"-push", "-push-option", "ci.skip,ci.skip"},
+		"expected key or key=value": {"-push", "-push-option", "=x"},
+	} {
+		_, _, err := getConfig(args)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("getConfig(%v) error = %v, want %s", args, err, want)
+		}
+	}
+}
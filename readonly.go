@@ -0,0 +1,66 @@
+package main
+
+import "fmt"
+
+// readOnlyCommands are the subcommands that never write to the worktree,
+// refs or the network, and can run under -assert-read-only.
+var readOnlyCommands = map[string]bool{
+	"affected":       true,
+	"check-embed":    true,
+	"compat":         true,
+	"export-history": true,
+	"inspect-binary": true,
+	"show":           true,
+	"since":          true,
+	"status":         true,
+	"train":          true,
+	"verify-chain":   true,
+	"verify-release": true,
+}
+
+// isAssertReadOnly reports whether arg is the -assert-read-only flag.
+func isAssertReadOnly(arg string) bool {
+	return arg == "-assert-read-only" || arg == "--assert-read-only"
+}
+
+// checkReadOnlyCommand fails for subcommands that can write.
+func checkReadOnlyCommand(name string) error {
+	if !readOnlyCommands[name] {
+		return fmt.Errorf("'bump %s' can write and can't run with -assert-read-only", name)
+	}
+	return nil
+}
+
+// readOnlyVCS refuses every write to the repository. -assert-read-only
+// implies -dry-run, which already skips them; this is the safety net for
+// code paths that would not.
+type readOnlyVCS struct {
+	vcs
+}
+
+// readOnlyGitVCS is readOnlyVCS for the git backends, which the git-only
+// features read through.
+type readOnlyGitVCS struct {
+	readOnlyVCS
+	goGitBackend
+}
+
+// withReadOnly wraps repo for -assert-read-only.
+func withReadOnly(repo vcs) vcs {
+	if g, ok := repo.(goGitBackend); ok {
+		return readOnlyGitVCS{readOnlyVCS: readOnlyVCS{repo}, goGitBackend: g}
+	}
+	return readOnlyVCS{repo}
+}
+
+func (readOnlyVCS) add(path string) error {
+	return fmt.Errorf("-assert-read-only: refusing to stage %s", path)
+}
+
+func (readOnlyVCS) commit(string) error {
+	return fmt.Errorf("-assert-read-only: refusing to commit")
+}
+
+func (readOnlyVCS) createTag(name, _ string) (string, error) {
+	return "", fmt.Errorf("-assert-read-only: refusing to create tag %s", name)
+}
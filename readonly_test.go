@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAssertReadOnly(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitsBefore := countCommits(t, repo)
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-assert-read-only", "-minor"}, nil)
+	if err != nil {
+		t.Fatalf("Expected a read-only run to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	if !strings.Contains(output.String(), "Updating version in file .version to v1.1.0") {
+		t.Errorf("Expected the release to be previewed, got:\n%s", output.String())
+	}
+	if countCommits(t, repo) != commitsBefore {
+		t.Error("Expected no commits")
+	}
+	if exists, _ := tagExists(repo, "v1.1.0"); exists {
+		t.Error("Expected no tag")
+	}
+	if content := readFile(t, ".version"); strings.TrimSpace(content) != "v1.0.0" {
+		t.Errorf("Expected .version to be untouched, got %q", content)
+	}
+
+	_, _, err = getConfig([]string{"-assert-read-only", "-autostash"})
+	if err == nil {
+		t.Error("Expected -assert-read-only and -autostash to conflict")
+	}
+
+	output.Reset()
+	err = run(context.Background(), &output, []string{"-assert-read-only", "status"}, nil)
+	if err != nil {
+		t.Fatalf("Expected a read-only status, got: %v", err)
+	}
+	if !strings.HasPrefix(output.String(), "Branch:") {
+		t.Errorf("Expected the status, got:\n%s", output.String())
+	}
+	err = run(context.Background(), &output, []string{"-assert-read-only", "changelog", "add", "Entry"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "can't run with -assert-read-only") {
+		t.Errorf("Expected changelog add to be refused, got: %v", err)
+	}
+}
+
+func TestReadOnlyVCS(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	readOnly := withReadOnly(&gitVCS{repo: repo})
+	if _, err := gitRepository(readOnly, "test"); err != nil {
+		t.Errorf("Expected the git repository to stay readable, got: %v", err)
+	}
+	if version, err := readOnly.lastTag(); err != nil || version != "v1.0.0" {
+		t.Errorf("Expected reads to pass through, got %s, %v", version, err)
+	}
+	if err := readOnly.add(".version"); err == nil {
+		t.Error("Expected add to be refused")
+	}
+	if err := readOnly.commit("message"); err == nil {
+		t.Error("Expected commit to be refused")
+	}
+	if _, err := readOnly.createTag("v1.1.0", ""); err == nil {
+		t.Error("Expected createTag to be refused")
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"golang.org/x/mod/semver"
+)
+
+// Actions of the .bumpreconcile rules.
+const (
+	// reconcilePromote releases a settled prerelease as the final version
+	reconcilePromote = "promote"
+	// reconcileExpire deletes the tags of prereleases that were never
+	// promoted
+	reconcileExpire = "expire"
+)
+
+// reconcileRule is a rule from .bumpreconcile, applied by "bump reconcile" to
+// the prereleases whose first identifier (rc in v1.2.0-rc.3) matches.
+type reconcileRule struct {
+	line       int
+	action     string
+	identifier string
+	days       int
+}
+
+// loadReconcileRules reads the prerelease rules. The format is line based:
+//
+//	promote rc after 7d     # an rc without new commits for 7 days is final
+//	expire beta after 30d   # betas never promoted are dropped after 30 days
+func loadReconcileRules(path string) ([]reconcileRule, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []reconcileRule
+	for i, line := range strings.Split(string(content), "\n") {
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 4 || (fields[0] != reconcilePromote && fields[0] != reconcileExpire) || fields[2] != "after" {
+			return nil, fmt.Errorf("line %d: expected 'promote|expire <identifier> after <days>d'", i+1)
+		}
+		rule := reconcileRule{line: i + 1, action: fields[0], identifier: fields[1]}
+		if !semver.IsValid("v0.0.0-"+rule.identifier) || strings.Contains(rule.identifier, ".") {
+			return nil, fmt.Errorf("line %d: invalid prerelease identifier '%s'", rule.line, rule.identifier)
+		}
+		rule.days, err = strconv.Atoi(strings.TrimSuffix(fields[3], "d"))
+		if err != nil || rule.days <= 0 {
+			return nil, fmt.Errorf("line %d: invalid age '%s': must be a number of days like 7d", rule.line, fields[3])
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// prereleaseIdentifier returns the first identifier of a prerelease version,
+// rc for v1.2.0-rc.3, or "" for a release.
+func prereleaseIdentifier(version string) string {
+	identifier, _, _ := strings.Cut(strings.TrimPrefix(semver.Prerelease(normalizeVersion(version)), "-"), ".")
+	return identifier
+}
+
+// finalVersion returns the release a prerelease leads up to, spelled like it.
+func finalVersion(version string) string {
+	canonical := semver.Canonical(normalizeVersion(version))
+	return semverScheme{}.format(strings.TrimSuffix(canonical, semver.Prerelease(canonical)), version)
+}
+
+// runReconcile implements "bump reconcile": it applies the .bumpreconcile
+// rules, meant for a scheduled CI job. The newest version is promoted to
+// its final release by a regular bump, with all its checks, if it is a
+// prerelease matching a promote rule that is old enough and has no commits
+// after it. Prereleases matching an expire rule that are old enough and
+// were never released lose their tag.
+func runReconcile(ctx context.Context, output io.Writer, args []string, env []string) error {
+	flagSet := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	dryRun := flagSet.Bool("dry-run", false, "Show what would be promoted and expired.")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if flagSet.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", flagSet.Args())
+	}
+	rules, err := loadReconcileRules(".bumpreconcile")
+	if errors.Is(err, os.ErrNotExist) {
+		return errors.New("no .bumpreconcile: nothing to reconcile")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load .bumpreconcile: %w", err)
+	}
+	repo, err := git.PlainOpen(".")
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	tags, err := allVersionTags(repo)
+	if err != nil {
+		return err
+	}
+	released := make(map[string]bool)
+	for _, tag := range tags {
+		released[normalizeVersion(tag.version)] = true
+	}
+	dated := latestVCS{repo: repo, strategy: latestTagDate}
+	age := func(tag versionTag) (int, error) {
+		date, err := dated.date(tag.ref)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", tag.version, err)
+		}
+		return int(now().Sub(date).Hours() / 24), nil
+	}
+
+	var latest *versionTag
+	if version, err := highestVersion(tagVersions(tags)); err == nil {
+		for i := range tags {
+			if tags[i].version == version {
+				latest = &tags[i]
+			}
+		}
+	}
+
+	acted := false
+	for _, rule := range rules {
+		if rule.action != reconcilePromote || latest == nil {
+			continue
+		}
+		final := finalVersion(latest.version)
+		if prereleaseIdentifier(latest.version) != rule.identifier || released[normalizeVersion(final)] {
+			continue
+		}
+		days, err := age(*latest)
+		if err != nil {
+			return err
+		}
+		if days < rule.days {
+			continue
+		}
+		changed, err := hasChangesSinceTag(repo, latest.version)
+		if err != nil {
+			return err
+		}
+		if changed {
+			_, _ = fmt.Fprintf(output, "Not promoting %s: there are commits after it\n", latest.version)
+			continue
+		}
+		_, _ = fmt.Fprintf(output, "Promoting %s to %s: %d days without new commits (.bumpreconcile line %d)\n", latest.version, final, days, rule.line)
+		argv := []string{"-version", final, "-allow-empty-release"}
+		if *dryRun {
+			argv = append(argv, "-dry-run")
+		}
+		err = runBump(ctx, output, argv, env)
+		if err != nil {
+			return fmt.Errorf("failed to promote %s: %w", latest.version, err)
+		}
+		released[normalizeVersion(final)] = true
+		acted = true
+		break // one release per run
+	}
+
+	for _, rule := range rules {
+		if rule.action != reconcileExpire {
+			continue
+		}
+		for _, tag := range tags {
+			if prereleaseIdentifier(tag.version) != rule.identifier || released[normalizeVersion(finalVersion(tag.version))] {
+				continue
+			}
+			days, err := age(tag)
+			if err != nil {
+				return err
+			}
+			if days < rule.days {
+				continue
+			}
+			acted = true
+			if *dryRun {
+				_, _ = fmt.Fprintf(output, "Would expire %s: %d days old (.bumpreconcile line %d)\n", tag.version, days, rule.line)
+				continue
+			}
+			err = repo.DeleteTag(tag.ref.Name().Short())
+			if err != nil {
+				return fmt.Errorf("failed to delete tag %s: %w", tag.version, err)
+			}
+			_, _ = fmt.Fprintf(output, "Expired %s: %d days old (.bumpreconcile line %d), deleted its tag\n", tag.version, days, rule.line)
+		}
+	}
+	if !acted {
+		_, _ = fmt.Fprintln(output, "Nothing to reconcile")
+	}
+	return nil
+}
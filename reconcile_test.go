@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestReconcile(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Add reconcile rules", map[string]string{".bumpreconcile": "promote rc after 7d\nexpire beta after 30d\n"})
+	old := &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now().AddDate(0, 0, -40)},
+		Message: "old beta",
+	}
+	released, err := tagCommit(repo, "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tag := range []string{"v1.0.0-beta.1", "v1.1.0-beta.1"} {
+		_, err := repo.CreateTag(tag, released.Hash, old)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-version", "v1.1.0-rc.1"}, nil)
+	if err != nil {
+		t.Fatalf("bump: %v\nOutput: %s", err, output.String())
+	}
+
+	// the rc is too young, the unreleased beta has expired
+	output.Reset()
+	err = run(context.Background(), &output, []string{"reconcile"}, nil)
+	if err != nil {
+		t.Fatalf("reconcile: %v\nOutput: %s", err, output.String())
+	}
+	if !strings.Contains(output.String(), "Expired v1.1.0-beta.1: 40 days old (.bumpreconcile line 2)") || strings.Contains(output.String(), "Promoting") {
+		t.Errorf("Expected only the beta to expire, got: %s", output.String())
+	}
+	if exists, _ := tagExists(repo, "v1.1.0-beta.1"); exists {
+		t.Error("Expected the tag of the expired beta to be deleted")
+	}
+	if exists, _ := tagExists(repo, "v1.0.0-beta.1"); !exists {
+		t.Error("Expected the beta of the released v1.0.0 to be kept")
+	}
+
+	later := time.Now().AddDate(0, 0, 8)
+	now = func() time.Time { return later }
+	t.Cleanup(func() { now = time.Now })
+	output.Reset()
+	err = run(context.Background(), &output, []string{"reconcile", "-dry-run"}, nil)
+	if err != nil {
+		t.Fatalf("reconcile: %v\nOutput: %s", err, output.String())
+	}
+	if !strings.Contains(output.String(), "Promoting v1.1.0-rc.1 to v1.1.0: 8 days without new commits (.bumpreconcile line 1)") {
+		t.Errorf("Expected the rc to be promoted, got: %s", output.String())
+	}
+	if exists, _ := tagExists(repo, "v1.1.0"); exists {
+		t.Error("Expected -dry-run not to tag")
+	}
+
+	output.Reset()
+	err = run(context.Background(), &output, []string{"reconcile"}, nil)
+	if err != nil {
+		t.Fatalf("reconcile: %v\nOutput: %s", err, output.String())
+	}
+	if exists, _ := tagExists(repo, "v1.1.0"); !exists {
+		t.Errorf("Expected v1.1.0 to be released, got: %s", output.String())
+	}
+	if content, _ := os.ReadFile(".version"); strings.TrimSpace(string(content)) != "v1.1.0" {
+		t.Errorf("Expected .version to be updated by the promotion, got %q", content)
+	}
+
+	output.Reset()
+	err = run(context.Background(), &output, []string{"reconcile"}, nil)
+	if err != nil || !strings.Contains(output.String(), "Nothing to reconcile") {
+		t.Errorf("Expected nothing left to do, got %v: %s", err, output.String())
+	}
+}
+
+func TestReconcileNewCommits(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Add reconcile rules", map[string]string{".bumpreconcile": "promote rc after 7d\n"})
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-version", "v1.1.0-rc.1"}, nil)
+	if err != nil {
+		t.Fatalf("bump: %v\nOutput: %s", err, output.String())
+	}
+	commitFiles(t, repo, "Fix", map[string]string{"fix.txt": "fix"})
+	later := time.Now().AddDate(0, 0, 8)
+	now = func() time.Time { return later }
+	t.Cleanup(func() { now = time.Now })
+
+	output.Reset()
+	err = run(context.Background(), &output, []string{"reconcile"}, nil)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if !strings.Contains(output.String(), "Not promoting v1.1.0-rc.1: there are commits after it") {
+		t.Errorf("Expected the rc not to be promoted, got: %s", output.String())
+	}
+
+	err = os.WriteFile(".bumpreconcile", []byte("promote rc after a week\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = run(context.Background(), &output, []string{"reconcile"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "line 1: expected 'promote|expire <identifier> after <days>d'") {
+		t.Errorf("Expected an invalid rule error, got: %v", err)
+	}
+}
@@ -0,0 +1,547 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/go-git/go-git/v5"
+	"golang.org/x/mod/semver"
+)
+
+// githubRelease is the part of a GitHub release "bump release" works with.
+type githubRelease struct {
+	ID        int64  `json:"id"`
+	HTMLURL   string `json:"html_url"`
+	UploadURL string `json:"upload_url"`
+	Assets    []struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	} `json:"assets"`
+}
+
+// runRelease implements "bump release attach": adding artifacts built after
+// the release, and the final notes, to the GitHub release of an existing tag.
+func runRelease(ctx context.Context, output io.Writer, args []string, env []string) error {
+	const usage = "usage: bump release attach [-remote name] [-notes file] [-clobber] [-dry-run] <tag> [file...]"
+	if len(args) == 0 || args[0] != "attach" {
+		return errors.New(usage)
+	}
+	flagSet := flag.NewFlagSet("release attach", flag.ContinueOnError)
+	remoteName := flagSet.String("remote", defaultRemote, "Remote whose GitHub repository has the release.")
+	notesFile := flagSet.String("notes", "", "File whose content replaces the release notes.")
+	clobber := flagSet.Bool("clobber", false, "Replace assets of the same name instead of failing.")
+	dryRun := flagSet.Bool("dry-run", false, "Print what would be uploaded without changing the release.")
+	err := flagSet.Parse(args[1:])
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if flagSet.NArg() == 0 || (flagSet.NArg() == 1 && *notesFile == "") {
+		return errors.New(usage)
+	}
+	tag := flagSet.Arg(0)
+	files, err := releaseAssets(flagSet.Args()[1:])
+	if err != nil {
+		return err
+	}
+	var notes []byte
+	if *notesFile != "" {
+		notes, err = os.ReadFile(*notesFile)
+		if err != nil {
+			return fmt.Errorf("failed to read -notes: %w", err)
+		}
+	}
+
+	repo, err := git.PlainOpen(".")
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	project, err := githubRepository(repo, *remoteName, env)
+	if err != nil {
+		return err
+	}
+	gh, err := newGitHubClient(env, output)
+	if err != nil {
+		return fmt.Errorf("release attach: %w", err)
+	}
+	var release githubRelease
+	err = gh.do(ctx, http.MethodGet, "/repos/"+project+"/releases/tags/"+url.PathEscape(tag), nil, &release)
+	if err != nil {
+		return fmt.Errorf("failed to find the release of %s in %s: %w", tag, project, err)
+	}
+
+	// check every name before uploading anything, so a conflict doesn't
+	// leave the release half updated
+	existing := make(map[string]int64, len(release.Assets))
+	for _, asset := range release.Assets {
+		existing[asset.Name] = asset.ID
+	}
+	for _, file := range files {
+		if _, ok := existing[filepath.Base(file)]; ok && !*clobber {
+			return fmt.Errorf("release %s already has an asset %s, use -clobber to replace it", tag, filepath.Base(file))
+		}
+	}
+	if *dryRun {
+		for _, file := range files {
+			_, _ = fmt.Fprintf(output, "Would upload %s to release %s\n", file, tag)
+		}
+		if notes != nil {
+			_, _ = fmt.Fprintf(output, "Would replace the notes of release %s with %s\n", tag, *notesFile)
+		}
+		return nil
+	}
+
+	assets, err := readReleaseAssets(files)
+	if err != nil {
+		return err
+	}
+	for _, asset := range assets {
+		if id, ok := existing[asset.name]; ok {
+			err = gh.do(ctx, http.MethodDelete, fmt.Sprintf("/repos/%s/releases/assets/%d", project, id), nil, nil)
+			if err != nil {
+				return fmt.Errorf("failed to replace asset %s: %w", asset.name, err)
+			}
+		}
+	}
+	err = uploadReleaseAssets(ctx, gh, project, release, tag, assets, output)
+	if err != nil {
+		return err
+	}
+	if notes != nil {
+		err = gh.do(ctx, http.MethodPatch, fmt.Sprintf("/repos/%s/releases/%d", project, release.ID), map[string]string{"body": string(notes)}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to update the notes of release %s: %w", tag, err)
+		}
+		_, _ = fmt.Fprintf(output, "Updated the notes of release %s\n", tag)
+	}
+	return nil
+}
+
+// releaseAssets expands the file arguments of "bump release attach". Globs
+// are expanded for shells that pass them on quoted, and each must match.
+func releaseAssets(args []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]string)
+	for _, arg := range args {
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s': %w", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no file matches %s", arg)
+		}
+		for _, file := range matches {
+			info, err := os.Stat(file)
+			if err != nil {
+				return nil, err
+			}
+			if info.IsDir() {
+				return nil, fmt.Errorf("%s is a directory", file)
+			}
+			name := filepath.Base(file)
+			if other, ok := seen[name]; ok && other != file {
+				return nil, fmt.Errorf("%s and %s would both be uploaded as %s", other, file, name)
+			}
+			if _, ok := seen[name]; !ok {
+				files = append(files, file)
+				seen[name] = file
+			}
+		}
+	}
+	return files, nil
+}
+
+// Limits of the release asset uploads.
+const (
+	// assetUploads is how many assets are uploaded at once
+	assetUploads = 4
+	// assetAttempts is how often an asset whose upload failed is sent
+	assetAttempts = 3
+)
+
+// releaseAsset is a file uploaded to a GitHub release.
+type releaseAsset struct {
+	name        string
+	contentType string
+	content     []byte
+	sha256      string
+}
+
+// githubAsset is the part of an uploaded GitHub release asset the upload
+// checks. GitHub reports the digest of what it received.
+type githubAsset struct {
+	ID     int64  `json:"id"`
+	State  string `json:"state"`
+	Digest string `json:"digest"`
+}
+
+// newReleaseAsset returns the asset of the named content. Its content type
+// is the one of its extension, else detected from the content.
+func newReleaseAsset(name string, content []byte) releaseAsset {
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		contentType = http.DetectContentType(content)
+	}
+	sum := sha256.Sum256(content)
+	return releaseAsset{name: name, contentType: contentType, content: content, sha256: hex.EncodeToString(sum[:])}
+}
+
+// readReleaseAssets reads the files uploaded as release assets, named by
+// their base names (see releaseAssets).
+func readReleaseAssets(files []string) ([]releaseAsset, error) {
+	assets := make([]releaseAsset, 0, len(files))
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		assets = append(assets, newReleaseAsset(filepath.Base(file), content))
+	}
+	return assets, nil
+}
+
+// uploadReleaseAssets uploads the assets to the release, assetUploads at a
+// time. An upload is checked against the digest GitHub reports. The failed
+// uploads are retried, each resuming where the previous round stopped: what
+// was uploaded stays, and a partial asset a failed upload left behind is
+// deleted first, as it would block the name.
+func uploadReleaseAssets(ctx context.Context, gh githubClient, project string, release githubRelease, tag string, assets []releaseAsset, output io.Writer) error {
+	// the client reports its retries from the concurrent uploads
+	client := *gh.forgeClient
+	client.log = &lockedWriter{w: client.log}
+	gh = githubClient{&client}
+	uploadURL, _, _ := strings.Cut(release.UploadURL, "{")
+	pending := assets
+	for attempt := 1; ; attempt++ {
+		errs := make([]error, len(pending))
+		slots := make(chan struct{}, assetUploads)
+		var wg sync.WaitGroup
+		for i, asset := range pending {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				slots <- struct{}{}
+				defer func() { <-slots }()
+				errs[i] = uploadReleaseAsset(ctx, gh, uploadURL, asset)
+			}()
+		}
+		wg.Wait()
+
+		var failed []releaseAsset
+		var failures []error
+		for i, asset := range pending {
+			if errs[i] != nil {
+				failed = append(failed, asset)
+				failures = append(failures, fmt.Errorf("failed to upload %s: %w", asset.name, errs[i]))
+				continue
+			}
+			_, _ = fmt.Fprintf(output, "Uploaded %s to release %s\n", asset.name, tag)
+		}
+		if len(failed) == 0 {
+			return nil
+		}
+		if attempt == assetAttempts || ctx.Err() != nil {
+			return errors.Join(failures...)
+		}
+		_, _ = fmt.Fprintf(output, "Retrying %d failed asset uploads to release %s\n", len(failed), tag)
+		err := deletePartialAssets(ctx, gh, project, release.ID, failed)
+		if err != nil {
+			return errors.Join(append(failures, err)...)
+		}
+		pending = failed
+	}
+}
+
+// uploadReleaseAsset uploads one asset and checks that GitHub received it
+// whole.
+func uploadReleaseAsset(ctx context.Context, gh githubClient, uploadURL string, asset releaseAsset) error {
+	content, _, err := gh.send(ctx, http.MethodPost, uploadURL+"?name="+url.QueryEscape(asset.name), asset.contentType, asset.content)
+	if err != nil {
+		return err
+	}
+	var uploaded githubAsset
+	err = json.Unmarshal(content, &uploaded)
+	if err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if uploaded.State != "" && uploaded.State != "uploaded" {
+		return fmt.Errorf("the asset is %s, not uploaded", uploaded.State)
+	}
+	if uploaded.Digest != "" && uploaded.Digest != "sha256:"+asset.sha256 {
+		return fmt.Errorf("checksum mismatch: uploaded %s, GitHub has %s", asset.sha256, uploaded.Digest)
+	}
+	return nil
+}
+
+// deletePartialAssets deletes the assets of the failed uploads that exist
+// on the release anyway.
+func deletePartialAssets(ctx context.Context, gh githubClient, project string, releaseID int64, failed []releaseAsset) error {
+	var release githubRelease
+	err := gh.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/releases/%d", project, releaseID), nil, &release)
+	if err != nil {
+		return fmt.Errorf("failed to list the assets of the release: %w", err)
+	}
+	names := make(map[string]bool, len(failed))
+	for _, asset := range failed {
+		names[asset.name] = true
+	}
+	for _, asset := range release.Assets {
+		if !names[asset.Name] {
+			continue
+		}
+		err = gh.do(ctx, http.MethodDelete, fmt.Sprintf("/repos/%s/releases/assets/%d", project, asset.ID), nil, nil)
+		if err != nil {
+			return fmt.Errorf("failed to delete the partial asset %s: %w", asset.Name, err)
+		}
+	}
+	return nil
+}
+
+// lockedWriter serializes the writes of concurrent uploads.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.w == nil {
+		return len(p), nil
+	}
+	return l.w.Write(p)
+}
+
+// releaseCreator creates the GitHub release of a pushed tag (-github-release),
+// uploading the -assets to it.
+type releaseCreator struct {
+	gh      githubClient
+	project string
+	assets  []string // globs
+}
+
+// newReleaseCreator prepares -github-release before the release is made, so
+// a missing token or a remote that isn't on GitHub can't fail it halfway. It
+// returns nil without -github-release.
+func newReleaseCreator(repo vcs, cfg config, env []string, output io.Writer) (*releaseCreator, error) {
+	if !cfg.githubRelease {
+		return nil, nil
+	}
+	gitRepo, err := gitRepository(repo, "-github-release")
+	if err != nil {
+		return nil, err
+	}
+	r, err := resolveRemotes(gitRepo, cfg)
+	if err != nil {
+		return nil, err
+	}
+	project, err := githubRepository(gitRepo, r.push, env)
+	if err != nil {
+		return nil, fmt.Errorf("-github-release: %w", err)
+	}
+	gh, err := newGitHubClient(env, output)
+	if err != nil {
+		return nil, fmt.Errorf("-github-release: %w", err)
+	}
+	if len(cfg.assets) > 0 {
+		_, err = releaseAssets(cfg.assets)
+		if err != nil {
+			return nil, fmt.Errorf("-assets: %w", err)
+		}
+	}
+	return &releaseCreator{gh: gh, project: project, assets: cfg.assets}, nil
+}
+
+// create creates the release of the pushed tag. Its notes are the release
+// notes of the released changelogs or, without any, the ones GitHub
+// generates from the pull requests since the previous release. Prereleases
+// are marked as such. The -assets are matched again after the release, so
+// files it wrote such as the -artifacts manifest are included, and uploaded
+// with a checksums.txt of them unless one of them has that name.
+func (c *releaseCreator) create(ctx context.Context, cfg config, output io.Writer, tag, version, notes string) error {
+	var assets []releaseAsset
+	if len(c.assets) > 0 {
+		files, err := releaseAssets(c.assets)
+		if err != nil {
+			return fmt.Errorf("-assets: %w", err)
+		}
+		assets, err = readReleaseAssets(files)
+		if err != nil {
+			return err
+		}
+		assets = withAssetChecksums(assets)
+	}
+	if cfg.dryRun {
+		_, _ = fmt.Fprintf(output, "Would create the GitHub release %s in %s\n", tag, c.project)
+		for _, asset := range assets {
+			_, _ = fmt.Fprintf(output, "Would upload %s to release %s\n", asset.name, tag)
+		}
+		return nil
+	}
+	body := map[string]any{
+		"tag_name":   tag,
+		"name":       tag,
+		"prerelease": semver.Prerelease(normalizeVersion(version)) != "",
+	}
+	if notes != "" {
+		body["body"] = notes
+	} else {
+		body["generate_release_notes"] = true
+	}
+	var release githubRelease
+	err := c.gh.do(ctx, http.MethodPost, "/repos/"+c.project+"/releases", body, &release)
+	if err != nil {
+		return fmt.Errorf("failed to create the GitHub release: %w", err)
+	}
+	_, _ = fmt.Fprintf(output, "Created GitHub release %s\n", release.HTMLURL)
+	if len(assets) == 0 {
+		return nil
+	}
+	err = uploadReleaseAssets(ctx, c.gh, c.project, release, tag, assets, output)
+	if err != nil {
+		return fmt.Errorf("GitHub release %s was created but uploading its assets failed: %w\nUpload the missing ones with: bump release attach -clobber %s <file...>", release.HTMLURL, err, tag)
+	}
+	return nil
+}
+
+// assetChecksums is the name of the checksum manifest uploaded with the
+// -assets.
+const assetChecksums = "checksums.txt"
+
+// withAssetChecksums adds the checksum manifest of the assets, in the format
+// of sha256sum, unless an asset already has its name.
+func withAssetChecksums(assets []releaseAsset) []releaseAsset {
+	var manifest bytes.Buffer
+	for _, asset := range assets {
+		if asset.name == assetChecksums {
+			return assets
+		}
+		_, _ = fmt.Fprintf(&manifest, "%s  %s\n", asset.sha256, asset.name)
+	}
+	return append(assets, newReleaseAsset(assetChecksums, manifest.Bytes()))
+}
+
+// gitlabReleaseCreator creates the GitLab release of a pushed tag
+// (-gitlab-release).
+type gitlabReleaseCreator struct {
+	gl      gitlabClient
+	project string
+	links   []releaseLink
+}
+
+// releaseLink is an asset link of a GitLab release, whose URL is a release
+// template, e.g. https://example.com/app-{{.Version}}.tar.gz.
+type releaseLink struct {
+	name string
+	url  string
+}
+
+// parseReleaseLinks parses the comma-separated name=url links of
+// -gitlab-release-links, checking that the URL templates parse.
+func parseReleaseLinks(value string) ([]releaseLink, error) {
+	var links []releaseLink
+	for _, spec := range strings.Split(value, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		name, link, ok := strings.Cut(spec, "=")
+		if !ok || strings.TrimSpace(name) == "" || strings.TrimSpace(link) == "" {
+			return nil, fmt.Errorf("invalid -gitlab-release-links link '%s': expected name=url", spec)
+		}
+		_, err := template.New(name).Funcs(templateFuncs(nil)).Parse(link)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -gitlab-release-links URL of %s: %w", name, err)
+		}
+		links = append(links, releaseLink{name: strings.TrimSpace(name), url: strings.TrimSpace(link)})
+	}
+	return links, nil
+}
+
+// newGitLabReleaseCreator prepares -gitlab-release before the release is
+// made, like newReleaseCreator does for GitHub. It returns nil without
+// -gitlab-release.
+func newGitLabReleaseCreator(repo vcs, cfg config, env []string, output io.Writer) (*gitlabReleaseCreator, error) {
+	if !cfg.gitlabRelease {
+		return nil, nil
+	}
+	gitRepo, err := gitRepository(repo, "-gitlab-release")
+	if err != nil {
+		return nil, err
+	}
+	r, err := resolveRemotes(gitRepo, cfg)
+	if err != nil {
+		return nil, err
+	}
+	project, err := gitlabProject(gitRepo, r.push, env)
+	if err != nil {
+		return nil, fmt.Errorf("-gitlab-release: %w", err)
+	}
+	gl, err := newGitLabClient(env, output)
+	if err != nil {
+		return nil, fmt.Errorf("-gitlab-release: %w", err)
+	}
+	return &gitlabReleaseCreator{gl: gl, project: project, links: cfg.releaseLinks}, nil
+}
+
+// create creates the release of the pushed tag, with the release notes of
+// the released changelogs or, without any, the list of changes, and the
+// asset links rendered for the release. GitLab doesn't generate notes.
+func (c *gitlabReleaseCreator) create(ctx context.Context, cfg config, output io.Writer, tag string, rel release) error {
+	description := rel.Notes
+	if description == "" {
+		var list strings.Builder
+		for _, change := range rel.Changes {
+			list.WriteString("- " + change + "\n")
+		}
+		description = list.String()
+	}
+	var links []map[string]string
+	for _, link := range c.links {
+		u, err := renderTemplate("-gitlab-release-links "+link.name, link.url, rel)
+		if err != nil {
+			return err
+		}
+		links = append(links, map[string]string{"name": link.name, "url": u})
+	}
+	if cfg.dryRun {
+		_, _ = fmt.Fprintf(output, "Would create the GitLab release %s in %s\n", tag, c.project)
+		for _, link := range links {
+			_, _ = fmt.Fprintf(output, "Would link %s: %s\n", link["name"], link["url"])
+		}
+		return nil
+	}
+	body := map[string]any{
+		"tag_name":    tag,
+		"name":        tag,
+		"description": description,
+	}
+	if len(links) > 0 {
+		body["assets"] = map[string]any{"links": links}
+	}
+	var release struct {
+		Links struct {
+			Self string `json:"self"`
+		} `json:"_links"`
+	}
+	err := c.gl.do(ctx, http.MethodPost, "/projects/"+url.PathEscape(c.project)+"/releases", body, &release)
+	if err != nil {
+		return fmt.Errorf("failed to create the GitLab release: %w", err)
+	}
+	_, _ = fmt.Fprintf(output, "Created GitLab release %s\n", release.Links.Self)
+	return nil
+}
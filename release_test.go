@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+)
+
+func TestReleaseAttach(t *testing.T) {
+	var mu sync.Mutex // the uploads are concurrent
+	var requests []string
+	uploads := make(map[string]string)
+	var notes map[string]string
+	var api *httptest.Server
+	api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/acme/app/releases/tags/v1.0.0":
+			_, _ = w.Write([]byte(`{"id":42,"upload_url":"` + api.URL + `/uploads/repos/acme/app/releases/42/assets{?name,label}","assets":[{"id":7,"name":"app.sbom.json"}]}`))
+		case "POST /uploads/repos/acme/app/releases/42/assets":
+			content, _ := io.ReadAll(r.Body)
+			uploads[r.URL.Query().Get("name")] = string(content)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":8,"state":"uploaded"}`))
+		case "DELETE /repos/acme/app/releases/assets/7":
+			w.WriteHeader(http.StatusNoContent)
+		case "PATCH /repos/acme/app/releases/42":
+			_ = json.NewDecoder(r.Body).Decode(&notes)
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(api.Close)
+	env := []string{"BUMP_GITHUB_TOKEN=gh-token", "BUMP_GITHUB_API=" + api.URL, "BUMP_GITHUB_REPO=acme/app", "BUMP_API_CACHE=" + t.TempDir()}
+
+	setupTaggedTestRepo(t, "v1.0.0")
+	err := os.Mkdir("dist", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"dist/app-linux.tar.gz": "linux", "dist/app-darwin.tar.gz": "darwin", "dist/app.sbom.json": "{}", "NOTES.md": "Final notes\n"} {
+		err = os.WriteFile(name, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"release", "attach", "v1.0.0", "dist/*.json"}, env)
+	if err == nil || !strings.Contains(err.Error(), "already has an asset app.sbom.json, use -clobber") {
+		t.Errorf("Expected an existing asset to fail the upload, got: %v", err)
+	}
+	err = run(context.Background(), &output, []string{"release", "attach", "v1.0.0", "dist/*.zip"}, env)
+	if err == nil || !strings.Contains(err.Error(), "no file matches dist/*.zip") {
+		t.Errorf("Expected a pattern matching nothing to fail, got: %v", err)
+	}
+	if len(uploads) != 0 {
+		t.Fatalf("Expected nothing uploaded after failures, got %v", uploads)
+	}
+
+	output.Reset()
+	err = run(context.Background(), &output, []string{"release", "attach", "-dry-run", "-notes", "NOTES.md", "v1.0.0", "dist/*.tar.gz"}, env)
+	if err != nil {
+		t.Fatalf("Expected the dry run to succeed, got: %v", err)
+	}
+	if len(uploads) != 0 || notes != nil || !strings.Contains(output.String(), "Would upload dist/app-linux.tar.gz to release v1.0.0") {
+		t.Errorf("Expected the dry run to only print, got uploads %v and output:\n%s", uploads, output.String())
+	}
+
+	output.Reset()
+	err = run(context.Background(), &output, []string{"release", "attach", "-clobber", "-notes", "NOTES.md", "v1.0.0", "dist/*.tar.gz", "dist/app.sbom.json"}, env)
+	if err != nil {
+		t.Fatalf("Expected the attach to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	expected := map[string]string{"app-linux.tar.gz": "linux", "app-darwin.tar.gz": "darwin", "app.sbom.json": "{}"}
+	if len(uploads) != len(expected) {
+		t.Errorf("Expected %v uploaded, got %v", expected, uploads)
+	}
+	for name, content := range expected {
+		if uploads[name] != content {
+			t.Errorf("Expected %s uploaded with %q, got %q", name, content, uploads[name])
+		}
+	}
+	if !strings.Contains(strings.Join(requests, "\n"), "DELETE /repos/acme/app/releases/assets/7") {
+		t.Errorf("Expected the replaced asset to be deleted, got requests:\n%s", strings.Join(requests, "\n"))
+	}
+	if notes["body"] != "Final notes\n" {
+		t.Errorf("Expected the notes to be replaced, got %v", notes)
+	}
+}
+
+func TestBumpGitHubRelease(t *testing.T) {
+	var created []map[string]any
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method+" "+r.URL.Path != "POST /repos/acme/app/releases" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		created = append(created, body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":1,"html_url":"https://github.com/acme/app/releases/tag/` + body["tag_name"].(string) + `"}`))
+	}))
+	t.Cleanup(api.Close)
+	env := []string{"BUMP_GITHUB_TOKEN=gh-token", "BUMP_GITHUB_API=" + api.URL, "BUMP_GITHUB_REPO=acme/app", "BUMP_API_CACHE=" + t.TempDir()}
+
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	remoteDir := t.TempDir()
+	_, err := git.PlainInit(remoteDir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{remoteDir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-github-release"}, env)
+	if err == nil || !strings.Contains(err.Error(), "-github-release needs -push") {
+		t.Errorf("Expected -github-release to need -push, got: %v", err)
+	}
+
+	err = run(context.Background(), &output, []string{"-push", "-github-release"}, env)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	if len(created) != 1 || created[0]["tag_name"] != "v1.0.1" || created[0]["generate_release_notes"] != true || created[0]["prerelease"] != false {
+		t.Errorf("Expected a release of v1.0.1 with generated notes, got %v", created)
+	}
+	if !strings.Contains(output.String(), "Created GitHub release https://github.com/acme/app/releases/tag/v1.0.1") {
+		t.Errorf("Expected the release in the output, got:\n%s", output.String())
+	}
+
+	commitFiles(t, repo, "Add changelog", map[string]string{changelogFile: "# Changelog\n\n## [Unreleased]\n\n- Faster startup\n"})
+	err = run(context.Background(), &output, []string{"-push", "-github-release", "-version", "v1.1.0-rc.1"}, env)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	if len(created) != 2 || created[1]["prerelease"] != true || !strings.Contains(created[1]["body"].(string), "Faster startup") {
+		t.Errorf("Expected a prerelease with the changelog's notes, got %v", created[1:])
+	}
+}
+
+func TestBumpGitHubReleaseAssets(t *testing.T) {
+	var mu sync.Mutex // the uploads are concurrent
+	uploads := make(map[string]string)
+	contentTypes := make(map[string]string)
+	var requests []string
+	failLinux := true
+	var api *httptest.Server
+	api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.Method + " " + r.URL.Path {
+		case "POST /repos/acme/app/releases":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":42,"html_url":"https://github.com/acme/app/releases/tag/v1.0.1","upload_url":"` + api.URL + `/uploads/repos/acme/app/releases/42/assets{?name,label}"}`))
+		case "POST /uploads/repos/acme/app/releases/42/assets":
+			name := r.URL.Query().Get("name")
+			content, _ := io.ReadAll(r.Body)
+			if name == "app-linux.tar.gz" && failLinux {
+				// the first upload breaks off, leaving a partial asset
+				failLinux = false
+				http.Error(w, "upload interrupted", http.StatusInternalServerError)
+				return
+			}
+			uploads[name] = string(content)
+			contentTypes[name] = r.Header.Get("Content-Type")
+			sum := sha256.Sum256(content)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":1,"state":"uploaded","digest":"sha256:` + hex.EncodeToString(sum[:]) + `"}`))
+		case "GET /repos/acme/app/releases/42":
+			_, _ = w.Write([]byte(`{"id":42,"assets":[{"id":9,"name":"app-linux.tar.gz"}]}`))
+		case "DELETE /repos/acme/app/releases/assets/9":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(api.Close)
+	env := []string{"BUMP_GITHUB_TOKEN=gh-token", "BUMP_GITHUB_API=" + api.URL, "BUMP_GITHUB_REPO=acme/app", "BUMP_API_CACHE=" + t.TempDir()}
+
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	remoteDir := t.TempDir()
+	_, err := git.PlainInit(remoteDir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{remoteDir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Mkdir("dist", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"dist/app-linux.tar.gz": "linux", "dist/app-darwin.tar.gz": "darwin", "dist/app.json": "{}", "dist/app": "#!/bin/sh\n"} {
+		err = os.WriteFile(name, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-push", "-assets", "dist/*"}, env)
+	if err == nil || !strings.Contains(err.Error(), "-assets needs -github-release") {
+		t.Errorf("Expected -assets to need -github-release, got: %v", err)
+	}
+	err = run(context.Background(), &output, []string{"-push", "-github-release", "-assets", "dist/*.zip"}, env)
+	if err == nil || !strings.Contains(err.Error(), "no file matches dist/*.zip") {
+		t.Errorf("Expected a pattern matching nothing to fail before the release, got: %v", err)
+	}
+	if exists, _ := tagExists(repo, "v1.0.1"); exists {
+		t.Fatal("Expected nothing to be released when -assets match nothing")
+	}
+
+	output.Reset()
+	err = run(context.Background(), &output, []string{"-push", "-github-release", "-assets", "dist/*"}, env)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	checksums := ""
+	for _, name := range []string{"app", "app-darwin.tar.gz", "app-linux.tar.gz", "app.json"} {
+		sum := sha256.Sum256([]byte(uploads[name]))
+		checksums += hex.EncodeToString(sum[:]) + "  " + name + "\n"
+	}
+	expected := map[string]string{"app-linux.tar.gz": "linux", "app-darwin.tar.gz": "darwin", "app.json": "{}", "app": "#!/bin/sh\n", "checksums.txt": checksums}
+	if len(uploads) != len(expected) {
+		t.Errorf("Expected %v uploaded, got %v", expected, uploads)
+	}
+	for name, content := range expected {
+		if uploads[name] != content {
+			t.Errorf("Expected %s uploaded with %q, got %q", name, content, uploads[name])
+		}
+	}
+	if contentTypes["app.json"] != "application/json" || contentTypes["app"] != "text/plain; charset=utf-8" {
+		t.Errorf("Expected content types from the extension, else the content, got %v", contentTypes)
+	}
+	if !strings.Contains(strings.Join(requests, "\n"), "DELETE /repos/acme/app/releases/assets/9") ||
+		!strings.Contains(output.String(), "Retrying 1 failed asset uploads to release v1.0.1") {
+		t.Errorf("Expected the partial asset to be deleted and its upload retried, got requests:\n%s\nOutput: %s", strings.Join(requests, "\n"), output.String())
+	}
+}
+
+func TestBumpGitLabRelease(t *testing.T) {
+	var created []map[string]any
+	var headers []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method+" "+r.URL.EscapedPath() != "POST /api/v4/projects/acme%2Ftools%2Fapp/releases" {
+			http.Error(w, "unexpected request "+r.URL.EscapedPath(), http.StatusBadRequest)
+			return
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		created = append(created, body)
+		headers = append(headers, r.Header.Get("JOB-TOKEN"))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"_links":{"self":"https://gitlab.example.com/acme/tools/app/-/releases/v1.0.1"}}`))
+	}))
+	t.Cleanup(api.Close)
+	env := []string{"CI_JOB_TOKEN=job-token", "CI_API_V4_URL=" + api.URL + "/api/v4", "CI_SERVER_HOST=gitlab.example.com", "BUMP_API_CACHE=" + t.TempDir()}
+
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	remoteDir := t.TempDir()
+	_, err := git.PlainInit(remoteDir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{remoteDir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-push", "-gitlab-release"}, env)
+	if err == nil || !strings.Contains(err.Error(), "remote 'origin' isn't on GitLab") {
+		t.Fatalf("Expected a remote off GitLab to be refused, got: %v", err)
+	}
+	if len(created) != 0 {
+		t.Fatalf("Expected nothing created, got %v", created)
+	}
+
+	env = append(env, "BUMP_GITLAB_PROJECT=acme/tools/app")
+	args := []string{"-push", "-gitlab-release", "-gitlab-release-links", "Linux=https://dl.example.com/app-{{.Version}}-linux.tar.gz"}
+	err = run(context.Background(), &output, args, env)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	if len(created) != 1 || created[0]["tag_name"] != "v1.0.1" || created[0]["description"] != "- Add new feature\n" || headers[0] != "job-token" {
+		t.Fatalf("Expected a release of v1.0.1 listing the changes, got %v", created)
+	}
+	links := created[0]["assets"].(map[string]any)["links"].([]any)
+	if len(links) != 1 || links[0].(map[string]any)["url"] != "https://dl.example.com/app-v1.0.1-linux.tar.gz" {
+		t.Errorf("Expected the rendered link, got %v", links)
+	}
+	if !strings.Contains(output.String(), "Created GitLab release https://gitlab.example.com/acme/tools/app/-/releases/v1.0.1") {
+		t.Errorf("Expected the release in the output, got:\n%s", output.String())
+	}
+
+	for _, args := range [][]string{{"-gitlab-release"}, {"-push", "-gitlab-release-links", "a=b"}, {"-push", "-gitlab-release", "-gitlab-release-links", "nourl"}} {
+		_, _, err = getConfig(args)
+		if err == nil {
+			t.Errorf("Expected %v to be refused", args)
+		}
+	}
+}
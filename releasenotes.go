@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// releaseNotes aggregates the released changelogs into one note listing
+// every module that shipped with its version and highlights, so a monorepo
+// release can be announced at once. The root module is named after the
+// project. It returns "" if no changelog was released.
+func releaseNotes(project, version string, changelogs []releasedChangelog) string {
+	if len(changelogs) == 0 {
+		return ""
+	}
+	var notes strings.Builder
+	fmt.Fprintf(&notes, "# %s %s\n", project, version)
+	for _, changelog := range changelogs {
+		name := changelog.module
+		if name == "." {
+			name = project
+		}
+		fmt.Fprintf(&notes, "\n## %s %s\n\n", name, version)
+		for _, entry := range changelog.highlights {
+			fmt.Fprintf(&notes, "- %s\n", entry)
+		}
+	}
+	return notes.String()
+}
+
+// writeReleaseNotes writes the aggregated release notes to the -release-notes
+// file and stages them with the release.
+func writeReleaseNotes(repo vcs, cfg config, output io.Writer, notes string) error {
+	if cfg.releaseNotes == "" || notes == "" {
+		return nil
+	}
+	_, _ = fmt.Fprintf(output, "Writing release notes %s\n", cfg.releaseNotes)
+	if cfg.dryRun {
+		return previewFile(output, cfg.releaseNotes, []byte(notes))
+	}
+	return writeReleaseFile(repo, cfg.releaseNotes, []byte(notes))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestBumpReleaseNotes(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Add modules", map[string]string{
+		"payments/.version":     "v1.0.0",
+		"payments/CHANGELOG.md": "# Changelog\n\n## [Unreleased]\n\n### Fixed\n\n- Double charge\n\n### Added\n\n* Refunds\n",
+		"billing/.version":      "v1.0.0",
+		"billing/CHANGELOG.md":  "# Changelog\n\n## [Unreleased]\n",
+		changelogFile:           "# Changelog\n\n## [Unreleased]\n\n- Faster startup\n",
+	})
+	setClock(t, "2024-05-01")
+
+	var output bytes.Buffer
+	env := []string{"BUMP_PROJECT=shop"}
+	err := run(context.Background(), &output, []string{"-minor", "-release-notes", "RELEASE_NOTES.md"}, env)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	want := `# shop v1.1.0
+
+## shop v1.1.0
+
+- Faster startup
+
+## payments v1.1.0
+
+- Double charge
+- Refunds
+`
+	if got := readFile(t, "RELEASE_NOTES.md"); got != want {
+		t.Errorf("Got release notes:\n%s\nwant:\n%s", got, want)
+	}
+	if got := readFile(t, "payments/CHANGELOG.md"); !strings.Contains(got, "## [v1.1.0] - 2024-05-01") {
+		t.Errorf("Expected the module's changelog to be released, got:\n%s", got)
+	}
+	if got := readFile(t, "billing/CHANGELOG.md"); strings.Contains(got, "v1.1.0") {
+		t.Errorf("Expected the changelog without entries to be left alone, got:\n%s", got)
+	}
+	head, err := repo.CommitObject(mustHead(t, repo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"RELEASE_NOTES.md", "payments/CHANGELOG.md", changelogFile} {
+		if _, err := head.File(file); err != nil {
+			t.Errorf("Expected %s in the release commit: %v", file, err)
+		}
+	}
+}
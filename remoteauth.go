@@ -0,0 +1,87 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// codeCommitHost matches the HTTPS endpoints of AWS CodeCommit, capturing the
+// region.
+var codeCommitHost = regexp.MustCompile(`^git-codecommit(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?$`)
+
+// remoteAuth returns the credentials for talking to a remote over HTTPS on
+// the hosts that need a scheme git only gets from a credential helper: AWS
+// CodeCommit, signed with SigV4 from the AWS_* variables, and Azure Repos,
+// with the personal access token in BUMP_AZURE_DEVOPS_TOKEN or
+// AZURE_DEVOPS_EXT_PAT. It returns nil for other remotes and for URLs with
+// credentials of their own, which go-git uses as they are, like it uses the
+// SSH agent for SSH remotes.
+func remoteAuth(remote *git.Remote, env []string) (transport.AuthMethod, error) {
+	if len(remote.Config().URLs) == 0 {
+		return nil, nil
+	}
+	u, err := url.Parse(remote.Config().URLs[0])
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.User != nil {
+		return nil, nil
+	}
+	host := strings.ToLower(u.Hostname())
+	if m := codeCommitHost.FindStringSubmatch(host); m != nil {
+		accessKey, secretKey := getenv(env, "AWS_ACCESS_KEY_ID"), getenv(env, "AWS_SECRET_ACCESS_KEY")
+		if accessKey == "" || secretKey == "" {
+			return nil, fmt.Errorf("remote '%s' is on AWS CodeCommit: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, or put Git credentials for CodeCommit in its URL", remote.Config().Name)
+		}
+		username := accessKey
+		if token := getenv(env, "AWS_SESSION_TOKEN"); token != "" {
+			username += "%" + token
+		}
+		return &githttp.BasicAuth{
+			Username: username,
+			Password: codeCommitPassword(secretKey, m[1], host, u.EscapedPath(), now()),
+		}, nil
+	}
+	if host == "dev.azure.com" || strings.HasSuffix(host, ".visualstudio.com") {
+		token := getenv(env, "BUMP_AZURE_DEVOPS_TOKEN")
+		if token == "" {
+			token = getenv(env, "AZURE_DEVOPS_EXT_PAT")
+		}
+		if token == "" {
+			return nil, nil
+		}
+		// Azure takes the token as the password of any user, which is the
+		// "Authorization: Basic base64(:token)" header its docs give
+		return &githttp.BasicAuth{Password: token}, nil
+	}
+	return nil, nil
+}
+
+// codeCommitPassword is the password the AWS CLI's CodeCommit credential
+// helper computes: the time and the SigV4 signature of a GIT request for the
+// repository path, valid for 15 minutes.
+func codeCommitPassword(secretKey, region, host, path string, t time.Time) string {
+	timestamp := t.UTC().Format("20060102T150405")
+	date := timestamp[:8]
+	canonical := sha256.Sum256([]byte("GIT\n" + path + "\n\nhost:" + host + "\n\nhost\n"))
+	scope := date + "/" + region + "/codecommit/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + timestamp + "\n" + scope + "\n" + hex.EncodeToString(canonical[:])
+	key := []byte("AWS4" + secretKey)
+	for _, part := range []string{date, region, "codecommit", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return timestamp + "Z" + hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// runSandboxed rehearses a bump (-sandbox): it clones the repository into a
+// temporary directory, performs the real bump there, reports the commits and
+// tags it made and removes the clone. The clone's remotes all point to a
+// temporary bare repository, so nothing can reach the real remotes. Only the
+// committed state is cloned: uncommitted and untracked files, such as build
+// output, are not part of the rehearsal.
+func runSandboxed(ctx context.Context, output io.Writer, argv []string, env []string, cfg config) error {
+	source, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	repo, err := openVCS(".", cfg.backend, nil)
+	if err != nil {
+		return err
+	}
+	dirty, err := repo.dirtyFiles()
+	if err != nil {
+		return err
+	}
+	if len(dirty) > 0 {
+		if !cfg.forced && !cfg.autostash {
+			return fmt.Errorf("repository is not clean (use -force or -autostash to override):\n%s", strings.Join(sortedDirtyFiles(dirty), "\n"))
+		}
+		_, _ = fmt.Fprintf(output, "Sandbox: %d uncommitted change(s) are not part of the rehearsal\n", len(dirty))
+	}
+	gitRepo, err := gitRepository(repo, "-sandbox")
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "bump-sandbox-")
+	if err != nil {
+		return fmt.Errorf("failed to create the sandbox: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	clone, err := cloneSandbox(gitRepo, dir)
+	if err != nil {
+		return fmt.Errorf("failed to create the sandbox: %w", err)
+	}
+	before, err := sandboxTags(clone)
+	if err != nil {
+		return err
+	}
+	head, err := clone.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	err = os.Chdir(filepath.Join(dir, "work"))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Chdir(source) }()
+	_, _ = fmt.Fprintf(output, "Sandbox: rehearsing in a clone at %s\n", dir)
+	err = runBump(ctx, output, withoutFlag(argv, "sandbox"), env)
+	if err != nil {
+		return fmt.Errorf("sandbox: %w", err)
+	}
+	return reportSandbox(output, clone, head.Hash(), before)
+}
+
+// cloneSandbox clones the repository into dir/work, with every remote
+// pointing to the bare repository dir/remote.git, which holds the same
+// branches and tags. The object databases are hard links to the repository's,
+// so this is cheap even for large repositories.
+func cloneSandbox(repo *git.Repository, dir string) (*git.Repository, error) {
+	objects := filepath.Join(".git", "objects")
+	info, err := os.Stat(objects)
+	if err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("-sandbox needs the repository's .git directory in the working directory")
+	}
+
+	remoteDir := filepath.Join(dir, "remote.git")
+	remote, err := git.PlainInit(remoteDir, true)
+	if err != nil {
+		return nil, err
+	}
+	err = linkTree(objects, filepath.Join(remoteDir, "objects"))
+	if err != nil {
+		return nil, err
+	}
+	clone, err := git.PlainInit(filepath.Join(dir, "work"), false)
+	if err != nil {
+		return nil, err
+	}
+	err = linkTree(objects, filepath.Join(dir, "work", ".git", "objects"))
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list references: %w", err)
+	}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		err := clone.Storer.SetReference(ref)
+		if err != nil || !(ref.Name().IsBranch() || ref.Name().IsTag()) {
+			return err
+		}
+		return remote.Storer.SetReference(ref)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy references: %w", err)
+	}
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	err = clone.Storer.SetReference(head)
+	if err != nil {
+		return nil, err
+	}
+
+	repoConfig, err := repo.Config()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository config: %w", err)
+	}
+	for _, r := range repoConfig.Remotes {
+		r.URLs = []string{remoteDir}
+	}
+	err = clone.SetConfig(repoConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write the sandbox config: %w", err)
+	}
+
+	w, err := clone.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := clone.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	err = w.Reset(&git.ResetOptions{Commit: resolved.Hash(), Mode: git.HardReset})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check out the sandbox: %w", err)
+	}
+	return clone, nil
+}
+
+// linkTree hard links the files under src into dst, copying them where links
+// aren't possible, such as across file systems.
+func linkTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if _, err := os.Lstat(target); err == nil {
+			return nil
+		}
+		if os.Link(path, target) == nil {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, content, 0644)
+	})
+}
+
+// sandboxTags returns the tags of the clone by name.
+func sandboxTags(repo *git.Repository) (map[string]bool, error) {
+	tags := make(map[string]bool)
+	err := forEachTag(repo, func(ref *plumbing.Reference) error {
+		tags[ref.Name().Short()] = true
+		return nil
+	})
+	return tags, err
+}
+
+// reportSandbox lists the commits and tags the rehearsal made.
+func reportSandbox(output io.Writer, clone *git.Repository, previous plumbing.Hash, before map[string]bool) error {
+	after, err := sandboxTags(clone)
+	if err != nil {
+		return err
+	}
+	head, err := clone.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	var commits []string
+	for hash := head.Hash(); hash != previous; {
+		commit, err := clone.CommitObject(hash)
+		if err != nil {
+			return fmt.Errorf("failed to read commit %s: %w", shortHash(hash.String()), err)
+		}
+		commits = append(commits, fmt.Sprintf("%s %s", shortHash(hash.String()), commitSubject(commit.Message)))
+		if len(commit.ParentHashes) == 0 {
+			break
+		}
+		hash = commit.ParentHashes[0]
+	}
+	var tags []string
+	for tag := range after {
+		if !before[tag] {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	_, _ = fmt.Fprintf(output, "Sandbox: the bump succeeded with %d commit(s) and %d tag(s), nothing was changed here\n", len(commits), len(tags))
+	for i := len(commits) - 1; i >= 0; i-- {
+		_, _ = fmt.Fprintf(output, "  commit %s\n", commits[i])
+	}
+	for _, tag := range tags {
+		_, _ = fmt.Fprintf(output, "  tag %s\n", tag)
+	}
+	return nil
+}
+
+// withoutFlag returns argv without the boolean flag, in any of its spellings.
+func withoutFlag(argv []string, name string) []string {
+	var result []string
+	for _, arg := range argv {
+		flagName, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if strings.HasPrefix(arg, "-") && flagName == name {
+			continue
+		}
+		result = append(result, arg)
+	}
+	return result
+}
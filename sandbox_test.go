@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	gitconfig "github.com/go-git/go-git/v5/config"
+)
+
+func TestSandbox(t *testing.T) {
+	dir, repo := setupTaggedTestRepo(t, "v1.0.0")
+	_, err := repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{"https://git.example.com/acme/app.git"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	head := mustHead(t, repo)
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-minor", "-sandbox"}, nil)
+	if err != nil {
+		t.Fatalf("bump: %v\nOutput: %s", err, output.String())
+	}
+	for _, want := range []string{"Sandbox: the bump succeeded with 1 commit(s) and 1 tag(s)", "bump version to v1.1.0", "  tag v1.1.0"} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("Expected %q in the output, got: %s", want, output.String())
+		}
+	}
+
+	if cwd, _ := os.Getwd(); cwd != dir {
+		t.Errorf("Expected to be back in %s, got %s", dir, cwd)
+	}
+	if mustHead(t, repo) != head {
+		t.Error("Expected no commit in the repository")
+	}
+	if exists, _ := tagExists(repo, "v1.1.0"); exists {
+		t.Error("Expected no tag in the repository")
+	}
+	if content, _ := os.ReadFile(".version"); string(content) != "v1.0.0" {
+		t.Errorf("Expected .version to be untouched, got %q", content)
+	}
+	remote, err := repo.Remote("origin")
+	if err != nil || remote.Config().URLs[0] != "https://git.example.com/acme/app.git" {
+		t.Errorf("Expected the remote to be untouched, got %v", remote)
+	}
+}
+
+func TestSandboxFailure(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	_, err := repo.CreateTag("v1.0.1", mustHead(t, repo), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-sandbox", "-version", "v1.0.1"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "sandbox: ") {
+		t.Errorf("Expected the rehearsal to fail, got: %v", err)
+	}
+
+	err = run(context.Background(), &output, []string{"-sandbox", "-dry-run"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "-sandbox can't be combined with -dry-run") {
+		t.Errorf("Expected -sandbox -dry-run to be refused, got: %v", err)
+	}
+}
+
+func TestSandboxRefusesIntegrations(t *testing.T) {
+	for _, args := range [][]string{
+		{"-sandbox", "-push", "-back-merge"},
+		{"-sandbox", "-k8s-annotate", "deployment/app"},
+		{"-sandbox", "-push", "-github-release"},
+		{"-sandbox", "-push", "-gitlab-release"},
+	} {
+		_, _, err := getConfig(args)
+		if err == nil || !strings.Contains(err.Error(), "-sandbox can't be combined with") {
+			t.Errorf("Expected %v to be refused, got: %v", args, err)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// sbom is a software bill of materials generated for a release.
+type sbom struct {
+	path    string // repository path the SBOM is committed to
+	content []byte
+	digest  string // hex SHA-256 of content
+}
+
+// generateSBOM produces the SBOM for version if -sbom is set, and returns nil
+// otherwise. BUMP_SBOM_COMMAND names an external generator, such as syft or
+// cyclonedx-gomod, that writes the SBOM to standard output; it runs through
+// the shell in env with BUMP_VERSION set. Without it bump writes a CycloneDX SBOM of
+// the dependencies in go.mod.
+func generateSBOM(ctx context.Context, cfg config, env []string, version string) (*sbom, error) {
+	if cfg.sbom == "" {
+		return nil, nil
+	}
+	var content []byte
+	var err error
+	if command := getenv(env, "BUMP_SBOM_COMMAND"); command != "" {
+		content, err = runSBOMCommand(ctx, command, env, version)
+	} else {
+		content, err = cycloneDXFromGoMod("go.mod", version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate SBOM: %w", err)
+	}
+	digest := sha256.Sum256(content)
+	return &sbom{path: repoPath(cfg.sbom), content: content, digest: hex.EncodeToString(digest[:])}, nil
+}
+
+func runSBOMCommand(ctx context.Context, command string, env []string, version string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(slices.Clone(env), "BUMP_VERSION="+version)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("BUMP_SBOM_COMMAND: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		return nil, errors.New("BUMP_SBOM_COMMAND produced no output")
+	}
+	return stdout.Bytes(), nil
+}
+
+// cycloneDX is the subset of the CycloneDX 1.5 JSON format bump writes.
+type cycloneDX struct {
+	BOMFormat   string `json:"bomFormat"`
+	SpecVersion string `json:"specVersion"`
+	Version     int    `json:"version"`
+	Metadata    struct {
+		Tools     []cycloneDXComponent `json:"tools"`
+		Component cycloneDXComponent   `json:"component"`
+	} `json:"metadata"`
+	Components []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXComponent struct {
+	Type    string `json:"type,omitempty"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	PURL    string `json:"purl,omitempty"`
+	Scope   string `json:"scope,omitempty"`
+}
+
+// cycloneDXFromGoMod describes the module in the go.mod file at version, with
+// its requirements as components. Replacements are applied, indirect
+// requirements are marked optional. The SBOM has no timestamp, so the same
+// release always gets the same digest.
+func cycloneDXFromGoMod(path, version string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.New("no go.mod found, set BUMP_SBOM_COMMAND to generate the SBOM")
+	}
+	if err != nil {
+		return nil, err
+	}
+	mod, err := modfile.Parse(path, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	if mod.Module == nil {
+		return nil, fmt.Errorf("%s has no module directive", path)
+	}
+
+	var bom cycloneDX
+	bom.BOMFormat, bom.SpecVersion, bom.Version = "CycloneDX", "1.5", 1
+	bom.Metadata.Tools = []cycloneDXComponent{{Type: "application", Name: "bump", Version: strings.TrimSpace(embeddedVersion)}}
+	bom.Metadata.Component = goComponent("application", mod.Module.Mod.Path, version)
+	bom.Components = []cycloneDXComponent{}
+	for _, req := range mod.Require {
+		modPath, modVersion := req.Mod.Path, req.Mod.Version
+		for _, rep := range mod.Replace {
+			if rep.Old.Path == modPath && (rep.Old.Version == "" || rep.Old.Version == modVersion) && rep.New.Version != "" {
+				modPath, modVersion = rep.New.Path, rep.New.Version
+			}
+		}
+		component := goComponent("library", modPath, modVersion)
+		component.Scope = "required"
+		if req.Indirect {
+			component.Scope = "optional"
+		}
+		bom.Components = append(bom.Components, component)
+	}
+	content, err := json.MarshalIndent(bom, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(content, '\n'), nil
+}
+
+func goComponent(kind, path, version string) cycloneDXComponent {
+	return cycloneDXComponent{Type: kind, Name: path, Version: version, PURL: "pkg:golang/" + path + "@" + version}
+}
+
+// writeSBOM writes the SBOM into the worktree and stages it, so it is part of
+// the release commit.
+func writeSBOM(repo vcs, cfg config, output io.Writer, s *sbom) error {
+	if s == nil {
+		return nil
+	}
+	_, _ = fmt.Fprintf(output, "Writing SBOM %s (sha256 %s)\n", s.path, s.digest)
+	if cfg.dryRun {
+		return previewFile(output, s.path, s.content)
+	}
+	osPath := filepath.FromSlash(s.path)
+	err := os.MkdirAll(filepath.Dir(osPath), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", s.path, err)
+	}
+	err = os.WriteFile(osPath, s.content, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write SBOM: %w", err)
+	}
+	err = repo.add(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to add SBOM: %w", err)
+	}
+	return nil
+}
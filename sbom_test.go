@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testGoMod = `module example.com/widget
+
+go 1.24
+
+require (
+	github.com/a/direct v1.2.0
+	github.com/b/indirect v0.3.1 // indirect
+	github.com/c/forked v1.0.0
+)
+
+replace github.com/c/forked => github.com/us/forked v1.0.1
+`
+
+func TestCycloneDXFromGoMod(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "go.mod")
+	err := os.WriteFile(path, []byte(testGoMod), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := cycloneDXFromGoMod(path, "v1.4.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bom cycloneDX
+	err = json.Unmarshal(content, &bom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bom.BOMFormat != "CycloneDX" || bom.Metadata.Component.PURL != "pkg:golang/example.com/widget@v1.4.0" {
+		t.Errorf("Unexpected SBOM header %+v", bom)
+	}
+	want := []cycloneDXComponent{
+		{Type: "library", Name: "github.com/a/direct", Version: "v1.2.0", PURL: "pkg:golang/github.com/a/direct@v1.2.0", Scope: "required"},
+		{Type: "library", Name: "github.com/b/indirect", Version: "v0.3.1", PURL: "pkg:golang/github.com/b/indirect@v0.3.1", Scope: "optional"},
+		{Type: "library", Name: "github.com/us/forked", Version: "v1.0.1", PURL: "pkg:golang/github.com/us/forked@v1.0.1", Scope: "required"},
+	}
+	if !reflect.DeepEqual(bom.Components, want) {
+		t.Errorf("Got components %+v, want %+v", bom.Components, want)
+	}
+
+	again, err := cycloneDXFromGoMod(path, "v1.4.0")
+	if err != nil || !bytes.Equal(content, again) {
+		t.Error("Expected the SBOM to be reproducible")
+	}
+}
+
+func TestBumpWithSBOM(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     []string
+		goMod   bool
+		check   func(t *testing.T, content []byte)
+		errText string
+	}{
+		{
+			name:  "built-in generator",
+			goMod: true,
+			check: func(t *testing.T, content []byte) {
+				if !strings.Contains(string(content), `"purl": "pkg:golang/example.com/widget@v1.0.1"`) {
+					t.Errorf("Unexpected SBOM:\n%s", content)
+				}
+			},
+		},
+		{
+			name: "external command",
+			env:  []string{`BUMP_SBOM_COMMAND=printf '{"release":"%s","by":"%s"}' "$BUMP_VERSION" "$SBOM_TOOL"`, "SBOM_TOOL=syft"},
+			check: func(t *testing.T, content []byte) {
+				if string(content) != `{"release":"v1.0.1","by":"syft"}` {
+					t.Errorf("Unexpected SBOM %q", content)
+				}
+			},
+		},
+		{
+			name:    "failing command",
+			env:     []string{"BUMP_SBOM_COMMAND=echo broken >&2; exit 3"},
+			errText: "BUMP_SBOM_COMMAND: exit status 3: broken",
+		},
+		{
+			name:    "no go.mod",
+			errText: "no go.mod found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := exec.LookPath("sh"); err != nil && len(tt.env) > 0 {
+				t.Skip("no shell to run BUMP_SBOM_COMMAND")
+			}
+			_, repo := setupTaggedTestRepo(t, "v1.0.0")
+			if tt.goMod {
+				commitFiles(t, repo, "Add go.mod", map[string]string{"go.mod": testGoMod})
+			}
+			commits := countCommits(t, repo)
+
+			var output bytes.Buffer
+			err := run(context.Background(), &output, []string{"-sbom", "release/sbom.json", "-tag-metadata", "json"}, tt.env)
+			if tt.errText != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errText) {
+					t.Fatalf("Expected error containing %q, got: %v", tt.errText, err)
+				}
+				if countCommits(t, repo) != commits {
+					t.Error("Expected no commit when the SBOM can't be generated")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+			}
+
+			// the SBOM is part of the tagged release commit
+			commit, err := tagCommit(repo, "v1.0.1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			file, err := commit.File("release/sbom.json")
+			if err != nil {
+				t.Fatalf("Expected the SBOM in the release commit: %v", err)
+			}
+			content, err := file.Contents()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, []byte(content))
+
+			digest := sha256.Sum256([]byte(content))
+			message := tagMessageOf(t, repo, "v1.0.1")
+			if !strings.Contains(message, "SBOM-SHA256: "+hex.EncodeToString(digest[:])+"\n") {
+				t.Errorf("Expected the SBOM digest in the tag message:\n%s", message)
+			}
+			meta, _, err := parseTagMetadata(message)
+			if err != nil || meta.SBOMSHA256 != hex.EncodeToString(digest[:]) {
+				t.Errorf("Expected the SBOM digest in the metadata, got %+v, %v", meta, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// versionScheme is how versions are spelled, ordered and incremented. The
+// core flow (picking the current version among the tags, validating
+// -version and the .version files, incrementing) goes through the scheme of
+// the run, so a new scheme is added by implementing this interface and
+// registering it in versionSchemes.
+type versionScheme interface {
+	// parse validates a version and returns its canonical form, in which
+	// differently spelled versions (v1.2.0 and 1.2.0) are equal
+	parse(version string) (string, error)
+	// compare orders two canonical versions like strings.Compare
+	compare(a, b string) int
+	// increment returns the version following current at cfg's level
+	increment(current string, cfg config) (string, error)
+	// format spells a canonical version the way like is spelled
+	format(version, like string) string
+}
+
+// defaultScheme is the scheme of runs without -scheme or BUMP_SCHEME.
+const defaultScheme = "semver"
+
+// versionSchemes are the schemes -scheme can select.
+var versionSchemes = map[string]versionScheme{
+	"semver": semverScheme{},
+}
+
+// scheme is the version scheme of the run.
+var scheme versionScheme = semverScheme{}
+
+// useScheme installs the scheme for a run and returns the function restoring
+// the previous one.
+func useScheme(s versionScheme) func() {
+	previous := scheme
+	scheme = s
+	return func() { scheme = previous }
+}
+
+// lookupScheme returns the registered scheme of the name.
+func lookupScheme(name string) (versionScheme, error) {
+	s, ok := versionSchemes[name]
+	if !ok {
+		names := make([]string, 0, len(versionSchemes))
+		for n := range versionSchemes {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown -scheme '%s': must be %s", name, strings.Join(names, ", "))
+	}
+	return s, nil
+}
+
+// semverScheme is semantic versioning, with or without the "v" prefix.
+type semverScheme struct{}
+
+func (semverScheme) parse(version string) (string, error) {
+	normalized := normalizeVersion(version)
+	if !semver.IsValid(normalized) {
+		return "", fmt.Errorf("invalid semantic version string: '%s'", version)
+	}
+	return normalized, nil
+}
+
+func (semverScheme) compare(a, b string) int {
+	return semver.Compare(a, b)
+}
+
+func (s semverScheme) increment(current string, cfg config) (string, error) {
+	parts := strings.Split(stripVPrefix(current), ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("invalid version format: %s", current)
+	}
+	var major, minor, patch int
+	_, err := fmt.Sscanf(stripVPrefix(current), "%d.%d.%d", &major, &minor, &patch)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse current version('%s'): %w", current, err)
+	}
+	switch effectiveAction(cfg, major) {
+	case incrementPatch:
+		patch++
+	case incrementMinor:
+		minor++
+		patch = 0
+	case incrementMajor:
+		major++
+		minor = 0
+		patch = 0
+	default:
+		return "", fmt.Errorf("invalid action: %d", cfg.action)
+	}
+	return s.format(fmt.Sprintf("v%d.%d.%d", major, minor, patch), current), nil
+}
+
+// format keeps the "v" prefix only if like has one.
+func (semverScheme) format(version, like string) string {
+	if hasVPrefix(like) {
+		return normalizeVersion(version)
+	}
+	return stripVPrefix(version)
+}
+
+// sortVersions sorts canonical versions in ascending order of the scheme,
+// with the spelling as the tie-breaker for equal versions (semver build
+// metadata) so the order is deterministic.
+func sortVersions(s versionScheme, versions []string) {
+	sort.Slice(versions, func(i, j int) bool {
+		if c := s.compare(versions[i], versions[j]); c != 0 {
+			return c < 0
+		}
+		return versions[i] < versions[j]
+	})
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// buildScheme numbers releases with a plain build number, the way a custom
+// scheme plugs in.
+type buildScheme struct{}
+
+func (buildScheme) parse(version string) (string, error) {
+	n, err := strconv.Atoi(version)
+	if err != nil || n < 0 {
+		return "", fmt.Errorf("invalid build number: '%s'", version)
+	}
+	return fmt.Sprintf("%010d", n), nil
+}
+
+func (buildScheme) compare(a, b string) int {
+	return strings.Compare(a, b)
+}
+
+func (buildScheme) increment(current string, _ config) (string, error) {
+	n, err := strconv.Atoi(current)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(n + 1), nil
+}
+
+func (buildScheme) format(version, _ string) string {
+	n, _ := strconv.Atoi(version)
+	return strconv.Itoa(n)
+}
+
+func TestCustomScheme(t *testing.T) {
+	versionSchemes["build"] = buildScheme{}
+	t.Cleanup(func() { delete(versionSchemes, "build") })
+	_, repo := setupTaggedTestRepo(t, "9")
+	_, err := repo.CreateTag("v3.0.0", mustHead(t, repo), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-scheme", "build"}, nil)
+	if err != nil {
+		t.Fatalf("bump: %v\nOutput: %s", err, output.String())
+	}
+	if !strings.Contains(output.String(), "Bumped version 9 --> 10") {
+		t.Errorf("Expected the build number to be incremented, got: %s", output.String())
+	}
+	if exists, _ := tagExists(repo, "10"); !exists {
+		t.Error("Expected tag 10")
+	}
+
+	// the next run compares numerically, not as strings
+	commitFiles(t, repo, "Change", map[string]string{"change.txt": "change"})
+	output.Reset()
+	err = run(context.Background(), &output, nil, []string{"BUMP_SCHEME=build"})
+	if err != nil {
+		t.Fatalf("bump: %v\nOutput: %s", err, output.String())
+	}
+	if !strings.Contains(output.String(), "Bumped version 10 --> 11") {
+		t.Errorf("Expected 10 to be the current version, got: %s", output.String())
+	}
+
+	err = run(context.Background(), &output, []string{"-scheme", "build", "-version", "v12"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid build number: 'v12'") {
+		t.Errorf("Expected -version to be validated by the scheme, got: %v", err)
+	}
+}
+
+func TestUnknownScheme(t *testing.T) {
+	setupTaggedTestRepo(t, "v1.0.0")
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-scheme", "calver"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "unknown -scheme 'calver': must be semver") {
+		t.Errorf("Expected an unknown scheme error, got: %v", err)
+	}
+}
+
+func TestSemverSchemeFormat(t *testing.T) {
+	tests := []struct {
+		current string
+		action  action
+		want    string
+	}{
+		{"v1.2.3", incrementPatch, "v1.2.4"},
+		{"1.2.3", incrementMinor, "1.3.0"},
+		{"v1.2.3", incrementMajor, "v2.0.0"},
+	}
+	for _, tt := range tests {
+		got, err := semverScheme{}.increment(tt.current, config{action: tt.action})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("increment(%s) = %s, want %s", tt.current, got, tt.want)
+		}
+	}
+}
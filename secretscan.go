@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// secretAllowMarker on a line stops -scan-secrets from reporting it, for
+// test fixtures and documentation examples.
+const secretAllowMarker = "bump:allow-secret"
+
+// secretRules are the built-in -scan-secrets rules: credentials with a
+// recognisable shape, and quoted values assigned to names that suggest one.
+var secretRules = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY( BLOCK)?-----`)},
+	{"AWS access key ID", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"GitHub token", regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"GitLab token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{"Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"Stripe secret key", regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{24,}\b`)},
+	{"hard-coded credential", regexp.MustCompile(`(?i)(password|passwd|secret|token|api_?key)["']?\s*[:=]\s*["'][^"'\s]{8,}["']`)},
+}
+
+// secretFinding is a line -scan-secrets flagged. The matched text isn't
+// kept, so reporting it can't leak the secret further.
+type secretFinding struct {
+	file string
+	line int
+	rule string
+}
+
+// scanSecrets refuses the release if the changes since the last version tag
+// (or -since) add lines that look like secrets (-scan-secrets), because a
+// published tag can't be taken back. The added lines are matched against
+// secretRules; BUMP_SECRET_SCAN_COMMAND additionally runs an external
+// scanner through the shell with the diff on its standard input, which
+// fails the release by exiting non-zero. The first release scans the whole
+// tree.
+func scanSecrets(ctx context.Context, repo vcs, cfg config, env []string, output io.Writer) error {
+	gitRepo, err := gitRepository(repo, "-scan-secrets")
+	if err != nil {
+		return err
+	}
+	head, err := gitRepo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	headCommit, err := gitRepo.CommitObject(head.Hash())
+	if err != nil {
+		return fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	to, err := headCommit.Tree()
+	if err != nil {
+		return err
+	}
+	since := cfg.since
+	if since == "" {
+		if current, err := repo.lastTag(); err == nil {
+			since, err = existingTag(repo, current)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	var from *object.Tree
+	if since != "" {
+		base, err := resolveCommit(gitRepo, since)
+		if err != nil {
+			return err
+		}
+		from, err = base.Tree()
+		if err != nil {
+			return err
+		}
+	}
+	changes, err := object.DiffTreeWithOptions(ctx, from, to, object.DefaultDiffTreeOptions)
+	if err != nil {
+		return fmt.Errorf("failed to diff the changes to scan: %w", err)
+	}
+	patch, err := changes.PatchContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to diff the changes to scan: %w", err)
+	}
+
+	findings := findSecrets(patch)
+	if command := getenv(env, "BUMP_SECRET_SCAN_COMMAND"); command != "" {
+		var unified bytes.Buffer
+		err = patch.Encode(&unified)
+		if err != nil {
+			return err
+		}
+		err = runSecretScanner(ctx, command, since, &unified)
+		if err != nil {
+			return err
+		}
+	}
+	scope := "the tree"
+	if since != "" {
+		scope = "the changes since " + since
+	}
+	if len(findings) == 0 {
+		_, _ = fmt.Fprintf(output, "No secrets found in %s\n", scope)
+		return nil
+	}
+	var report strings.Builder
+	for _, f := range findings {
+		_, _ = fmt.Fprintf(&report, "\n  %s:%d: %s", f.file, f.line, f.rule)
+	}
+	return fmt.Errorf("possible secrets in %s, and a published tag is forever:%s\nremove them from the history, or mark false positives with %s", scope, report.String(), secretAllowMarker)
+}
+
+// findSecrets matches the lines a patch adds against secretRules, reporting
+// each line once, by its number in the new file. Binary files are skipped.
+func findSecrets(patch *object.Patch) []secretFinding {
+	var findings []secretFinding
+	for _, fp := range patch.FilePatches() {
+		_, to := fp.Files()
+		if to == nil || fp.IsBinary() {
+			continue
+		}
+		line := 0
+		for _, chunk := range fp.Chunks() {
+			if chunk.Type() == diff.Delete {
+				continue
+			}
+			lines := strings.Split(strings.TrimSuffix(chunk.Content(), "\n"), "\n")
+			if chunk.Type() == diff.Equal {
+				line += len(lines)
+				continue
+			}
+			for _, text := range lines {
+				line++
+				if strings.Contains(text, secretAllowMarker) {
+					continue
+				}
+				for _, rule := range secretRules {
+					if rule.pattern.MatchString(text) {
+						findings = append(findings, secretFinding{file: to.Path(), line: line, rule: rule.name})
+						break
+					}
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// runSecretScanner runs BUMP_SECRET_SCAN_COMMAND with the unified diff on its
+// standard input and BUMP_SINCE set to the scanned base, empty for the whole
+// tree.
+func runSecretScanner(ctx context.Context, command, since string, diff io.Reader) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), "BUMP_SINCE="+since)
+	cmd.Stdin = diff
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("BUMP_SECRET_SCAN_COMMAND rejected the release: %w: %s", err, strings.TrimSpace(out.String()))
+	}
+	return nil
+}
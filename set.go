@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"golang.org/x/mod/semver"
+)
+
+// streamAssignment is a stream and the version "bump set" gives it.
+type streamAssignment struct {
+	stream  string
+	version string
+	tag     string
+	files   []string // version files of the stream
+}
+
+// runSet implements "bump set": it applies explicit versions to several
+// streams of .bumpstreams in one transaction, for coordinated releases whose
+// versions are decided elsewhere. Everything is validated before anything
+// is written; the version files go into a single commit, then every stream
+// is tagged. If a tag can't be created, the tags already created are removed
+// again.
+func runSet(_ context.Context, output io.Writer, args []string, env []string) error {
+	flagSet := flag.NewFlagSet("set", flag.ContinueOnError)
+	dryRun := flagSet.Bool("dry-run", false, "Show what would be set without writing anything.")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if flagSet.NArg() == 0 {
+		return errors.New("usage: bump set [-dry-run] <stream>=<version>...")
+	}
+	streams, err := loadStreams(".bumpstreams")
+	if err != nil {
+		return fmt.Errorf("failed to load .bumpstreams: %w", err)
+	}
+	repo, err := git.PlainOpen(".")
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	cfg := config{streams: streams, dateFormat: defaultDateFormat, tagTemplate: getenv(env, "BUMP_TAG_TEMPLATE")}
+	var assignments []streamAssignment
+	for _, arg := range flagSet.Args() {
+		name, version, ok := strings.Cut(arg, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("invalid assignment '%s': must be <stream>=<version>", arg)
+		}
+		if _, err := selectStream(streams, name); err != nil {
+			return err
+		}
+		if !semver.IsValid(normalizeVersion(version)) {
+			return fmt.Errorf("invalid semantic version string for %s: '%s'", name, version)
+		}
+		for _, other := range assignments {
+			if other.stream == name {
+				return fmt.Errorf("stream %s is set twice", name)
+			}
+		}
+		cfg.stream = name
+		tag := releaseTag(cfg, version)
+		exists, err := tagExists(repo, tag)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("tag '%s' already exists", tag)
+		}
+		assignments = append(assignments, streamAssignment{stream: name, version: version, tag: tag})
+	}
+
+	dirty, err := (&gitVCS{repo: repo}).dirtyFiles()
+	if err != nil {
+		return err
+	}
+	if len(dirty) > 0 {
+		return fmt.Errorf("repository is not clean:\n%s", strings.Join(sortedDirtyFiles(dirty), "\n"))
+	}
+	versionFiles, err := findVersionFiles()
+	if err != nil {
+		return err
+	}
+	writeRules, err := loadWorktreeRules()
+	if err != nil {
+		return err
+	}
+	protected, err := loadProtectedPaths(".bumpprotect")
+	if err != nil {
+		return fmt.Errorf("failed to load .bumpprotect: %w", err)
+	}
+	for i := range assignments {
+		for _, file := range versionFiles {
+			if !writeRules.excluded(file) && streamOf(streams, file) == assignments[i].stream {
+				assignments[i].files = append(assignments[i].files, file)
+			}
+		}
+		if len(assignments[i].files) == 0 {
+			return fmt.Errorf("stream %s has no .version files", assignments[i].stream)
+		}
+		err = checkProtected(protected, assignments[i].files)
+		if err != nil {
+			return err
+		}
+	}
+
+	var summary []string
+	for _, a := range assignments {
+		_, _ = fmt.Fprintf(output, "Setting %s to %s, tag=%s\n", a.stream, a.version, a.tag)
+		for _, file := range a.files {
+			content, err := os.ReadFile(filepath.FromSlash(file))
+			if err != nil {
+				return fmt.Errorf("failed to read file: %w", err)
+			}
+			newContent := versionFileContent(config{}, writeRules, file, content, a.version)
+			if *dryRun {
+				err = previewFile(output, file, newContent)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			err = os.WriteFile(filepath.FromSlash(file), newContent, 0644)
+			if err != nil {
+				return fmt.Errorf("failed to write file: %w", err)
+			}
+			err = add(repo, file)
+			if err != nil {
+				return err
+			}
+		}
+		summary = append(summary, a.stream+" "+a.version)
+	}
+	if *dryRun {
+		return nil
+	}
+	err = commit(repo, "set versions: "+strings.Join(summary, ", "), nil)
+	if err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	g := &gitVCS{repo: repo}
+	var created []string
+	for _, a := range assignments {
+		_, err := g.createTag(a.tag, defaultTagMessage)
+		if err != nil {
+			for _, tag := range created {
+				_ = repo.DeleteTag(tag)
+			}
+			return fmt.Errorf("failed to create tag %s, the versions are committed but no stream is tagged: %w", a.tag, err)
+		}
+		created = append(created, a.tag)
+	}
+	_, _ = fmt.Fprintf(output, "Set %s\n", strings.Join(summary, ", "))
+	return nil
+}
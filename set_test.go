@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Add products", map[string]string{
+		".bumpstreams":     "server server\ncli cmd/cli cli-\n",
+		"server/.version":  "v0.3.0\n",
+		"cmd/cli/.version": "v2.0.0\n",
+	})
+	before := countCommits(t, repo)
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"set", "-dry-run", "server=v2.1.0", "cli=v1.7.3"}, nil)
+	if err != nil {
+		t.Fatalf("set -dry-run: %v\nOutput: %s", err, output.String())
+	}
+	if !strings.Contains(output.String(), "+v2.1.0") || readFile(t, "server/.version") != "v0.3.0\n" {
+		t.Errorf("Dry run should preview without writing, got:\n%s", output.String())
+	}
+
+	output.Reset()
+	err = run(context.Background(), &output, []string{"set", "server=v2.1.0", "cli=v1.7.3"}, nil)
+	if err != nil {
+		t.Fatalf("set: %v\nOutput: %s", err, output.String())
+	}
+	if got := readFile(t, "server/.version"); got != "v2.1.0\n" {
+		t.Errorf("server/.version = %q", got)
+	}
+	if got := readFile(t, "cmd/cli/.version"); got != "v1.7.3\n" {
+		t.Errorf("cmd/cli/.version = %q", got)
+	}
+	if got := readFile(t, ".version"); got != "v1.0.0" {
+		t.Errorf(".version of the default stream changed to %q", got)
+	}
+	if got := countCommits(t, repo); got != before+1 {
+		t.Errorf("Expected one commit for all streams, got %d", got-before)
+	}
+	if msg := commitMessages(t, repo, 1)[0]; msg != "set versions: server v2.1.0, cli v1.7.3" {
+		t.Errorf("Unexpected commit message %q", msg)
+	}
+	head := mustHead(t, repo)
+	for _, tag := range []string{"server/v2.1.0", "cli-v1.7.3"} {
+		commit, err := tagCommit(repo, tag)
+		if err != nil {
+			t.Fatalf("tag %s: %v", tag, err)
+		}
+		if commit.Hash != head {
+			t.Errorf("Tag %s is not on the set commit", tag)
+		}
+	}
+}
+
+func TestSetRejects(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Add products", map[string]string{
+		".bumpstreams":    "server server\nempty empty\n",
+		"server/.version": "v0.3.0\n",
+		"empty/README.md": "nothing to version",
+	})
+	_, err := repo.CreateTag("server/v0.4.0", mustHead(t, repo), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := countCommits(t, repo)
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"set"}, "usage"},
+		{[]string{"set", "server"}, "invalid assignment"},
+		{[]string{"set", "nope=v1.0.0"}, "nope"},
+		{[]string{"set", "server=banana"}, "invalid semantic version"},
+		{[]string{"set", "server=v0.5.0", "server=v0.6.0"}, "set twice"},
+		{[]string{"set", "server=v0.4.0"}, "already exists"},
+		{[]string{"set", "server=v0.5.0", "empty=v1.0.0"}, "no .version files"},
+	}
+	for _, tt := range tests {
+		var output bytes.Buffer
+		err := run(context.Background(), &output, tt.args, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("bump %v: expected error containing %q, got %v", tt.args, tt.want, err)
+		}
+	}
+	if got := readFile(t, "server/.version"); got != "v0.3.0\n" {
+		t.Errorf("A rejected set wrote server/.version: %q", got)
+	}
+	if got := countCommits(t, repo); got != before {
+		t.Errorf("A rejected set committed")
+	}
+}
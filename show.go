@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// tagReport is what "bump show" knows about a release tag.
+type tagReport struct {
+	Tag        string    `json:"tag"`
+	Annotated  bool      `json:"annotated"`
+	Tagger     string    `json:"tagger,omitempty"`
+	TagDate    time.Time `json:"tag_date,omitzero"`
+	Annotation string    `json:"annotation,omitempty"`
+	Signature  string    `json:"signature"`
+	Verified   bool      `json:"verified"`
+	Commit     string    `json:"commit"`
+	Subject    string    `json:"subject"`
+	Author     string    `json:"author"`
+	Date       time.Time `json:"date"`
+	// Changelog is the changelog file at the tag's commit with a section of
+	// the version, Section that section's content
+	Changelog  string `json:"changelog,omitempty"`
+	Section    string `json:"section,omitempty"`
+	ReleaseURL string `json:"release_url,omitempty"`
+}
+
+// runShow implements "bump show": everything about one release tag, the
+// annotation, the tagger, the signature, the commit, the changelog section
+// and the forge's release page.
+func runShow(_ context.Context, output io.Writer, args []string, env []string) error {
+	flagSet := flag.NewFlagSet("show", flag.ContinueOnError)
+	keyFile := flagSet.String("key", getenv(env, "BUMP_RELEASE_PUBKEY"), "Armored OpenPGP public key(s) to verify the tag signature with.")
+	signersFile := flagSet.String("allowed-signers", getenv(env, "BUMP_ALLOWED_SIGNERS"), "SSH allowed signers file to verify the tag signature with.")
+	format := flagSet.String("format", "text", "Output format: text or json.")
+	remoteName := flagSet.String("remote", "", "Remote whose forge has the release page (default: the push remote, as for a bump).")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if flagSet.NArg() != 1 {
+		return errors.New("usage: bump show [-key pubkey] [-allowed-signers file] [-format text|json] [-remote name] <tag>")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("invalid format '%s': must be text or json", *format)
+	}
+	var keys releaseKeys
+	if *keyFile != "" {
+		_, err = loadKeyRing(*keyFile, "-key")
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(*keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read -key: %w", err)
+		}
+		keys.pgp = string(content)
+	}
+	if *signersFile != "" {
+		keys.ssh, err = loadAllowedSigners(*signersFile, "-allowed-signers")
+		if err != nil {
+			return err
+		}
+	}
+	streams, err := loadStreams(".bumpstreams")
+	if err != nil {
+		return fmt.Errorf("failed to load .bumpstreams: %w", err)
+	}
+	repo, err := git.PlainOpen(".")
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	remote := *remoteName
+	if remote == "" {
+		r, err := resolveRemotes(repo, config{remote: getenv(env, "BUMP_REMOTE"), pushRemote: getenv(env, "BUMP_PUSH_REMOTE")})
+		if err != nil {
+			return err
+		}
+		remote = r.push
+	}
+
+	report, err := showTag(repo, keys, streams, flagSet.Arg(0), remote, env)
+	if err != nil {
+		return err
+	}
+	if *format == "json" {
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	printTagReport(output, report)
+	return nil
+}
+
+// showTag collects the report of a tag, linking its release on the remote's
+// forge.
+func showTag(repo *git.Repository, keys releaseKeys, streams []versionStream, name, remote string, env []string) (tagReport, error) {
+	report := tagReport{Tag: name}
+	ref, err := repo.Tag(name)
+	if err != nil {
+		return report, fmt.Errorf("failed to find tag %s: %w", name, err)
+	}
+	commit, err := peelTag(repo, ref.Hash())
+	if err != nil {
+		return report, fmt.Errorf("failed to resolve tag %s: %w", name, err)
+	}
+	report.Commit = commit.Hash.String()
+	report.Subject = commitSubject(commit.Message)
+	report.Author = fmt.Sprintf("%s <%s>", commit.Author.Name, commit.Author.Email)
+	report.Date = commit.Author.When
+
+	if tag, err := repo.TagObject(ref.Hash()); err == nil {
+		report.Annotated = true
+		report.Tagger = fmt.Sprintf("%s <%s>", tag.Tagger.Name, tag.Tagger.Email)
+		report.TagDate = tag.Tagger.When
+		report.Annotation = strings.TrimSpace(tag.Message)
+	}
+	signer, err := tagSigner(repo, ref, keys)
+	if err != nil {
+		report.Signature = err.Error()
+	} else {
+		report.Signature = "signed by " + signer
+		report.Verified = true
+	}
+
+	file, version := changelogOfTag(streams, name)
+	section, err := changelogSection(commit, file, version)
+	if err != nil {
+		return report, err
+	}
+	if section != "" {
+		report.Changelog, report.Section = file, section
+	}
+	report.ReleaseURL = forgeReleaseURL(repo, remote, name, env)
+	return report, nil
+}
+
+// changelogOfTag returns the changelog a tag's release is recorded in, that of
+// the stream whose prefix the tag has, and the version the tag names.
+func changelogOfTag(streams []versionStream, tag string) (string, string) {
+	for _, stream := range streams {
+		if version, ok := strings.CutPrefix(tag, stream.prefix); ok {
+			return path.Join(stream.path, changelogFile), version
+		}
+	}
+	return changelogFile, tag
+}
+
+// changelogSection returns the section of the version in the changelog as
+// of the commit, without its heading, or "" if there is none.
+func changelogSection(commit *object.Commit, file, version string) (string, error) {
+	f, err := commit.File(file)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	content, err := f.Contents()
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if !isVersionHeading(line, version) {
+			continue
+		}
+		end := len(lines)
+		for j := i + 1; j < len(lines); j++ {
+			if strings.HasPrefix(lines[j], "## ") || isLinkReference(lines[j]) {
+				end = j
+				break
+			}
+		}
+		return strings.TrimSpace(strings.Join(lines[i+1:end], "\n")), nil
+	}
+	return "", nil
+}
+
+// isVersionHeading reports whether a line is the changelog heading of the
+// version, "## [v1.3.0] - 2024-05-01" or "## 1.3.0", with or without "v".
+func isVersionHeading(line, version string) bool {
+	heading, ok := strings.CutPrefix(line, "## ")
+	if !ok {
+		return false
+	}
+	heading = strings.TrimPrefix(heading, "[")
+	for _, spelling := range []string{normalizeVersion(version), stripVPrefix(version)} {
+		if rest, ok := strings.CutPrefix(heading, spelling); ok && (rest == "" || strings.ContainsAny(rest[:1], "] ")) {
+			return true
+		}
+	}
+	return false
+}
+
+// forgeReleaseURL returns the release page of the tag if the remote is hosted
+// on github.com or gitlab.com, whose URLs are known, or its tag page on
+// Bitbucket, which has no releases: bitbucket.org or the Bitbucket Server
+// (Data Center) at BUMP_BITBUCKET_URL. It is "" otherwise.
+func forgeReleaseURL(repo *git.Repository, remoteName, tag string, env []string) string {
+	remote, err := repo.Remote(remoteName)
+	if err != nil || len(remote.Config().URLs) == 0 {
+		return ""
+	}
+	host, project := forgeProject(remote.Config().URLs[0])
+	escaped := url.PathEscape(tag)
+	switch host {
+	case "github.com":
+		return fmt.Sprintf("https://github.com/%s/releases/tag/%s", project, escaped)
+	case "gitlab.com":
+		return fmt.Sprintf("https://gitlab.com/%s/-/releases/%s", project, escaped)
+	case "bitbucket.org":
+		return fmt.Sprintf("https://bitbucket.org/%s/src/%s", project, escaped)
+	}
+	return bitbucketServerTagURL(getenv(env, "BUMP_BITBUCKET_URL"), host, project, tag)
+}
+
+// bitbucketServerTagURL returns the page of the tag on the Bitbucket Server at
+// base if the remote's host is the server's, or "".
+func bitbucketServerTagURL(base, host, project, tag string) string {
+	key, name, ok := bitbucketServerRepo(base, host, project)
+	if !ok {
+		return ""
+	}
+	owner := "projects/" + key
+	if user, ok := strings.CutPrefix(key, "~"); ok {
+		owner = "users/" + user
+	}
+	return fmt.Sprintf("%s/%s/repos/%s/browse?at=%s", strings.TrimSuffix(base, "/"), owner, name, url.QueryEscape("refs/tags/"+tag))
+}
+
+// bitbucketServerRepo returns the project key and the name of a repository
+// on the Bitbucket Server at base, if the remote's host is the server's. Its
+// clone URLs are https://host/scm/PROJ/repo.git and
+// ssh://git@host:7999/proj/repo.git, with ~user in place of the project for
+// personal repositories.
+func bitbucketServerRepo(base, host, project string) (key, name string, ok bool) {
+	server, err := url.Parse(base)
+	if base == "" || err != nil || host == "" || !strings.EqualFold(server.Hostname(), host) {
+		return "", "", false
+	}
+	project = strings.TrimPrefix(project, strings.Trim(server.Path, "/")+"/")
+	key, name, ok = strings.Cut(strings.TrimPrefix(project, "scm/"), "/")
+	if !ok || strings.Contains(name, "/") {
+		return "", "", false
+	}
+	if !strings.HasPrefix(key, "~") {
+		key = strings.ToUpper(key)
+	}
+	return key, name, true
+}
+
+// forgeProject splits a remote URL, in URL or scp-like form, into the host and
+// the project path without ".git".
+func forgeProject(remote string) (string, string) {
+	var host, project string
+	if u, err := url.Parse(remote); err == nil && u.Host != "" {
+		host, project = u.Hostname(), u.Path
+	} else if at, rest, ok := strings.Cut(remote, ":"); ok && !strings.Contains(at, "/") {
+		host, project = at[strings.LastIndex(at, "@")+1:], rest
+	}
+	return host, strings.TrimSuffix(strings.Trim(project, "/"), ".git")
+}
+
+func printTagReport(output io.Writer, report tagReport) {
+	w := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "Tag:\t%s\n", report.Tag)
+	if report.Annotated {
+		_, _ = fmt.Fprintf(w, "Tagger:\t%s, %s\n", report.Tagger, report.TagDate.Format(time.RFC3339))
+	} else {
+		_, _ = fmt.Fprintf(w, "Tagger:\tnone, lightweight tag\n")
+	}
+	_, _ = fmt.Fprintf(w, "Signature:\t%s\n", report.Signature)
+	_, _ = fmt.Fprintf(w, "Commit:\t%s %s\n", shortHash(report.Commit), report.Subject)
+	_, _ = fmt.Fprintf(w, "Author:\t%s, %s\n", report.Author, report.Date.Format(time.RFC3339))
+	if report.ReleaseURL != "" {
+		_, _ = fmt.Fprintf(w, "Release:\t%s\n", report.ReleaseURL)
+	}
+	_ = w.Flush()
+	if report.Annotation != "" {
+		_, _ = fmt.Fprintf(output, "\n%s\n", report.Annotation)
+	}
+	if report.Section != "" {
+		_, _ = fmt.Fprintf(output, "\nChangelog (%s):\n%s\n", report.Changelog, report.Section)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+)
+
+func TestShow(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	key, _ := testSigningKey(t)
+	err := key.DecryptPrivateKeys([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKey := testPublicKey(t, key)
+	_, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{"git@github.com:acme/app.git"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, repo, "Release v1.1.0", map[string]string{
+		".version":     "v1.1.0\n",
+		"CHANGELOG.md": "# Changelog\n\n## [Unreleased]\n\n## [v1.1.0] - 2024-05-01\n\n### Added\n\n- Widgets\n\n## [v1.0.0] - 2024-04-01\n\n- First\n\n[v1.1.0]: https://github.com/acme/app/compare/v1.0.0...v1.1.0\n",
+	})
+	_, err = repo.CreateTag("v1.1.0", mustHead(t, repo), &git.CreateTagOptions{Message: "Release v1.1.0\n\nWidgets for everyone.", SignKey: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"show", "-key", pubKey, "v1.1.0"}, nil)
+	if err != nil {
+		t.Fatalf("show: %v", err)
+	}
+	for _, want := range []string{
+		"Signature:  signed by Release Bot",
+		"Release v1.1.0\n\nWidgets for everyone.",
+		"Commit:     " + shortHash(mustHead(t, repo).String()) + " Release v1.1.0",
+		"Release:    https://github.com/acme/app/releases/tag/v1.1.0",
+		"Changelog (CHANGELOG.md):\n### Added\n\n- Widgets\n",
+	} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("Expected %q in the output:\n%s", want, output.String())
+		}
+	}
+
+	// the lightweight v1.0.0 has no changelog section at its commit
+	output.Reset()
+	err = run(context.Background(), &output, []string{"show", "-format", "json", "v1.0.0"}, nil)
+	if err != nil {
+		t.Fatalf("show: %v", err)
+	}
+	var report tagReport
+	err = json.Unmarshal(output.Bytes(), &report)
+	if err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, output.String())
+	}
+	if report.Annotated || report.Verified || report.Signature != "lightweight tag, nothing is signed" || report.Section != "" {
+		t.Errorf("Unexpected report of a lightweight tag: %+v", report)
+	}
+
+	// the release page is on the forge of the remote a bump publishes to
+	_, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: "upstream", URLs: []string{"https://gitlab.com/acme/app.git"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	output.Reset()
+	err = run(context.Background(), &output, []string{"show", "v1.1.0"}, []string{"BUMP_REMOTE=upstream"})
+	if err != nil || !strings.Contains(output.String(), "Release:    https://gitlab.com/acme/app/-/releases/v1.1.0") {
+		t.Errorf("Expected the release page on BUMP_REMOTE's forge, got: %v\n%s", err, output.String())
+	}
+	output.Reset()
+	err = run(context.Background(), &output, []string{"show", "-remote", "origin", "v1.1.0"}, []string{"BUMP_REMOTE=upstream"})
+	if err != nil || !strings.Contains(output.String(), "Release:    https://github.com/acme/app/releases/tag/v1.1.0") {
+		t.Errorf("Expected -remote to pick the forge, got: %v\n%s", err, output.String())
+	}
+}
+
+func TestForgeProject(t *testing.T) {
+	tests := []struct {
+		remote, host, project string
+	}{
+		{"git@github.com:acme/app.git", "github.com", "acme/app"},
+		{"https://gitlab.com/group/sub/app.git", "gitlab.com", "group/sub/app"},
+		{"ssh://git@github.com/acme/app", "github.com", "acme/app"},
+		{"/srv/git/app.git", "", ""},
+	}
+	for _, tt := range tests {
+		host, project := forgeProject(tt.remote)
+		if host != tt.host || project != tt.project {
+			t.Errorf("forgeProject(%s) = %s, %s, want %s, %s", tt.remote, host, project, tt.host, tt.project)
+		}
+	}
+}
+
+func TestForgeReleaseURL(t *testing.T) {
+	env := []string{"BUMP_BITBUCKET_URL=https://git.acme.test/bitbucket"}
+	tests := []struct {
+		remote, want string
+	}{
+		{"git@github.com:acme/app.git", "https://github.com/acme/app/releases/tag/v1.0.0"},
+		{"https://gitlab.com/group/app.git", "https://gitlab.com/group/app/-/releases/v1.0.0"},
+		{"git@bitbucket.org:acme/app.git", "https://bitbucket.org/acme/app/src/v1.0.0"},
+		{"https://git.acme.test/bitbucket/scm/shop/app.git", "https://git.acme.test/bitbucket/projects/SHOP/repos/app/browse?at=refs%2Ftags%2Fv1.0.0"},
+		{"ssh://git@git.acme.test:7999/shop/app.git", "https://git.acme.test/bitbucket/projects/SHOP/repos/app/browse?at=refs%2Ftags%2Fv1.0.0"},
+		{"ssh://git@git.acme.test:7999/~jdoe/app.git", "https://git.acme.test/bitbucket/users/jdoe/repos/app/browse?at=refs%2Ftags%2Fv1.0.0"},
+		{"https://git.example.test/scm/shop/app.git", ""},
+	}
+	for _, tt := range tests {
+		repo, err := git.PlainInit(t.TempDir(), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{tt.remote}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := forgeReleaseURL(repo, "origin", "v1.0.0", env); got != tt.want {
+			t.Errorf("forgeReleaseURL(%s) = %q, want %q", tt.remote, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// signedTagsVCS signs the version tags it creates (-sign) with an OpenPGP
+// key. It sits inside the tag name wrappers, which hand it the final names,
+// and inside readOnlyVCS, which refuses to tag at all.
+type signedTagsVCS struct {
+	vcs
+	repo *git.Repository
+	key  *openpgp.Entity
+	loc  *time.Location // of the tags, nil for the local time zone
+}
+
+// withSignedTags wraps repo for -sign, tagging in loc. The key is the one -signing-key or
+// git's user.signingkey names, from the keyring in BUMP_SIGNING_KEY or the
+// GnuPG secret keyring; without either, the first private key in the keyring.
+// With -ssh-key or git's gpg.format set to ssh, an SSH key signs instead (see
+// withSSHSigning).
+func withSignedTags(repo vcs, keyID, sshKey string, loc *time.Location, env []string) (vcs, error) {
+	gitRepo, err := gitRepository(repo, "-sign")
+	if err != nil {
+		return nil, err
+	}
+	format, err := gitConfigOption(gitRepo, "gpg", "format")
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case sshKey != "" || format == "ssh":
+		return withSSHSigning(repo, gitRepo, sshKey, loc, env)
+	case format != "" && format != "openpgp":
+		return nil, fmt.Errorf("-sign doesn't support gpg.format %s", format)
+	}
+	if keyID == "" {
+		keyID, err = gitConfigOption(gitRepo, "user", "signingkey")
+		if err != nil {
+			return nil, err
+		}
+	}
+	key, err := loadSigningKey(env, keyID)
+	if err != nil {
+		return nil, err
+	}
+	return signedTagsVCS{vcs: repo, repo: gitRepo, key: key, loc: loc}, nil
+}
+
+func (s signedTagsVCS) goGit() *git.Repository {
+	return s.repo
+}
+
+// createTag creates the signed annotated tag on the commit the wrapped vcs
+// releases, its HEAD or the -branch tip.
+func (s signedTagsVCS) createTag(name, message string) (string, error) {
+	target, err := s.head()
+	if err != nil {
+		return "", err
+	}
+	opts, err := tagOptions(s.repo, message, s.loc)
+	if err != nil {
+		return "", err
+	}
+	opts.SignKey = s.key
+	ref, err := s.repo.CreateTag(name, plumbing.NewHash(target), opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to create signed tag: %w", err)
+	}
+	return ref.Hash().String(), nil
+}
+
+// loadSigningKey finds the private key for -sign in the keyring file named by
+// BUMP_SIGNING_KEY, armored or binary, or else in GnuPG's secring.gpg, and
+// decrypts it with BUMP_SIGNING_KEY_PASSPHRASE. GnuPG 2.1 and later keep
+// private keys where they can't be read, so they have to be exported.
+func loadSigningKey(env []string, keyID string) (*openpgp.Entity, error) {
+	path, source := getenv(env, "BUMP_SIGNING_KEY"), "BUMP_SIGNING_KEY"
+	if path == "" {
+		home := getenv(env, "GNUPGHOME")
+		if home == "" {
+			dir, err := os.UserHomeDir()
+			if err != nil {
+				return nil, err
+			}
+			home = filepath.Join(dir, ".gnupg")
+		}
+		path, source = filepath.Join(home, "secring.gpg"), "the GnuPG secret keyring"
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && source != "BUMP_SIGNING_KEY" {
+		return nil, errors.New("-sign found no private key: export it with 'gpg --export-secret-keys --armor <key id> > key.asc' and set BUMP_SIGNING_KEY to the file")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", source, err)
+	}
+	var keys openpgp.EntityList
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte("-----BEGIN")) {
+		keys, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(content))
+	} else {
+		keys, err = openpgp.ReadKeyRing(bytes.NewReader(content))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", source, err)
+	}
+
+	for _, key := range keys {
+		if key.PrivateKey == nil || !matchesKeyID(key, keyID) {
+			continue
+		}
+		if key.PrivateKey.Encrypted {
+			err = key.DecryptPrivateKeys([]byte(getenv(env, "BUMP_SIGNING_KEY_PASSPHRASE")))
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt the signing key: %w", err)
+			}
+		}
+		return key, nil
+	}
+	if keyID == "" {
+		return nil, fmt.Errorf("%s holds no private key", source)
+	}
+	return nil, fmt.Errorf("%s holds no private key %s", source, keyID)
+}
+
+// matchesKeyID reports whether the key is the one GnuPG would pick for the
+// specifier: a key id or fingerprint of the key or one of its subkeys, with
+// or without 0x, or an email address of one of its identities. An empty
+// specifier matches any key.
+func matchesKeyID(key *openpgp.Entity, id string) bool {
+	if id == "" {
+		return true
+	}
+	if strings.Contains(id, "@") {
+		email := strings.Trim(id, "<>")
+		for _, identity := range key.Identities {
+			if identity.UserId != nil && strings.EqualFold(identity.UserId.Email, email) {
+				return true
+			}
+		}
+		return false
+	}
+	id = strings.ToUpper(strings.TrimPrefix(strings.TrimSuffix(id, "!"), "0x"))
+	fingerprints := []string{fmt.Sprintf("%X", key.PrimaryKey.Fingerprint)}
+	for _, subkey := range key.Subkeys {
+		fingerprints = append(fingerprints, fmt.Sprintf("%X", subkey.PublicKey.Fingerprint))
+	}
+	for _, fingerprint := range fingerprints {
+		if len(id) >= 8 && strings.HasSuffix(fingerprint, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// gitConfigOption returns an option of git's configuration as git would: the
+// repository's value, else the global one, else the system one.
+func gitConfigOption(repo *git.Repository, section, option string) (string, error) {
+	local, err := repo.Config()
+	if err != nil {
+		return "", fmt.Errorf("failed to read repository config: %w", err)
+	}
+	if value := local.Raw.Section(section).Option(option); value != "" {
+		return value, nil
+	}
+	for _, scope := range []gitconfig.Scope{gitconfig.GlobalScope, gitconfig.SystemScope} {
+		cfg, err := gitconfig.LoadConfig(scope)
+		if err != nil {
+			return "", fmt.Errorf("failed to read git config: %w", err)
+		}
+		if value := cfg.Raw.Section(section).Option(option); value != "" {
+			return value, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBumpSign(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	key, keyFile := testSigningKey(t)
+	err := key.DecryptPrivateKeys([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKey, err := os.ReadFile(testPublicKey(t, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := []string{"BUMP_SIGNING_KEY=" + keyFile, "BUMP_SIGNING_KEY_PASSPHRASE=secret"}
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-sign", "-signing-key", "nobody@example.com"}, env)
+	if err == nil || !strings.Contains(err.Error(), "BUMP_SIGNING_KEY holds no private key nobody@example.com") {
+		t.Errorf("Expected an unknown key to fail the bump, got: %v", err)
+	}
+
+	// the key id from git config
+	repoConfig, err := repo.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repoConfig.Raw.Section("user").SetOption("signingkey", "0x"+key.PrimaryKey.KeyIdString())
+	err = repo.SetConfig(repoConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = run(context.Background(), &output, []string{"-minor", "-sign"}, env)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	ref, err := repo.Tag("v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag, err := repo.TagObject(ref.Hash())
+	if err != nil {
+		t.Fatalf("Expected an annotated tag: %v", err)
+	}
+	if tag.Target != mustHead(t, repo) {
+		t.Errorf("Expected the tag on the release commit, got %s", tag.Target)
+	}
+	_, err = tag.Verify(string(pubKey))
+	if err != nil {
+		t.Errorf("Expected a valid signature, got: %v", err)
+	}
+
+	err = run(context.Background(), &output, []string{"-sign"}, []string{"BUMP_SIGNING_KEY=" + keyFile})
+	if err == nil || !strings.Contains(err.Error(), "failed to decrypt the signing key") {
+		t.Errorf("Expected a missing passphrase to fail the bump, got: %v", err)
+	}
+}
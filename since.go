@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"golang.org/x/mod/semver"
+)
+
+// sinceReport lists the releases after a version, for "bump since".
+type sinceReport struct {
+	Since    string         `json:"since"`
+	Releases []sinceRelease `json:"releases"`
+}
+
+// sinceRelease is a release with its notes: the changelog section of its
+// version at the tag's commit or, without one, the tag annotation.
+type sinceRelease struct {
+	Tag     string    `json:"tag"`
+	Version string    `json:"version"`
+	Date    time.Time `json:"date"`
+	Notes   string    `json:"notes,omitempty"`
+	// NotesFrom is where the notes come from: the changelog file, or "tag"
+	NotesFrom string `json:"notes_from,omitempty"`
+}
+
+// runSince implements "bump since": the releases after a version, newest
+// first, with their notes, for upgrade notes of users several versions
+// behind.
+func runSince(_ context.Context, output io.Writer, args []string, _ []string) error {
+	const usage = "usage: bump since [-stream name] [-prereleases] [-format markdown|json] <version>"
+	flagSet := flag.NewFlagSet("since", flag.ContinueOnError)
+	streamName := flagSet.String("stream", "", "Stream of .bumpstreams whose releases to list.")
+	prereleases := flagSet.Bool("prereleases", false, "Also list prereleases.")
+	format := flagSet.String("format", "markdown", "Output format: markdown or json.")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if flagSet.NArg() != 1 {
+		return errors.New(usage)
+	}
+	if *format != "markdown" && *format != "json" {
+		return fmt.Errorf("invalid format '%s': must be markdown or json", *format)
+	}
+	since := flagSet.Arg(0)
+	if !semver.IsValid(normalizeVersion(since)) {
+		return fmt.Errorf("invalid version '%s'", since)
+	}
+	streams, err := loadStreams(".bumpstreams")
+	if err != nil {
+		return fmt.Errorf("failed to load .bumpstreams: %w", err)
+	}
+	stream, err := selectStream(streams, *streamName)
+	if err != nil {
+		return err
+	}
+	prefix := ""
+	if stream != nil {
+		prefix = stream.prefix
+	}
+	repo, err := git.PlainOpen(".")
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	report, err := releasesSince(repo, streams, prefix, since, *prereleases)
+	if err != nil {
+		return err
+	}
+	if *format == "json" {
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	printSince(output, report)
+	return nil
+}
+
+// releasesSince collects the releases of the stream with the tag prefix
+// that are newer than the version, newest first.
+func releasesSince(repo *git.Repository, streams []versionStream, prefix, since string, prereleases bool) (sinceReport, error) {
+	report := sinceReport{Since: since, Releases: []sinceRelease{}}
+	history, err := exportHistory(repo)
+	if err != nil {
+		return report, err
+	}
+	for i := len(history.Releases) - 1; i >= 0; i-- {
+		r := history.Releases[i]
+		version, ok := strings.CutPrefix(r.Tag, prefix)
+		if !ok || version != r.Version {
+			continue // another stream's tag
+		}
+		normalized := normalizeVersion(version)
+		if semver.Compare(normalized, normalizeVersion(since)) <= 0 {
+			break
+		}
+		if semver.Prerelease(normalized) != "" && !prereleases {
+			continue
+		}
+		release := sinceRelease{Tag: r.Tag, Version: version, Date: r.Date}
+		commit, err := tagCommit(repo, r.Tag)
+		if err != nil {
+			return report, err
+		}
+		file, changelogVersion := changelogOfTag(streams, r.Tag)
+		section, err := changelogSection(commit, file, changelogVersion)
+		if err != nil {
+			return report, err
+		}
+		if section != "" {
+			release.Notes, release.NotesFrom = section, file
+		} else if notes := tagNotes(r.Message); notes != "" {
+			release.Notes, release.NotesFrom = notes, "tag"
+		}
+		report.Releases = append(report.Releases, release)
+	}
+	return report, nil
+}
+
+// tagNotes returns what a tag annotation says about the release: the
+// annotation without the metadata block and ownTagTrailer, or "" for bump's
+// default message.
+func tagNotes(message string) string {
+	if start := strings.Index(message, metadataBegin); start >= 0 {
+		message = message[:start]
+	}
+	message = strings.TrimSpace(strings.ReplaceAll(message, "\n"+ownTagTrailer, ""))
+	if message == defaultTagMessage {
+		return ""
+	}
+	return message
+}
+
+func printSince(output io.Writer, report sinceReport) {
+	if len(report.Releases) == 0 {
+		_, _ = fmt.Fprintf(output, "No releases since %s\n", report.Since)
+		return
+	}
+	_, _ = fmt.Fprintf(output, "# Releases since %s\n", report.Since)
+	for _, r := range report.Releases {
+		_, _ = fmt.Fprintf(output, "\n## %s (%s)\n\n", r.Tag, r.Date.Format(defaultDateFormat))
+		if r.Notes == "" {
+			_, _ = fmt.Fprintf(output, "No release notes.\n")
+			continue
+		}
+		_, _ = fmt.Fprintf(output, "%s\n", r.Notes)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+)
+
+func TestSince(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Release v1.1.0", map[string]string{
+		"CHANGELOG.md": "# Changelog\n\n## [Unreleased]\n\n## [v1.1.0] - 2024-05-01\n\n### Added\n\n- Widgets\n",
+	})
+	_, err := repo.CreateTag("v1.1.0", mustHead(t, repo), &git.CreateTagOptions{Message: defaultTagMessage})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, repo, "Release v1.2.0-rc.1", map[string]string{"main.go": "package main\n"})
+	_, err = repo.CreateTag("v1.2.0-rc.1", mustHead(t, repo), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, repo, "Release v1.2.0", map[string]string{"main.go": "package main\n\n"})
+	message := "Faster startup.\n\n" + metadataBegin + "\n{}\n" + metadataEnd + "\n"
+	_, err = repo.CreateTag("v1.2.0", mustHead(t, repo), &git.CreateTagOptions{Message: message})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.CreateTag("server/v9.0.0", mustHead(t, repo), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"since", "1.0.0"}, nil)
+	if err != nil {
+		t.Fatalf("since: %v", err)
+	}
+	got := output.String()
+	for _, want := range []string{
+		"# Releases since 1.0.0\n\n## v1.2.0 (",
+		")\n\nFaster startup.\n\n## v1.1.0 (",
+		")\n\n### Added\n\n- Widgets\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in the output:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "rc.1") || strings.Contains(got, "v9.0.0") || strings.Contains(got, "## v1.0.0") {
+		t.Errorf("Expected only the default stream's releases after v1.0.0, got:\n%s", got)
+	}
+
+	output.Reset()
+	err = run(context.Background(), &output, []string{"since", "-prereleases", "-format", "json", "v1.1.0"}, nil)
+	if err != nil {
+		t.Fatalf("since: %v", err)
+	}
+	var report sinceReport
+	err = json.Unmarshal(output.Bytes(), &report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Releases) != 2 || report.Releases[0].NotesFrom != "tag" || report.Releases[1].Tag != "v1.2.0-rc.1" || report.Releases[1].Notes != "" {
+		t.Errorf("Expected v1.2.0 and its release candidate, got %+v", report.Releases)
+	}
+
+	output.Reset()
+	err = run(context.Background(), &output, []string{"since", "v1.2.0"}, nil)
+	if err != nil || !strings.Contains(output.String(), "No releases since v1.2.0") {
+		t.Errorf("Expected no releases, got %v:\n%s", err, output.String())
+	}
+	err = run(context.Background(), &output, []string{"since", "latest"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid version 'latest'") {
+		t.Errorf("Expected an invalid version to be refused, got: %v", err)
+	}
+}
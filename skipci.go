@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CI skip styles (-skip-ci) mark the release commit so CI doesn't run a
+// redundant pipeline for it. Most CI systems honour [skip ci] and [ci skip]
+// in the commit message; GitHub also honours the skip-checks trailer.
+const (
+	skipCISkipCI     = "skip-ci"     // "[skip ci]" appended to the subject
+	skipCICISkip     = "ci-skip"     // "[ci skip]" appended to the subject
+	skipCINoCI       = "no-ci"       // "[no ci]" appended to the subject
+	skipCISkipChecks = "skip-checks" // "skip-checks: true" trailer
+)
+
+const skipCIStylesHelp = skipCISkipCI + ", " + skipCICISkip + ", " + skipCINoCI + " or " + skipCISkipChecks
+
+var skipCIMarkers = map[string]string{
+	skipCISkipCI: "[skip ci]",
+	skipCICISkip: "[ci skip]",
+	skipCINoCI:   "[no ci]",
+}
+
+// checkSkipCI validates a CI skip style, empty for none.
+func checkSkipCI(style string) error {
+	if _, ok := skipCIMarkers[style]; ok || style == "" || style == skipCISkipChecks {
+		return nil
+	}
+	return fmt.Errorf("invalid -skip-ci '%s': must be %s", style, skipCIStylesHelp)
+}
+
+// commitMessage builds a release commit message from the subject, the body
+// and the trailers, if any, marked with the CI skip style.
+func commitMessage(style, subject, body, trailers string) string {
+	if marker, ok := skipCIMarkers[style]; ok {
+		subject += " " + marker
+	}
+	if style == skipCISkipChecks {
+		trailers = strings.TrimPrefix(trailers+"\nskip-checks: true", "\n")
+	}
+	message := subject
+	for _, paragraph := range []string{body, trailers} {
+		if paragraph != "" {
+			message += "\n\n" + paragraph
+		}
+	}
+	return message
+}
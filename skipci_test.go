@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCommitMessage(t *testing.T) {
+	tests := []struct {
+		style    string
+		trailers string
+		want     string
+	}{
+		{"", "", "bump version to v1.0.1"},
+		{skipCISkipCI, "", "bump version to v1.0.1 [skip ci]"},
+		{skipCICISkip, "", "bump version to v1.0.1 [ci skip]"},
+		{skipCINoCI, "A: b", "bump version to v1.0.1 [no ci]\n\nA: b"},
+		{skipCISkipChecks, "", "bump version to v1.0.1\n\nskip-checks: true"},
+		{skipCISkipChecks, "A: b", "bump version to v1.0.1\n\nA: b\nskip-checks: true"},
+	}
+	for _, tt := range tests {
+		got := commitMessage(tt.style, "bump version to v1.0.1", "", tt.trailers)
+		if got != tt.want {
+			t.Errorf("commitMessage(%q, %q) = %q, want %q", tt.style, tt.trailers, got, tt.want)
+		}
+	}
+}
+
+func TestBumpSkipCI(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	var output bytes.Buffer
+	err := run(context.Background(), &output, nil, []string{"BUMP_SKIP_CI=ci-skip"})
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v", err)
+	}
+	messages := commitMessages(t, repo, 1)
+	if messages[0] != "bump version to v1.0.1 [ci skip]" {
+		t.Errorf("Expected the CI skip marker from BUMP_SKIP_CI, got %q", messages[0])
+	}
+
+	err = run(context.Background(), &output, []string{"-skip-ci", "always"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid -skip-ci 'always'") {
+		t.Errorf("Expected an invalid style to be refused, got: %v", err)
+	}
+}
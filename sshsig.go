@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// sshSignatureNamespace is the namespace git signs tags and commits in.
+const sshSignatureNamespace = "git"
+
+// sshSignature is the blob of an SSH signature (PROTOCOL.sshsig in OpenSSH),
+// after the "SSHSIG" magic.
+type sshSignature struct {
+	Version   uint32
+	PublicKey []byte
+	Namespace string
+	Reserved  string
+	HashAlg   string
+	Signature []byte
+}
+
+// sshSignedData is what an SSH signature actually signs: the hash of the
+// message in its namespace.
+type sshSignedData struct {
+	Namespace string
+	Reserved  string
+	HashAlg   string
+	Hash      []byte
+}
+
+const sshSigMagic = "SSHSIG"
+
+// sshHash returns the hash of an SSH signature's hash algorithm.
+func sshHash(alg string) (hash.Hash, error) {
+	switch alg {
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported hash algorithm '%s'", alg)
+}
+
+// verifySSHSignature verifies an armored SSH signature of message in the
+// namespace and returns the key that made it. Whether the key may sign is up
+// to the caller.
+func verifySSHSignature(armored, message []byte, namespace string) (ssh.PublicKey, error) {
+	block, _ := pem.Decode(armored)
+	if block == nil || block.Type != "SSH SIGNATURE" {
+		return nil, errors.New("not an armored SSH signature")
+	}
+	blob, ok := bytes.CutPrefix(block.Bytes, []byte(sshSigMagic))
+	if !ok {
+		return nil, errors.New("malformed SSH signature")
+	}
+	var sig sshSignature
+	err := ssh.Unmarshal(blob, &sig)
+	if err != nil {
+		return nil, fmt.Errorf("malformed SSH signature: %w", err)
+	}
+	if sig.Version != 1 {
+		return nil, fmt.Errorf("unsupported SSH signature version %d", sig.Version)
+	}
+	if sig.Namespace != namespace {
+		return nil, fmt.Errorf("SSH signature is for namespace '%s', not '%s'", sig.Namespace, namespace)
+	}
+	key, err := ssh.ParsePublicKey(sig.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("malformed SSH signature key: %w", err)
+	}
+	var signature ssh.Signature
+	err = ssh.Unmarshal(sig.Signature, &signature)
+	if err != nil {
+		return nil, fmt.Errorf("malformed SSH signature: %w", err)
+	}
+	h, err := sshHash(sig.HashAlg)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(message)
+	signed := append([]byte(sshSigMagic), ssh.Marshal(sshSignedData{
+		Namespace: sig.Namespace,
+		Reserved:  sig.Reserved,
+		HashAlg:   sig.HashAlg,
+		Hash:      h.Sum(nil),
+	})...)
+	err = key.Verify(signed, &signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SSH signature: %w", err)
+	}
+	return key, nil
+}
+
+// allowedSigner is a key of an allowed signers file and who it belongs to.
+type allowedSigner struct {
+	principals string
+	key        ssh.PublicKey
+}
+
+// loadAllowedSigners reads an allowed signers file in the format of git's
+// gpg.ssh.allowedSignersFile:
+//
+//	release@example.com ssh-ed25519 AAAAC3Nz...
+//	alice@example.com,bob@example.com namespaces="git" ssh-ed25519 AAAAC3Nz...
+func loadAllowedSigners(path, source string) ([]allowedSigner, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", source, err)
+	}
+	var signers []allowedSigner
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		principals, rest, _ := strings.Cut(line, " ")
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(rest))
+		if err != nil {
+			return nil, fmt.Errorf("%s: line %d: %w", source, i+1, err)
+		}
+		signers = append(signers, allowedSigner{principals: principals, key: key})
+	}
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("%s holds no keys", source)
+	}
+	return signers, nil
+}
+
+// allowedSSHSigner returns the allowed signer with the key, if any.
+func allowedSSHSigner(signers []allowedSigner, key ssh.PublicKey) (allowedSigner, bool) {
+	for _, signer := range signers {
+		if bytes.Equal(signer.key.Marshal(), key.Marshal()) {
+			return signer, true
+		}
+	}
+	return allowedSigner{}, false
+}
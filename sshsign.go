@@ -0,0 +1,252 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// sshSignedVCS signs the release commits and version tags with an SSH key,
+// like git does with gpg.format=ssh. It takes the place of signedTagsVCS.
+type sshSignedVCS struct {
+	vcs
+	repo   *git.Repository
+	signer ssh.Signer
+	loc    *time.Location // of the tags, nil for the local time zone
+}
+
+// withSSHSigning wraps repo for -sign with an SSH key: -ssh-key, else git's
+// user.signingkey, either a private key file, a public key file whose private
+// key is in the SSH agent, or a "key::" literal public key in the agent.
+func withSSHSigning(repo vcs, gitRepo *git.Repository, keySpec string, loc *time.Location, env []string) (vcs, error) {
+	if keySpec == "" {
+		var err error
+		keySpec, err = gitConfigOption(gitRepo, "user", "signingkey")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if keySpec == "" {
+		return nil, errors.New("-sign with gpg.format ssh needs -ssh-key or git config user.signingkey")
+	}
+	signer, err := loadSSHSigner(env, keySpec)
+	if err != nil {
+		return nil, err
+	}
+	return sshSignedVCS{vcs: repo, repo: gitRepo, signer: signer, loc: loc}, nil
+}
+
+func (s sshSignedVCS) goGit() *git.Repository {
+	return s.repo
+}
+
+// commit commits through the wrapped vcs, then replaces the new commit by a
+// signed copy. Commits -branch makes without a checkout aren't signed.
+func (s sshSignedVCS) commit(message string) error {
+	err := s.vcs.commit(message)
+	if err != nil {
+		return err
+	}
+	id, err := s.head()
+	if err != nil {
+		return err
+	}
+	commit, err := s.repo.CommitObject(plumbing.NewHash(id))
+	if err != nil {
+		return fmt.Errorf("failed to read the release commit: %w", err)
+	}
+	payload, err := encodedPayload(commit.EncodeWithoutSignature)
+	if err != nil {
+		return err
+	}
+	signature, err := sshSign(s.signer, payload)
+	if err != nil {
+		return fmt.Errorf("failed to sign commit: %w", err)
+	}
+	commit.PGPSignature = string(signature)
+	signed, err := storeObject(s.repo, plumbing.CommitObject, commit.Encode)
+	if err != nil {
+		return fmt.Errorf("failed to store signed commit: %w", err)
+	}
+	branch, err := s.branch()
+	if err != nil {
+		return err
+	}
+	ref := plumbing.HEAD
+	if branch != "" {
+		ref = plumbing.NewBranchReferenceName(branch)
+	}
+	err = s.repo.Storer.CheckAndSetReference(plumbing.NewHashReference(ref, signed), plumbing.NewHashReference(ref, commit.Hash))
+	if err != nil {
+		return fmt.Errorf("failed to move %s to the signed commit: %w", ref.Short(), err)
+	}
+	return nil
+}
+
+// createTag creates the signed annotated tag on the commit the wrapped vcs
+// releases. go-git only signs with OpenPGP, so the tag object is built here.
+func (s sshSignedVCS) createTag(name, message string) (string, error) {
+	target, err := s.head()
+	if err != nil {
+		return "", err
+	}
+	_, err = s.repo.Tag(name)
+	if err == nil {
+		return "", fmt.Errorf("failed to create signed tag: %w", git.ErrTagExists)
+	}
+	opts, err := tagOptions(s.repo, message, s.loc)
+	if err != nil {
+		return "", err
+	}
+	err = opts.Validate(s.repo, plumbing.NewHash(target))
+	if err != nil {
+		return "", fmt.Errorf("failed to create signed tag: %w", err)
+	}
+	tag := &object.Tag{
+		Name:       name,
+		Tagger:     *opts.Tagger,
+		Message:    opts.Message,
+		TargetType: plumbing.CommitObject,
+		Target:     plumbing.NewHash(target),
+	}
+	payload, err := tagPayload(tag)
+	if err != nil {
+		return "", err
+	}
+	signature, err := sshSign(s.signer, payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign tag: %w", err)
+	}
+	tag.PGPSignature = string(signature)
+	hash, err := storeObject(s.repo, plumbing.TagObject, tag.Encode)
+	if err != nil {
+		return "", fmt.Errorf("failed to store signed tag: %w", err)
+	}
+	err = s.repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewTagReferenceName(name), hash))
+	if err != nil {
+		return "", fmt.Errorf("failed to create signed tag: %w", err)
+	}
+	return hash.String(), nil
+}
+
+// encodedPayload returns the bytes an encode function writes, e.g. an object
+// without its signature.
+func encodedPayload(encode func(plumbing.EncodedObject) error) ([]byte, error) {
+	encoded := &plumbing.MemoryObject{}
+	err := encode(encoded)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := encoded.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = reader.Close() }()
+	return io.ReadAll(reader)
+}
+
+// sshSign makes the armored SSH signature of message in git's namespace, as
+// ssh-keygen -Y sign does. RSA keys sign with SHA-512, never SHA-1.
+func sshSign(signer ssh.Signer, message []byte) ([]byte, error) {
+	hash := sha512.Sum512(message)
+	signed := append([]byte(sshSigMagic), ssh.Marshal(sshSignedData{
+		Namespace: sshSignatureNamespace,
+		HashAlg:   "sha512",
+		Hash:      hash[:],
+	})...)
+	var signature *ssh.Signature
+	var err error
+	if algorithmSigner, ok := signer.(ssh.AlgorithmSigner); ok && signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		signature, err = algorithmSigner.SignWithAlgorithm(rand.Reader, signed, ssh.KeyAlgoRSASHA512)
+	} else {
+		signature, err = signer.Sign(rand.Reader, signed)
+	}
+	if err != nil {
+		return nil, err
+	}
+	blob := append([]byte(sshSigMagic), ssh.Marshal(sshSignature{
+		Version:   1,
+		PublicKey: signer.PublicKey().Marshal(),
+		Namespace: sshSignatureNamespace,
+		HashAlg:   "sha512",
+		Signature: ssh.Marshal(*signature),
+	})...)
+	return pem.EncodeToMemory(&pem.Block{Type: "SSH SIGNATURE", Bytes: blob}), nil
+}
+
+// loadSSHSigner returns the signer of an SSH signing key spec. Encrypted
+// private keys are decrypted with BUMP_SSH_KEY_PASSPHRASE; public keys are
+// looked up in the agent at SSH_AUTH_SOCK.
+func loadSSHSigner(env []string, spec string) (ssh.Signer, error) {
+	if literal, ok := strings.CutPrefix(spec, "key::"); ok {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(literal))
+		if err != nil {
+			return nil, fmt.Errorf("invalid SSH signing key: %w", err)
+		}
+		return agentSigner(env, key)
+	}
+	if rest, ok := strings.CutPrefix(spec, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		spec = filepath.Join(home, rest)
+	}
+	content, err := os.ReadFile(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH signing key: %w", err)
+	}
+	if key, _, _, _, err := ssh.ParseAuthorizedKey(content); err == nil {
+		return agentSigner(env, key)
+	}
+	signer, err := ssh.ParsePrivateKey(content)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		passphrase := getenv(env, "BUMP_SSH_KEY_PASSPHRASE")
+		if passphrase == "" {
+			return nil, fmt.Errorf("SSH signing key %s is encrypted: set BUMP_SSH_KEY_PASSPHRASE or load it into the SSH agent and name its public key", spec)
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(content, []byte(passphrase))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH signing key %s: %w", spec, err)
+	}
+	return signer, nil
+}
+
+// agentSigner returns the signer of the SSH agent holding the key.
+func agentSigner(env []string, key ssh.PublicKey) (ssh.Signer, error) {
+	socket := getenv(env, "SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, errors.New("the SSH signing key is a public key, but SSH_AUTH_SOCK isn't set to reach its agent")
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the SSH agent: %w", err)
+	}
+	// the connection stays open for the signer, for the rest of the run
+	signers, err := agent.NewClient(conn).Signers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the SSH agent's keys: %w", err)
+	}
+	for _, signer := range signers {
+		if string(signer.PublicKey().Marshal()) == string(key.Marshal()) {
+			return signer, nil
+		}
+	}
+	return nil, fmt.Errorf("the SSH agent doesn't hold the signing key %s", ssh.FingerprintSHA256(key))
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func TestBumpSignSSH(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(private, "", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "id_ed25519")
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(block), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshKey, err := ssh.NewPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers := []allowedSigner{{principals: "release@example.com", key: sshKey}}
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-ssh-key", keyFile}, nil)
+	if err == nil || !strings.Contains(err.Error(), "is encrypted: set BUMP_SSH_KEY_PASSPHRASE") {
+		t.Errorf("Expected an encrypted key to need its passphrase, got: %v", err)
+	}
+	err = run(context.Background(), &output, []string{"-ssh-key", keyFile, "-signing-key", "release@example.com"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "-ssh-key can't be combined with -signing-key") {
+		t.Errorf("Expected -ssh-key and -signing-key to conflict, got: %v", err)
+	}
+
+	err = run(context.Background(), &output, []string{"-minor", "-ssh-key", keyFile}, []string{"BUMP_SSH_KEY_PASSPHRASE=secret"})
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	ref, err := repo.Tag("v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := tagSigner(repo, ref, releaseKeys{ssh: signers})
+	if err != nil {
+		t.Fatalf("Expected a valid SSH signature on the tag, got: %v", err)
+	}
+	if !strings.HasPrefix(signer, "release@example.com") {
+		t.Errorf("Expected the tag signed by release@example.com, got %s", signer)
+	}
+	assertSSHSignedHead(t, repo, sshKey)
+
+	// gpg.format=ssh with user.signingkey naming the public key of a key in
+	// the agent, as git does it
+	keyring := agent.NewKeyring()
+	err = keyring.Add(agent.AddedKey{PrivateKey: private})
+	if err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() { _ = agent.ServeAgent(keyring, conn) }()
+		}
+	}()
+	pubFile := keyFile + ".pub"
+	err = os.WriteFile(pubFile, ssh.MarshalAuthorizedKey(sshKey), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repoConfig, err := repo.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repoConfig.Raw.Section("gpg").SetOption("format", "ssh")
+	repoConfig.Raw.Section("user").SetOption("signingkey", pubFile)
+	err = repo.SetConfig(repoConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, repo, "Fix", map[string]string{"fix.txt": "fix\n"})
+	err = run(context.Background(), &output, []string{"-sign"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "SSH_AUTH_SOCK isn't set") {
+		t.Errorf("Expected a public key to need the agent, got: %v", err)
+	}
+	err = run(context.Background(), &output, []string{"-sign"}, []string{"SSH_AUTH_SOCK=" + socket})
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	ref, err = repo.Tag("v1.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tagSigner(repo, ref, releaseKeys{ssh: signers})
+	if err != nil {
+		t.Errorf("Expected a valid SSH signature on the tag, got: %v", err)
+	}
+	assertSSHSignedHead(t, repo, sshKey)
+}
+
+// assertSSHSignedHead checks that HEAD is signed by the SSH key.
+func assertSSHSignedHead(t *testing.T, repo *git.Repository, key ssh.PublicKey) {
+	t.Helper()
+	head, err := repo.CommitObject(mustHead(t, repo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(head.Message, "bump version to") {
+		t.Errorf("Expected HEAD to be the release commit, got %q", head.Message)
+	}
+	payload, err := encodedPayload(head.EncodeWithoutSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := verifySSHSignature([]byte(head.PGPSignature), payload, sshSignatureNamespace)
+	if err != nil {
+		t.Fatalf("Expected a valid SSH signature on the release commit, got: %v", err)
+	}
+	if !bytes.Equal(signer.Marshal(), key.Marshal()) {
+		t.Errorf("Expected the release commit signed by %s, got %s", ssh.FingerprintSHA256(key), ssh.FingerprintSHA256(signer))
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// releasedChange reports whether the uncommitted change of a file is meant
+// for the release commit rather than blocking the bump: the file matches
+// -include, or it is fully staged and -staged-only commits the index as is.
+func releasedChange(cfg config, file string, status *git.FileStatus) bool {
+	if cfg.stagedOnly && status.Worktree == git.Unmodified &&
+		status.Staging != git.Unmodified && status.Staging != git.Untracked {
+		return true
+	}
+	return matchesInclude(cfg.include, file)
+}
+
+// matchesInclude reports whether the repository path matches a -include glob.
+func matchesInclude(globs []string, file string) bool {
+	for _, glob := range globs {
+		if ok, _ := path.Match(glob, file); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutReleasedChanges removes the changes the release commit includes
+// (see releasedChange) from the dirty files.
+func withoutReleasedChanges(repo vcs, cfg config, dirty map[string]string) (map[string]string, error) {
+	if len(cfg.include) == 0 && !cfg.stagedOnly {
+		return dirty, nil
+	}
+	gitRepo, err := gitRepository(repo, "-include and -staged-only")
+	if err != nil {
+		return nil, err
+	}
+	status, err := (&gitVCS{repo: gitRepo}).status()
+	if err != nil {
+		return nil, err
+	}
+	remaining := make(map[string]string, len(dirty))
+	for file, description := range dirty {
+		if fileStatus, ok := status[file]; ok && releasedChange(cfg, file, fileStatus) {
+			continue
+		}
+		remaining[file] = description
+	}
+	return remaining, nil
+}
+
+// includedFiles returns the changed and new files matching -include, which
+// are staged into the release commit along with the version files. Files in
+// skip, the version files themselves, are left out.
+func includedFiles(repo vcs, cfg config, skip []string) ([]string, error) {
+	if len(cfg.include) == 0 {
+		return nil, nil
+	}
+	gitRepo, err := gitRepository(repo, "-include")
+	if err != nil {
+		return nil, err
+	}
+	w, err := gitRepo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("repo.Worktree: %w", err)
+	}
+	// the raw status: unlike the cleanliness check this wants new files and
+	// plain worktree modifications too
+	status, err := w.Status()
+	if err != nil {
+		return nil, fmt.Errorf("worktree.Status: %w", err)
+	}
+	skipped := make(map[string]bool, len(skip))
+	for _, file := range skip {
+		skipped[repoPath(file)] = true
+	}
+	var files []string
+	for file, fileStatus := range status {
+		if fileStatus.Worktree == git.Unmodified && fileStatus.Staging == git.Unmodified {
+			continue
+		}
+		if !skipped[file] && matchesInclude(cfg.include, file) {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// headFile returns the content of the file in the HEAD commit.
+func headFile(t *testing.T, repo *git.Repository, name string) string {
+	t.Helper()
+	commit, err := repo.CommitObject(mustHead(t, repo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := commit.File(name)
+	if err != nil {
+		t.Fatalf("%s is not in the release commit: %v", name, err)
+	}
+	content, err := file.Contents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestBumpInclude(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Add docs", map[string]string{"docs/api.md": "old docs"})
+	for name, content := range map[string]string{"docs/api.md": "regenerated docs", "docs/new.md": "new page", "notes.txt": "scratch"} {
+		err := os.WriteFile(name, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-include", "docs/*", "-commit-body"}, nil)
+	if err != nil {
+		t.Fatalf("bump: %v\nOutput: %s", err, output.String())
+	}
+	if !strings.Contains(output.String(), "Including docs/api.md in the release commit") {
+		t.Errorf("Expected the included files to be reported, got: %s", output.String())
+	}
+	if got := headFile(t, repo, "docs/api.md"); got != "regenerated docs" {
+		t.Errorf("Expected the regenerated docs in the release commit, got %q", got)
+	}
+	if got := headFile(t, repo, "docs/new.md"); got != "new page" {
+		t.Errorf("Expected the new page in the release commit, got %q", got)
+	}
+	commit, err := repo.CommitObject(mustHead(t, repo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(commit.Message, "- docs/new.md") {
+		t.Errorf("Expected the commit body to list the included files, got: %s", commit.Message)
+	}
+	if _, err := commit.File("notes.txt"); err == nil {
+		t.Error("Expected notes.txt not to be committed")
+	}
+
+	err = run(context.Background(), &output, []string{"-include", "docs/[", "-patch"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid -include pattern 'docs/['") {
+		t.Errorf("Expected an invalid pattern error, got: %v", err)
+	}
+}
+
+func TestBumpStagedOnly(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	err := os.WriteFile("hooks.txt", []byte("staged by a hook"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = add(repo, "hooks.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-patch"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "repository is not clean") {
+		t.Fatalf("Expected the staged file to block a plain bump, got: %v", err)
+	}
+	err = run(context.Background(), &output, []string{"-patch", "-staged-only"}, nil)
+	if err != nil {
+		t.Fatalf("bump: %v\nOutput: %s", err, output.String())
+	}
+	if got := headFile(t, repo, "hooks.txt"); got != "staged by a hook" {
+		t.Errorf("Expected the staged file in the release commit, got %q", got)
+	}
+	if got := headFile(t, repo, ".version"); strings.TrimSpace(got) != "v1.0.1" {
+		t.Errorf("Expected the version file in the release commit, got %q", got)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// stashDir is where stashed changes are preserved if they cannot be restored.
+var stashDir = filepath.Join(".git", "bump-autostash")
+
+type stashEntry struct {
+	path    string
+	content []byte
+	exists  bool        // false if the change was a deletion
+	mode    os.FileMode // permissions of the worktree file
+	staging git.StatusCode
+	index   index.Entry // the staged version, unless staging is Unmodified
+	clean   []byte
+}
+
+// autostash holds uncommitted changes that were removed from the worktree so
+// the bump can run on a clean tree.
+type autostash struct {
+	repo    *git.Repository
+	entries []stashEntry
+}
+
+// bumpWithAutostash stashes the dirty files, performs the bump and restores the
+// stash afterwards, even if the bump failed.
+func bumpWithAutostash(ctx context.Context, repo vcs, cfg config, env []string, output io.Writer) error {
+	gitRepo, err := gitRepository(repo, "-autostash")
+	if err != nil {
+		return err
+	}
+	dirty, err := (&gitVCS{repo: gitRepo}).status()
+	if err != nil {
+		return err
+	}
+	for file, status := range dirty {
+		if releasedChange(cfg, file, status) {
+			delete(dirty, file) // goes into the release commit instead
+		}
+	}
+	stash, err := stashChanges(gitRepo, dirty)
+	if err != nil {
+		return fmt.Errorf("autostash: %w", err)
+	}
+	_, _ = fmt.Fprintf(output, "Autostashed changes in %d file(s)\n", len(stash.entries))
+
+	bumpErr := bump(ctx, repo, cfg, env, output)
+
+	err = stash.restore()
+	if err != nil {
+		return errors.Join(bumpErr, fmt.Errorf("autostash: %w", err))
+	}
+	_, _ = fmt.Fprintf(output, "Restored autostashed changes\n")
+	return bumpErr
+}
+
+// stashChanges records the content of every dirty file and resets those files
+// to HEAD. Files that the bump itself rewrites cannot be stashed, as restoring
+// them would clobber the new version.
+func stashChanges(repo *git.Repository, dirty git.Status) (*autostash, error) {
+	paths := make([]string, 0, len(dirty))
+	for file := range dirty {
+		if path.Base(file) == ".version" {
+			return nil, fmt.Errorf("cannot stash %s: it is rewritten by the bump", file)
+		}
+		paths = append(paths, file)
+	}
+	sort.Strings(paths)
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the index: %w", err)
+	}
+	stash := &autostash{repo: repo}
+	for _, file := range paths {
+		entry := stashEntry{path: file, staging: dirty[file].Staging}
+		if staged, err := idx.Entry(file); err == nil {
+			entry.index = *staged
+		}
+		entry.content, err = os.ReadFile(filepath.FromSlash(file))
+		switch {
+		case err == nil:
+			entry.exists = true
+		case errors.Is(err, os.ErrNotExist):
+		default:
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		if entry.exists {
+			info, err := os.Stat(filepath.FromSlash(file))
+			if err != nil {
+				return nil, err
+			}
+			entry.mode = info.Mode().Perm()
+		}
+		stash.entries = append(stash.entries, entry)
+	}
+
+	w, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("repo.Worktree: %w", err)
+	}
+	err = w.Reset(&git.ResetOptions{Commit: head.Hash(), Mode: git.HardReset, Files: paths})
+	if err != nil {
+		return nil, fmt.Errorf("worktree.Reset: %w", err)
+	}
+	// Files that don't exist in HEAD are not touched by the reset
+	for i, entry := range stash.entries {
+		file, err := headCommit.File(entry.path)
+		if errors.Is(err, object.ErrFileNotFound) {
+			_, _ = w.Remove(entry.path)
+			err = os.Remove(filepath.FromSlash(entry.path))
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("failed to remove %s: %w", entry.path, err)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up %s in HEAD: %w", entry.path, err)
+		}
+		contents, err := file.Contents()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from HEAD: %w", entry.path, err)
+		}
+		stash.entries[i].clean = []byte(contents)
+	}
+	return stash, nil
+}
+
+// restore writes the stashed changes back into the worktree, with their
+// permissions, and the staged ones back into the index. If any stashed file
+// was changed in the meantime nothing is restored; the stash is saved to
+// stashDir instead so no work is lost.
+func (s *autostash) restore() error {
+	for _, entry := range s.entries {
+		current, err := os.ReadFile(filepath.FromSlash(entry.path))
+		if errors.Is(err, os.ErrNotExist) {
+			current = nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read %s: %w", entry.path, err)
+		}
+		if !bytes.Equal(current, entry.clean) {
+			saveErr := s.save()
+			if saveErr != nil {
+				return fmt.Errorf("%s changed during the bump and the stash could not be saved: %w", entry.path, saveErr)
+			}
+			return fmt.Errorf("%s changed during the bump; stashed changes were saved to %s", entry.path, stashDir)
+		}
+	}
+
+	for _, entry := range s.entries {
+		osPath := filepath.FromSlash(entry.path)
+		if !entry.exists {
+			err := os.Remove(osPath)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to remove %s: %w", entry.path, err)
+			}
+			continue
+		}
+		err := os.MkdirAll(filepath.Dir(osPath), 0755)
+		if err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", entry.path, err)
+		}
+		err = os.WriteFile(osPath, entry.content, entry.mode)
+		if err == nil {
+			err = os.Chmod(osPath, entry.mode)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to restore %s: %w", entry.path, err)
+		}
+	}
+	return s.restoreIndex()
+}
+
+// restoreIndex puts the staged changes back into the index, so what was
+// staged before the bump still is, and what wasn't still isn't.
+func (s *autostash) restoreIndex() error {
+	idx, err := s.repo.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("failed to read the index: %w", err)
+	}
+	for _, entry := range s.entries {
+		switch entry.staging {
+		case git.Unmodified, git.Untracked:
+			continue
+		case git.Deleted:
+			_, _ = idx.Remove(entry.path)
+			continue
+		}
+		staged := entry.index
+		if current, err := idx.Entry(entry.path); err == nil {
+			*current = staged
+		} else {
+			idx.Entries = append(idx.Entries, &staged)
+		}
+	}
+	err = s.repo.Storer.SetIndex(idx)
+	if err != nil {
+		return fmt.Errorf("failed to restore the staged changes: %w", err)
+	}
+	return nil
+}
+
+// save writes the stashed file contents below stashDir.
+func (s *autostash) save() error {
+	for _, entry := range s.entries {
+		if !entry.exists {
+			continue
+		}
+		target := filepath.Join(stashDir, filepath.FromSlash(entry.path))
+		err := os.MkdirAll(filepath.Dir(target), 0755)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(target, entry.content, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestBumpWithAutostash(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+
+	// Dirty the worktree with a modified tracked file and a staged new file
+	err := os.WriteFile("README.md", []byte("# work in progress"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile("draft.txt", []byte("draft"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = w.Add("draft.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-autostash"}, nil)
+	if err != nil {
+		t.Fatalf("Expected autostash bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+
+	exists, err := tagExists(repo, "v1.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exists {
+		t.Error("Expected tag v1.0.1 to be created")
+	}
+
+	// The bump commit must not contain the stashed changes
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := headCommit.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].Name != ".version" {
+		t.Errorf("Expected bump commit to only change .version, got %v", stats)
+	}
+
+	// The stashed changes must be back in place
+	content, err := os.ReadFile("README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "# work in progress" {
+		t.Errorf("Expected README.md to be restored, got %q", string(content))
+	}
+	_, err = os.Stat("draft.txt")
+	if err != nil {
+		t.Errorf("Expected draft.txt to be restored: %v", err)
+	}
+	status, err := w.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.IsClean() {
+		t.Errorf("Expected restored changes to leave the worktree dirty")
+	}
+}
+
+func TestBumpWithAutostashDirtyVersionFile(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+
+	err := os.WriteFile(".version", []byte("v1.0.0-dev"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = w.Add(".version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitsBefore := countCommits(t, repo)
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-autostash"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "rewritten by the bump") {
+		t.Fatalf("Expected autostash to refuse a dirty .version, got: %v", err)
+	}
+	if countCommits(t, repo) != commitsBefore {
+		t.Errorf("Expected no commit to be created")
+	}
+	content, err := os.ReadFile(".version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "v1.0.0-dev" {
+		t.Errorf("Expected dirty .version to be left alone, got %q", string(content))
+	}
+}
+
+func TestBumpWithAutostashKeepsStaging(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Add scripts", map[string]string{"run.sh": "echo v1\n", "notes.txt": "a\n"})
+
+	// A staged change, a partly staged one and an unstaged executable
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for file, content := range map[string]string{"README.md": "# staged", "notes.txt": "b\n"} {
+		err = os.WriteFile(file, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = w.Add(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = os.WriteFile("notes.txt", []byte("c\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile("run.sh", []byte("echo v2\n"), 0755)
+	if err == nil {
+		err = os.Chmod("run.sh", 0755)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-autostash"}, nil)
+	if err != nil {
+		t.Fatalf("Expected autostash bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+
+	status, err := w.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := status.File("README.md"); s.Staging != git.Modified || s.Worktree != git.Unmodified {
+		t.Errorf("Expected README.md to stay staged, got %q%q", s.Staging, s.Worktree)
+	}
+	if s := status.File("notes.txt"); s.Staging != git.Modified || s.Worktree != git.Modified {
+		t.Errorf("Expected notes.txt to stay partly staged, got %q%q", s.Staging, s.Worktree)
+	}
+	if s := status.File("run.sh"); s.Staging != git.Unmodified {
+		t.Errorf("Expected run.sh to stay unstaged, got %q%q", s.Staging, s.Worktree)
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := idx.Entry("notes.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Hash != plumbing.ComputeHash(plumbing.BlobObject, []byte("b\n")) {
+		t.Errorf("Expected the staged version of notes.txt to be restored")
+	}
+	info, err := os.Stat("run.sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("Expected run.sh to stay executable, got %v", info.Mode())
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// statusReport is the state of the repository ahead of a release.
+type statusReport struct {
+	Branch     string         `json:"branch"`
+	Dirty      []string       `json:"dirty"`
+	LatestTag  string         `json:"latest_tag"`
+	Commits    int            `json:"commits_since_tag"`
+	Unreleased int            `json:"unreleased_entries"`
+	Modules    []moduleStatus `json:"modules"`
+}
+
+// moduleStatus is the version of a module and whether it changed since the
+// latest tag.
+type moduleStatus struct {
+	module
+	Version string `json:"version"`
+	Changed bool   `json:"changed"`
+}
+
+// runStatus implements "bump status": the where-are-we view before a
+// release.
+func runStatus(_ context.Context, output io.Writer, args []string, _ []string) error {
+	flagSet := flag.NewFlagSet("status", flag.ContinueOnError)
+	format := flagSet.String("format", "text", "Output format: text or json.")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if flagSet.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", flagSet.Args())
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("invalid format '%s': must be text or json", *format)
+	}
+
+	repo, err := git.PlainOpen(".")
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	report, err := repositoryStatus(repo)
+	if err != nil {
+		return err
+	}
+	if *format == "json" {
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	printStatus(output, report)
+	return nil
+}
+
+// repositoryStatus collects the status. Without version tags every module
+// counts as changed.
+func repositoryStatus(repo *git.Repository) (statusReport, error) {
+	report := statusReport{Dirty: []string{}, Modules: []moduleStatus{}}
+	worktree := &gitVCS{repo: repo}
+	branch, err := worktree.branch()
+	if err != nil {
+		return report, err
+	}
+	report.Branch = branch
+	if branch == "" {
+		report.Branch = "(detached)"
+	}
+
+	dirty, err := worktree.dirtyFiles()
+	if err != nil {
+		return report, err
+	}
+	report.Dirty = append(report.Dirty, sortedDirtyFiles(dirty)...)
+
+	var changed map[string]bool
+	report.LatestTag, err = lastTag(repo)
+	if err == nil {
+		commits, err := commitsSince(repo, report.LatestTag, commitsAll)
+		if err != nil {
+			return report, err
+		}
+		report.Commits = len(commits)
+		base, err := resolveCommit(repo, report.LatestTag)
+		if err != nil {
+			return report, err
+		}
+		files, err := changedFiles(repo, base)
+		if err != nil {
+			return report, err
+		}
+		changed = make(map[string]bool)
+		for _, file := range files {
+			changed[file] = true
+		}
+	}
+
+	report.Unreleased, err = unreleasedEntries(changelogFile)
+	if err != nil {
+		return report, err
+	}
+
+	modules, err := findModules()
+	if err != nil {
+		return report, err
+	}
+	var files []string
+	for file := range changed {
+		files = append(files, file)
+	}
+	affected := make(map[string]bool)
+	for _, m := range affectedModules(modules, files) {
+		affected[m.Path] = true
+	}
+	for _, m := range modules {
+		content, err := os.ReadFile(filepath.FromSlash(path.Join(m.Path, ".version")))
+		if err != nil {
+			return report, fmt.Errorf("failed to read version of %s: %w", m.Path, err)
+		}
+		report.Modules = append(report.Modules, moduleStatus{
+			module:  m,
+			Version: parseVersionFile(content),
+			Changed: changed == nil || affected[m.Path],
+		})
+	}
+	return report, nil
+}
+
+// unreleasedEntries counts the entries of the changelog's Unreleased
+// section.
+func unreleasedEntries(file string) (int, error) {
+	content, err := os.ReadFile(filepath.FromSlash(file))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	lines := strings.Split(string(content), "\n")
+	start, end := unreleasedSection(lines)
+	if start < 0 {
+		return 0, nil
+	}
+	return len(sectionEntries(lines[start+1 : end])), nil
+}
+
+func printStatus(output io.Writer, report statusReport) {
+	w := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "Branch:\t%s\n", report.Branch)
+	if len(report.Dirty) == 0 {
+		_, _ = fmt.Fprintf(w, "Worktree:\tclean\n")
+	} else {
+		_, _ = fmt.Fprintf(w, "Worktree:\t%d dirty file(s)\n", len(report.Dirty))
+	}
+	if report.LatestTag == "" {
+		_, _ = fmt.Fprintf(w, "Latest tag:\tnone\n")
+	} else {
+		_, _ = fmt.Fprintf(w, "Latest tag:\t%s, %d commit(s) since\n", report.LatestTag, report.Commits)
+	}
+	_, _ = fmt.Fprintf(w, "Unreleased:\t%d changelog entry(s)\n", report.Unreleased)
+	_ = w.Flush()
+
+	if len(report.Modules) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintln(output, "Modules:")
+	pathWidth, versionWidth := 0, 0
+	for _, m := range report.Modules {
+		pathWidth = max(pathWidth, len(m.Path))
+		versionWidth = max(versionWidth, len(m.Version), 1)
+	}
+	for _, m := range report.Modules {
+		version := m.Version
+		if version == "" {
+			version = "-"
+		}
+		line := fmt.Sprintf("  %-*s  %-*s", pathWidth, m.Path, versionWidth, version)
+		if m.Changed {
+			line += "  changed"
+		}
+		_, _ = fmt.Fprintln(output, strings.TrimRight(line, " "))
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestStatus(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Add modules", map[string]string{
+		"payments/.version": "v2.1.0",
+		"billing/.version":  "v0.3.0",
+		changelogFile:       "# Changelog\n\n## [Unreleased]\n\n### Fixed\n\n- Crash\n- Leak\n",
+	})
+	_, err := repo.CreateTag("v1.1.0", mustHead(t, repo), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, repo, "Fix payments", map[string]string{"payments/pay.go": "package payments"})
+	commitFiles(t, repo, "Tune payments", map[string]string{"payments/pay.go": "package payments // tuned"})
+	err = os.WriteFile("feature.txt", []byte("work in progress"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = w.Add("feature.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"status"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `Branch:      master
+Worktree:    1 dirty file(s)
+Latest tag:  v1.1.0, 2 commit(s) since
+Unreleased:  2 changelog entry(s)
+Modules:
+  .         v1.0.0
+  billing   v0.3.0
+  payments  v2.1.0  changed
+`
+	if output.String() != want {
+		t.Errorf("Got status:\n%s\nwant:\n%s", output.String(), want)
+	}
+
+	output.Reset()
+	err = run(context.Background(), &output, []string{"status", "-format", "json"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report statusReport
+	err = json.Unmarshal(output.Bytes(), &report)
+	if err != nil {
+		t.Fatalf("Invalid JSON status: %v\n%s", err, output.String())
+	}
+	if report.LatestTag != "v1.1.0" || report.Commits != 2 || len(report.Dirty) != 1 || len(report.Modules) != 3 || !report.Modules[2].Changed {
+		t.Errorf("Unexpected status %+v", report)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// versionStream is an independently versioned product in the repository,
+// such as a server and a CLI shipped from one tree. Its version files are the
+// .version files below its directory, its tags carry its prefix:
+// server/v1.2.0 (see tagFormat).
+type versionStream struct {
+	name   string
+	path   string // repository path of the stream's directory
+	prefix string
+}
+
+// loadStreams reads the version streams. The format is line based:
+//
+//	server  server          # tags server/v1.2.0
+//	cli     cmd/cli  cli-   # tags cli-v0.4.1
+//
+// The tag prefix defaults to the name and a slash. Version files outside
+// every stream belong to the default stream, bumped without -stream.
+func loadStreams(file string) ([]versionStream, error) {
+	content, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var streams []versionStream
+	for i, line := range strings.Split(string(content), "\n") {
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 3 || len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected '<name> <directory> [tag prefix]'", i+1)
+		}
+		s := versionStream{name: fields[0], path: path.Clean(fields[1]), prefix: fields[0] + "/"}
+		if len(fields) == 3 {
+			s.prefix = fields[2]
+		}
+		if !channelName.MatchString(s.name) {
+			return nil, fmt.Errorf("line %d: invalid stream name '%s'", i+1, s.name)
+		}
+		if s.path == "." || path.IsAbs(s.path) || strings.HasPrefix(s.path, "../") {
+			return nil, fmt.Errorf("line %d: stream %s needs a subdirectory of the repository", i+1, s.name)
+		}
+		for _, other := range streams {
+			switch {
+			case other.name == s.name:
+				return nil, fmt.Errorf("line %d: stream %s already declared", i+1, s.name)
+			case other.path == s.path:
+				return nil, fmt.Errorf("line %d: %s is already the directory of stream %s", i+1, s.path, other.name)
+			case strings.HasPrefix(other.prefix, s.prefix) || strings.HasPrefix(s.prefix, other.prefix):
+				return nil, fmt.Errorf("line %d: tag prefix '%s' overlaps with stream %s", i+1, s.prefix, other.name)
+			}
+		}
+		streams = append(streams, s)
+	}
+	return streams, nil
+}
+
+// selectStream returns the named stream, or nil for the default stream.
+func selectStream(streams []versionStream, name string) (*versionStream, error) {
+	if name == "" {
+		return nil, nil
+	}
+	for i := range streams {
+		if streams[i].name == name {
+			return &streams[i], nil
+		}
+	}
+	if len(streams) == 0 {
+		return nil, fmt.Errorf("unknown stream '%s': no streams in .bumpstreams", name)
+	}
+	return nil, fmt.Errorf("unknown stream '%s'", name)
+}
+
+// streamOf returns the name of the stream owning the version file: the one
+// with the innermost directory containing it, or "" for the default stream.
+func streamOf(streams []versionStream, versionFile string) string {
+	owner, depth := "", -1
+	for _, s := range streams {
+		if strings.HasPrefix(versionFile, s.path+"/") && len(s.path) > depth {
+			owner, depth = s.name, len(s.path)
+		}
+	}
+	return owner
+}
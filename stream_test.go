@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadStreams(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []versionStream
+		errText string
+	}{
+		{
+			name:    "streams",
+			content: "# products\nserver server/   # server/v1.2.0\ncli cmd/cli cli-\n",
+			want:    []versionStream{{name: "server", path: "server", prefix: "server/"}, {name: "cli", path: "cmd/cli", prefix: "cli-"}},
+		},
+		{name: "syntax", content: "server\n", errText: "line 1: expected '<name> <directory> [tag prefix]'"},
+		{name: "root", content: "server .\n", errText: "line 1: stream server needs a subdirectory"},
+		{name: "outside", content: "server ../server\n", errText: "line 1: stream server needs a subdirectory"},
+		{name: "duplicate name", content: "server a\nserver b\n", errText: "line 2: stream server already declared"},
+		{name: "duplicate path", content: "server a\ncli a/\n", errText: "line 2: a is already the directory of stream server"},
+		{name: "overlapping prefix", content: "server a s-\ncli b s-cli-\n", errText: "line 2: tag prefix 's-cli-' overlaps with stream server"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), ".bumpstreams")
+			err := os.WriteFile(file, []byte(tt.content), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			streams, err := loadStreams(file)
+			if tt.errText != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errText) {
+					t.Fatalf("Expected error containing %q, got: %v", tt.errText, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(streams) != len(tt.want) {
+				t.Fatalf("Got streams %+v, want %+v", streams, tt.want)
+			}
+			for i := range streams {
+				if streams[i] != tt.want[i] {
+					t.Errorf("Got stream %+v, want %+v", streams[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStreamOf(t *testing.T) {
+	streams := []versionStream{{name: "server", path: "server"}, {name: "admin", path: "server/admin"}}
+	for file, want := range map[string]string{
+		".version":                   "",
+		"serverless/.version":        "",
+		"server/.version":            "server",
+		"server/api/.version":        "server",
+		"server/admin/.version":      "admin",
+		"server/admin/tool/.version": "admin",
+	} {
+		if got := streamOf(streams, file); got != want {
+			t.Errorf("streamOf(%s) = %q, want %q", file, got, want)
+		}
+	}
+}
+
+func TestBumpStreams(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Add products", map[string]string{
+		".bumpstreams":      "server server\ncli cmd/cli cli-\n",
+		"server/.version":   "v0.3.0\n",
+		"cmd/cli/.version":  "v2.0.0\n",
+		"cmd/cli/README.md": "cli",
+	})
+	for _, tag := range []string{"server/v0.3.0", "cli-v2.0.0"} {
+		_, err := repo.CreateTag(tag, mustHead(t, repo), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	commitFiles(t, repo, "Improve server", map[string]string{"server/main.go": "package main"})
+
+	bump := func(args ...string) {
+		t.Helper()
+		var output bytes.Buffer
+		err := run(context.Background(), &output, args, nil)
+		if err != nil {
+			t.Fatalf("bump %v: %v\nOutput: %s", args, err, output.String())
+		}
+	}
+	versions := func() string {
+		t.Helper()
+		var got []string
+		for _, file := range []string{".version", "server/.version", "cmd/cli/.version"} {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, strings.TrimSpace(string(content)))
+		}
+		return strings.Join(got, " ")
+	}
+
+	bump("-stream", "server", "-minor")
+	if got := versions(); got != "v1.0.0 v0.4.0 v2.0.0" {
+		t.Errorf("After bumping server got versions %s", got)
+	}
+	bump("-patch")
+	if got := versions(); got != "v1.0.1 v0.4.0 v2.0.0" {
+		t.Errorf("After bumping the default stream got versions %s", got)
+	}
+	bump("-stream", "cli", "-major", "-force")
+	if got := versions(); got != "v1.0.1 v0.4.0 v3.0.0" {
+		t.Errorf("After bumping cli got versions %s", got)
+	}
+	for _, tag := range []string{"server/v0.4.0", "v1.0.1", "cli-v3.0.0"} {
+		if exists, _ := tagExists(repo, tag); !exists {
+			t.Errorf("Expected tag %s", tag)
+		}
+	}
+	last, err := lastTag(repo)
+	if err != nil || last != "v1.0.1" {
+		t.Errorf("Expected stream tags not to count for the default stream, got %q, %v", last, err)
+	}
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-stream", "server", "-version", "v0.4.0"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "tag 'server/v0.4.0' already exists") {
+		t.Errorf("Expected the stream's tag to be taken, got: %v", err)
+	}
+	err = run(context.Background(), &output, []string{"-stream", "worker"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "unknown stream 'worker'") {
+		t.Errorf("Expected an unknown stream error, got: %v", err)
+	}
+}
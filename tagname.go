@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// defaultTagTemplate names the tag of a version: the stream's tag prefix, if
+// any, and the version.
+const defaultTagTemplate = "{{.Prefix}}{{.Version}}"
+
+// tagNameData is the data of tag templates.
+type tagNameData struct {
+	Prefix  string // tag prefix of the stream, empty for the default stream
+	Module  string // name of the stream, empty for the default stream
+	Version string // the version as written to .version files
+	Date    string // release date in the -date-format layout
+}
+
+// Placeholders standing in for the parts of a tag name that vary between
+// releases, when a template is turned into a pattern.
+const (
+	versionPlaceholder = "\x00version\x00"
+	datePlaceholder    = "\x00date\x00"
+)
+
+// versionPattern matches a semantic version without its "v".
+const versionPattern = `[0-9]+\.[0-9]+\.[0-9]+(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?`
+
+// tagFormat names version tags with a template, and recognizes them with the
+// pattern derived from the same template, so the tags bump finds are the
+// tags it creates.
+type tagFormat struct {
+	text    string
+	tmpl    *template.Template
+	data    tagNameData
+	pattern *regexp.Regexp
+	// spelledV is set if the template writes the "v" of the version itself,
+	// as in {{.Module}}/v{{.Version}}
+	spelledV bool
+}
+
+// newTagFormat parses a tag template. It must use {{.Version}} exactly once.
+func newTagFormat(text string, data tagNameData) (*tagFormat, error) {
+	tmpl, err := template.New("tag").Funcs(templateFuncs(nil)).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag template '%s': %w", text, err)
+	}
+	f := &tagFormat{text: text, tmpl: tmpl, data: data}
+
+	shape, err := f.render(versionPlaceholder, datePlaceholder)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag template '%s': %w", text, err)
+	}
+	if strings.Count(shape, versionPlaceholder) != 1 {
+		return nil, fmt.Errorf("invalid tag template '%s': must contain {{.Version}} exactly once", text)
+	}
+	f.spelledV = strings.Contains(shape, "v"+versionPlaceholder)
+	version := "(v?" + versionPattern + ")"
+	if f.spelledV {
+		version = "(" + versionPattern + ")"
+	}
+	pattern := regexp.QuoteMeta(shape)
+	pattern = strings.Replace(pattern, versionPlaceholder, version, 1)
+	pattern = strings.ReplaceAll(pattern, datePlaceholder, ".+?")
+	f.pattern = regexp.MustCompile("^" + pattern + "$")
+	return f, nil
+}
+
+func (f *tagFormat) render(version, date string) (string, error) {
+	data := f.data
+	data.Version, data.Date = version, date
+	var buf bytes.Buffer
+	err := f.tmpl.Execute(&buf, data)
+	return buf.String(), err
+}
+
+// tag returns the tag name of version. The template has been executed
+// successfully when it was parsed, so errors are not expected here.
+func (f *tagFormat) tag(version string) string {
+	if f.spelledV {
+		version = strings.TrimPrefix(version, "v")
+	}
+	name, err := f.render(version, f.data.Date)
+	if err != nil {
+		return version
+	}
+	return name
+}
+
+// version returns the version a tag name stands for, if it is one of the
+// format's tags.
+func (f *tagFormat) version(tagName string) (string, bool) {
+	match := f.pattern.FindStringSubmatch(tagName)
+	if match == nil {
+		return "", false
+	}
+	if f.spelledV {
+		return "v" + match[1], true
+	}
+	return match[1], true
+}
+
+// releaseTagFormat returns the tag format of the stream being bumped, or nil
+// if its tags are the plain versions.
+func releaseTagFormat(cfg config) (*tagFormat, error) {
+	stream, err := selectStream(cfg.streams, cfg.stream)
+	if err != nil {
+		return nil, err
+	}
+	if stream == nil && cfg.tagTemplate == "" {
+		return nil, nil
+	}
+	text := cfg.tagTemplate
+	if text == "" {
+		text = defaultTagTemplate
+	}
+	data := tagNameData{Date: cfg.now().Format(cfg.dateFormat)}
+	if stream != nil {
+		data.Prefix, data.Module = stream.prefix, stream.name
+	}
+	return newTagFormat(text, data)
+}
+
+// tagFormatVCS confines the version tags to a tag format: the versions it
+// reports and takes are the versions the format's tags stand for.
+type tagFormatVCS struct {
+	vcs
+	repo   *git.Repository
+	format *tagFormat
+	// keep filters the tags, see tagKeeper
+	keep func(*plumbing.Reference) bool
+}
+
+// withTagFormat wraps repo for -stream and -tag-template.
+func withTagFormat(repo vcs, format *tagFormat) (vcs, error) {
+	gitRepo, err := gitRepository(repo, "-stream and -tag-template")
+	if err != nil {
+		return nil, err
+	}
+	return tagFormatVCS{vcs: repo, repo: gitRepo, format: format, keep: keepTags(repo)}, nil
+}
+
+func (t tagFormatVCS) goGit() *git.Repository {
+	return t.repo
+}
+
+// versionTags returns the format's tags.
+func (t tagFormatVCS) versionTags() ([]versionTag, error) {
+	var tags []versionTag
+	err := forEachTag(t.repo, func(ref *plumbing.Reference) error {
+		if version, ok := t.format.version(ref.Name().Short()); ok && t.keep(ref) {
+			tags = append(tags, versionTag{version: version, ref: ref})
+		}
+		return nil
+	})
+	return tags, err
+}
+
+// lastTag returns the highest version among the format's tags.
+func (t tagFormatVCS) lastTag() (string, error) {
+	tags, err := t.versionTags()
+	if err != nil {
+		return "", err
+	}
+	version, err := highestVersion(tagVersions(tags))
+	if errors.Is(err, errNoVersionTags) {
+		return "", noVersionError(fmt.Sprintf("no version tags matching '%s' found", t.format.text))
+	}
+	if err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+// tagName returns the name of the format's existing tag of version. A
+// template with the date renders another name on another day, so released
+// versions are looked up rather than rendered.
+func (t tagFormatVCS) tagName(version string) (string, bool, error) {
+	tags, err := t.versionTags()
+	if err != nil {
+		return "", false, err
+	}
+	for _, tag := range tags {
+		if normalizeVersion(tag.version) == normalizeVersion(version) {
+			return tag.ref.Name().Short(), true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// tagExists reports whether version has a tag of the format, of any date, or
+// the name it would be tagged with today is taken.
+func (t tagFormatVCS) tagExists(version string) (bool, error) {
+	_, found, err := t.tagName(version)
+	if err != nil || found {
+		return found, err
+	}
+	return t.vcs.tagExists(t.format.tag(version))
+}
+
+func (t tagFormatVCS) hasChangesSince(version string) (bool, error) {
+	name, found, err := t.tagName(version)
+	if err != nil {
+		return false, err
+	}
+	if !found {
+		name = t.format.tag(version)
+	}
+	return t.vcs.hasChangesSince(name)
+}
+
+// changesSince takes a version of the format, or a commit (-since).
+func (t tagFormatVCS) changesSince(rev, strategy string) ([]string, error) {
+	if rev != "" {
+		name, found, err := t.tagName(rev)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			rev = name
+		}
+	}
+	return t.vcs.changesSince(rev, strategy)
+}
+
+func (t tagFormatVCS) createTag(version, message string) (string, error) {
+	return t.vcs.createTag(t.format.tag(version), message)
+}
+
+// existingTag returns the name of the tag of a released version, found among
+// the version tags of the wrappers that rename or filter them. Without any,
+// the tag is the version itself.
+func existingTag(repo vcs, version string) (string, error) {
+	lister, ok := repo.(versionTagLister)
+	if !ok {
+		return version, nil
+	}
+	tags, err := lister.versionTags()
+	if err != nil {
+		return "", err
+	}
+	for _, tag := range tags {
+		if normalizeVersion(tag.version) == normalizeVersion(version) {
+			return tag.ref.Name().Short(), nil
+		}
+	}
+	return "", fmt.Errorf("no tag found for version %s", version)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestTagFormat(t *testing.T) {
+	tests := []struct {
+		template string
+		data     tagNameData
+		version  string
+		tag      string
+	}{
+		{defaultTagTemplate, tagNameData{}, "v1.2.0", "v1.2.0"},
+		{defaultTagTemplate, tagNameData{Prefix: "server/"}, "1.2.0", "server/1.2.0"},
+		{"{{.Module}}/v{{.Version}}", tagNameData{Module: "cli"}, "v1.2.0-rc.1", "cli/v1.2.0-rc.1"},
+		{"release/{{.Date}}/{{.Version}}", tagNameData{Date: "2024-05-01"}, "v2.0.0", "release/2024-05-01/v2.0.0"},
+	}
+	for _, tt := range tests {
+		format, err := newTagFormat(tt.template, tt.data)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.template, err)
+		}
+		if tag := format.tag(tt.version); tag != tt.tag {
+			t.Errorf("%s: got tag %s, want %s", tt.template, tag, tt.tag)
+		}
+		version, ok := format.version(tt.tag)
+		if !ok || version != tt.version {
+			t.Errorf("%s: got version %s (%v) of %s, want %s", tt.template, version, ok, tt.tag, tt.version)
+		}
+	}
+
+	// tags of other release dates are found, tags of other shapes aren't
+	format, err := newTagFormat("release/{{.Date}}/{{.Version}}", tagNameData{Date: "2024-05-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, ok := format.version("release/2023-12-24/v1.9.0"); !ok || version != "v1.9.0" {
+		t.Errorf("Expected an older release to be found, got %s (%v)", version, ok)
+	}
+	for _, tag := range []string{"v1.9.0", "release/v1.9.0", "release/2023-12-24/latest"} {
+		if _, ok := format.version(tag); ok {
+			t.Errorf("Expected %s not to match", tag)
+		}
+	}
+
+	for _, template := range []string{"release", "{{.Version}}-{{.Version}}", "{{.Nope}}{{.Version}}", "{{.Version"} {
+		if _, err := newTagFormat(template, tagNameData{}); err == nil {
+			t.Errorf("Expected template %q to be rejected", template)
+		}
+	}
+}
+
+func TestBumpTagTemplate(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	_, err := repo.CreateTag("release/v1.4.0", mustHead(t, repo), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, repo, "Fix a bug", map[string]string{"fix.txt": "fixed"})
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"-minor", "-tag-template", "release/{{.Version}}"}, nil)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	if !strings.Contains(output.String(), "Bumped version v1.4.0 --> v1.5.0") {
+		t.Errorf("Expected the templated tags to be the version stream, got:\n%s", output.String())
+	}
+	if exists, _ := tagExists(repo, "release/v1.5.0"); !exists {
+		t.Error("Expected tag release/v1.5.0")
+	}
+	if exists, _ := tagExists(repo, "v1.5.0"); exists {
+		t.Error("Expected no plain version tag")
+	}
+
+	err = run(context.Background(), &output, []string{"-patch", "-provenance"}, []string{"BUMP_TAG_TEMPLATE=release/{{.Version}}"})
+	if err == nil || !strings.Contains(err.Error(), "can't be combined") {
+		t.Errorf("Expected the template to conflict with -provenance, got: %v", err)
+	}
+}
+
+func TestBumpTagTemplateWithDate(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	released, err := repo.Tag("v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.CreateTag("release/2024-01-01/v1.1.0", released.Hash(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	args := []string{"-auto", "-tag-template", "release/{{.Date}}/{{.Version}}", "-date-format", "2006-01-02"}
+	err = run(context.Background(), &output, args, nil)
+	if err != nil {
+		t.Fatalf("Expected the tag of another day to be found, got: %v\nOutput: %s", err, output.String())
+	}
+	if !strings.Contains(output.String(), "Commits since release/2024-01-01/v1.1.0 call for a patch bump") {
+		t.Errorf("Expected the commits since the existing tag, got:\n%s", output.String())
+	}
+	today := "release/" + now().Format("2006-01-02") + "/v1.1.1"
+	if exists, _ := tagExists(repo, today); !exists {
+		t.Errorf("Expected tag %s, got:\n%s", today, output.String())
+	}
+}
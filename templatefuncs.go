@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// shortHashLength is the length of the abbreviated commit ids of shortHash.
+const shortHashLength = 7
+
+// templateFuncs are the functions available to every template. They are part
+// of bump's interface: add functions, but don't change the existing ones. The
+// value a function operates on comes last, so they chain in pipelines:
+//
+//	{{.Changes | filter "^feat" | join ", " | upper}}
+func templateFuncs(env []string) template.FuncMap {
+	return template.FuncMap{
+		"upper":        strings.ToUpper,
+		"lower":        strings.ToLower,
+		"trim":         strings.TrimSpace,
+		"trimPrefix":   func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix":   func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":      func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains":     func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":    func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"split":        func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":         func(sep string, list []string) string { return strings.Join(list, sep) },
+		"default":      templateDefault,
+		"date":         func(layout string, t time.Time) string { return t.Format(layout) },
+		"shortHash":    shortHash,
+		"filter":       func(pattern string, list []string) ([]string, error) { return filterMatching(pattern, list, true) },
+		"reject":       func(pattern string, list []string) ([]string, error) { return filterMatching(pattern, list, false) },
+		"regexReplace": regexReplace,
+		"env":          func(key string) string { return templateEnv(env, key) },
+	}
+}
+
+// templateDefault returns value, or fallback if value is empty.
+func templateDefault(fallback, value string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// shortHash abbreviates a commit id.
+func shortHash(hash string) string {
+	if len(hash) > shortHashLength {
+		return hash[:shortHashLength]
+	}
+	return hash
+}
+
+// filterMatching returns the items that match the regular expression, or with
+// keep false, the ones that don't.
+func filterMatching(pattern string, list []string, keep bool) ([]string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+	}
+	filtered := []string{}
+	for _, item := range list {
+		if re.MatchString(item) == keep {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered, nil
+}
+
+// regexReplace replaces the matches of the regular expression in s. The
+// replacement can refer to submatches as $1 or ${name}.
+func regexReplace(pattern, replacement, s string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+	}
+	return re.ReplaceAllString(s, replacement), nil
+}
+
+// templateEnv looks up an environment variable for a template. Secrets are
+// never handed out (see redact.go), since rendered messages leave the
+// machine.
+func templateEnv(env []string, key string) string {
+	if isSecretEnv(key) {
+		return ""
+	}
+	return getenv(env, key)
+}
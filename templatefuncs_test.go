@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	rel := release{
+		Project:  "widget",
+		Previous: "v1.0.0",
+		Version:  "v1.1.0",
+		Changes:  []string{"feat: add knobs", "fix: tighten bolts", "chore: tidy", "feat(ui): dark mode"},
+		Commit:   "0123456789abcdef0123456789abcdef01234567",
+		Date:     time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC),
+		env:      []string{"CI_PIPELINE=4711", "BUMP_SLACK_WEBHOOK=https://hooks.example.com/secret", "DEPLOY_TOKEN=hunter22"},
+	}
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{`{{.Project | upper}} {{.Version | lower}}`, "WIDGET v1.1.0"},
+		{`{{.Date | date "2006-01-02"}}`, "2026-10-15"},
+		{`{{shortHash .Commit}}`, "0123456"},
+		{`{{.Changes | filter "^feat" | join "; "}}`, "feat: add knobs; feat(ui): dark mode"},
+		{`{{.Changes | reject "^(feat|fix)" | join ","}}`, "chore: tidy"},
+		{`{{range .Changes}}{{regexReplace "^(\\w+)(\\(.*\\))?: " "[$1] " .}}|{{end}}`, "[feat] add knobs|[fix] tighten bolts|[chore] tidy|[feat] dark mode|"},
+		{`{{.Version | trimPrefix "v" | replace "." "_"}}`, "1_1_0"},
+		{`{{env "CI_PIPELINE"}} {{env "MISSING" | default "none"}}`, "4711 none"},
+		{`[{{env "BUMP_SLACK_WEBHOOK"}}{{env "DEPLOY_TOKEN"}}]`, "[]"},
+		{`{{if .Previous | hasPrefix "v1."}}same major{{end}}`, "same major"},
+		{`{{split "," "a,b" | len}} {{"  x  " | trim}}`, "2 x"},
+	}
+	for _, tt := range tests {
+		got, err := renderTemplate("test", tt.template, rel)
+		if err != nil {
+			t.Errorf("%s: %v", tt.template, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.template, got, tt.want)
+		}
+	}
+
+	_, err := renderTemplate("test", `{{.Changes | filter "("}}`, rel)
+	if err == nil || !strings.Contains(err.Error(), "invalid pattern '('") {
+		t.Errorf("Expected an invalid pattern error, got: %v", err)
+	}
+}
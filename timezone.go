@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// defaultDateFormat is the layout of the dates bump writes, such as the
+// changelog headings.
+const defaultDateFormat = "2006-01-02"
+
+// loadTimezone returns the location of -timezone, or nil to keep the local
+// time zone.
+func loadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return nil, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -timezone '%s': %w", name, err)
+	}
+	return loc, nil
+}
+
+// now returns the current time in the run's -timezone, the time zone of the
+// release date, the hotfix stamps and the changelog headings.
+func (cfg config) now() time.Time {
+	if cfg.location == nil {
+		return now()
+	}
+	return now().In(cfg.location)
+}
+
+// signatures returns the author and committer git's configuration names,
+// dated now in loc, for the commits and tags of a run with -timezone; go-git
+// would date them in the local time zone. They are nil without loc, leaving
+// them to go-git. Tags take the author as their tagger, like go-git does.
+func signatures(repo *git.Repository, loc *time.Location) (author, committer *object.Signature, err error) {
+	if loc == nil {
+		return nil, nil, nil
+	}
+	cfg, err := repo.ConfigScoped(gitconfig.SystemScope)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read the git configuration: %w", err)
+	}
+	when := now().In(loc)
+	signature := func(name, email string) *object.Signature {
+		if name == "" || email == "" {
+			return nil
+		}
+		return &object.Signature{Name: name, Email: email, When: when}
+	}
+	author = signature(cfg.Author.Name, cfg.Author.Email)
+	if author == nil {
+		author = signature(cfg.User.Name, cfg.User.Email)
+	}
+	if author == nil {
+		return nil, nil, git.ErrMissingAuthor
+	}
+	committer = signature(cfg.Committer.Name, cfg.Committer.Email)
+	if committer == nil {
+		committer = author
+	}
+	return author, committer, nil
+}
+
+// tagOptions returns the options of an annotated tag with the message,
+// tagged now in loc (see signatures).
+func tagOptions(repo *git.Repository, message string, loc *time.Location) (*git.CreateTagOptions, error) {
+	tagger, _, err := signatures(repo, loc)
+	if err != nil {
+		return nil, err
+	}
+	return &git.CreateTagOptions{Tagger: tagger, Message: message}, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBumpTimezone(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Add changelog", map[string]string{changelogFile: "# Changelog\n\n## [Unreleased]\n\n- Retries\n"})
+	local, tz := time.Local, os.Getenv("TZ")
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-timezone", "Pacific/Kiritimati", "-date-format", "02.01.2006"}, nil)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	if time.Local != local || os.Getenv("TZ") != tz {
+		t.Error("Expected the process's time zone to be left alone")
+	}
+
+	ref, err := repo.Tag("v1.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag, err := repo.TagObject(ref.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := tag.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for what, when := range map[string]time.Time{"tag": tag.Tagger.When, "commit": commit.Committer.When} {
+		if _, offset := when.Zone(); offset != 14*60*60 {
+			t.Errorf("Expected the %s timestamp in UTC+14, got %s", what, when)
+		}
+	}
+	file, err := commit.File(changelogFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := file.Contents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := time.Now().In(tag.Tagger.When.Location()).Format("02.01.2006")
+	if !strings.Contains(content, "## [v1.0.1] - "+date) {
+		t.Errorf("Expected the changelog heading dated %s, got:\n%s", date, content)
+	}
+
+	if _, err := exec.LookPath("git"); err == nil {
+		commitFiles(t, repo, "Fix crash", map[string]string{"main.go": "package main\n"})
+		err = run(context.Background(), &output, []string{"-backend", "cli", "-timezone", "Pacific/Kiritimati"}, nil)
+		if err != nil {
+			t.Fatalf("Expected bump to succeed with the git binary, got: %v\nOutput: %s", err, output.String())
+		}
+		commit, err := repo.CommitObject(mustHead(t, repo))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, offset := commit.Committer.When.Zone(); offset != 14*60*60 {
+			t.Errorf("Expected the git binary's commit in UTC+14, got %s", commit.Committer.When)
+		}
+	}
+
+	err = run(context.Background(), &output, []string{"-force"}, []string{"BUMP_TIMEZONE=Mars/Olympus"})
+	if err == nil || !strings.Contains(err.Error(), "invalid -timezone 'Mars/Olympus'") {
+		t.Errorf("Expected an unknown time zone to be refused, got: %v", err)
+	}
+}
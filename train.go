@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const trainDateLayout = "2006-01-02"
+
+// now is the clock release trains are checked against, replaced in tests.
+var now = time.Now
+
+// trainRule is a release train from .bumptrain: releases of a level may only
+// leave on the scheduled days.
+type trainRule struct {
+	line     int
+	level    string
+	schedule string // the schedule as written, for messages
+	weekday  time.Weekday
+	interval int       // in weeks
+	from     time.Time // first departure; zero for weekly trains without one
+}
+
+// trainIntervals are the spellings accepted for "every Nth weekday".
+var trainIntervals = map[string]int{
+	"other": 2, "second": 2, "2nd": 2,
+	"third": 3, "3rd": 3,
+	"fourth": 4, "4th": 4,
+}
+
+// loadTrains reads the release train schedule. The format is line based:
+//
+//	minor every second tuesday from 2026-01-06   # every other Tuesday
+//	major every 6 monday from 2026-01-05          # every sixth Monday
+//	patch every friday                            # every Friday
+//
+// A train running less often than weekly needs the date of a departure to
+// count from. Levels without a train can be released any day.
+func loadTrains(path string) ([]trainRule, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil // No schedule is fine
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []trainRule
+	seen := make(map[string]int)
+	for i, line := range strings.Split(string(content), "\n") {
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(strings.ToLower(line))
+		if len(fields) == 0 {
+			continue
+		}
+		rule, err := parseTrain(fields)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		rule.line = i + 1
+		if previous, ok := seen[rule.level]; ok {
+			return nil, fmt.Errorf("line %d: %s train already scheduled on line %d", rule.line, rule.level, previous)
+		}
+		seen[rule.level] = rule.line
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// parseTrain parses the fields of a single schedule line.
+func parseTrain(fields []string) (trainRule, error) {
+	if len(fields) < 3 || fields[1] != "every" {
+		return trainRule{}, fmt.Errorf("invalid train '%s': expected '<level> every [interval] <weekday> [from <date>]'", strings.Join(fields, " "))
+	}
+	rule := trainRule{level: fields[0], schedule: strings.Join(fields[1:], " "), interval: 1}
+	switch rule.level {
+	case "patch", "minor", "major":
+	default:
+		return trainRule{}, fmt.Errorf("invalid level '%s': must be patch, minor or major", rule.level)
+	}
+
+	rest := fields[2:]
+	if interval, ok := trainIntervals[rest[0]]; ok {
+		rule.interval, rest = interval, rest[1:]
+	} else if interval, err := strconv.Atoi(rest[0]); err == nil {
+		if interval < 1 {
+			return trainRule{}, fmt.Errorf("invalid interval '%s'", rest[0])
+		}
+		rule.interval, rest = interval, rest[1:]
+	}
+	if len(rest) == 0 {
+		return trainRule{}, errors.New("missing weekday")
+	}
+	weekday, ok := parseWeekday(rest[0])
+	if !ok {
+		return trainRule{}, fmt.Errorf("invalid weekday '%s'", rest[0])
+	}
+	rule.weekday, rest = weekday, rest[1:]
+
+	switch {
+	case len(rest) == 2 && rest[0] == "from":
+		from, err := time.Parse(trainDateLayout, rest[1])
+		if err != nil {
+			return trainRule{}, fmt.Errorf("invalid date '%s'", rest[1])
+		}
+		if from.Weekday() != rule.weekday {
+			return trainRule{}, fmt.Errorf("%s is a %s, not a %s", rest[1], from.Weekday(), rule.weekday)
+		}
+		rule.from = from
+	case len(rest) > 0:
+		return trainRule{}, fmt.Errorf("unexpected '%s'", strings.Join(rest, " "))
+	case rule.interval > 1:
+		return trainRule{}, errors.New("a train running less often than weekly needs 'from <date>'")
+	}
+	return rule, nil
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.ToLower(day.String()) == name {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// calendarDay returns the date of t in its own location, as midnight UTC, so
+// days can be compared and counted without daylight saving surprises.
+func calendarDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// departs reports whether the train leaves on day.
+func (r trainRule) departs(day time.Time) bool {
+	day = calendarDay(day)
+	if day.Weekday() != r.weekday {
+		return false
+	}
+	if r.from.IsZero() {
+		return true
+	}
+	if day.Before(r.from) {
+		return false
+	}
+	weeks := int(day.Sub(r.from).Hours()) / (24 * 7)
+	return weeks%r.interval == 0
+}
+
+// next returns the first departure on or after day.
+func (r trainRule) next(day time.Time) time.Time {
+	day = calendarDay(day)
+	if day.Before(r.from) {
+		return r.from
+	}
+	for !r.departs(day) {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// id identifies the departure on day, e.g. minor-2026-10-13.
+func (r trainRule) id(day time.Time) string {
+	return r.level + "-" + calendarDay(day).Format(trainDateLayout)
+}
+
+// releaseTrain checks a bump against the release train schedule and returns
+// the identifier of the train the release leaves on. Releases of a level
+// without a train, and off-schedule releases allowed by -off-train, return an
+// empty identifier.
+func releaseTrain(cfg config, today time.Time) (string, error) {
+	if cfg.version != "" {
+		return "", nil // explicitly set versions don't ride a train
+	}
+	rules, err := loadTrains(".bumptrain")
+	if err != nil {
+		return "", fmt.Errorf("failed to load .bumptrain: %w", err)
+	}
+	for _, rule := range rules {
+		if rule.level != cfg.action.String() {
+			continue
+		}
+		if rule.departs(today) {
+			return rule.id(today), nil
+		}
+		if cfg.offTrain {
+			return "", nil
+		}
+		return "", fmt.Errorf("the %s train runs %s, next departure is %s (use -off-train to release anyway)",
+			rule.level, rule.schedule, rule.next(today).Format(trainDateLayout))
+	}
+	return "", nil
+}
+
+// runTrain implements "bump train": it prints the release train schedule.
+// Given a level, it fails unless that level's train departs on the day, so CI
+// can gate scheduled releases on it.
+func runTrain(_ context.Context, output io.Writer, args []string, _ []string) error {
+	flagSet := flag.NewFlagSet("train", flag.ContinueOnError)
+	date := flagSet.String("date", "", "Check the schedule for this day (YYYY-MM-DD) instead of today.")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if flagSet.NArg() > 1 {
+		return fmt.Errorf("unexpected arguments: %s", flagSet.Args()[1:])
+	}
+	today := now()
+	if *date != "" {
+		today, err = time.Parse(trainDateLayout, *date)
+		if err != nil {
+			return fmt.Errorf("invalid date '%s': must be YYYY-MM-DD", *date)
+		}
+	}
+
+	rules, err := loadTrains(".bumptrain")
+	if err != nil {
+		return fmt.Errorf("failed to load .bumptrain: %w", err)
+	}
+	level := flagSet.Arg(0)
+	if level == "" {
+		if len(rules) == 0 {
+			_, _ = fmt.Fprintln(output, "No release trains scheduled")
+		}
+		for _, rule := range rules {
+			next := rule.next(today)
+			_, _ = fmt.Fprintf(output, "%s: %s, next departure %s (%s)\n",
+				rule.level, rule.schedule, next.Format(trainDateLayout), rule.id(next))
+		}
+		return nil
+	}
+
+	for _, rule := range rules {
+		if rule.level != level {
+			continue
+		}
+		if !rule.departs(today) {
+			return fmt.Errorf("the %s train does not depart on %s, next departure is %s",
+				level, calendarDay(today).Format(trainDateLayout), rule.next(today).Format(trainDateLayout))
+		}
+		_, _ = fmt.Fprintf(output, "The %s train departs on %s (%s)\n", level, calendarDay(today).Format(trainDateLayout), rule.id(today))
+		return nil
+	}
+	return fmt.Errorf("no %s train in .bumptrain", level)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func day(t *testing.T, date string) time.Time {
+	d, err := time.Parse(trainDateLayout, date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// setClock makes now return the given day for the duration of the test
+func setClock(t *testing.T, date string) {
+	today := day(t, date).Add(15 * time.Hour)
+	now = func() time.Time { return today }
+	t.Cleanup(func() { now = time.Now })
+}
+
+func TestLoadTrains(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		errContains string
+	}{
+		{
+			name: "all schedule forms",
+			content: `# release calendar
+minor every second Tuesday from 2026-01-06
+major every 6 monday from 2026-01-05
+patch every friday   # weekly
+`,
+		},
+		{name: "unknown level", content: "hotfix every friday\n", errContains: "line 1: invalid level 'hotfix'"},
+		{name: "unknown weekday", content: "minor every other caturday from 2026-01-06\n", errContains: "invalid weekday 'caturday'"},
+		{name: "missing anchor", content: "minor every other tuesday\n", errContains: "needs 'from <date>'"},
+		{name: "anchor on wrong weekday", content: "minor every other tuesday from 2026-01-07\n", errContains: "2026-01-07 is a Wednesday"},
+		{name: "duplicate level", content: "minor every tuesday\n\nminor every friday\n", errContains: "line 3: minor train already scheduled on line 1"},
+		{name: "not a schedule", content: "minor on tuesdays\n", errContains: "invalid train"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".bumptrain")
+			err := os.WriteFile(path, []byte(tt.content), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = loadTrains(path)
+			if tt.errContains == "" {
+				if err != nil {
+					t.Fatalf("loadTrains() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Fatalf("loadTrains() error = %v, want error containing %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestTrainDeparts(t *testing.T) {
+	rule, err := parseTrain(strings.Fields("minor every second tuesday from 2026-01-06"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		date    string
+		departs bool
+		next    string
+	}{
+		{date: "2026-01-06", departs: true, next: "2026-01-06"},
+		{date: "2025-12-23", departs: false, next: "2026-01-06"}, // before the first departure
+		{date: "2026-01-13", departs: false, next: "2026-01-20"},
+		{date: "2026-10-13", departs: true, next: "2026-10-13"},
+		{date: "2026-10-14", departs: false, next: "2026-10-27"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			if got := rule.departs(day(t, tt.date)); got != tt.departs {
+				t.Errorf("departs(%s) = %v, want %v", tt.date, got, tt.departs)
+			}
+			if got := rule.next(day(t, tt.date)).Format(trainDateLayout); got != tt.next {
+				t.Errorf("next(%s) = %s, want %s", tt.date, got, tt.next)
+			}
+		})
+	}
+}
+
+func TestBumpReleaseTrain(t *testing.T) {
+	tests := []struct {
+		name        string
+		date        string
+		args        []string
+		wantTrain   string
+		errContains string
+	}{
+		{name: "on schedule", date: "2026-10-13", args: []string{"-minor"}, wantTrain: "minor-2026-10-13"},
+		{name: "off schedule", date: "2026-10-14", args: []string{"-minor"}, errContains: "next departure is 2026-10-27 (use -off-train"},
+		{name: "off schedule with override", date: "2026-10-14", args: []string{"-minor", "-off-train"}},
+		{name: "level without a train", date: "2026-10-14", args: []string{"-patch"}},
+		{name: "explicit version", date: "2026-10-14", args: []string{"-version", "v1.1.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, repo := setupTaggedTestRepo(t, "v1.0.0")
+			err := os.WriteFile(".bumptrain", []byte("minor every other tuesday from 2026-01-06\n"), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			setClock(t, tt.date)
+			commits := countCommits(t, repo)
+
+			var output bytes.Buffer
+			err = run(context.Background(), &output, append(tt.args, "-force", "-tag-metadata", "json"), nil)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("Expected error containing %q, got: %v", tt.errContains, err)
+				}
+				if countCommits(t, repo) != commits {
+					t.Error("Expected no commit for an off-schedule release")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+			}
+
+			version, err := os.ReadFile(".version")
+			if err != nil {
+				t.Fatal(err)
+			}
+			message := tagMessageOf(t, repo, string(version))
+			meta, _, err := parseTagMetadata(message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meta.Train != tt.wantTrain {
+				t.Errorf("Expected train %q in the metadata, got %q", tt.wantTrain, meta.Train)
+			}
+			hasTrailer := strings.Contains(message, "Release-Train: "+tt.wantTrain+"\n")
+			if hasTrailer != (tt.wantTrain != "") {
+				t.Errorf("Unexpected Release-Train annotation in tag message:\n%s", message)
+			}
+		})
+	}
+}
+
+func TestRunTrain(t *testing.T) {
+	t.Chdir(t.TempDir())
+	setClock(t, "2026-10-14")
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"train"}, nil)
+	if err != nil || output.String() != "No release trains scheduled\n" {
+		t.Fatalf("Unexpected result without schedule: %v, %q", err, output.String())
+	}
+
+	err = os.WriteFile(".bumptrain", []byte("minor every other tuesday from 2026-01-06\npatch every wednesday\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output.Reset()
+	err = run(context.Background(), &output, []string{"train"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "minor: every other tuesday from 2026-01-06, next departure 2026-10-27 (minor-2026-10-27)\n" +
+		"patch: every wednesday, next departure 2026-10-14 (patch-2026-10-14)\n"
+	if output.String() != want {
+		t.Errorf("Got schedule:\n%s\nwant:\n%s", output.String(), want)
+	}
+
+	output.Reset()
+	err = run(context.Background(), &output, []string{"train", "patch"}, nil)
+	if err != nil || !strings.Contains(output.String(), "departs on 2026-10-14 (patch-2026-10-14)") {
+		t.Errorf("Expected the patch train to depart, got: %v, %q", err, output.String())
+	}
+	err = run(context.Background(), &output, []string{"train", "minor"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "next departure is 2026-10-27") {
+		t.Errorf("Expected the minor train not to depart, got: %v", err)
+	}
+	err = run(context.Background(), &output, []string{"train", "-date", "2026-10-27", "minor"}, nil)
+	if err != nil {
+		t.Errorf("Expected the minor train to depart on 2026-10-27, got: %v", err)
+	}
+	err = run(context.Background(), &output, []string{"train", "major"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "no major train") {
+		t.Errorf("Expected missing train error, got: %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// vcs is the version control system a release is made in. The bump flow only
+// talks to this interface; git (through go-git) is the reference backend and
+// other systems such as Mercurial or Jujutsu implement the same operations.
+type vcs interface {
+	// name identifies the backend in messages
+	name() string
+	// dirtyFiles describes every uncommitted change that should block a bump,
+	// keyed by repository path
+	dirtyFiles() (map[string]string, error)
+	// branch returns the current branch, or "" if it has none
+	branch() (string, error)
+	// lastTag returns the highest version tag, or an error matching
+	// errNoVersionTags if there is none yet
+	lastTag() (string, error)
+	tagExists(name string) (bool, error)
+	// hasChangesSince reports whether there are commits after the tag
+	hasChangesSince(tag string) (bool, error)
+	// changesSince returns the subject lines of the commits after the tag or
+	// commit, selected by the commit strategy (see commitsAll)
+	changesSince(rev, strategy string) ([]string, error)
+	// add stages a file for the next commit
+	add(path string) error
+	commit(message string) error
+	// createTag tags the current commit and returns the tag's object id;
+	// without a message the tag is lightweight
+	createTag(name, message string) (string, error)
+	// head returns the id of the current commit
+	head() (string, error)
+}
+
+// vcsMarkers map the metadata directory of a repository to its backend name.
+var vcsMarkers = []struct {
+	dir  string
+	name string
+}{
+	{dir: ".jj", name: "jj"},
+	{dir: ".hg", name: "hg"},
+	{dir: ".git", name: "git"},
+}
+
+// openVCS opens the repository in dir with the backend matching its metadata
+// directory. Jujutsu repositories are usually colocated with git, so .jj is
+// checked first. For git, backend selects the implementation (see
+// openGitBackend), which dates commits and tags in loc.
+func openVCS(dir, backend string, loc *time.Location) (vcs, error) {
+	for _, marker := range vcsMarkers {
+		_, err := os.Stat(filepath.Join(dir, marker.dir))
+		if err != nil {
+			continue
+		}
+		if marker.name != "git" {
+			return nil, fmt.Errorf("%s repositories are not supported yet", marker.name)
+		}
+	}
+	return openGitBackend(dir, backend, loc)
+}
+
+// gitVCS implements vcs for git repositories using go-git.
+type gitVCS struct {
+	repo *git.Repository
+	loc  *time.Location // of commits and tags, nil for the local time zone
+}
+
+func (g *gitVCS) name() string {
+	return "git"
+}
+
+func (g *gitVCS) goGit() *git.Repository {
+	return g.repo
+}
+
+// status returns the worktree status without the entries that shouldn't block
+// bumping.
+func (g *gitVCS) status() (git.Status, error) {
+	w, err := g.repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("repo.Worktree: %w", err)
+	}
+	status, err := w.Status()
+	if err != nil {
+		return nil, fmt.Errorf("worktree.Status: %w", err)
+	}
+
+	// Filter out files that shouldn't block bumping
+	// go-git's Status() can report files that native git doesn't consider dirty:
+	// - Ignored files (untracked in both worktree and staging)
+	// - Files with only metadata changes (permissions) when filemode=false
+	// - Line ending differences when autocrlf is configured
+	//
+	// The approach: if a file is Modified in worktree but Unmodified in staging,
+	// it's likely a go-git quirk. We trust native git's behavior over go-git.
+	cleanStatus := make(git.Status)
+	for file, fileStatus := range status {
+		// Skip untracked files (includes ignored files)
+		if fileStatus.Worktree == git.Untracked && fileStatus.Staging == git.Untracked {
+			continue
+		}
+		// Skip files that show as Modified/Unmodified - this is a go-git quirk
+		// where it detects changes that git itself doesn't consider dirty
+		// (e.g., filemode, line endings with autocrlf, etc.)
+		if fileStatus.Worktree == git.Modified && fileStatus.Staging == git.Unmodified {
+			continue
+		}
+		cleanStatus[file] = fileStatus
+	}
+	return cleanStatus, nil
+}
+
+func (g *gitVCS) dirtyFiles() (map[string]string, error) {
+	status, err := g.status()
+	if err != nil {
+		return nil, err
+	}
+	dirty := make(map[string]string, len(status))
+	for file, fileStatus := range status {
+		dirty[file] = fmt.Sprintf("worktree=%v staging=%v", fileStatus.Worktree, fileStatus.Staging)
+	}
+	return dirty, nil
+}
+
+func (g *gitVCS) branch() (string, error) {
+	head, err := g.repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	if !head.Name().IsBranch() {
+		return "", nil
+	}
+	return head.Name().Short(), nil
+}
+
+func (g *gitVCS) lastTag() (string, error) {
+	return lastTag(g.repo)
+}
+
+func (g *gitVCS) tagExists(name string) (bool, error) {
+	return tagExists(g.repo, name)
+}
+
+func (g *gitVCS) hasChangesSince(tag string) (bool, error) {
+	return hasChangesSinceTag(g.repo, tag)
+}
+
+func (g *gitVCS) changesSince(rev, strategy string) ([]string, error) {
+	return changesSince(g.repo, rev, strategy)
+}
+
+func (g *gitVCS) add(path string) error {
+	return add(g.repo, path)
+}
+
+func (g *gitVCS) commit(message string) error {
+	return commit(g.repo, message, g.loc)
+}
+
+func (g *gitVCS) createTag(name, message string) (string, error) {
+	head, err := g.repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	var opts *git.CreateTagOptions
+	if message != "" {
+		opts, err = tagOptions(g.repo, message, g.loc)
+		if err != nil {
+			return "", err
+		}
+	}
+	ref, err := g.repo.CreateTag(name, head.Hash(), opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to create tag: %w", err)
+	}
+	return ref.Hash().String(), nil
+}
+
+func (g *gitVCS) head() (string, error) {
+	head, err := g.repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	return head.Hash().String(), nil
+}
+
+// goGitBackend is implemented by the git backends, which can hand out a go-git
+// handle of their repository.
+type goGitBackend interface {
+	goGit() *git.Repository
+}
+
+// gitRepository returns the go-git repository behind v for the features that
+// are specific to git, or an error naming the feature if v isn't git.
+func gitRepository(v vcs, feature string) (*git.Repository, error) {
+	g, ok := v.(goGitBackend)
+	if !ok {
+		return nil, fmt.Errorf("%s is only supported for git repositories, not %s", feature, v.name())
+	}
+	return g.goGit(), nil
+}
+
+// sortedDirtyFiles formats the dirty files for an error message.
+func sortedDirtyFiles(dirty map[string]string) []string {
+	files := make([]string, 0, len(dirty))
+	for file, description := range dirty {
+		files = append(files, fmt.Sprintf("  %s: %s", file, description))
+	}
+	sort.Strings(files)
+	return files
+}
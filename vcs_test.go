@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/mod/semver"
+)
+
+// fakeVCS is an in-memory vcs used to check that the bump flow only relies
+// on the interface
+type fakeVCS struct {
+	tags    map[string]string // name -> message
+	changes []string
+	staged  []string
+	commits []string
+}
+
+func (f *fakeVCS) name() string                           { return "fake" }
+func (f *fakeVCS) dirtyFiles() (map[string]string, error) { return nil, nil }
+func (f *fakeVCS) branch() (string, error)                { return "main", nil }
+func (f *fakeVCS) head() (string, error)                  { return "fakehead", nil }
+
+func (f *fakeVCS) lastTag() (string, error) {
+	latest := ""
+	for tag := range f.tags {
+		if latest == "" || semver.Compare(normalizeVersion(tag), normalizeVersion(latest)) > 0 {
+			latest = tag
+		}
+	}
+	if latest == "" {
+		return "", errNoVersionTags
+	}
+	return latest, nil
+}
+
+func (f *fakeVCS) tagExists(name string) (bool, error) {
+	_, ok := f.tags[name]
+	return ok, nil
+}
+
+func (f *fakeVCS) hasChangesSince(string) (bool, error)          { return len(f.changes) > 0, nil }
+func (f *fakeVCS) changesSince(string, string) ([]string, error) { return f.changes, nil }
+
+func (f *fakeVCS) add(path string) error {
+	f.staged = append(f.staged, path)
+	return nil
+}
+
+func (f *fakeVCS) commit(message string) error {
+	f.commits = append(f.commits, message)
+	return nil
+}
+
+func (f *fakeVCS) createTag(name, message string) (string, error) {
+	f.tags[name] = message
+	return "faketag", nil
+}
+
+func TestBumpThroughVCSInterface(t *testing.T) {
+	t.Chdir(t.TempDir())
+	err := os.WriteFile(".version", []byte("v1.2.3"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &fakeVCS{
+		tags:    map[string]string{"v1.2.3": "", "v1.0.0": ""},
+		changes: []string{"fix: something"},
+	}
+
+	var output bytes.Buffer
+	err = bump(context.Background(), repo, config{action: incrementMinor}, nil, &output)
+	if err != nil {
+		t.Fatalf("bump() error = %v", err)
+	}
+
+	if _, ok := repo.tags["v1.3.0"]; !ok {
+		t.Errorf("Expected tag v1.3.0, got tags %v", repo.tags)
+	}
+	if len(repo.staged) != 1 || repo.staged[0] != ".version" {
+		t.Errorf("Expected .version to be staged, got %v", repo.staged)
+	}
+	if len(repo.commits) != 1 || repo.commits[0] != "bump version to v1.3.0" {
+		t.Errorf("Unexpected commits: %v", repo.commits)
+	}
+	if !strings.Contains(output.String(), "Bumped version v1.2.3 --> v1.3.0, tag=faketag") {
+		t.Errorf("Unexpected output: %s", output.String())
+	}
+}
+
+func TestOpenVCS(t *testing.T) {
+	tests := []struct {
+		name        string
+		dirs        []string
+		wantName    string
+		errContains string
+	}{
+		{name: "git", dirs: []string{".git"}, wantName: "git"},
+		{name: "mercurial", dirs: []string{".hg"}, errContains: "hg repositories are not supported yet"},
+		{name: "colocated jujutsu", dirs: []string{".git", ".jj"}, errContains: "jj repositories are not supported yet"},
+		{name: "no repository", errContains: "failed to open repository"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if len(tt.dirs) > 0 && tt.dirs[0] == ".git" {
+				dir, _ = setupTestRepo(t)
+			}
+			for _, d := range tt.dirs {
+				err := os.MkdirAll(dir+"/"+d, 0755)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			repo, err := openVCS(dir, "", nil)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("openVCS() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("openVCS() error = %v", err)
+			}
+			if repo.name() != tt.wantName {
+				t.Errorf("openVCS() backend = %s, want %s", repo.name(), tt.wantName)
+			}
+		})
+	}
+}
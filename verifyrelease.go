@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+)
+
+// releaseCheck is one check of verify-release.
+type releaseCheck struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail"`
+}
+
+// releaseReport is the outcome of verify-release for a tag.
+type releaseReport struct {
+	Tag    string         `json:"tag"`
+	Commit string         `json:"commit"`
+	Branch string         `json:"branch"`
+	Checks []releaseCheck `json:"checks"`
+	Passed bool           `json:"passed"`
+}
+
+func (r *releaseReport) check(name string, passed bool, format string, args ...any) {
+	r.Checks = append(r.Checks, releaseCheck{Name: name, Passed: passed, Detail: fmt.Sprintf(format, args...)})
+}
+
+// releaseKeys are the keys allowed to sign release tags.
+type releaseKeys struct {
+	pgp string // armored OpenPGP key ring
+	ssh []allowedSigner
+}
+
+// runVerifyRelease implements "bump verify-release": it checks that a
+// release tag is signed by an allowed key, that its commit is on the default
+// branch and that the .version file there matches the tag, and prints a
+// pass/fail report for compliance audits.
+func runVerifyRelease(_ context.Context, output io.Writer, args []string, env []string) error {
+	flagSet := flag.NewFlagSet("verify-release", flag.ContinueOnError)
+	keyFile := flagSet.String("key", getenv(env, "BUMP_RELEASE_PUBKEY"), "Armored OpenPGP public key(s) allowed to sign release tags.")
+	signersFile := flagSet.String("allowed-signers", getenv(env, "BUMP_ALLOWED_SIGNERS"), "SSH allowed signers file of the keys allowed to sign release tags.")
+	branch := flagSet.String("branch", "", "Branch releases must be on (default: origin's HEAD, else main or master).")
+	format := flagSet.String("format", "text", "Output format: text or json.")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if flagSet.NArg() != 1 {
+		return errors.New("usage: bump verify-release [-key pubkey] [-allowed-signers file] [-branch name] [-format text|json] <tag>")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("invalid format '%s': must be text or json", *format)
+	}
+	if *keyFile == "" && *signersFile == "" {
+		return errors.New("no keys to verify with: use -key or -allowed-signers (BUMP_RELEASE_PUBKEY, BUMP_ALLOWED_SIGNERS)")
+	}
+	var keys releaseKeys
+	if *keyFile != "" {
+		// parse once up front so a broken key file isn't reported as a bad signature
+		_, err = loadKeyRing(*keyFile, "-key")
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(*keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read -key: %w", err)
+		}
+		keys.pgp = string(content)
+	}
+	if *signersFile != "" {
+		keys.ssh, err = loadAllowedSigners(*signersFile, "-allowed-signers")
+		if err != nil {
+			return err
+		}
+	}
+	streams, err := loadStreams(".bumpstreams")
+	if err != nil {
+		return fmt.Errorf("failed to load .bumpstreams: %w", err)
+	}
+	repo, err := git.PlainOpen(".")
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	report, err := verifyReleaseTag(repo, keys, streams, flagSet.Arg(0), *branch)
+	if err != nil {
+		return err
+	}
+	if *format == "json" {
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+		if err != nil {
+			return err
+		}
+	} else {
+		printReleaseReport(output, report)
+	}
+	if !report.Passed {
+		return fmt.Errorf("release %s failed verification", report.Tag)
+	}
+	return nil
+}
+
+// verifyReleaseTag runs the checks of verify-release on a tag. Errors are
+// for tags that can't be checked at all; failed checks end up in the report.
+func verifyReleaseTag(repo *git.Repository, keys releaseKeys, streams []versionStream, tag, branch string) (releaseReport, error) {
+	report := releaseReport{Tag: tag}
+	ref, err := repo.Tag(tag)
+	if err != nil {
+		return report, fmt.Errorf("failed to find tag %s: %w", tag, err)
+	}
+	commit, err := peelTag(repo, ref.Hash())
+	if err != nil {
+		return report, fmt.Errorf("failed to resolve tag %s: %w", tag, err)
+	}
+	report.Commit = commit.Hash.String()
+
+	signer, err := tagSigner(repo, ref, keys)
+	if err != nil {
+		report.check("signature", false, "%v", err)
+	} else {
+		report.check("signature", true, "signed by %s", signer)
+	}
+
+	branchRef, err := defaultBranch(repo, branch)
+	if err != nil {
+		report.check("branch", false, "%v", err)
+	} else {
+		report.Branch = branchRef.Name().Short()
+		head, err := repo.CommitObject(branchRef.Hash())
+		if err != nil {
+			return report, fmt.Errorf("failed to read %s: %w", report.Branch, err)
+		}
+		onBranch, err := commit.IsAncestor(head)
+		if err != nil {
+			return report, fmt.Errorf("failed to walk %s: %w", report.Branch, err)
+		}
+		if onBranch {
+			report.check("branch", true, "%s is reachable from %s", shortHash(report.Commit), report.Branch)
+		} else {
+			report.check("branch", false, "%s is not reachable from %s", shortHash(report.Commit), report.Branch)
+		}
+	}
+
+	versionFile, version := ".version", tag
+	for _, s := range streams {
+		if rest, ok := strings.CutPrefix(tag, s.prefix); ok {
+			versionFile, version = path.Join(s.path, ".version"), rest
+		}
+	}
+	file, err := commit.File(versionFile)
+	if err != nil {
+		report.check("version", false, "no %s at %s", versionFile, shortHash(report.Commit))
+	} else {
+		content, err := file.Contents()
+		if err != nil {
+			return report, fmt.Errorf("failed to read %s: %w", versionFile, err)
+		}
+		found := parseVersionFile([]byte(content))
+		if normalizeVersion(found) == normalizeVersion(version) {
+			report.check("version", true, "%s is %s", versionFile, found)
+		} else {
+			report.check("version", false, "%s is %s, not %s", versionFile, found, version)
+		}
+	}
+
+	report.Passed = true
+	for _, check := range report.Checks {
+		report.Passed = report.Passed && check.Passed
+	}
+	return report, nil
+}
+
+// tagSigner verifies the signature of an annotated tag with the allowed keys
+// and describes who made it.
+func tagSigner(repo *git.Repository, ref *plumbing.Reference, keys releaseKeys) (string, error) {
+	tag, err := repo.TagObject(ref.Hash())
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		return "", errors.New("lightweight tag, nothing is signed")
+	}
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case tag.PGPSignature == "":
+		return "", errors.New("tag is not signed")
+	case strings.HasPrefix(tag.PGPSignature, "-----BEGIN SSH SIGNATURE-----"):
+		if len(keys.ssh) == 0 {
+			return "", errors.New("SSH signature, but no allowed signers to verify it with")
+		}
+		return sshTagSigner(tag, keys.ssh)
+	default:
+		if keys.pgp == "" {
+			return "", errors.New("OpenPGP signature, but no key to verify it with")
+		}
+		entity, err := tag.Verify(keys.pgp)
+		if err != nil {
+			return "", fmt.Errorf("invalid OpenPGP signature: %w", err)
+		}
+		name := entity.PrimaryKey.KeyIdString()
+		if identity := entity.PrimaryIdentity(); identity != nil {
+			name = fmt.Sprintf("%s (%s)", identity.Name, name)
+		}
+		return name, nil
+	}
+}
+
+// tagPayload returns what the signature of a tag signs: the tag object
+// without the signature.
+func tagPayload(tag *object.Tag) ([]byte, error) {
+	return encodedPayload(tag.EncodeWithoutSignature)
+}
+
+func sshTagSigner(tag *object.Tag, signers []allowedSigner) (string, error) {
+	payload, err := tagPayload(tag)
+	if err != nil {
+		return "", err
+	}
+	key, err := verifySSHSignature([]byte(tag.PGPSignature), payload, sshSignatureNamespace)
+	if err != nil {
+		return "", err
+	}
+	signer, ok := allowedSSHSigner(signers, key)
+	if !ok {
+		return "", fmt.Errorf("signed by %s, which is not an allowed signer", ssh.FingerprintSHA256(key))
+	}
+	return fmt.Sprintf("%s (%s)", signer.principals, ssh.FingerprintSHA256(key)), nil
+}
+
+// defaultBranch returns the branch releases are cut from: the named one, or
+// the branch origin's HEAD points to, or main or master.
+func defaultBranch(repo *git.Repository, name string) (*plumbing.Reference, error) {
+	if name != "" {
+		for _, refName := range []plumbing.ReferenceName{plumbing.NewBranchReferenceName(name), plumbing.NewRemoteReferenceName("origin", name)} {
+			if ref, err := repo.Reference(refName, true); err == nil {
+				return ref, nil
+			}
+		}
+		return nil, fmt.Errorf("no branch %s", name)
+	}
+	if ref, err := repo.Reference(plumbing.NewRemoteHEADReferenceName("origin"), true); err == nil {
+		return ref, nil
+	}
+	for _, name := range []string{"main", "master"} {
+		if ref, err := repo.Reference(plumbing.NewBranchReferenceName(name), true); err == nil {
+			return ref, nil
+		}
+	}
+	return nil, errors.New("no default branch: origin has no HEAD and there is no main or master (use -branch)")
+}
+
+func printReleaseReport(output io.Writer, report releaseReport) {
+	_, _ = fmt.Fprintf(output, "%s (%s)\n", report.Tag, shortHash(report.Commit))
+	for _, check := range report.Checks {
+		result := "PASS"
+		if !check.Passed {
+			result = "FAIL"
+		}
+		_, _ = fmt.Fprintf(output, "%s %-9s %s\n", result, check.Name, check.Detail)
+	}
+	if report.Passed {
+		_, _ = fmt.Fprintf(output, "Release %s verified\n", report.Tag)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestVerifyReleasePGP(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	key, _ := testSigningKey(t)
+	err := key.DecryptPrivateKeys([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKey := testPublicKey(t, key)
+	commitFiles(t, repo, "Release v1.1.0", map[string]string{".version": "v1.1.0\n"})
+	_, err = repo.CreateTag("v1.1.0", mustHead(t, repo), &git.CreateTagOptions{Message: "v1.1.0", SignKey: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"verify-release", "-key", pubKey, "v1.1.0"}, nil)
+	if err != nil {
+		t.Fatalf("Expected v1.1.0 to verify, got: %v\nOutput: %s", err, output.String())
+	}
+	for _, want := range []string{"PASS signature signed by Release Bot", "PASS branch    ", "reachable from master", "PASS version   .version is v1.1.0", "Release v1.1.0 verified"} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("Expected %q in the report:\n%s", want, output.String())
+		}
+	}
+
+	// the unsigned v1.0.0, tagged on a commit whose .version matches
+	output.Reset()
+	err = run(context.Background(), &output, []string{"verify-release", "-key", pubKey, "-format", "json", "v1.0.0"}, nil)
+	if err == nil {
+		t.Fatal("Expected an unsigned tag to fail")
+	}
+	var report releaseReport
+	if err := json.Unmarshal(output.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid JSON report: %v\n%s", err, output.String())
+	}
+	if report.Passed || len(report.Checks) != 3 || report.Checks[0].Passed || !report.Checks[1].Passed || !report.Checks[2].Passed {
+		t.Errorf("Expected only the signature check to fail, got %+v", report)
+	}
+
+	// a key that isn't allowed
+	other, _ := testSigningKey(t)
+	err = run(context.Background(), &output, []string{"verify-release", "-key", testPublicKey(t, other), "v1.1.0"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "v1.1.0 failed verification") {
+		t.Errorf("Expected another key to fail, got: %v", err)
+	}
+}
+
+func TestVerifyReleaseBranchAndVersion(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	key, _ := testSigningKey(t)
+	err := key.DecryptPrivateKeys([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKey := testPublicKey(t, key)
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("experiment"), Create: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, repo, "Try something", map[string]string{"main.go": "package main"})
+	_, err = repo.CreateTag("v1.2.0", mustHead(t, repo), &git.CreateTagOptions{Message: "v1.2.0", SignKey: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := verifyReleaseTag(repo, releaseKeys{pgp: readFile(t, pubKey)}, nil, "v1.2.0", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Passed || !report.Checks[0].Passed || report.Checks[1].Passed || report.Checks[2].Passed {
+		t.Errorf("Expected the branch and version checks to fail, got %+v", report)
+	}
+	if report.Checks[2].Detail != ".version is v1.0.0, not v1.2.0" {
+		t.Errorf("Unexpected version detail %q", report.Checks[2].Detail)
+	}
+	report, err = verifyReleaseTag(repo, releaseKeys{pgp: readFile(t, pubKey)}, nil, "v1.2.0", "experiment")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Branch != "experiment" || !report.Checks[1].Passed {
+		t.Errorf("Expected the tag to be on -branch experiment, got %+v", report)
+	}
+}
+
+func TestVerifyReleaseSSH(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Add server", map[string]string{
+		".bumpstreams":    "server server\n",
+		"server/.version": "v0.4.0\n",
+	})
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshKey, err := ssh.NewPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers := filepath.Join(t.TempDir(), "allowed_signers")
+	err = os.WriteFile(signers, []byte("# release keys\nrelease@example.com "+string(ssh.MarshalAuthorizedKey(sshKey))), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createSSHSignedTag(t, repo, "server/v0.4.0", private)
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"verify-release", "-allowed-signers", signers, "server/v0.4.0"}, nil)
+	if err != nil {
+		t.Fatalf("Expected server/v0.4.0 to verify, got: %v\nOutput: %s", err, output.String())
+	}
+	for _, want := range []string{"signed by release@example.com (" + ssh.FingerprintSHA256(sshKey) + ")", "server/.version is v0.4.0"} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("Expected %q in the report:\n%s", want, output.String())
+		}
+	}
+
+	// the same tag signed by a key that isn't allowed
+	_, other, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.DeleteTag("server/v0.4.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	createSSHSignedTag(t, repo, "server/v0.4.0", other)
+	output.Reset()
+	err = run(context.Background(), &output, []string{"verify-release", "-allowed-signers", signers, "server/v0.4.0"}, nil)
+	if err == nil || !strings.Contains(output.String(), "which is not an allowed signer") {
+		t.Errorf("Expected an unknown SSH key to fail, got: %v\nOutput: %s", err, output.String())
+	}
+}
+
+func TestVerifyReleaseNeedsKeys(t *testing.T) {
+	setupTaggedTestRepo(t, "v1.0.0")
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"verify-release", "v1.0.0"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "no keys to verify with") {
+		t.Errorf("Expected a missing key error, got: %v", err)
+	}
+}
+
+// createSSHSignedTag tags HEAD with an annotated tag signed the way git
+// signs with gpg.format=ssh.
+func createSSHSignedTag(t *testing.T, repo *git.Repository, name string, key ed25519.PrivateKey) {
+	t.Helper()
+	tag := &object.Tag{
+		Name:       name,
+		Tagger:     object.Signature{Name: "Release Bot", Email: "release@example.com", When: time.Now()},
+		Message:    name + "\n",
+		TargetType: plumbing.CommitObject,
+		Target:     mustHead(t, repo),
+	}
+	payload, err := tagPayload(tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag.PGPSignature = testSSHSignature(t, key, payload)
+	encoded := repo.Storer.NewEncodedObject()
+	err = tag.Encode(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := repo.Storer.SetEncodedObject(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewTagReferenceName(name), hash))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testSSHSignature signs message in the git namespace and armors the
+// signature like ssh-keygen -Y sign.
+func testSSHSignature(t *testing.T, key ed25519.PrivateKey, message []byte) string {
+	t.Helper()
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := sshSign(signer, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(signature)
+}
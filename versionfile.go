@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// versionFileHeader starts a .version file in the structured format 2, a TOML
+// document carrying metadata for other tools along with the version:
+//
+//	# bump .version format 2
+//	version = "v1.2.3"
+//	schema = 2
+//	channel = "stable"
+//	released_at = 2024-05-01T10:12:00Z
+//
+// bump maintains version, schema and released_at and keeps everything else as
+// it is. A file without the header holds just the version.
+const versionFileHeader = "# bump .version format 2"
+
+// versionFileSchema is the schema key of structured version files.
+const versionFileSchema = "2"
+
+// isStructuredVersionFile reports whether .version content starts with the
+// format 2 header.
+func isStructuredVersionFile(content []byte) bool {
+	first, _, _ := strings.Cut(string(bytes.TrimPrefix(content, utf8BOM)), "\n")
+	return strings.TrimSpace(first) == versionFileHeader
+}
+
+// structuredVersion returns the version key of a structured version file, or
+// "" if it has none yet.
+func structuredVersion(content []byte) string {
+	for _, line := range strings.Split(string(content), "\n") {
+		if key, value, ok := versionFileKey(line); ok && key == "version" {
+			return value
+		}
+	}
+	return ""
+}
+
+// versionFileKey splits a `key = value` line of a structured version file.
+// Strings are unquoted; other values are returned as written, without a
+// trailing comment.
+func versionFileKey(line string) (string, string, bool) {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "#") {
+		return "", "", false
+	}
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return "", "", false
+	}
+	value = strings.TrimSpace(value)
+	if quoted, err := strconv.QuotedPrefix(value); err == nil {
+		if unquoted, err := strconv.Unquote(quoted); err == nil {
+			return strings.TrimSpace(key), unquoted, true
+		}
+	}
+	if idx := strings.Index(value, "#"); idx >= 0 {
+		value = strings.TrimSpace(value[:idx])
+	}
+	return strings.TrimSpace(key), value, true
+}
+
+// structuredVersionFileContent rewrites the keys bump maintains in a
+// structured version file, appending those missing, with the given line
+// ending. Comments and the other keys are kept.
+func structuredVersionFileContent(old []byte, ending, version string, releasedAt time.Time) []byte {
+	content := strings.ReplaceAll(string(bytes.TrimPrefix(old, utf8BOM)), "\r\n", "\n")
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	values := []struct{ key, value string }{
+		{"version", strconv.Quote(version)},
+		{"schema", versionFileSchema},
+		{"released_at", releasedAt.Truncate(time.Second).Format(time.RFC3339)},
+	}
+	for _, v := range values {
+		found := false
+		for i, line := range lines {
+			if key, _, ok := versionFileKey(line); ok && key == v.key {
+				lines[i] = v.key + " = " + v.value
+				found = true
+			}
+		}
+		if !found {
+			lines = append(lines, v.key+" = "+v.value)
+		}
+	}
+	separator := ending
+	if separator == "" {
+		separator = "\n"
+	}
+	return []byte(strings.Join(lines, separator) + ending)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseStructuredVersionFile(t *testing.T) {
+	tests := map[string]string{
+		"# bump .version format 2\nversion = \"v1.2.3\"\nschema = 2\n":                                      "v1.2.3",
+		"\ufeff# bump .version format 2\r\nchannel = \"beta\" # edge\r\nversion = \"v1.2.3\" # current\r\n": "v1.2.3",
+		"# bump .version format 2\nschema = 2\n":                                                            "",
+		"# a plain file starting with a comment\n":                                                          "# a plain file starting with a comment",
+	}
+	for content, want := range tests {
+		if got := parseVersionFile([]byte(content)); got != want {
+			t.Errorf("parseVersionFile(%q) = %q, want %q", content, got, want)
+		}
+	}
+}
+
+func TestBumpStructuredVersionFile(t *testing.T) {
+	setClock(t, "2024-05-01")
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Structured .version", map[string]string{
+		".version":     "# bump .version format 2\r\nversion = \"v1.0.0\"\r\n# the channel deployments follow\r\nchannel = \"stable\"\r\n",
+		"lib/.version": "v1.0.0\n",
+	})
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"-minor"}, nil)
+	if err != nil {
+		t.Fatalf("Expected bump to succeed, got: %v\nOutput: %s", err, output.String())
+	}
+	releasedAt := now().Format(time.RFC3339)
+	want := "# bump .version format 2\r\nversion = \"v1.1.0\"\r\n# the channel deployments follow\r\nchannel = \"stable\"\r\nschema = 2\r\nreleased_at = " + releasedAt + "\r\n"
+	if got := readFile(t, ".version"); got != want {
+		t.Errorf("Got .version %q, want %q", got, want)
+	}
+	if got := readFile(t, "lib/.version"); got != "v1.1.0\n" {
+		t.Errorf("Expected the plain lib/.version to stay plain, got %q", got)
+	}
+	if exists, _ := tagExists(repo, "v1.1.0"); !exists {
+		t.Error("Expected v1.1.0 to be tagged")
+	}
+}
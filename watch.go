@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// releaseTrailer is the commit trailer asking watch mode for a release, e.g.
+// "Release: minor" on a merge commit.
+const releaseTrailer = "Release"
+
+// releaseLevels orders the levels a release trailer can ask for.
+var releaseLevels = map[string]int{"patch": 1, "minor": 2, "major": 3}
+
+// runWatch implements "bump watch": an experimental daemon for trunk-based
+// teams that watches the default branch and bumps by itself once commits
+// asking for a release land. It checks every -interval and, with -listen,
+// whenever a push webhook arrives. Flags after "--" are passed to the bump.
+func runWatch(ctx context.Context, output io.Writer, args []string, env []string) error {
+	flagSet := flag.NewFlagSet("watch", flag.ContinueOnError)
+	interval := flagSet.Duration("interval", time.Minute, "How often to check the branch for releases.")
+	listen := flagSet.String("listen", "", "Address to receive push webhooks on, e.g. :8080 (default: poll only).")
+	branch := flagSet.String("branch", "", "Branch to release from (default: origin's HEAD, else main or master).")
+	once := flagSet.Bool("once", false, "Check once and exit.")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	bumpArgs := flagSet.Args()
+	for _, arg := range bumpArgs {
+		if _, ok := releaseLevels[strings.TrimLeft(arg, "-")]; ok {
+			return fmt.Errorf("%s: the level comes from the %s trailer", arg, releaseTrailer)
+		}
+	}
+	if *interval <= 0 {
+		return errors.New("-interval must be positive")
+	}
+	cfg, _, err := getConfig(bumpArgs)
+	if err != nil {
+		return err
+	}
+	cfg.remote = cfg.envDefault("remote", cfg.remote, env, "BUMP_REMOTE")
+
+	trigger := make(chan struct{}, 1)
+	if *listen != "" && !*once {
+		listener, err := net.Listen("tcp", *listen)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", *listen, err)
+		}
+		server := &http.Server{Handler: webhookTrigger(getenv(env, "BUMP_WATCH_SECRET"), trigger)}
+		go func() { _ = server.Serve(listener) }()
+		defer func() { _ = server.Close() }()
+		_, _ = fmt.Fprintf(output, "Listening for push webhooks on %s\n", listener.Addr())
+	}
+
+	for {
+		err := watchOnce(ctx, output, *branch, cfg, bumpArgs, env)
+		if *once {
+			return err
+		}
+		if err != nil {
+			_, _ = fmt.Fprintf(output, "watch: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(*interval):
+		case <-trigger:
+		}
+	}
+}
+
+// watchOnce brings the branch up to date from the remote the bump resolves
+// tags from and bumps if a commit since the last release asks for one.
+func watchOnce(ctx context.Context, output io.Writer, branch string, cfg config, bumpArgs, env []string) error {
+	repo, err := git.PlainOpen(".")
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	if branch == "" {
+		ref, err := defaultBranch(repo, "")
+		if err != nil {
+			return err
+		}
+		branch = ref.Name().Short()
+		if ref.Name().IsRemote() {
+			_, branch, _ = strings.Cut(branch, "/")
+		}
+	}
+	current, err := (&gitVCS{repo: repo}).branch()
+	if err != nil {
+		return err
+	}
+	if current != branch {
+		return fmt.Errorf("watch needs %s checked out, not '%s'", branch, current)
+	}
+	r, err := resolveRemotes(repo, cfg)
+	if err != nil {
+		return err
+	}
+	err = pullBranch(ctx, repo, r.fetch, branch)
+	if err != nil {
+		return err
+	}
+
+	tag, err := lastTag(repo)
+	if err != nil {
+		return fmt.Errorf("failed to get last tag: %w", err)
+	}
+	level, err := requestedRelease(output, repo, tag)
+	if err != nil || level == "" {
+		return err
+	}
+	_, _ = fmt.Fprintf(output, "Releasing a %s version after %s\n", level, tag)
+	return runBump(ctx, output, append(append([]string{}, bumpArgs...), "-"+level), env)
+}
+
+// pullBranch fast-forwards the branch from the remote, if there is one.
+func pullBranch(ctx context.Context, repo *git.Repository, remote, branch string) error {
+	if _, err := repo.Remote(remote); errors.Is(err, git.ErrRemoteNotFound) {
+		return nil
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+	err = worktree.PullContext(ctx, &git.PullOptions{
+		RemoteName:    remote,
+		ReferenceName: plumbing.NewBranchReferenceName(branch),
+		SingleBranch:  true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("failed to pull %s: %w", branch, err)
+	}
+	return nil
+}
+
+// requestedRelease returns the highest level asked for by the release
+// trailers of the first-parent commits since the tag, or "" if no commit asks
+// for a release.
+func requestedRelease(output io.Writer, repo *git.Repository, tag string) (string, error) {
+	commits, err := commitsSince(repo, tag, commitsFirstParent)
+	if err != nil {
+		return "", err
+	}
+	level := ""
+	for _, c := range commits {
+		value, ok := commitTrailers(c.Message)[releaseTrailer]
+		if !ok {
+			continue
+		}
+		value = strings.ToLower(value)
+		if _, valid := releaseLevels[value]; !valid {
+			_, _ = fmt.Fprintf(output, "Ignoring %s: invalid %s trailer '%s'\n", shortHash(c.Hash.String()), releaseTrailer, value)
+			continue
+		}
+		if releaseLevels[value] > releaseLevels[level] {
+			level = value
+		}
+	}
+	return level, nil
+}
+
+// webhookTrigger accepts push webhooks and triggers a check. With a secret,
+// the payload must carry its HMAC in X-Hub-Signature-256 as GitHub and Gitea
+// send it.
+func webhookTrigger(secret string, trigger chan<- struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+		if secret != "" {
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write(body)
+			want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+			if !hmac.Equal([]byte(r.Header.Get("X-Hub-Signature-256")), []byte(want)) {
+				http.Error(w, "invalid signature", http.StatusUnauthorized)
+				return
+			}
+		}
+		select {
+		case trigger <- struct{}{}:
+		default: // a check is already pending
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
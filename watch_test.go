@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+)
+
+func TestWatchOnce(t *testing.T) {
+	_, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Fix a bug", map[string]string{"main.go": "package main"})
+
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"watch", "-once"}, nil)
+	if err != nil {
+		t.Fatalf("watch -once: %v\nOutput: %s", err, output.String())
+	}
+	if exists, _ := tagExists(repo, "v1.0.1"); exists {
+		t.Error("Expected no release without a Release trailer")
+	}
+
+	commitFiles(t, repo, "Merge feature\n\nRelease: minor", map[string]string{"feature.go": "package main"})
+	commitFiles(t, repo, "Merge fix\n\nRelease: patch", map[string]string{"fix.go": "package main"})
+	commitFiles(t, repo, "Merge typo\n\nRelease: huge", map[string]string{"typo.go": "package main"})
+	output.Reset()
+	err = run(context.Background(), &output, []string{"watch", "-once", "--", "-force"}, nil)
+	if err != nil {
+		t.Fatalf("watch -once: %v\nOutput: %s", err, output.String())
+	}
+	for _, want := range []string{"invalid Release trailer 'huge'", "Releasing a minor version after v1.0.0"} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("Expected %q in the output:\n%s", want, output.String())
+		}
+	}
+	if exists, _ := tagExists(repo, "v1.1.0"); !exists {
+		t.Errorf("Expected watch to release v1.1.0\nOutput: %s", output.String())
+	}
+
+	// nothing new since the release
+	commits := countCommits(t, repo)
+	err = run(context.Background(), &output, []string{"watch", "-once"}, nil)
+	if err != nil || countCommits(t, repo) != commits {
+		t.Errorf("Expected watch to do nothing after the release, got: %v", err)
+	}
+}
+
+func TestWatchPullsFromRemote(t *testing.T) {
+	dir, repo := setupTaggedTestRepo(t, "v1.0.0")
+	commitFiles(t, repo, "Merge feature\n\nRelease: minor", map[string]string{"feature.go": "package main"})
+
+	// upstream has the commit asking for the release, the checkout doesn't
+	upstream := t.TempDir()
+	_, err := git.PlainClone(upstream, true, &git.CloneOptions{URL: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: "upstream", URLs: []string{upstream}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := repo.CommitObject(mustHead(t, repo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Reset(&git.ResetOptions{Commit: head.ParentHashes[0], Mode: git.HardReset})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	err = run(context.Background(), &output, []string{"watch", "-once"}, []string{"BUMP_REMOTE=upstream"})
+	if err != nil {
+		t.Fatalf("watch -once: %v\nOutput: %s", err, output.String())
+	}
+	if exists, _ := tagExists(repo, "v1.1.0"); !exists {
+		t.Errorf("Expected watch to pull from BUMP_REMOTE and release v1.1.0\nOutput: %s", output.String())
+	}
+}
+
+func TestWatchRejects(t *testing.T) {
+	setupTaggedTestRepo(t, "v1.0.0")
+	var output bytes.Buffer
+	err := run(context.Background(), &output, []string{"watch", "-once", "-branch", "main"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "watch needs main checked out, not 'master'") {
+		t.Errorf("Expected a branch error, got: %v", err)
+	}
+	err = run(context.Background(), &output, []string{"watch", "-once", "--", "-major"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "the level comes from the Release trailer") {
+		t.Errorf("Expected a level flag to be rejected, got: %v", err)
+	}
+}
+
+func TestWebhookTrigger(t *testing.T) {
+	trigger := make(chan struct{}, 1)
+	handler := webhookTrigger("s3cret", trigger)
+	body := `{"ref":"refs/heads/main"}`
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(body))
+
+	post := func(signature string) int {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("X-Hub-Signature-256", signature)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := post("sha256=00"); code != http.StatusUnauthorized {
+		t.Errorf("Expected a bad signature to be rejected, got %d", code)
+	}
+	if len(trigger) != 0 {
+		t.Error("A rejected webhook triggered a check")
+	}
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if code := post(signature); code != http.StatusAccepted {
+		t.Errorf("Expected a signed webhook to be accepted, got %d", code)
+	}
+	// a second webhook while a check is pending doesn't block
+	if code := post(signature); code != http.StatusAccepted {
+		t.Errorf("Expected a second webhook to be accepted, got %d", code)
+	}
+	if len(trigger) != 1 {
+		t.Errorf("Expected one pending check, got %d", len(trigger))
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const defaultNotificationMessage = `{{.Project}} {{.Version}} released{{if .Changes}}
+{{range .Changes}}
+• {{.}}{{end}}{{end}}`
+
+// webhookAnnouncer posts a rendered message as JSON to a chat service.
+type webhookAnnouncer struct {
+	backend  string
+	method   string
+	url      string
+	token    string // sent as a bearer token if set
+	template string
+	// payload wraps the rendered message in the service's JSON format
+	payload func(rel release, text string) any
+}
+
+// notificationTemplate returns the message template for a backend:
+// BUMP_<BACKEND>_TEMPLATE, then BUMP_NOTIFY_TEMPLATE, then the default.
+func notificationTemplate(env []string, backend string) (string, error) {
+	key := "BUMP_" + strings.ToUpper(backend) + "_TEMPLATE"
+	if getenv(env, key) != "" {
+		return templateFromEnv(env, key, "")
+	}
+	return templateFromEnv(env, "BUMP_NOTIFY_TEMPLATE", defaultNotificationMessage)
+}
+
+// newWebhookAnnouncer creates a backend posting to the webhook URL stored in
+// BUMP_<BACKEND>_WEBHOOK. It returns nil if the variable is unset.
+func newWebhookAnnouncer(env []string, backend string, payload func(rel release, text string) any) (announcer, error) {
+	webhook := getenv(env, "BUMP_"+strings.ToUpper(backend)+"_WEBHOOK")
+	if webhook == "" {
+		return nil, nil
+	}
+	tmpl, err := notificationTemplate(env, backend)
+	if err != nil {
+		return nil, fmt.Errorf("%s announcer: %w", backend, err)
+	}
+	return &webhookAnnouncer{
+		backend:  backend,
+		method:   http.MethodPost,
+		url:      webhook,
+		template: tmpl,
+		payload:  payload,
+	}, nil
+}
+
+func slackBackend(_ config, env []string) (announcer, error) {
+	return newWebhookAnnouncer(env, "slack", func(_ release, text string) any {
+		return map[string]string{"text": text}
+	})
+}
+
+func discordBackend(_ config, env []string) (announcer, error) {
+	return newWebhookAnnouncer(env, "discord", func(_ release, text string) any {
+		return map[string]string{"content": text}
+	})
+}
+
+func teamsBackend(_ config, env []string) (announcer, error) {
+	return newWebhookAnnouncer(env, "teams", func(rel release, text string) any {
+		return map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  fmt.Sprintf("%s %s released", rel.Project, rel.Version),
+			"title":    fmt.Sprintf("%s %s", rel.Project, rel.Version),
+			"text":     text,
+		}
+	})
+}
+
+// matrixBackend sends an m.room.message event to BUMP_MATRIX_ROOM on
+// BUMP_MATRIX_HOMESERVER, authenticated with BUMP_MATRIX_TOKEN.
+func matrixBackend(_ config, env []string) (announcer, error) {
+	homeserver := getenv(env, "BUMP_MATRIX_HOMESERVER")
+	room := getenv(env, "BUMP_MATRIX_ROOM")
+	token := getenv(env, "BUMP_MATRIX_TOKEN")
+	if homeserver == "" && room == "" {
+		return nil, nil
+	}
+	if homeserver == "" || room == "" || token == "" {
+		return nil, fmt.Errorf("matrix announcer: BUMP_MATRIX_HOMESERVER, BUMP_MATRIX_ROOM and BUMP_MATRIX_TOKEN must be set")
+	}
+	tmpl, err := notificationTemplate(env, "matrix")
+	if err != nil {
+		return nil, fmt.Errorf("matrix announcer: %w", err)
+	}
+	txn := make([]byte, 8)
+	_, _ = rand.Read(txn)
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/bump-%s",
+		strings.TrimSuffix(homeserver, "/"), url.PathEscape(room), hex.EncodeToString(txn))
+	return &webhookAnnouncer{
+		backend:  "matrix",
+		method:   http.MethodPut,
+		url:      endpoint,
+		token:    token,
+		template: tmpl,
+		payload: func(_ release, text string) any {
+			return map[string]string{"msgtype": "m.text", "body": text}
+		},
+	}, nil
+}
+
+func (w *webhookAnnouncer) name() string {
+	return w.backend
+}
+
+func (w *webhookAnnouncer) announce(ctx context.Context, rel release) error {
+	text, err := renderTemplate(w.backend, w.template, rel)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(w.payload(rel, strings.TrimSpace(text)))
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, w.method, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.token != "" {
+		req.Header.Set("Authorization", "Bearer "+w.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected response %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingServer captures the requests sent to it
+type recordingServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []recordedRequest
+}
+
+type recordedRequest struct {
+	method string
+	path   string
+	auth   string
+	body   map[string]any
+}
+
+func newRecordingServer(t *testing.T, status int) *recordingServer {
+	rs := &recordingServer{}
+	rs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		rs.mu.Lock()
+		rs.requests = append(rs.requests, recordedRequest{
+			method: r.Method,
+			path:   r.URL.Path,
+			auth:   r.Header.Get("Authorization"),
+			body:   body,
+		})
+		rs.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(rs.Close)
+	return rs
+}
+
+func (rs *recordingServer) received() []recordedRequest {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return append([]recordedRequest(nil), rs.requests...)
+}
+
+func TestWebhookAnnouncers(t *testing.T) {
+	srv := newRecordingServer(t, http.StatusOK)
+	rel := release{Project: "widget", Previous: "v1.0.0", Version: "v1.1.0", Changes: []string{"feat: add knobs"}}
+
+	tests := []struct {
+		name      string
+		env       []string
+		method    string
+		path      string
+		auth      string
+		field     string
+		wantTexts []string
+	}{
+		{
+			name:      "slack",
+			env:       []string{"BUMP_SLACK_WEBHOOK=" + srv.URL + "/slack"},
+			method:    http.MethodPost,
+			path:      "/slack",
+			field:     "text",
+			wantTexts: []string{"widget v1.1.0 released", "• feat: add knobs"},
+		},
+		{
+			name:      "discord",
+			env:       []string{"BUMP_DISCORD_WEBHOOK=" + srv.URL + "/discord"},
+			method:    http.MethodPost,
+			path:      "/discord",
+			field:     "content",
+			wantTexts: []string{"widget v1.1.0 released"},
+		},
+		{
+			name:      "teams",
+			env:       []string{"BUMP_TEAMS_WEBHOOK=" + srv.URL + "/teams"},
+			method:    http.MethodPost,
+			path:      "/teams",
+			field:     "summary",
+			wantTexts: []string{"widget v1.1.0 released"},
+		},
+		{
+			name: "matrix",
+			env: []string{
+				"BUMP_MATRIX_HOMESERVER=" + srv.URL,
+				"BUMP_MATRIX_ROOM=!room:example.org",
+				"BUMP_MATRIX_TOKEN=secret",
+			},
+			method:    http.MethodPut,
+			path:      "/_matrix/client/v3/rooms/!room:example.org/send/m.room.message/",
+			auth:      "Bearer secret",
+			field:     "body",
+			wantTexts: []string{"widget v1.1.0 released"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			announcers, err := configuredAnnouncers(config{announce: true}, tt.env)
+			if err != nil {
+				t.Fatalf("configuredAnnouncers() error = %v", err)
+			}
+			if len(announcers) != 1 || announcers[0].name() != tt.name {
+				t.Fatalf("Expected only the %s announcer, got %v", tt.name, announcers)
+			}
+			before := len(srv.received())
+			var output bytes.Buffer
+			err = announce(context.Background(), announcers, &output, rel)
+			if err != nil {
+				t.Fatalf("announce() error = %v", err)
+			}
+
+			requests := srv.received()
+			if len(requests) != before+1 {
+				t.Fatalf("Expected one request, got %d", len(requests)-before)
+			}
+			req := requests[len(requests)-1]
+			if req.method != tt.method {
+				t.Errorf("Expected method %s, got %s", tt.method, req.method)
+			}
+			if !strings.HasPrefix(req.path, tt.path) {
+				t.Errorf("Expected path with prefix %q, got %q", tt.path, req.path)
+			}
+			if req.auth != tt.auth {
+				t.Errorf("Expected Authorization %q, got %q", tt.auth, req.auth)
+			}
+			text, _ := req.body[tt.field].(string)
+			for _, want := range tt.wantTexts {
+				if !strings.Contains(text, want) {
+					t.Errorf("Expected %s to contain %q, got %q", tt.field, want, text)
+				}
+			}
+		})
+	}
+}
+
+func TestAnnounceSelection(t *testing.T) {
+	env := []string{
+		"BUMP_SLACK_WEBHOOK=http://example.invalid/slack",
+		"BUMP_DISCORD_WEBHOOK=http://example.invalid/discord",
+		"BUMP_ANNOUNCE=discord",
+	}
+	announcers, err := configuredAnnouncers(config{announce: true}, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(announcers) != 1 || announcers[0].name() != "discord" {
+		t.Errorf("Expected BUMP_ANNOUNCE to select only discord, got %v", announcers)
+	}
+}
+
+func TestAnnounceReportsFailures(t *testing.T) {
+	failing := newRecordingServer(t, http.StatusForbidden)
+	working := newRecordingServer(t, http.StatusNoContent)
+	env := []string{
+		"BUMP_SLACK_WEBHOOK=" + failing.URL,
+		"BUMP_DISCORD_WEBHOOK=" + working.URL,
+	}
+	announcers, err := configuredAnnouncers(config{announce: true}, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	err = announce(context.Background(), announcers, &output, release{Project: "widget", Version: "v2.0.0"})
+	if err == nil || !strings.Contains(err.Error(), "slack: unexpected response 403") {
+		t.Fatalf("Expected slack failure to be reported, got: %v", err)
+	}
+	if len(working.received()) != 1 {
+		t.Errorf("Expected the discord announcement to be sent despite the slack failure")
+	}
+	if !strings.Contains(output.String(), "Announced v2.0.0 via discord") {
+		t.Errorf("Expected output to report the discord announcement, got: %s", output.String())
+	}
+}
+
+func TestMatrixBackendRequiresToken(t *testing.T) {
+	_, err := matrixBackend(config{}, []string{"BUMP_MATRIX_HOMESERVER=https://matrix.example.org", "BUMP_MATRIX_ROOM=!r:example.org"})
+	if err == nil || !strings.Contains(err.Error(), "BUMP_MATRIX_TOKEN") {
+		t.Errorf("Expected missing token error, got: %v", err)
+	}
+}